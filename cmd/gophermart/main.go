@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"github.com/Gerfey/gophermart/internal/app"
 	"github.com/Gerfey/gophermart/internal/config"
 	"github.com/Gerfey/gophermart/internal/handler"
 	"github.com/Gerfey/gophermart/internal/repository"
@@ -11,7 +12,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
 func main() {
@@ -31,25 +31,50 @@ func main() {
 		log.Fatalf("Ошибка подключения к базе данных: %s", err.Error())
 	}
 
+	lifecycle := app.NewLifecycle()
+	lifecycle.OnClose("postgres", func(ctx context.Context) error {
+		db.Close()
+		return nil
+	})
+
 	repos := repository.NewRepository(db)
 
 	services := service.NewService(repos, cfg)
 
-	handlers := handler.NewHandler(services)
+	if cfg.Mode == config.ModeReconcile {
+		code := reconcile(services, cfg)
+		db.Close()
+		os.Exit(code)
+	}
+
+	if cfg.RunsServer() {
+		handlers := handler.NewHandler(services)
+		handlers.SetTrustedProxies(cfg.TrustedProxies)
 
-	server := handler.NewServer(cfg.RunAddress, handlers.InitRoutes())
+		server := handler.NewServer(cfg.RunAddress, handlers.InitRoutes())
+		server.RegisterOnShutdown(handlers.CloseStreams)
+		lifecycle.OnStop("http", server.Shutdown)
 
-	go func() {
-		if err := server.Run(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Ошибка запуска HTTP-сервера: %s", err.Error())
-		}
-	}()
+		go func() {
+			if err := server.Run(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Ошибка запуска HTTP-сервера: %s", err.Error())
+			}
+		}()
 
-	log.Infof("Сервер запущен на адресе %s", cfg.RunAddress)
+		log.Infof("Сервер запущен на адресе %s", cfg.RunAddress)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	go services.Orders.ProcessOrdersBackground(ctx)
-	defer cancel()
+		lifecycle.Go("events", services.Events.Run)
+	}
+
+	if cfg.RunsWorker() {
+		log.Infof("Фоновая обработка заказов запущена, идентификатор обработчика %s", cfg.WorkerID)
+		lifecycle.Go("orders", services.Orders.ProcessOrdersBackground)
+		lifecycle.Go("webhooks", services.Webhooks.RunDispatcher)
+		lifecycle.Go("reservations", services.Balances.RunReservationSweeper)
+		lifecycle.Go("tiers", services.Tiers.RunTierRecalculation)
+		lifecycle.Go("finance", services.Finance.RunReportMaterialization)
+		lifecycle.Go("reconciliation", services.Reconciliation.RunReconciliation)
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
@@ -57,16 +82,29 @@ func main() {
 
 	log.Info("Начинаем остановку сервера")
 
-	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	if err := lifecycle.Shutdown(ctx); err != nil {
 		log.Errorf("Ошибка при завершении работы сервера: %s", err.Error())
 	}
 
-	cancel()
+	log.Info("Сервер остановлен")
+}
 
-	db.Close()
+// reconcile выполняет однократную сверку и возвращает код завершения: 1 при ошибке сверки,
+// 2 при неисправленных расхождениях.
+func reconcile(services *service.Service, cfg *config.Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	log.Info("Сервер остановлен")
+	run, err := services.Reconciliation.Reconcile(ctx, cfg.ReconciliationAutoFix)
+	if err != nil {
+		log.Errorf("Ошибка сверки с системой расчета: %s", err.Error())
+		return 1
+	}
+	if run.Discrepancies > run.Corrected {
+		return 2
+	}
+	return 0
 }
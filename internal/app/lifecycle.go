@@ -0,0 +1,115 @@
+// Package app управляет жизненным циклом приложения GopherMart:
+// запуском фоновых задач и корректной остановкой компонентов в нужном порядке.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Lifecycle отвечает за порядок остановки приложения.
+// Остановка выполняется в три этапа:
+//  1. вызываются stop-хуки (например, HTTP-сервер перестает принимать запросы);
+//  2. отменяется контекст фоновых задач и ожидается их завершение;
+//  3. вызываются close-хуки (например, закрытие пула соединений с базой данных).
+type Lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	stopHooks  []hook
+	closeHooks []hook
+}
+
+// NewLifecycle создает новый менеджер жизненного цикла приложения.
+func NewLifecycle() *Lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Lifecycle{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go запускает фоновую задачу. Задача должна завершиться после отмены переданного контекста,
+// дождавшись окончания начатой работы.
+func (l *Lifecycle) Go(name string, fn func(ctx context.Context)) {
+	l.wg.Add(1)
+
+	go func() {
+		defer l.wg.Done()
+
+		log.Infof("Запуск фоновой задачи %s", name)
+		fn(l.ctx)
+		log.Infof("Фоновая задача %s завершена", name)
+	}()
+}
+
+// OnStop регистрирует хук, вызываемый до остановки фоновых задач.
+// Хуки вызываются в порядке регистрации.
+func (l *Lifecycle) OnStop(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopHooks = append(l.stopHooks, hook{name: name, fn: fn})
+}
+
+// OnClose регистрирует хук, вызываемый после завершения всех фоновых задач.
+// Хуки вызываются в обратном порядке регистрации.
+func (l *Lifecycle) OnClose(name string, fn func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closeHooks = append(l.closeHooks, hook{name: name, fn: fn})
+}
+
+// Shutdown выполняет остановку приложения. Контекст ограничивает время ожидания
+// завершения фоновых задач: по его истечении close-хуки все равно вызываются.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	stopHooks := append([]hook(nil), l.stopHooks...)
+	closeHooks := append([]hook(nil), l.closeHooks...)
+	l.mu.Unlock()
+
+	var errs []error
+
+	for _, h := range stopHooks {
+		if err := h.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("ошибка остановки %s: %w", h.name, err))
+		}
+	}
+
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("Все фоновые задачи завершены")
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("фоновые задачи не завершились вовремя: %w", ctx.Err()))
+	}
+
+	for i := len(closeHooks) - 1; i >= 0; i-- {
+		h := closeHooks[i]
+		if err := h.fn(context.WithoutCancel(ctx)); err != nil {
+			errs = append(errs, fmt.Errorf("ошибка закрытия %s: %w", h.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
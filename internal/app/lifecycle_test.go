@@ -0,0 +1,66 @@
+package app_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleShutdownOrder(t *testing.T) {
+	lifecycle := app.NewLifecycle()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	lifecycle.OnClose("postgres", func(ctx context.Context) error {
+		record("close postgres")
+		return nil
+	})
+	lifecycle.OnStop("http", func(ctx context.Context) error {
+		record("stop http")
+		return nil
+	})
+	lifecycle.Go("orders", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		record("orders drained")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, lifecycle.Shutdown(ctx))
+	assert.Equal(t, []string{"stop http", "orders drained", "close postgres"}, events)
+}
+
+func TestLifecycleShutdownDeadline(t *testing.T) {
+	lifecycle := app.NewLifecycle()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	closed := false
+	lifecycle.OnClose("postgres", func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+	lifecycle.Go("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := lifecycle.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, closed)
+}
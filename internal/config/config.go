@@ -2,28 +2,203 @@ package config
 
 import (
 	"cmp"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	// ModeServe запускает HTTP API и, если не указан флаг -no-worker, фоновую обработку заказов.
+	ModeServe = "serve"
+	// ModeWorker запускает только фоновую обработку заказов без HTTP API.
+	ModeWorker = "worker"
+	// ModeReconcile выполняет однократную сверку с системой расчета и завершает работу.
+	ModeReconcile = "reconcile"
+
+	defaultShutdownTimeout = 10 * time.Second
+
+	// defaultUnregisteredOrderTTL время, после которого заказ, неизвестный системе расчета,
+	// переводится в конечный статус UNREGISTERED.
+	defaultUnregisteredOrderTTL = 24 * time.Hour
+	// defaultUnregisteredOrderMaxBackoff верхняя граница интервала между проверками такого заказа.
+	defaultUnregisteredOrderMaxBackoff = 30 * time.Minute
+
+	// defaultBalanceReservationTTL время, после которого неподтвержденный резерв баллов снимается.
+	defaultBalanceReservationTTL = 15 * time.Minute
+	// defaultTierRecalcInterval интервал пересчета уровней лояльности пользователей.
+	defaultTierRecalcInterval = time.Hour
+	// defaultFinanceReportDelay время после полуночи UTC, в которое рассчитываются финансовые отчеты за прошедшие сутки.
+	defaultFinanceReportDelay = time.Hour
+	// defaultReconciliationInterval интервал плановой сверки с системой расчета.
+	defaultReconciliationInterval = 24 * time.Hour
+	// defaultReconciliationSampleSize число обработанных заказов, повторно запрашиваемых в системе расчета за одну сверку.
+	defaultReconciliationSampleSize = 1000
+
+	// RateLimitBackendMemory хранит счетчики запросов в памяти процесса, подходит для одного экземпляра.
+	RateLimitBackendMemory = "memory"
+	// RateLimitBackendPostgres хранит счетчики в Postgres, общие для всех экземпляров сервиса.
+	RateLimitBackendPostgres = "postgres"
+
+	// Имена ограничений частоты запросов, задаваемых в RATE_LIMITS.
+	RateLimitRegister = "register"
+	RateLimitLogin    = "login"
+	RateLimitOrders   = "orders"
+	RateLimitWithdraw = "withdraw"
+	RateLimitTransfer = "transfer"
+
+	// Типы правил списания баллов, задаваемых в WITHDRAWAL_RULES.
+	WithdrawalRuleMinAmount   = "min_amount"
+	WithdrawalRuleMaxPerOrder = "max_per_order"
+	WithdrawalRuleDailyCap    = "daily_cap"
+	WithdrawalRuleMonthlyCap  = "monthly_cap"
+	WithdrawalRuleCoolingOff  = "cooling_off"
+
+	// withdrawalRuleMaxOrderShare ограничивал списание долей стоимости заказа. Стоимость передавал
+	// клиент в запросе на списание, и проверить ее было не по чему, поэтому правило отключено,
+	// а конфигурация с ним отклоняется, чтобы лимит не пропадал незаметно.
+	withdrawalRuleMaxOrderShare = "max_order_share"
+
+	// Типы правил проверки номера заказа, задаваемых в ORDER_NUMBER_RULES.
+	OrderNumberRuleLuhn   = "luhn"
+	OrderNumberRuleLength = "length"
+	OrderNumberRulePrefix = "prefix"
+	OrderNumberRuleRegex  = "regex"
+)
+
+// RateLimit ограничение числа запросов Limit за окно Window. Нулевой Limit отключает ограничение.
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+var defaultRateLimits = map[string]RateLimit{
+	RateLimitRegister: {Limit: 5, Window: time.Minute},
+	RateLimitLogin:    {Limit: 10, Window: time.Minute},
+	RateLimitOrders:   {Limit: 60, Window: time.Minute},
+	RateLimitWithdraw: {Limit: 30, Window: time.Minute},
+	RateLimitTransfer: {Limit: 30, Window: time.Minute},
+}
+
+// OrderNumberRule правило проверки номера заказа. Min и Max используются правилом length,
+// Prefix — правилом prefix, Pattern — правилом regex.
+type OrderNumberRule struct {
+	Type    string `json:"type"`
+	Min     int    `json:"min,omitempty"`
+	Max     int    `json:"max,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// WithdrawalRule правило списания баллов. Amount используется правилами min_amount, max_per_order,
+// daily_cap и monthly_cap, Period — правилом cooling_off.
+type WithdrawalRule struct {
+	Type   string  `json:"type"`
+	Amount float64 `json:"amount,omitempty"`
+	Period string  `json:"period,omitempty"`
+}
+
+// LoyaltyTier уровень лояльности. Пользователь получает уровень с наибольшим порогом Threshold,
+// не превышающим сумму его начислений за последние 12 месяцев. Начисление системы расчета
+// умножается на Multiplier, и к нему добавляется Bonus.
+type LoyaltyTier struct {
+	Name       string  `json:"name"`
+	Threshold  float64 `json:"threshold"`
+	Multiplier float64 `json:"multiplier"`
+	Bonus      float64 `json:"bonus,omitempty"`
+}
+
+// Tenant витрина, обслуживаемая общим развертыванием. Арендатор определяется по заголовку
+// X-Tenant или по одному из хостов Hosts; пустой AccrualSystemAddress означает общую систему расчета.
+type Tenant struct {
+	Name                 string   `json:"name"`
+	AccrualSystemAddress string   `json:"accrual_system_address,omitempty"`
+	Hosts                []string `json:"hosts,omitempty"`
+}
+
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// StaffToken служебный токен API сотрудника с его именем и ролью.
+type StaffToken struct {
+	Name  string
+	Role  model.Role
+	Token string
+}
+
 type Config struct {
-	RunAddress           string
-	DatabaseURI          string
-	AccrualSystemAddress string
-	JWTSigningKey        string
+	Mode                        string
+	NoWorker                    bool
+	WorkerID                    string
+	RunAddress                  string
+	DatabaseURI                 string
+	AccrualSystemAddress        string
+	JWTSigningKey               string
+	ShutdownTimeout             time.Duration
+	AdminAPIToken               string
+	StaffTokens                 []StaffToken
+	RateLimitBackend            string
+	RateLimits                  map[string]RateLimit
+	TrustedProxies              []string
+	UnregisteredOrderTTL        time.Duration
+	UnregisteredOrderMaxBackoff time.Duration
+	OrderNumberRules            map[string][]OrderNumberRule
+	Tenants                     []Tenant
+	TransferDailyLimit          float64
+	BalanceReservationTTL       time.Duration
+	WithdrawalRules             map[string][]WithdrawalRule
+	LoyaltyTiers                []LoyaltyTier
+	TierRecalcInterval          time.Duration
+	ReferralReferrerBonus       float64
+	ReferralRefereeBonus        float64
+	ReferralMaxRewards          int
+	ReferralMaxPerIP            int
+	FinanceReportDelay          time.Duration
+	ReconciliationInterval      time.Duration
+	ReconciliationSampleSize    int
+	ReconciliationAutoFix       bool
+}
+
+// RunsWorker сообщает, должен ли текущий процесс обрабатывать заказы в фоне.
+func (c *Config) RunsWorker() bool {
+	return c.Mode == ModeWorker || (c.Mode == ModeServe && !c.NoWorker)
+}
+
+// RunsServer сообщает, должен ли текущий процесс обслуживать HTTP API.
+func (c *Config) RunsServer() bool {
+	return c.Mode == ModeServe
 }
 
 func LoadConfig() (*Config, error) {
 	loadDotEnvFile()
 
-	cfg := &Config{}
+	cfg := &Config{Mode: ModeServe}
+
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cfg.Mode, args = args[0], args[1:]
+	}
+
+	if cfg.Mode != ModeServe && cfg.Mode != ModeWorker && cfg.Mode != ModeReconcile {
+		return nil, fmt.Errorf("неизвестный режим запуска %q, ожидается %s, %s или %s", cfg.Mode, ModeServe, ModeWorker, ModeReconcile)
+	}
 
 	flag.StringVar(&cfg.RunAddress, "a", "", "адрес и порт запуска сервиса")
 	flag.StringVar(&cfg.DatabaseURI, "d", "", "URI подключения к базе данных")
 	flag.StringVar(&cfg.AccrualSystemAddress, "r", "", "адрес системы расчета начислений")
-	flag.Parse()
+	flag.BoolVar(&cfg.NoWorker, "no-worker", false, "не запускать фоновую обработку заказов в режиме serve")
+	flag.BoolVar(&cfg.ReconciliationAutoFix, "fix", false, "исправлять расхождения, найденные сверкой")
+	flag.CommandLine.Parse(args)
 
 	viper.AutomaticEnv()
 
@@ -31,15 +206,402 @@ func LoadConfig() (*Config, error) {
 	viper.BindEnv("DATABASE_URI")
 	viper.BindEnv("ACCRUAL_SYSTEM_ADDRESS")
 	viper.BindEnv("JWT_SIGNING_KEY")
+	viper.BindEnv("SHUTDOWN_TIMEOUT")
+	viper.BindEnv("WORKER_ID")
+	viper.BindEnv("ADMIN_API_TOKEN")
+	viper.BindEnv("STAFF_API_TOKENS")
+	viper.BindEnv("RATE_LIMIT_BACKEND")
+	viper.BindEnv("RATE_LIMITS")
+	viper.BindEnv("TRUSTED_PROXIES")
+	viper.BindEnv("UNREGISTERED_ORDER_TTL")
+	viper.BindEnv("UNREGISTERED_ORDER_MAX_BACKOFF")
+	viper.BindEnv("ORDER_NUMBER_RULES")
+	viper.BindEnv("TENANTS")
+	viper.BindEnv("TRANSFER_DAILY_LIMIT")
+	viper.BindEnv("BALANCE_RESERVATION_TTL")
+	viper.BindEnv("WITHDRAWAL_RULES")
+	viper.BindEnv("LOYALTY_TIERS")
+	viper.BindEnv("TIER_RECALC_INTERVAL")
+	viper.BindEnv("REFERRAL_REFERRER_BONUS")
+	viper.BindEnv("REFERRAL_REFEREE_BONUS")
+	viper.BindEnv("REFERRAL_MAX_REWARDS")
+	viper.BindEnv("REFERRAL_MAX_PER_IP")
+	viper.BindEnv("FINANCE_REPORT_DELAY")
+	viper.BindEnv("RECONCILIATION_INTERVAL")
+	viper.BindEnv("RECONCILIATION_SAMPLE_SIZE")
+	viper.BindEnv("RECONCILIATION_AUTO_FIX")
 
 	cfg.RunAddress = cmp.Or(cfg.RunAddress, viper.GetString("RUN_ADDRESS"), ":8080")
 	cfg.DatabaseURI = cmp.Or(cfg.DatabaseURI, viper.GetString("DATABASE_URI"))
 	cfg.AccrualSystemAddress = cmp.Or(cfg.AccrualSystemAddress, viper.GetString("ACCRUAL_SYSTEM_ADDRESS"))
 	cfg.JWTSigningKey = cmp.Or(cfg.JWTSigningKey, viper.GetString("JWT_SIGNING_KEY"))
+	cfg.ShutdownTimeout = cmp.Or(viper.GetDuration("SHUTDOWN_TIMEOUT"), defaultShutdownTimeout)
+	cfg.WorkerID = cmp.Or(viper.GetString("WORKER_ID"), defaultWorkerID())
+	cfg.AdminAPIToken = viper.GetString("ADMIN_API_TOKEN")
+	cfg.UnregisteredOrderTTL = cmp.Or(viper.GetDuration("UNREGISTERED_ORDER_TTL"), defaultUnregisteredOrderTTL)
+	cfg.UnregisteredOrderMaxBackoff = cmp.Or(viper.GetDuration("UNREGISTERED_ORDER_MAX_BACKOFF"), defaultUnregisteredOrderMaxBackoff)
+	cfg.BalanceReservationTTL = cmp.Or(viper.GetDuration("BALANCE_RESERVATION_TTL"), defaultBalanceReservationTTL)
+	cfg.TierRecalcInterval = cmp.Or(viper.GetDuration("TIER_RECALC_INTERVAL"), defaultTierRecalcInterval)
+
+	staffTokens, err := parseStaffTokens(viper.GetString("STAFF_API_TOKENS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.StaffTokens = staffTokens
+
+	cfg.RateLimitBackend = cmp.Or(viper.GetString("RATE_LIMIT_BACKEND"), RateLimitBackendMemory)
+	if cfg.RateLimitBackend != RateLimitBackendMemory && cfg.RateLimitBackend != RateLimitBackendPostgres {
+		return nil, fmt.Errorf("неизвестное хранилище ограничений запросов %q, ожидается %s или %s", cfg.RateLimitBackend, RateLimitBackendMemory, RateLimitBackendPostgres)
+	}
+
+	rateLimits, err := parseRateLimits(viper.GetString("RATE_LIMITS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.RateLimits = rateLimits
+
+	trustedProxies, err := parseTrustedProxies(viper.GetString("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	orderNumberRules, err := parseOrderNumberRules(viper.GetString("ORDER_NUMBER_RULES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.OrderNumberRules = orderNumberRules
+
+	withdrawalRules, err := parseWithdrawalRules(viper.GetString("WITHDRAWAL_RULES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.WithdrawalRules = withdrawalRules
+
+	loyaltyTiers, err := parseLoyaltyTiers(viper.GetString("LOYALTY_TIERS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.LoyaltyTiers = loyaltyTiers
+
+	tenants, err := parseTenants(viper.GetString("TENANTS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Tenants = tenants
+
+	cfg.TransferDailyLimit = viper.GetFloat64("TRANSFER_DAILY_LIMIT")
+	if cfg.TransferDailyLimit < 0 {
+		return nil, fmt.Errorf("дневной лимит переводов не может быть отрицательным: %v", cfg.TransferDailyLimit)
+	}
+
+	cfg.ReferralReferrerBonus = viper.GetFloat64("REFERRAL_REFERRER_BONUS")
+	cfg.ReferralRefereeBonus = viper.GetFloat64("REFERRAL_REFEREE_BONUS")
+	if cfg.ReferralReferrerBonus < 0 || cfg.ReferralRefereeBonus < 0 {
+		return nil, fmt.Errorf("реферальные бонусы не могут быть отрицательными")
+	}
+
+	cfg.ReferralMaxRewards = viper.GetInt("REFERRAL_MAX_REWARDS")
+	cfg.ReferralMaxPerIP = viper.GetInt("REFERRAL_MAX_PER_IP")
+	if cfg.ReferralMaxRewards < 0 || cfg.ReferralMaxPerIP < 0 {
+		return nil, fmt.Errorf("ограничения реферальной программы не могут быть отрицательными")
+	}
+
+	cfg.FinanceReportDelay = cmp.Or(viper.GetDuration("FINANCE_REPORT_DELAY"), defaultFinanceReportDelay)
+	if cfg.FinanceReportDelay < 0 || cfg.FinanceReportDelay >= 24*time.Hour {
+		return nil, fmt.Errorf("FINANCE_REPORT_DELAY должен быть в пределах суток")
+	}
+
+	// Нулевые значения сверки имеют смысл: интервал 0 отключает плановую сверку,
+	// размер выборки 0 означает проверку всех обработанных заказов.
+	cfg.ReconciliationInterval = defaultReconciliationInterval
+	if viper.IsSet("RECONCILIATION_INTERVAL") {
+		cfg.ReconciliationInterval = viper.GetDuration("RECONCILIATION_INTERVAL")
+	}
+	cfg.ReconciliationSampleSize = defaultReconciliationSampleSize
+	if viper.IsSet("RECONCILIATION_SAMPLE_SIZE") {
+		cfg.ReconciliationSampleSize = viper.GetInt("RECONCILIATION_SAMPLE_SIZE")
+	}
+	if cfg.ReconciliationInterval < 0 || cfg.ReconciliationSampleSize < 0 {
+		return nil, fmt.Errorf("параметры сверки не могут быть отрицательными")
+	}
+	cfg.ReconciliationAutoFix = cfg.ReconciliationAutoFix || viper.GetBool("RECONCILIATION_AUTO_FIX")
 
 	return cfg, nil
 }
 
+// parseTenants разбирает список арендаторов в формате JSON, например
+// [{"name":"shop2","accrual_system_address":"http://accrual-shop2:8080","hosts":["shop2.example.com"]}].
+func parseTenants(value string) ([]Tenant, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal([]byte(value), &tenants); err != nil {
+		return nil, fmt.Errorf("некорректный формат TENANTS: %w", err)
+	}
+
+	names := make(map[string]bool, len(tenants))
+	hosts := make(map[string]string)
+	for _, tenant := range tenants {
+		if !tenantNamePattern.MatchString(tenant.Name) {
+			return nil, fmt.Errorf("некорректное имя арендатора %q, допускаются строчные латинские буквы, цифры, _ и -", tenant.Name)
+		}
+		if names[tenant.Name] {
+			return nil, fmt.Errorf("арендатор %q указан несколько раз", tenant.Name)
+		}
+		names[tenant.Name] = true
+
+		for _, host := range tenant.Hosts {
+			host = strings.ToLower(host)
+			if owner, ok := hosts[host]; ok {
+				return nil, fmt.Errorf("хост %q указан у арендаторов %q и %q", host, owner, tenant.Name)
+			}
+			hosts[host] = tenant.Name
+		}
+	}
+
+	return tenants, nil
+}
+
+// parseOrderNumberRules разбирает правила проверки номеров заказов по арендаторам в формате JSON,
+// например {"default":[{"type":"luhn"}],"shop2":[{"type":"regex","pattern":"^SH[0-9A-Z]{8}$"}]}.
+// Правила одного арендатора применяются все вместе.
+func parseOrderNumberRules(value string) (map[string][]OrderNumberRule, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var rules map[string][]OrderNumberRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("некорректный формат ORDER_NUMBER_RULES: %w", err)
+	}
+
+	for tenant, tenantRules := range rules {
+		if len(tenantRules) == 0 {
+			return nil, fmt.Errorf("не заданы правила проверки номера заказа для %q", tenant)
+		}
+
+		for _, rule := range tenantRules {
+			if err := validateOrderNumberRule(rule); err != nil {
+				return nil, fmt.Errorf("некорректное правило проверки номера заказа для %q: %w", tenant, err)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func validateOrderNumberRule(rule OrderNumberRule) error {
+	switch rule.Type {
+	case OrderNumberRuleLuhn:
+		return nil
+	case OrderNumberRuleLength:
+		if rule.Min < 0 || rule.Max < 0 || (rule.Max > 0 && rule.Min > rule.Max) {
+			return fmt.Errorf("некорректные границы длины %d..%d", rule.Min, rule.Max)
+		}
+		return nil
+	case OrderNumberRulePrefix:
+		if rule.Prefix == "" {
+			return fmt.Errorf("не задан префикс")
+		}
+		return nil
+	case OrderNumberRuleRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("некорректное регулярное выражение %q: %w", rule.Pattern, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестный тип правила %q", rule.Type)
+	}
+}
+
+// parseWithdrawalRules разбирает правила списания баллов по арендаторам в формате JSON, например
+// {"default":[{"type":"min_amount","amount":10},{"type":"cooling_off","period":"72h"}]}.
+func parseWithdrawalRules(value string) (map[string][]WithdrawalRule, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var rules map[string][]WithdrawalRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("некорректный формат WITHDRAWAL_RULES: %w", err)
+	}
+
+	for tenant, tenantRules := range rules {
+		for _, rule := range tenantRules {
+			if err := validateWithdrawalRule(rule); err != nil {
+				return nil, fmt.Errorf("некорректное правило списания для %q: %w", tenant, err)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func validateWithdrawalRule(rule WithdrawalRule) error {
+	switch rule.Type {
+	case WithdrawalRuleMinAmount, WithdrawalRuleMaxPerOrder, WithdrawalRuleDailyCap, WithdrawalRuleMonthlyCap:
+		if rule.Amount <= 0 {
+			return fmt.Errorf("сумма правила %s должна быть положительной", rule.Type)
+		}
+		return nil
+	case withdrawalRuleMaxOrderShare:
+		return fmt.Errorf("правило %s не поддерживается: стоимость заказа не проверяется сервисом", rule.Type)
+	case WithdrawalRuleCoolingOff:
+		period, err := time.ParseDuration(rule.Period)
+		if err != nil || period <= 0 {
+			return fmt.Errorf("некорректный период правила %s %q", rule.Type, rule.Period)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестный тип правила %q", rule.Type)
+	}
+}
+
+// parseLoyaltyTiers разбирает уровни лояльности в формате JSON, например
+// [{"name":"BRONZE","threshold":0,"multiplier":1},{"name":"SILVER","threshold":1000,"multiplier":1.1}].
+// Уровни сортируются по порогу; первый уровень должен начинаться с нулевого порога.
+func parseLoyaltyTiers(value string) ([]LoyaltyTier, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var tiers []LoyaltyTier
+	if err := json.Unmarshal([]byte(value), &tiers); err != nil {
+		return nil, fmt.Errorf("некорректный формат LOYALTY_TIERS: %w", err)
+	}
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].Threshold < tiers[j].Threshold
+	})
+
+	names := make(map[string]bool, len(tiers))
+	for i, tier := range tiers {
+		if strings.TrimSpace(tier.Name) == "" {
+			return nil, fmt.Errorf("не указано имя уровня лояльности")
+		}
+		if names[tier.Name] {
+			return nil, fmt.Errorf("уровень лояльности %q указан несколько раз", tier.Name)
+		}
+		names[tier.Name] = true
+
+		if i > 0 && tier.Threshold == tiers[i-1].Threshold {
+			return nil, fmt.Errorf("у уровней %q и %q одинаковый порог %v", tiers[i-1].Name, tier.Name, tier.Threshold)
+		}
+		if tier.Multiplier < 1 {
+			return nil, fmt.Errorf("множитель уровня %q должен быть не меньше 1", tier.Name)
+		}
+		if tier.Bonus < 0 {
+			return nil, fmt.Errorf("надбавка уровня %q не может быть отрицательной", tier.Name)
+		}
+	}
+
+	if tiers[0].Threshold != 0 {
+		return nil, fmt.Errorf("порог начального уровня лояльности %q должен быть равен 0", tiers[0].Name)
+	}
+
+	return tiers, nil
+}
+
+// parseRateLimits разбирает ограничения в формате "имя=число/окно,имя=число/окно", например
+// "login=10/1m,orders=100/1m", и дополняет ими значения по умолчанию.
+func parseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit, len(defaultRateLimits))
+	for name, limit := range defaultRateLimits {
+		limits[name] = limit
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректное ограничение запросов %q, ожидается формат имя=число/окно", item)
+		}
+
+		count, window, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("некорректное ограничение запросов %q, ожидается формат имя=число/окно", item)
+		}
+
+		limit, err := strconv.Atoi(count)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("некорректное число запросов в ограничении %q", item)
+		}
+
+		duration, err := time.ParseDuration(window)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("некорректное окно в ограничении %q", item)
+		}
+
+		limits[strings.TrimSpace(name)] = RateLimit{Limit: limit, Window: duration}
+	}
+
+	return limits, nil
+}
+
+// parseTrustedProxies разбирает список IP-адресов и подсетей доверенных прокси через запятую.
+// Пустое значение означает, что прокси не доверяет никто.
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("неверный адрес доверенного прокси %q", proxy)
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// parseStaffTokens разбирает список токенов сотрудников в формате "имя:роль:токен,имя:роль:токен".
+// Ошибки указывают номер записи в списке, а не ее текст, чтобы токен не попал в журнал.
+func parseStaffTokens(value string) ([]StaffToken, error) {
+	var tokens []StaffToken
+
+	for i, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("некорректная запись %d в STAFF_API_TOKENS, ожидается формат имя:роль:токен", i+1)
+		}
+
+		role := model.Role(parts[1])
+		if !slices.Contains(model.Roles, role) {
+			return nil, fmt.Errorf("неизвестная роль в записи %d STAFF_API_TOKENS, допустимые роли: %v", i+1, model.Roles)
+		}
+
+		tokens = append(tokens, StaffToken{Name: parts[0], Role: role, Token: parts[2]})
+	}
+
+	return tokens, nil
+}
+
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "gophermart"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
 func loadDotEnvFile() {
 	if err := godotenv.Load(); err != nil {
 		logrus.Warnf("Не удалось загрузить .env файл из корня проекта: %v", err)
@@ -2,6 +2,8 @@ package errors
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -9,4 +11,81 @@ var (
 	ErrUserBalanceNotFound = errors.New("баланс пользователя не найден")
 	ErrInvalidLuhn         = errors.New("номер заказа не соответствует алгоритму Луна")
 	ErrOrderAlreadyExists  = errors.New("заказ уже зарегистрирован другим пользователем")
+	ErrWebhookNotFound     = errors.New("подписка или доставка вебхука не найдена")
+	ErrInvalidWebhook      = errors.New("некорректные параметры подписки на вебхуки")
+	ErrInvalidStaffToken   = errors.New("неверный токен сотрудника")
+	ErrUserNotFound        = errors.New("пользователь не найден")
+	ErrOrderNotFound       = errors.New("заказ не найден")
+	ErrOrderNotRequeueable = errors.New("обработанный заказ нельзя отправить на повторную проверку")
+	ErrInvalidAdjustment   = errors.New("некорректная корректировка баланса")
+	ErrInvalidRole         = errors.New("неизвестная роль")
+	ErrInvalidExportFormat = errors.New("неподдерживаемый формат выгрузки")
+	ErrInvalidPeriod       = errors.New("некорректный период")
+	ErrInvalidBatch        = errors.New("некорректный пакет заказов")
+	ErrInvalidOrderNumber  = errors.New("некорректный номер заказа")
+	ErrUnknownTenant       = errors.New("неизвестный арендатор")
+	ErrTransferToSelf      = errors.New("нельзя перевести баллы самому себе")
+	ErrTransferLimit       = errors.New("превышен дневной лимит переводов")
+	ErrIdempotencyConflict = errors.New("ключ идемпотентности уже использован для другого запроса")
+	ErrReservationNotFound = errors.New("резерв баллов не найден")
+	ErrReservationClosed   = errors.New("резерв баллов уже завершен")
+	ErrWithdrawalRejected  = errors.New("списание нарушает правила")
+	ErrCampaignNotFound    = errors.New("кампания не найдена")
+	ErrInvalidCampaign     = errors.New("некорректные параметры кампании")
+	ErrInvalidReferralCode = errors.New("неверный реферальный код")
+	ErrUserDeleted         = errors.New("учетная запись удалена")
+	ErrReconciliationStale = errors.New("данные изменились после сверки")
+	ErrAccrualRateLimited  = errors.New("превышен лимит запросов к системе расчета")
+	ErrOrderProcessed      = errors.New("заказ уже обработан")
 )
+
+// OrderNumberError описывает нарушенное правило проверки номера заказа.
+// Сопоставляется с ErrInvalidOrderNumber и, если задана, с причиной Err.
+type OrderNumberError struct {
+	Rule   string
+	Reason string
+	Err    error
+}
+
+func (e *OrderNumberError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidOrderNumber.Error(), e.Reason)
+}
+
+func (e *OrderNumberError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrInvalidOrderNumber}
+	}
+	return []error{ErrInvalidOrderNumber, e.Err}
+}
+
+// WithdrawalRuleError описывает нарушенное правило списания баллов.
+// Сопоставляется с ErrWithdrawalRejected.
+type WithdrawalRuleError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *WithdrawalRuleError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWithdrawalRejected.Error(), e.Reason)
+}
+
+func (e *WithdrawalRuleError) Unwrap() error {
+	return ErrWithdrawalRejected
+}
+
+// AccrualRateLimitError ответ 429 системы расчета. RetryAfter — пауза из заголовка Retry-After,
+// нулевая, если заголовок не указан или не разобран. Сопоставляется с ErrAccrualRateLimited.
+type AccrualRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccrualRateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s, повтор через %s", ErrAccrualRateLimited.Error(), e.RetryAfter)
+	}
+	return ErrAccrualRateLimited.Error()
+}
+
+func (e *AccrualRateLimitError) Unwrap() error {
+	return ErrAccrualRateLimited
+}
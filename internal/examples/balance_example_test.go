@@ -71,7 +71,7 @@ func Example_getBalance() {
 	
 	userID := int64(1)
 	
-	_ = repos.Balances.AddAccrual(context.Background(), userID, 500.0)
+	_ = repos.Balances.AddAccrual(context.Background(), userID, 0, 500.0, nil)
 	
 	balanceReq2 := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
 	balanceReq2.Header.Set("Authorization", token)
@@ -122,7 +122,7 @@ func Example_withdrawFromBalance() {
 	
 	userID := int64(1)
 	
-	_ = repos.Balances.AddAccrual(context.Background(), userID, 1000.0)
+	_ = repos.Balances.AddAccrual(context.Background(), userID, 0, 1000.0, nil)
 	
 	withdrawRequest := model.WithdrawRequest{
 		Order: "4561261212345467",
@@ -212,7 +212,7 @@ func Example_getWithdrawals() {
 	
 	userID := int64(1)
 	orderNumber := "4561261212345467"
-	_ = repos.Balances.AddAccrual(context.Background(), userID, 1000.0)
+	_ = repos.Balances.AddAccrual(context.Background(), userID, 0, 1000.0, nil)
 	
 	withdrawRequest := model.WithdrawRequest{
 		Order: orderNumber,
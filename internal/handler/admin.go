@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// searchUsers ищет пользователей по подстроке логина.
+// Параметр запроса login задает искомую подстроку, пустое значение возвращает последних пользователей.
+// Метод доступен по пути GET /api/admin/users
+//
+// Коды ответов:
+//   - 200 OK: возвращает список пользователей с балансами в формате JSON
+//   - 204 No Content: пользователи не найдены
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) searchUsers(c *gin.Context) {
+	users, err := h.services.Admin.SearchUsers(c, c.Query("login"))
+	if err != nil {
+		log.Errorf("Ошибка поиска пользователей: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка поиска пользователей")
+		return
+	}
+
+	if len(users) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// getUser возвращает пользователя вместе с его балансом.
+// Метод доступен по пути GET /api/admin/users/{id}
+//
+// Коды ответов:
+//   - 200 OK: возвращает пользователя в формате JSON
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getUser(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.services.Admin.GetUser(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения пользователя: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения пользователя")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// getUserOrders возвращает заказы пользователя.
+// Метод доступен по пути GET /api/admin/users/{id}/orders
+//
+// Коды ответов:
+//   - 200 OK: возвращает список заказов в формате JSON
+//   - 204 No Content: у пользователя нет заказов
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getUserOrders(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	orders, err := h.services.Admin.GetUserOrders(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения заказов пользователя: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения заказов")
+		return
+	}
+
+	if len(orders) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// getUserWithdrawals возвращает историю списаний пользователя.
+// Метод доступен по пути GET /api/admin/users/{id}/withdrawals
+//
+// Коды ответов:
+//   - 200 OK: возвращает список списаний в формате JSON
+//   - 204 No Content: у пользователя нет списаний
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getUserWithdrawals(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := h.services.Admin.GetUserWithdrawals(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения списаний пользователя: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения списаний")
+		return
+	}
+
+	if len(withdrawals) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, withdrawals)
+}
+
+// getBalanceAdjustments возвращает историю ручных корректировок баланса пользователя.
+// Метод доступен по пути GET /api/admin/users/{id}/balance/adjustments
+//
+// Коды ответов:
+//   - 200 OK: возвращает список корректировок в формате JSON
+//   - 204 No Content: корректировок нет
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getBalanceAdjustments(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	adjustments, err := h.services.Admin.GetBalanceAdjustments(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения корректировок баланса: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения корректировок")
+		return
+	}
+
+	if len(adjustments) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustments)
+}
+
+// adjustBalance применяет ручную корректировку баланса пользователя.
+// Принимает JSON с суммой (положительной для начисления, отрицательной для списания) и причиной.
+// Автором корректировки записывается сотрудник, выполнивший запрос.
+// Метод доступен по пути POST /api/admin/users/{id}/balance/adjustments
+//
+// Коды ответов:
+//   - 201 Created: корректировка применена
+//   - 400 Bad Request: неверный формат запроса, нулевая сумма или пустая причина
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 402 Payment Required: корректировка приводит к отрицательному балансу
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) adjustBalance(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.BalanceAdjustmentRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на корректировку баланса: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	adjustment, err := h.services.Admin.AdjustBalance(c, userID, input, actor)
+	if err != nil {
+		log.Errorf("Ошибка корректировки баланса: %s", err.Error())
+		respondAdminError(c, err, "ошибка корректировки баланса")
+		return
+	}
+
+	log.Infof("Сотрудник %s скорректировал баланс пользователя %d на %.2f", actor, userID, adjustment.Amount)
+
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// requeueOrder отправляет заказ на немедленную повторную проверку в системе начислений,
+// в том числе заказ в конечном статусе UNREGISTERED.
+// Метод доступен по пути POST /api/admin/orders/{number}/requeue
+//
+// Коды ответов:
+//   - 202 Accepted: заказ поставлен в очередь на проверку
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: заказ не найден
+//   - 409 Conflict: заказ уже обработан
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) requeueOrder(c *gin.Context) {
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.services.Admin.RequeueOrder(c, c.Param("number"), actor); err != nil {
+		log.Errorf("Ошибка повторной постановки заказа: %s", err.Error())
+		respondAdminError(c, err, "ошибка повторной постановки заказа")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// getUserRoles возвращает роли пользователя.
+// Метод доступен по пути GET /api/admin/users/{id}/roles
+//
+// Коды ответов:
+//   - 200 OK: возвращает список ролей в формате JSON
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getUserRoles(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.services.Admin.GetUserRoles(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения ролей пользователя: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения ролей")
+		return
+	}
+
+	c.JSON(http.StatusOK, model.UserRolesRequest{Roles: roles})
+}
+
+// setUserRoles заменяет набор ролей пользователя.
+// Принимает JSON со списком ролей (customer, support, finance, admin).
+// Новые роли действуют со следующего запроса пользователя, повторный вход не нужен.
+// Метод доступен по пути PUT /api/admin/users/{id}/roles
+//
+// Коды ответов:
+//   - 200 OK: роли изменены, возвращает итоговый список ролей
+//   - 400 Bad Request: неверный формат запроса или неизвестная роль
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) setUserRoles(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.UserRolesRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на изменение ролей: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	roles, err := h.services.Admin.SetUserRoles(c, userID, input.Roles, actor)
+	if err != nil {
+		log.Errorf("Ошибка изменения ролей пользователя: %s", err.Error())
+		respondAdminError(c, err, "ошибка изменения ролей")
+		return
+	}
+
+	log.Infof("Сотрудник %s изменил роли пользователя %d: %v", actor, userID, roles)
+
+	c.JSON(http.StatusOK, model.UserRolesRequest{Roles: roles})
+}
+
+// getRoleChanges возвращает журнал выдачи и отзыва ролей пользователя.
+// Метод доступен по пути GET /api/admin/users/{id}/roles/history
+//
+// Коды ответов:
+//   - 200 OK: возвращает список изменений в формате JSON
+//   - 204 No Content: роли пользователя не изменялись
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: пользователь не найден
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getRoleChanges(c *gin.Context) {
+	userID, ok := parseUserIDParam(c)
+	if !ok {
+		return
+	}
+
+	changes, err := h.services.Admin.GetRoleChanges(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения истории ролей: %s", err.Error())
+		respondAdminError(c, err, "ошибка получения истории ролей")
+		return
+	}
+
+	if len(changes) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+func parseUserIDParam(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "некорректный идентификатор пользователя")
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func respondAdminError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, customerrors.ErrUserNotFound),
+		errors.Is(err, customerrors.ErrUserBalanceNotFound),
+		errors.Is(err, customerrors.ErrOrderNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, customerrors.ErrOrderNotRequeueable):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, customerrors.ErrInvalidAdjustment),
+		errors.Is(err, customerrors.ErrInvalidRole):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, customerrors.ErrInsufficientFunds):
+		newErrorResponse(c, http.StatusPaymentRequired, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, message)
+	}
+}
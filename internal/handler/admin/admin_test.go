@@ -0,0 +1,278 @@
+package admin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockAdminService := mockservice.NewMockAdminService(ctrl)
+
+	services := &service.Service{
+		Users: mockUserService,
+		Staff: mockStaffService,
+		Admin: mockAdminService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken("admin_token").
+		Return(model.Staff{Name: "alice", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+	mockStaffService.EXPECT().
+		ParseStaffToken("support_token").
+		Return(model.Staff{Name: "bob", Role: model.RoleSupport}, nil).
+		AnyTimes()
+	mockStaffService.EXPECT().
+		ParseStaffToken(gomock.Any()).
+		Return(model.Staff{}, customerrors.ErrInvalidStaffToken).
+		AnyTimes()
+	mockUserService.EXPECT().
+		ParseToken("customer_jwt").
+		Return(&model.UserIdentity{UserID: 7, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+	mockUserService.EXPECT().
+		ParseToken("admin_jwt").
+		Return(&model.UserIdentity{UserID: 9, Roles: []model.Role{model.RoleCustomer, model.RoleAdmin}}, nil).
+		AnyTimes()
+	mockUserService.EXPECT().
+		ParseToken("revoked_jwt").
+		Return(&model.UserIdentity{UserID: 11, Roles: []model.Role{model.RoleCustomer, model.RoleAdmin}}, nil).
+		AnyTimes()
+	mockUserService.EXPECT().
+		GetRoles(gomock.Any(), int64(7)).
+		Return([]model.Role{model.RoleCustomer}, nil).
+		AnyTimes()
+	mockUserService.EXPECT().
+		GetRoles(gomock.Any(), int64(9)).
+		Return([]model.Role{model.RoleCustomer, model.RoleAdmin}, nil).
+		AnyTimes()
+	mockUserService.EXPECT().
+		GetRoles(gomock.Any(), int64(11)).
+		Return([]model.Role{model.RoleCustomer}, nil).
+		AnyTimes()
+
+	t.Run("SupportCanSearchUsers", func(t *testing.T) {
+		users := []*model.UserSummary{{ID: 1, Login: "user1", Current: 100}}
+
+		mockAdminService.EXPECT().
+			SearchUsers(gomock.Any(), "user").
+			Return(users, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users?login=user", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response []*model.UserSummary
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, users[0].Login, response[0].Login)
+	})
+
+	t.Run("UserNotFound", func(t *testing.T) {
+		mockAdminService.EXPECT().
+			GetUser(gomock.Any(), int64(42)).
+			Return(nil, fmt.Errorf("ошибка получения пользователя: %w", customerrors.ErrUserNotFound))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users/42", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("InvalidUserID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users/abc/orders", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("SuccessfulRequeue", func(t *testing.T) {
+		mockAdminService.EXPECT().
+			RequeueOrder(gomock.Any(), "12345678903", "bob").
+			Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/orders/12345678903/requeue", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("RequeueProcessedOrder", func(t *testing.T) {
+		mockAdminService.EXPECT().
+			RequeueOrder(gomock.Any(), "79927398713", "bob").
+			Return(customerrors.ErrOrderNotRequeueable)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/orders/79927398713/requeue", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("SupportCannotAdjustBalance", func(t *testing.T) {
+		body, _ := json.Marshal(model.BalanceAdjustmentRequest{Amount: 50, Reason: "компенсация"})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/users/1/balance/adjustments", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer support_token")
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("SuccessfulBalanceAdjustment", func(t *testing.T) {
+		input := model.BalanceAdjustmentRequest{Amount: 50, Reason: "компенсация"}
+
+		mockAdminService.EXPECT().
+			AdjustBalance(gomock.Any(), int64(1), input, "alice").
+			Return(&model.BalanceAdjustment{ID: 1, UserID: 1, Amount: 50, Reason: input.Reason, Actor: "alice"}, nil)
+
+		body, _ := json.Marshal(input)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/users/1/balance/adjustments", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer admin_token")
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response model.BalanceAdjustment
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", response.Actor)
+	})
+
+	t.Run("AdjustmentBelowZero", func(t *testing.T) {
+		input := model.BalanceAdjustmentRequest{Amount: -500, Reason: "ошибочное начисление"}
+
+		mockAdminService.EXPECT().
+			AdjustBalance(gomock.Any(), int64(1), input, "alice").
+			Return(nil, customerrors.ErrInsufficientFunds)
+
+		body, _ := json.Marshal(input)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/users/1/balance/adjustments", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer admin_token")
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	})
+
+	t.Run("CustomerTokenForbidden", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer customer_jwt")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("RevokedRoleForbidden", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users/1/roles", nil)
+		req.Header.Set("Authorization", "Bearer revoked_jwt")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("AdminJWTSetsRoles", func(t *testing.T) {
+		input := model.UserRolesRequest{Roles: []model.Role{model.RoleCustomer, model.RoleFinance}}
+
+		mockAdminService.EXPECT().
+			SetUserRoles(gomock.Any(), int64(1), input.Roles, "user:9").
+			Return(input.Roles, nil)
+
+		body, _ := json.Marshal(input)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/admin/users/1/roles", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer admin_jwt")
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.UserRolesRequest
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, input.Roles, response.Roles)
+	})
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		input := model.UserRolesRequest{Roles: []model.Role{"superuser"}}
+
+		mockAdminService.EXPECT().
+			SetUserRoles(gomock.Any(), int64(1), input.Roles, "alice").
+			Return(nil, fmt.Errorf("%w: %q", customerrors.ErrInvalidRole, "superuser"))
+
+		body, _ := json.Marshal(input)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/api/admin/users/1/roles", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", "Bearer admin_token")
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("SupportCannotManageRoles", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users/1/roles/history", nil)
+		req.Header.Set("Authorization", "Bearer support_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
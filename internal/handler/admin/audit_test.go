@@ -0,0 +1,104 @@
+package admin_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockAuditService := mockservice.NewMockAuditService(ctrl)
+
+	services := &service.Service{
+		Staff: mockStaffService,
+		Audit: mockAuditService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken("admin_token").
+		Return(model.Staff{Name: "alice", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+
+	t.Run("FilteredQuery", func(t *testing.T) {
+		expectedFilter := model.AuditFilter{
+			Actor:   "user:1",
+			Action:  model.AuditBalanceWithdrawn,
+			From:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			AfterID: 10,
+			Limit:   50,
+		}
+
+		mockAuditService.EXPECT().
+			GetEvents(gomock.Any(), expectedFilter).
+			Return([]*model.AuditEvent{{ID: 11, Actor: "user:1", Action: model.AuditBalanceWithdrawn}}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/audit?actor=user:1&action=balance.withdrawn&from=2026-01-01&after_id=10&limit=50", nil)
+		req.Header.Set("Authorization", "Bearer admin_token")
+		req.Header.Set("X-Request-ID", "req-42")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "req-42", w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("InvalidDate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/audit?from=yesterday", nil)
+		req.Header.Set("Authorization", "Bearer admin_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("CSVExport", func(t *testing.T) {
+		mockAuditService.EXPECT().
+			Export(gomock.Any(), model.AuditFilter{}, model.ExportCSV, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ model.AuditFilter, _ model.ExportFormat, w io.Writer) error {
+				_, err := io.WriteString(w, "id,created_at,actor\n")
+				return err
+			})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/audit/export", nil)
+		req.Header.Set("Authorization", "Bearer admin_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "id,created_at,actor\n", w.Body.String())
+	})
+
+	t.Run("UnsupportedExportFormat", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/audit/export?format=xml", nil)
+		req.Header.Set("Authorization", "Bearer admin_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,133 @@
+package admin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCampaigns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockCampaignService := mockservice.NewMockCampaignService(ctrl)
+
+	services := &service.Service{
+		Staff:     mockStaffService,
+		Campaigns: mockCampaignService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken("admin_token").
+		Return(model.Staff{Name: "admin", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+	mockStaffService.EXPECT().
+		ParseStaffToken("support_token").
+		Return(model.Staff{Name: "support", Role: model.RoleSupport}, nil).
+		AnyTimes()
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	request := model.CampaignRequest{Name: "Приветственный бонус", Type: model.CampaignWelcomeBonus, Amount: 100}
+	campaign := &model.Campaign{
+		ID:     1,
+		Tenant: model.DefaultTenant,
+		Name:   request.Name,
+		Type:   request.Type,
+		Amount: request.Amount,
+		Active: true,
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		mockCampaignService.EXPECT().
+			CreateCampaign(gomock.Any(), request, "admin").
+			Return(campaign, nil)
+
+		w := do("POST", "/api/admin/campaigns", "admin_token", request)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("CreateInvalid", func(t *testing.T) {
+		invalid := model.CampaignRequest{Name: "Двойные баллы", Type: model.CampaignAccrualMultiplier, Multiplier: 1}
+		mockCampaignService.EXPECT().
+			CreateCampaign(gomock.Any(), invalid, "admin").
+			Return(nil, fmt.Errorf("%w: множитель должен быть больше 1", customerrors.ErrInvalidCampaign))
+
+		w := do("POST", "/api/admin/campaigns", "admin_token", invalid)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ForbiddenForSupport", func(t *testing.T) {
+		w := do("GET", "/api/admin/campaigns", "support_token", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		mockCampaignService.EXPECT().
+			GetCampaigns(gomock.Any()).
+			Return([]*model.Campaign{campaign}, nil)
+
+		w := do("GET", "/api/admin/campaigns", "admin_token", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		mockCampaignService.EXPECT().
+			GetCampaigns(gomock.Any()).
+			Return(nil, nil)
+
+		w = do("GET", "/api/admin/campaigns", "admin_token", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("UpdateNotFound", func(t *testing.T) {
+		mockCampaignService.EXPECT().
+			UpdateCampaign(gomock.Any(), int64(7), request, "admin").
+			Return(nil, fmt.Errorf("ошибка получения кампании: %w", customerrors.ErrCampaignNotFound))
+
+		w := do("PUT", "/api/admin/campaigns/7", "admin_token", request)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockCampaignService.EXPECT().
+			DeleteCampaign(gomock.Any(), int64(1), "admin").
+			Return(nil)
+
+		w := do("DELETE", "/api/admin/campaigns/1", "admin_token", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		w := do("GET", "/api/admin/campaigns/abc", "admin_token", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,153 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinanceReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockFinanceService := mockservice.NewMockFinanceService(ctrl)
+
+	services := &service.Service{
+		Staff:   mockStaffService,
+		Finance: mockFinanceService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken("finance_token").
+		Return(model.Staff{Name: "finance", Role: model.RoleFinance}, nil).
+		AnyTimes()
+	mockStaffService.EXPECT().
+		ParseStaffToken("admin_token").
+		Return(model.Staff{Name: "admin", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	from := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.October, 31, 0, 0, 0, 0, time.UTC)
+	liability := 1200.5
+	reports := []*model.FinanceDailyReport{
+		{Day: "2026-10-01", Tenant: model.DefaultTenant, Accrued: 500, Withdrawn: 100, NewUsers: 3, OrdersProcessed: 2},
+		{Day: "2026-10-02", Tenant: model.DefaultTenant, Accrued: 250, OrdersInvalid: 1, Liability: &liability},
+	}
+
+	t.Run("Daily", func(t *testing.T) {
+		mockFinanceService.EXPECT().GetDailyReports(gomock.Any(), from, to).Return(reports, nil)
+
+		w := get("/api/admin/reports/daily?from=2026-10-01&to=2026-10-31", "finance_token")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response []model.FinanceDailyReport
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response, 2)
+		assert.Nil(t, response[0].Liability)
+		assert.Equal(t, liability, *response[1].Liability)
+	})
+
+	t.Run("DailyEmpty", func(t *testing.T) {
+		mockFinanceService.EXPECT().GetDailyReports(gomock.Any(), time.Time{}, time.Time{}).Return(nil, nil)
+
+		w := get("/api/admin/reports/daily", "finance_token")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("DailyCSV", func(t *testing.T) {
+		mockFinanceService.EXPECT().
+			ExportDailyReports(gomock.Any(), from, to, model.ExportCSV, gomock.Any()).
+			DoAndReturn(func(_ any, _, _ time.Time, _ model.ExportFormat, w io.Writer) error {
+				_, err := io.WriteString(w, "day,tenant\n2026-10-01,default\n")
+				return err
+			})
+
+		w := get("/api/admin/reports/daily?from=2026-10-01&to=2026-10-31&format=csv", "finance_token")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "finance-daily.csv")
+		assert.Equal(t, "day,tenant\n2026-10-01,default\n", w.Body.String())
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		w := get("/api/admin/reports/daily?format=xlsx", "finance_token")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidPeriod", func(t *testing.T) {
+		mockFinanceService.EXPECT().
+			GetDailyReports(gomock.Any(), to, from).
+			Return(nil, fmt.Errorf("%w", customerrors.ErrInvalidPeriod))
+
+		w := get("/api/admin/reports/daily?from=2026-10-31&to=2026-10-01", "finance_token")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidDate", func(t *testing.T) {
+		w := get("/api/admin/reports/summary?to=31.10.2026", "finance_token")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		summary := &model.FinanceSummary{From: "2026-10-01", To: "2026-10-31", Days: 2, Accrued: 750, Liability: &liability, LiabilityDay: "2026-10-02"}
+		mockFinanceService.EXPECT().GetSummary(gomock.Any(), from, to).Return(summary, nil)
+
+		w := get("/api/admin/reports/summary?from=2026-10-01&to=2026-10-31", "finance_token")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.FinanceSummary
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, *summary, response)
+	})
+
+	t.Run("SummaryError", func(t *testing.T) {
+		mockFinanceService.EXPECT().
+			GetSummary(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("ошибка базы данных"))
+
+		w := get("/api/admin/reports/summary", "finance_token")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("ForbiddenForAdmin", func(t *testing.T) {
+		w := get("/api/admin/reports/daily", "admin_token")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/admin/reports/summary", nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,173 @@
+package admin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/Gerfey/gophermart/internal/tests"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	tests.SetupTestLogging()
+	os.Exit(m.Run())
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockWebhookService := mockservice.NewMockWebhookService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Staff:    mockStaffService,
+		Webhooks: mockWebhookService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken("staff_token").
+		Return(model.Staff{Name: "admin", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+	mockStaffService.EXPECT().
+		ParseStaffToken("wrong_token").
+		Return(model.Staff{}, customerrors.ErrInvalidStaffToken).
+		AnyTimes()
+	mockUserService.EXPECT().
+		ParseToken("wrong_token").
+		Return(nil, errors.New("невалидный токен")).
+		AnyTimes()
+
+	t.Run("UnauthorizedWithoutStaffToken", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/webhooks", nil)
+		req.Header.Set("Authorization", "Bearer wrong_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("SuccessfulSubscriptionCreation", func(t *testing.T) {
+		input := model.WebhookSubscriptionRequest{
+			URL:        "https://partner.example/hooks",
+			EventTypes: []model.WebhookEventType{model.WebhookEventOrderProcessed},
+		}
+
+		mockWebhookService.EXPECT().
+			CreateSubscription(gomock.Any(), input).
+			Return(&model.WebhookSubscription{
+				ID:         1,
+				Tenant:     model.DefaultTenant,
+				URL:        input.URL,
+				Secret:     "generated_secret",
+				EventTypes: input.EventTypes,
+				Active:     true,
+			}, nil)
+
+		body, _ := json.Marshal(input)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/webhooks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer staff_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response model.WebhookSubscription
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "generated_secret", response.Secret)
+	})
+
+	t.Run("InvalidSubscription", func(t *testing.T) {
+		input := model.WebhookSubscriptionRequest{
+			URL:        "ftp://partner.example/hooks",
+			EventTypes: []model.WebhookEventType{model.WebhookEventOrderProcessed},
+		}
+
+		mockWebhookService.EXPECT().
+			CreateSubscription(gomock.Any(), input).
+			Return(nil, customerrors.ErrInvalidWebhook)
+
+		body, _ := json.Marshal(input)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/webhooks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer staff_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStaffService := mockservice.NewMockStaffService(ctrl)
+	mockWebhookService := mockservice.NewMockWebhookService(ctrl)
+
+	services := &service.Service{
+		Staff:    mockStaffService,
+		Webhooks: mockWebhookService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	mockStaffService.EXPECT().
+		ParseStaffToken(gomock.Any()).
+		Return(model.Staff{Name: "admin", Role: model.RoleAdmin}, nil).
+		AnyTimes()
+
+	t.Run("SuccessfulReplay", func(t *testing.T) {
+		mockWebhookService.EXPECT().
+			ReplayDelivery(gomock.Any(), int64(7)).
+			Return(nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/webhooks/deliveries/7/replay", nil)
+		req.Header.Set("Authorization", "Bearer staff_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("DeliveryNotFound", func(t *testing.T) {
+		mockWebhookService.EXPECT().
+			ReplayDelivery(gomock.Any(), int64(8)).
+			Return(customerrors.ErrWebhookNotFound)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/admin/webhooks/deliveries/8/replay", nil)
+		req.Header.Set("Authorization", "Bearer staff_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
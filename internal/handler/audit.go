@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const dateLayout = "2006-01-02"
+
+// getAuditEvents возвращает события журнала аудита.
+// Параметры запроса actor, action, target, from, to (RFC 3339 или YYYY-MM-DD), limit и after_id
+// ограничивают выборку; для следующей страницы в after_id передается идентификатор последнего события.
+// Метод доступен по пути GET /api/admin/audit
+//
+// Коды ответов:
+//   - 200 OK: возвращает список событий в формате JSON
+//   - 204 No Content: событий нет
+//   - 400 Bad Request: некорректные параметры фильтра
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getAuditEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := h.services.Audit.GetEvents(c, filter)
+	if err != nil {
+		log.Errorf("Ошибка получения журнала аудита: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения журнала аудита")
+		return
+	}
+
+	if len(events) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// exportAuditEvents выгружает события журнала аудита построчно в формате CSV или JSONL.
+// Принимает те же фильтры, что и GET /api/admin/audit, и параметр format (csv по умолчанию или jsonl).
+// Метод доступен по пути GET /api/admin/audit/export
+//
+// Коды ответов:
+//   - 200 OK: выгрузка в запрошенном формате
+//   - 400 Bad Request: некорректные параметры фильтра или формат
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+func (h *Handler) exportAuditEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := model.ExportFormat(c.DefaultQuery("format", string(model.ExportCSV)))
+
+	var contentType string
+	switch format {
+	case model.ExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	case model.ExportJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "неподдерживаемый формат выгрузки")
+		return
+	}
+
+	// Журнал выгружается целиком и может передаваться дольше таймаута записи сервера.
+	clearWriteDeadline(c, "выгрузки журнала аудита")
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit.%s"`, format))
+	c.Status(http.StatusOK)
+
+	// Заголовки уже отправлены, поэтому ошибка в середине выгрузки только логируется.
+	if err := h.services.Audit.Export(c, filter, format, c.Writer); err != nil {
+		log.Errorf("Ошибка выгрузки журнала аудита: %s", err.Error())
+	}
+}
+
+func parseAuditFilter(c *gin.Context) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: model.AuditAction(c.Query("action")),
+		Target: c.Query("target"),
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeParam(c, "to"); err != nil {
+		return filter, err
+	}
+
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("некорректный параметр limit")
+		}
+	}
+
+	if value := c.Query("after_id"); value != "" {
+		if filter.AfterID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return filter, fmt.Errorf("некорректный параметр after_id")
+		}
+	}
+
+	return filter, nil
+}
+
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректный параметр %s, ожидается RFC 3339 или YYYY-MM-DD", name)
+	}
+
+	return t, nil
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	customerrors "github.com/Gerfey/gophermart/internal/errors"
@@ -11,11 +14,17 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
 // getBalance возвращает текущий баланс пользователя.
 // Метод доступен по пути GET /api/user/balance
 //
 // Коды ответов:
-//   - 200 OK: возвращает информацию о балансе в формате JSON (текущий баланс и сумма списаний)
+//   - 200 OK: возвращает информацию о балансе в формате JSON (доступный баланс, сумма списаний
+//     и сумма активных резервов)
 //   - 401 Unauthorized: пользователь не аутентифицирован
 //   - 500 Internal Server Error: внутренняя ошибка сервера
 func (h *Handler) getBalance(c *gin.Context) {
@@ -37,15 +46,17 @@ func (h *Handler) getBalance(c *gin.Context) {
 }
 
 // withdrawFromBalance обрабатывает запрос на списание средств с баланса пользователя.
-// Принимает JSON с номером заказа и суммой для списания, проверяет валидность номера по алгоритму Луна
-// и достаточность средств на балансе. Метод доступен по пути POST /api/user/balance/withdraw
+// Принимает JSON с номером заказа, суммой для списания и необязательной стоимостью заказа, проверяет номер
+// правилами проверки номеров заказов, списание правилами списания арендатора и достаточность средств на балансе.
+// Метод доступен по пути POST /api/user/balance/withdraw
 //
 // Коды ответов:
 //   - 200 OK: средства успешно списаны
 //   - 400 Bad Request: неверный формат запроса или некорректные данные
 //   - 401 Unauthorized: пользователь не аутентифицирован
 //   - 402 Payment Required: недостаточно средств на балансе
-//   - 422 Unprocessable Entity: номер заказа не соответствует алгоритму Луна
+//   - 422 Unprocessable Entity: номер заказа не прошел проверку или списание нарушает правила списания,
+//     в ответе указано нарушенное правило
 //   - 500 Internal Server Error: внутренняя ошибка сервера
 func (h *Handler) withdrawFromBalance(c *gin.Context) {
 	userID, err := getUserID(c)
@@ -67,16 +78,19 @@ func (h *Handler) withdrawFromBalance(c *gin.Context) {
 		return
 	}
 
-	err = h.services.Balances.Withdraw(c, userID, input.Order, input.Sum)
+	err = h.services.Balances.Withdraw(c, userID, input)
 	if err != nil {
 		log.Errorf("Ошибка списания баллов: %s", err.Error())
 
 		if errors.Is(err, customerrors.ErrInsufficientFunds) {
 			newErrorResponse(c, http.StatusPaymentRequired, err.Error())
 			return
-		} else if errors.Is(err, customerrors.ErrInvalidLuhn) {
+		} else if errors.Is(err, customerrors.ErrInvalidOrderNumber) {
 			newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
 			return
+		} else if errors.Is(err, customerrors.ErrWithdrawalRejected) {
+			newWithdrawalRuleResponse(c, err)
+			return
 		}
 
 		newErrorResponse(c, http.StatusInternalServerError, "ошибка списания баллов")
@@ -86,6 +100,308 @@ func (h *Handler) withdrawFromBalance(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// transferPoints переводит баллы другому пользователю того же арендатора по логину.
+// Принимает JSON с логином получателя и суммой; заголовок Idempotency-Key обязателен, повторный
+// запрос с тем же ключом возвращает ранее выполненный перевод без повторного списания.
+// Метод доступен по пути POST /api/user/balance/transfer
+//
+// Коды ответов:
+//   - 200 OK: перевод выполнен, в ответе данные перевода в формате JSON
+//   - 400 Bad Request: неверный формат запроса, не указан ключ идемпотентности или перевод самому себе
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 402 Payment Required: недостаточно средств на балансе
+//   - 404 Not Found: получатель не найден
+//   - 409 Conflict: ключ идемпотентности уже использован для перевода с другими параметрами
+//   - 422 Unprocessable Entity: превышен дневной лимит переводов
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) transferPoints(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	idempotencyKey := strings.TrimSpace(c.GetHeader(idempotencyKeyHeader))
+	if idempotencyKey == "" || len(idempotencyKey) > maxIdempotencyKeyLength {
+		newErrorResponse(c, http.StatusBadRequest, "заголовок Idempotency-Key обязателен и не должен превышать 255 символов")
+		return
+	}
+
+	var input model.TransferRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на перевод: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	transfer, err := h.services.Balances.Transfer(c, userID, input, idempotencyKey)
+	if err != nil {
+		log.Errorf("Ошибка перевода баллов: %s", err.Error())
+
+		switch {
+		case errors.Is(err, customerrors.ErrTransferToSelf):
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, customerrors.ErrInsufficientFunds):
+			newErrorResponse(c, http.StatusPaymentRequired, err.Error())
+		case errors.Is(err, customerrors.ErrUserNotFound):
+			newErrorResponse(c, http.StatusNotFound, "получатель не найден")
+		case errors.Is(err, customerrors.ErrIdempotencyConflict):
+			newErrorResponse(c, http.StatusConflict, err.Error())
+		case errors.Is(err, customerrors.ErrTransferLimit):
+			newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, "ошибка перевода баллов")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+// getTransfers возвращает историю входящих и исходящих переводов пользователя.
+// Метод доступен по пути GET /api/user/balance/transfers
+//
+// Коды ответов:
+//   - 200 OK: возвращает список переводов в формате JSON
+//   - 204 No Content: у пользователя нет переводов
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getTransfers(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	transfers, err := h.services.Balances.GetTransfers(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения истории переводов: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения истории переводов")
+		return
+	}
+
+	if len(transfers) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+// getStatement выгружает выписку движения баллов пользователя за период с остатками на начало и конец.
+// Параметры запроса from и to (RFC 3339 или YYYY-MM-DD) задают период, to не включается; по умолчанию
+// выписка строится с начала текущего месяца по текущий момент. Параметр format — csv (по умолчанию), xlsx или pdf.
+// Метод доступен по пути GET /api/user/statement
+//
+// Коды ответов:
+//   - 200 OK: выписка в запрошенном формате
+//   - 400 Bad Request: некорректный период или неподдерживаемый формат
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getStatement(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := model.ExportFormat(c.DefaultQuery("format", string(model.ExportCSV)))
+
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case model.ExportXLSX:
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case model.ExportPDF:
+		contentType = "application/pdf"
+	}
+	// Выписка за длинный период может передаваться дольше таймаута записи сервера.
+	clearWriteDeadline(c, "выписки")
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statement.%s"`, format))
+
+	err = h.services.Balances.Statement(c, userID, from, to, format, c.Writer)
+	if err == nil {
+		return
+	}
+
+	log.Errorf("Ошибка выгрузки выписки: %s", err.Error())
+	if c.Writer.Written() {
+		// Часть выписки уже отправлена, поэтому ошибка только логируется.
+		return
+	}
+
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidExportFormat), errors.Is(err, customerrors.ErrInvalidPeriod):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка выгрузки выписки")
+	}
+}
+
+// reservePoints резервирует баллы под заказ на время оплаты. Зарезервированные баллы
+// недоступны для списания, но не учитываются в сумме списаний до подтверждения резерва.
+// Метод доступен по пути POST /api/user/balance/reservations
+//
+// Коды ответов:
+//   - 201 Created: резерв создан, в ответе данные резерва со сроком действия в формате JSON
+//   - 400 Bad Request: неверный формат запроса или некорректные данные
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 402 Payment Required: недостаточно средств на балансе
+//   - 422 Unprocessable Entity: номер заказа не прошел проверку или списание нарушает правила списания,
+//     в ответе указано нарушенное правило
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) reservePoints(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	var input model.ReservationRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на резервирование: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	if strings.TrimSpace(input.Order) == "" {
+		newErrorResponse(c, http.StatusBadRequest, "номер заказа и сумма должны быть указаны корректно")
+		return
+	}
+
+	reservation, err := h.services.Balances.Reserve(c, userID, input)
+	if err != nil {
+		log.Errorf("Ошибка резервирования баллов: %s", err.Error())
+
+		if errors.Is(err, customerrors.ErrInsufficientFunds) {
+			newErrorResponse(c, http.StatusPaymentRequired, err.Error())
+			return
+		} else if errors.Is(err, customerrors.ErrInvalidOrderNumber) {
+			newErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if errors.Is(err, customerrors.ErrWithdrawalRejected) {
+			newWithdrawalRuleResponse(c, err)
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка резервирования баллов")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// confirmReservation подтверждает резерв и списывает зарезервированные баллы.
+// Повторное подтверждение возвращает резерв без изменений.
+// Метод доступен по пути POST /api/user/balance/reservations/{id}/confirm
+//
+// Коды ответов:
+//   - 200 OK: резерв подтвержден, в ответе данные резерва в формате JSON
+//   - 400 Bad Request: некорректный идентификатор резерва
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: резерв не найден
+//   - 409 Conflict: резерв отменен или истек срок его действия
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) confirmReservation(c *gin.Context) {
+	h.resolveReservation(c, h.services.Balances.ConfirmReservation)
+}
+
+// cancelReservation отменяет резерв и возвращает баллы в доступный баланс.
+// Повторная отмена возвращает резерв без изменений.
+// Метод доступен по пути POST /api/user/balance/reservations/{id}/cancel
+//
+// Коды ответов:
+//   - 200 OK: резерв отменен, в ответе данные резерва в формате JSON
+//   - 400 Bad Request: некорректный идентификатор резерва
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: резерв не найден
+//   - 409 Conflict: резерв уже подтвержден или истек срок его действия
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) cancelReservation(c *gin.Context) {
+	h.resolveReservation(c, h.services.Balances.CancelReservation)
+}
+
+func (h *Handler) resolveReservation(c *gin.Context, resolve func(ctx context.Context, userID, reservationID int64) (*model.Reservation, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	reservationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "некорректный идентификатор резерва")
+		return
+	}
+
+	reservation, err := resolve(c, userID, reservationID)
+	if err != nil {
+		log.Errorf("Ошибка завершения резерва: %s", err.Error())
+
+		switch {
+		case errors.Is(err, customerrors.ErrReservationNotFound):
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, customerrors.ErrReservationClosed):
+			newErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, "ошибка завершения резерва")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// getReservations возвращает резервы пользователя, начиная с последнего.
+// Метод доступен по пути GET /api/user/balance/reservations
+//
+// Коды ответов:
+//   - 200 OK: возвращает список резервов в формате JSON
+//   - 204 No Content: у пользователя нет резервов
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getReservations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	reservations, err := h.services.Balances.GetReservations(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения резервов: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения резервов")
+		return
+	}
+
+	if len(reservations) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
 // getWithdrawals возвращает историю списаний средств пользователя.
 // Метод доступен по пути GET /api/user/withdrawals
 //
@@ -3,6 +3,7 @@ package balance_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/Gerfey/gophermart/internal/tests"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/handler"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/Gerfey/gophermart/internal/service"
@@ -46,7 +48,7 @@ func TestGetBalance(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	t.Run("SuccessfulBalanceRetrieval", func(t *testing.T) {
@@ -97,7 +99,7 @@ func TestWithdraw(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	t.Run("SuccessfulWithdrawal", func(t *testing.T) {
@@ -107,7 +109,7 @@ func TestWithdraw(t *testing.T) {
 		}
 
 		mockBalanceService.EXPECT().
-			Withdraw(gomock.Any(), userID, withdrawRequest.Order, withdrawRequest.Sum).
+			Withdraw(gomock.Any(), userID, withdrawRequest).
 			Return(nil)
 
 		requestBody, _ := json.Marshal(withdrawRequest)
@@ -121,6 +123,35 @@ func TestWithdraw(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("RuleViolation", func(t *testing.T) {
+		withdrawRequest := model.WithdrawRequest{
+			Order: "1234567890",
+			Sum:   60.0,
+		}
+
+		mockBalanceService.EXPECT().
+			Withdraw(gomock.Any(), userID, withdrawRequest).
+			Return(fmt.Errorf("ошибка списания баллов: %w", &customerrors.WithdrawalRuleError{
+				Rule:   "max_per_order",
+				Reason: "по заказу можно списать не более 50, уже списано 0",
+			}))
+
+		requestBody, _ := json.Marshal(withdrawRequest)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/balance/withdraw", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var response model.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "max_per_order", response.Rule)
+		assert.Equal(t, "списание нарушает правила: по заказу можно списать не более 50, уже списано 0", response.Error)
+	})
+
 	t.Run("InvalidRequestBody", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("POST", "/api/user/balance/withdraw", bytes.NewBuffer([]byte("invalid json")))
@@ -155,7 +186,7 @@ func TestGetWithdrawals(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	t.Run("SuccessfulWithdrawalsRetrieval", func(t *testing.T) {
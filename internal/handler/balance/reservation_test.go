@@ -0,0 +1,148 @@
+package balance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockBalanceService := mockservice.NewMockBalanceService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Balances: mockBalanceService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	reservation := &model.Reservation{
+		ID:          5,
+		OrderNumber: "2377225624",
+		Amount:      100,
+		Status:      model.ReservationStatusActive,
+		ExpiresAt:   createdAt.Add(15 * time.Minute),
+		CreatedAt:   createdAt,
+	}
+
+	t.Run("Reserve", func(t *testing.T) {
+		request := model.ReservationRequest{Order: "2377225624", Sum: 100}
+		mockBalanceService.EXPECT().
+			Reserve(gomock.Any(), userID, request).
+			Return(reservation, nil)
+
+		w := do("POST", "/api/user/balance/reservations", request)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response model.Reservation
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, *reservation, response)
+	})
+
+	t.Run("ReserveInsufficientFunds", func(t *testing.T) {
+		request := model.ReservationRequest{Order: "2377225624", Sum: 1000}
+		mockBalanceService.EXPECT().
+			Reserve(gomock.Any(), userID, request).
+			Return(nil, fmt.Errorf("ошибка резервирования баллов: %w", customerrors.ErrInsufficientFunds))
+
+		w := do("POST", "/api/user/balance/reservations", request)
+		assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	})
+
+	t.Run("ReserveInvalidRequest", func(t *testing.T) {
+		w := do("POST", "/api/user/balance/reservations", map[string]any{"order": "2377225624", "sum": 0})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		confirmed := *reservation
+		confirmed.Status = model.ReservationStatusConfirmed
+
+		mockBalanceService.EXPECT().
+			ConfirmReservation(gomock.Any(), userID, int64(5)).
+			Return(&confirmed, nil)
+
+		w := do("POST", "/api/user/balance/reservations/5/confirm", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("ConfirmClosed", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			ConfirmReservation(gomock.Any(), userID, int64(5)).
+			Return(nil, fmt.Errorf("ошибка подтверждения резерва: %w", customerrors.ErrReservationClosed))
+
+		w := do("POST", "/api/user/balance/reservations/5/confirm", nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("CancelNotFound", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			CancelReservation(gomock.Any(), userID, int64(6)).
+			Return(nil, fmt.Errorf("ошибка отмены резерва: %w", customerrors.ErrReservationNotFound))
+
+		w := do("POST", "/api/user/balance/reservations/6/cancel", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		w := do("POST", "/api/user/balance/reservations/abc/cancel", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			GetReservations(gomock.Any(), userID).
+			Return([]*model.Reservation{reservation}, nil)
+
+		w := do("GET", "/api/user/balance/reservations", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		mockBalanceService.EXPECT().
+			GetReservations(gomock.Any(), userID).
+			Return(nil, nil)
+
+		w = do("GET", "/api/user/balance/reservations", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
@@ -0,0 +1,163 @@
+package balance_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatement(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockBalanceService := mockservice.NewMockBalanceService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Balances: mockBalanceService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/user/statement"+query, nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	from := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("CSV", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, from, to, model.ExportCSV, gomock.Any()).
+			DoAndReturn(func(_ any, _ int64, _, _ time.Time, _ model.ExportFormat, w io.Writer) error {
+				_, err := io.WriteString(w, "date,operation\n")
+				return err
+			})
+
+		w := get("?from=2026-10-01&to=2026-11-01")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "statement.csv")
+		assert.Equal(t, "date,operation\n", w.Body.String())
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, time.Time{}, time.Time{}, model.ExportXLSX, gomock.Any()).
+			Return(nil)
+
+		w := get("?format=xlsx")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("OutlivesWriteTimeout", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, gomock.Any(), gomock.Any(), model.ExportCSV, gomock.Any()).
+			DoAndReturn(func(_ any, _ int64, _, _ time.Time, _ model.ExportFormat, w io.Writer) error {
+				time.Sleep(150 * time.Millisecond)
+				_, err := io.WriteString(w, "date,operation\n")
+				return err
+			})
+
+		server := httptest.NewUnstartedServer(router)
+		server.Config.WriteTimeout = 50 * time.Millisecond
+		server.Start()
+		defer server.Close()
+
+		req, _ := http.NewRequest("GET", server.URL+"/api/user/statement", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "date,operation\n", string(body))
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, time.Time{}, time.Time{}, model.ExportPDF, gomock.Any()).
+			Return(nil)
+
+		w := get("?format=pdf")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "statement.pdf")
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, gomock.Any(), gomock.Any(), model.ExportFormat("ods"), gomock.Any()).
+			Return(fmt.Errorf("%w: %q", customerrors.ErrInvalidExportFormat, "ods"))
+
+		w := get("?format=ods")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("InvalidPeriod", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, to, from, model.ExportCSV, gomock.Any()).
+			Return(fmt.Errorf("%w", customerrors.ErrInvalidPeriod))
+
+		w := get("?from=2026-11-01&to=2026-10-01")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidDate", func(t *testing.T) {
+		w := get("?from=01.10.2026")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ServiceError", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			Statement(gomock.Any(), userID, gomock.Any(), gomock.Any(), model.ExportCSV, gomock.Any()).
+			Return(errors.New("ошибка базы данных"))
+
+		w := get("")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/user/statement", nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,174 @@
+package balance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockBalanceService := mockservice.NewMockBalanceService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Balances: mockBalanceService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	request := model.TransferRequest{Recipient: "bob", Sum: 150}
+
+	send := func(body any, key string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/user/balance/transfer", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		expected := &model.TransferResponse{
+			ID:           7,
+			Direction:    model.TransferDirectionOut,
+			Counterparty: "bob",
+			Sum:          150,
+			CreatedAt:    time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		}
+
+		mockBalanceService.EXPECT().
+			Transfer(gomock.Any(), userID, request, "key-1").
+			Return(expected, nil)
+
+		w := send(request, "key-1")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.TransferResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, *expected, response)
+	})
+
+	t.Run("MissingIdempotencyKey", func(t *testing.T) {
+		w := send(request, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidRequestBody", func(t *testing.T) {
+		w := send(map[string]any{"recipient": "bob", "sum": -5}, "key-2")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	errorCases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"ToSelf", customerrors.ErrTransferToSelf, http.StatusBadRequest},
+		{"InsufficientFunds", customerrors.ErrInsufficientFunds, http.StatusPaymentRequired},
+		{"RecipientNotFound", customerrors.ErrUserNotFound, http.StatusNotFound},
+		{"IdempotencyConflict", customerrors.ErrIdempotencyConflict, http.StatusConflict},
+		{"DailyLimit", customerrors.ErrTransferLimit, http.StatusUnprocessableEntity},
+		{"InternalError", fmt.Errorf("ошибка базы данных"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockBalanceService.EXPECT().
+				Transfer(gomock.Any(), userID, request, "key-3").
+				Return(nil, fmt.Errorf("ошибка перевода баллов: %w", tc.err))
+
+			w := send(request, "key-3")
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
+
+func TestGetTransfers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockBalanceService := mockservice.NewMockBalanceService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Balances: mockBalanceService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	t.Run("Success", func(t *testing.T) {
+		transfers := []model.TransferResponse{
+			{ID: 2, Direction: model.TransferDirectionIn, Counterparty: "bob", Sum: 50, CreatedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+			{ID: 1, Direction: model.TransferDirectionOut, Counterparty: "bob", Sum: 150, CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		}
+
+		mockBalanceService.EXPECT().
+			GetTransfers(gomock.Any(), userID).
+			Return(transfers, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/balance/transfers", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response []model.TransferResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, transfers, response)
+	})
+
+	t.Run("NoContent", func(t *testing.T) {
+		mockBalanceService.EXPECT().
+			GetTransfers(gomock.Any(), userID).
+			Return([]model.TransferResponse{}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/balance/transfers", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// createCampaign создает промо-кампанию.
+// Принимает JSON с названием, типом (welcome_bonus, accrual_multiplier, nth_order), параметрами бонуса,
+// периодом действия и, опционально, арендатором. Метод доступен по пути POST /api/admin/campaigns
+//
+// Коды ответов:
+//   - 201 Created: кампания создана, в ответе данные кампании в формате JSON
+//   - 400 Bad Request: неверный формат запроса или некорректные параметры кампании
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) createCampaign(c *gin.Context) {
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.CampaignRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на создание кампании: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	campaign, err := h.services.Campaigns.CreateCampaign(c, input, actor)
+	if err != nil {
+		log.Errorf("Ошибка создания кампании: %s", err.Error())
+		respondCampaignError(c, err, "ошибка создания кампании")
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// getCampaigns возвращает список промо-кампаний, начиная с последней.
+// Метод доступен по пути GET /api/admin/campaigns
+//
+// Коды ответов:
+//   - 200 OK: возвращает список кампаний в формате JSON
+//   - 204 No Content: кампаний нет
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getCampaigns(c *gin.Context) {
+	campaigns, err := h.services.Campaigns.GetCampaigns(c)
+	if err != nil {
+		log.Errorf("Ошибка получения кампаний: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения кампаний")
+		return
+	}
+
+	if len(campaigns) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// getCampaign возвращает промо-кампанию.
+// Метод доступен по пути GET /api/admin/campaigns/{id}
+//
+// Коды ответов:
+//   - 200 OK: возвращает кампанию в формате JSON
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: кампания не найдена
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getCampaign(c *gin.Context) {
+	id, ok := parseCampaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.services.Campaigns.GetCampaign(c, id)
+	if err != nil {
+		log.Errorf("Ошибка получения кампании: %s", err.Error())
+		respondCampaignError(c, err, "ошибка получения кампании")
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// updateCampaign заменяет параметры промо-кампании. Уже начисленные бонусы не меняются.
+// Метод доступен по пути PUT /api/admin/campaigns/{id}
+//
+// Коды ответов:
+//   - 200 OK: кампания изменена, в ответе данные кампании в формате JSON
+//   - 400 Bad Request: неверный формат запроса, некорректный идентификатор или параметры кампании
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: кампания не найдена
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) updateCampaign(c *gin.Context) {
+	id, ok := parseCampaignID(c)
+	if !ok {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.CampaignRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на изменение кампании: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	campaign, err := h.services.Campaigns.UpdateCampaign(c, id, input, actor)
+	if err != nil {
+		log.Errorf("Ошибка изменения кампании: %s", err.Error())
+		respondCampaignError(c, err, "ошибка изменения кампании")
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// deleteCampaign удаляет промо-кампанию. Начисленные по ней бонусы сохраняются.
+// Метод доступен по пути DELETE /api/admin/campaigns/{id}
+//
+// Коды ответов:
+//   - 204 No Content: кампания удалена
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: кампания не найдена
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) deleteCampaign(c *gin.Context) {
+	id, ok := parseCampaignID(c)
+	if !ok {
+		return
+	}
+
+	actor, err := getActor(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := h.services.Campaigns.DeleteCampaign(c, id, actor); err != nil {
+		log.Errorf("Ошибка удаления кампании: %s", err.Error())
+		respondCampaignError(c, err, "ошибка удаления кампании")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getBonuses возвращает бонусы промо-кампаний, начисленные пользователю, с названием кампании
+// и номером заказа, за который начислен бонус.
+// Метод доступен по пути GET /api/user/bonuses
+//
+// Коды ответов:
+//   - 200 OK: возвращает список бонусов в формате JSON
+//   - 204 No Content: бонусов нет
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getBonuses(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	bonuses, err := h.services.Campaigns.GetBonuses(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения бонусов: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения бонусов")
+		return
+	}
+
+	if len(bonuses) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, bonuses)
+}
+
+func parseCampaignID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "некорректный идентификатор кампании")
+		return 0, false
+	}
+
+	return id, true
+}
+
+func respondCampaignError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, customerrors.ErrCampaignNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, customerrors.ErrInvalidCampaign):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+
+	// defaultCompressMinSize ответы меньше этого размера отправляются без сжатия:
+	// выигрыш на них меньше накладных расходов на заголовки gzip.
+	defaultCompressMinSize = 1024
+	// maxDecompressedBodySize ограничивает распакованное тело запроса, чтобы
+	// небольшой сжатый запрос не мог занять всю память сервера.
+	maxDecompressedBodySize = 10 << 20
+)
+
+var compressibleContentTypes = []string{
+	"application/json",
+	"application/x-ndjson",
+	"application/xml",
+	"text/plain",
+	"text/csv",
+	"text/html",
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	zlibWriters = sync.Pool{New: func() any { return zlib.NewWriter(io.Discard) }}
+)
+
+// compress распаковывает тела запросов с заголовком Content-Encoding: gzip или deflate
+// и сжимает ответы согласно Accept-Encoding, если их размер не меньше minSize.
+func (h *Handler) compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !decompressRequest(c) {
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minSize:        minSize,
+		}
+		c.Writer = cw
+		defer func() {
+			if err := cw.Close(); err != nil {
+				log.Errorf("Ошибка завершения сжатого ответа: %s", err.Error())
+			}
+			c.Writer = cw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+func decompressRequest(c *gin.Context) bool {
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding"))) {
+	case "", "identity":
+		return true
+	case encodingGzip:
+		reader, err = gzip.NewReader(c.Request.Body)
+	case encodingDeflate:
+		reader, err = zlib.NewReader(c.Request.Body)
+	default:
+		newErrorResponse(c, http.StatusUnsupportedMediaType, "неподдерживаемое сжатие тела запроса")
+		return false
+	}
+
+	if err != nil {
+		log.Errorf("Ошибка распаковки тела запроса: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "некорректное сжатое тело запроса")
+		return false
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, reader, maxDecompressedBodySize)
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Del("Content-Length")
+	c.Request.ContentLength = -1
+
+	return true
+}
+
+// negotiateEncoding выбирает gzip или deflate из заголовка Accept-Encoding с учетом
+// весов q; при равных весах предпочитается gzip.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			name = encodingGzip
+		}
+		if name != encodingGzip && name != encodingDeflate {
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && name == encodingGzip) {
+			best, bestQ = name, q
+		}
+	}
+
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
+
+// compressWriter накапливает начало ответа, пока не станет ясно, превышает ли он порог
+// сжатия, после чего пишет либо через gzip/deflate, либо напрямую.
+type compressWriter struct {
+	gin.ResponseWriter
+
+	encoding string
+	minSize  int
+	buf      []byte
+	decided  bool
+	writer   io.WriteCloser
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		return w.write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) < w.minSize {
+		return len(data), nil
+	}
+
+	if err := w.decide(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush отправляет накопленные данные клиенту. Потоковые ответы решают вопрос
+// о сжатии по первому сбросу, чтобы не задерживать данные до достижения порога.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			log.Errorf("Ошибка записи ответа: %s", err.Error())
+			return
+		}
+	}
+
+	if flusher, ok := w.writer.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			log.Errorf("Ошибка сброса сжатого ответа: %s", err.Error())
+		}
+	}
+
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close дописывает ответ, который так и не достиг порога сжатия, и завершает поток сжатия.
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+
+	if w.writer == nil {
+		return nil
+	}
+
+	err := w.writer.Close()
+	w.release()
+	return err
+}
+
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	if w.shouldCompress() {
+		header := w.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+
+		w.writer = w.acquire()
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.write(buf)
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if len(w.buf) < w.minSize {
+		return false
+	}
+
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	for _, compressible := range compressibleContentTypes {
+		if strings.HasPrefix(contentType, compressible) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *compressWriter) write(data []byte) (int, error) {
+	if w.writer != nil {
+		return w.writer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) acquire() io.WriteCloser {
+	if w.encoding == encodingGzip {
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(w.ResponseWriter)
+		return gz
+	}
+
+	zw := zlibWriters.Get().(*zlib.Writer)
+	zw.Reset(w.ResponseWriter)
+	return zw
+}
+
+func (w *compressWriter) release() {
+	switch writer := w.writer.(type) {
+	case *gzip.Writer:
+		gzipWriters.Put(writer)
+	case *zlib.Writer:
+		zlibWriters.Put(writer)
+	}
+	w.writer = nil
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// getDailyReports возвращает суточные финансовые отчеты арендатора: начисления по заказам, списания,
+// новых пользователей, заказы по конечным статусам и обязательства по баллам.
+// Параметры запроса from и to (YYYY-MM-DD) задают период включительно, по умолчанию — последние 30 суток;
+// параметр format — json (по умолчанию) или csv.
+// Метод доступен по пути GET /api/admin/reports/daily
+//
+// Коды ответов:
+//   - 200 OK: возвращает отчеты в формате JSON или CSV
+//   - 204 No Content: отчетов за период нет
+//   - 400 Bad Request: некорректный период или формат
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getDailyReports(c *gin.Context) {
+	from, to, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+
+	switch format := model.ExportFormat(c.DefaultQuery("format", string(model.ExportJSON))); format {
+	case model.ExportJSON:
+		reports, err := h.services.Finance.GetDailyReports(c, from, to)
+		if err != nil {
+			log.Errorf("Ошибка получения финансовых отчетов: %s", err.Error())
+			respondReportError(c, err)
+			return
+		}
+
+		if len(reports) == 0 {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		c.JSON(http.StatusOK, reports)
+	case model.ExportCSV:
+		// Отчеты за период невелики, поэтому выгрузка собирается целиком и ошибку можно вернуть кодом ответа.
+		var buf bytes.Buffer
+		if err := h.services.Finance.ExportDailyReports(c, from, to, format, &buf); err != nil {
+			log.Errorf("Ошибка выгрузки финансовых отчетов: %s", err.Error())
+			respondReportError(c, err)
+			return
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="finance-daily.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "неподдерживаемый формат выгрузки")
+	}
+}
+
+// getReportSummary возвращает итоги суточных финансовых отчетов арендатора за период и последние
+// зафиксированные обязательства по баллам. Принимает те же параметры from и to, что и GET /api/admin/reports/daily.
+// Метод доступен по пути GET /api/admin/reports/summary
+//
+// Коды ответов:
+//   - 200 OK: возвращает итоги в формате JSON
+//   - 400 Bad Request: некорректный период
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getReportSummary(c *gin.Context) {
+	from, to, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.services.Finance.GetSummary(c, from, to)
+	if err != nil {
+		log.Errorf("Ошибка получения итогов финансовых отчетов: %s", err.Error())
+		respondReportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func parseReportPeriod(c *gin.Context) (from, to time.Time, ok bool) {
+	var err error
+	if from, err = parseTimeParam(c, "from"); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return from, to, false
+	}
+	if to, err = parseTimeParam(c, "to"); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return from, to, false
+	}
+	return from, to, true
+}
+
+func respondReportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, customerrors.ErrInvalidPeriod), errors.Is(err, customerrors.ErrInvalidExportFormat):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения финансовых отчетов")
+	}
+}
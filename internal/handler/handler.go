@@ -5,9 +5,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/Gerfey/gophermart/internal/service"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -16,7 +20,11 @@ import (
 // Handler структура, содержащая все HTTP обработчики для API.
 // Использует сервисный слой для выполнения бизнес-логики.
 type Handler struct {
-	services *service.Service
+	services       *service.Service
+	trustedProxies []string
+
+	streamsDone      chan struct{}
+	closeStreamsOnce sync.Once
 }
 
 // NewHandler создает новый экземпляр Handler с указанными сервисами.
@@ -27,43 +35,164 @@ type Handler struct {
 //   - *Handler: новый экземпляр обработчика
 func NewHandler(services *service.Service) *Handler {
 	return &Handler{
-		services: services,
+		services:    services,
+		streamsDone: make(chan struct{}),
 	}
 }
 
+// SetTrustedProxies задает адреса и подсети прокси, которым разрешено передавать IP клиента
+// в заголовке X-Forwarded-For. Без них IP клиента берется из адреса соединения, иначе клиент
+// мог бы подставить чужой IP и обойти ограничения, привязанные к IP.
+// Должен вызываться до InitRoutes.
+func (h *Handler) SetTrustedProxies(proxies []string) {
+	h.trustedProxies = proxies
+}
+
+// CloseStreams завершает все открытые потоки событий. Вызывается при остановке сервера,
+// так как долгоживущие соединения иначе задерживают корректное завершение работы.
+func (h *Handler) CloseStreams() {
+	h.closeStreamsOnce.Do(func() {
+		close(h.streamsDone)
+	})
+}
+
 // InitRoutes инициализирует все маршруты API и возвращает настроенный роутер.
 // Настраивает следующие эндпоинты:
 //   - POST /api/user/register - регистрация нового пользователя
 //   - POST /api/user/login - аутентификация пользователя
 //   - POST /api/user/orders - загрузка нового заказа (требует аутентификации)
+//   - POST /api/user/orders/batch - пакетная загрузка заказов (требует аутентификации)
 //   - GET /api/user/orders - получение списка заказов (требует аутентификации)
+//   - GET /api/user/orders/stream - поток событий заказов и баланса (требует аутентификации)
+//   - GET /api/user/orders/{number} - заказ с историей статусов (требует аутентификации)
 //   - GET /api/user/balance - получение текущего баланса (требует аутентификации)
 //   - POST /api/user/balance/withdraw - списание средств (требует аутентификации)
+//   - POST /api/user/balance/transfer - перевод баллов другому пользователю (требует аутентификации)
+//   - GET /api/user/balance/transfers - история переводов (требует аутентификации)
+//   - GET, POST /api/user/balance/reservations - резервы баллов под заказ (требует аутентификации)
+//   - POST /api/user/balance/reservations/{id}/confirm - подтверждение резерва (требует аутентификации)
+//   - POST /api/user/balance/reservations/{id}/cancel - отмена резерва (требует аутентификации)
 //   - GET /api/user/withdrawals - история списаний (требует аутентификации)
+//   - GET /api/user/statement - выписка движения баллов в CSV или XLSX (требует аутентификации)
+//   - GET /api/user/profile - профиль с уровнем лояльности и историей уровней (требует аутентификации)
+//   - GET /api/user/bonuses - бонусы промо-кампаний (требует аутентификации)
+//   - GET /api/user/referrals - реферальный код и приглашенные пользователи (требует аутентификации)
+//   - GET /api/user/export - выгрузка персональных данных в JSON или ZIP (требует аутентификации)
+//   - DELETE /api/user - удаление учетной записи (требует аутентификации)
+//   - GET /api/admin/users - поиск пользователей по логину (роль support или admin)
+//   - GET /api/admin/users/{id} - пользователь и его баланс (роль support или admin)
+//   - GET /api/admin/users/{id}/orders - заказы пользователя (роль support или admin)
+//   - GET /api/admin/users/{id}/withdrawals - списания пользователя (роль support или admin)
+//   - GET /api/admin/users/{id}/balance/adjustments - корректировки баланса (роль support или admin)
+//   - POST /api/admin/orders/{number}/requeue - повторная проверка заказа (роль support или admin)
+//   - POST /api/admin/users/{id}/balance/adjustments - ручная корректировка баланса (роль admin)
+//   - GET, PUT /api/admin/users/{id}/roles - роли пользователя (роль admin)
+//   - GET /api/admin/users/{id}/roles/history - журнал изменений ролей (роль admin)
+//   - GET /api/admin/audit - журнал аудита с фильтрами (роль admin)
+//   - GET /api/admin/audit/export - выгрузка журнала аудита в CSV или JSONL (роль admin)
+//   - GET, POST /api/admin/webhooks - подписки на вебхуки (роль admin)
+//   - DELETE /api/admin/webhooks/{id} - удаление подписки (роль admin)
+//   - GET /api/admin/webhooks/deliveries - доставки вебхуков (роль admin)
+//   - POST /api/admin/webhooks/deliveries/{id}/replay - повторная отправка (роль admin)
+//   - GET, POST /api/admin/campaigns - промо-кампании (роль admin)
+//   - GET, PUT, DELETE /api/admin/campaigns/{id} - промо-кампания (роль admin)
+//   - GET /api/admin/reports/daily - суточные финансовые отчеты в JSON или CSV (роль finance)
+//   - GET /api/admin/reports/summary - итоги финансовых отчетов за период (роль finance)
+//
+// Регистрация и вход ограничены по IP клиента, который берется из X-Forwarded-For только
+// для доверенных прокси (см. SetTrustedProxies), загрузка заказов, списание и переводы — по пользователю.
+// Арендатор запроса определяется заголовком X-Tenant или хостом; неизвестный арендатор — 400.
 //
 // Возвращает:
 //   - *gin.Engine: настроенный роутер с зарегистрированными обработчиками
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	// Обработчики передают *gin.Context в сервисы как context.Context,
+	// поэтому значения и отмена должны браться из контекста запроса.
+	router.ContextWithFallback = true
+	if err := router.SetTrustedProxies(h.trustedProxies); err != nil {
+		log.Errorf("Ошибка настройки доверенных прокси, заголовки X-Forwarded-For не учитываются: %s", err.Error())
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(h.requestMeta)
+	router.Use(h.resolveTenant)
+	router.Use(h.compress(defaultCompressMinSize))
 
 	api := router.Group("/api")
 	{
 		user := api.Group("/user")
 		{
-			user.POST("/register", h.registerUser)
-			user.POST("/login", h.loginUser)
+			user.POST("/register", h.rateLimit(config.RateLimitRegister, clientIPKey), h.registerUser)
+			user.POST("/login", h.rateLimit(config.RateLimitLogin, clientIPKey), h.loginUser)
+			user.DELETE("", h.userIdentity, h.deleteAccount)
 
 			authenticated := user.Group("/", h.userIdentity)
 			{
-				authenticated.POST("/orders", h.createOrder)
+				authenticated.POST("/orders", h.rateLimit(config.RateLimitOrders, userIDKey), h.createOrder)
+				authenticated.POST("/orders/batch", h.rateLimit(config.RateLimitOrders, userIDKey), h.createOrdersBatch)
 				authenticated.GET("/orders", h.getOrders)
+				authenticated.GET("/orders/stream", h.streamOrders)
+				authenticated.GET("/orders/:number", h.getOrder)
 
 				authenticated.GET("/balance", h.getBalance)
-				authenticated.POST("/balance/withdraw", h.withdrawFromBalance)
+				authenticated.POST("/balance/withdraw", h.rateLimit(config.RateLimitWithdraw, userIDKey), h.withdrawFromBalance)
+				authenticated.POST("/balance/transfer", h.rateLimit(config.RateLimitTransfer, userIDKey), h.transferPoints)
+				authenticated.GET("/balance/transfers", h.getTransfers)
+				authenticated.GET("/balance/reservations", h.getReservations)
+				authenticated.POST("/balance/reservations", h.rateLimit(config.RateLimitWithdraw, userIDKey), h.reservePoints)
+				authenticated.POST("/balance/reservations/:id/confirm", h.confirmReservation)
+				authenticated.POST("/balance/reservations/:id/cancel", h.cancelReservation)
 				authenticated.GET("/withdrawals", h.getWithdrawals)
+				authenticated.GET("/statement", h.getStatement)
+				authenticated.GET("/profile", h.getProfile)
+				authenticated.GET("/bonuses", h.getBonuses)
+				authenticated.GET("/referrals", h.getReferrals)
+				authenticated.GET("/export", h.exportUserData)
+			}
+		}
+
+		staff := api.Group("/admin", h.staffIdentity)
+		{
+			support := staff.Group("/", h.requireRole(model.RoleSupport, model.RoleAdmin))
+			{
+				support.GET("/users", h.searchUsers)
+				support.GET("/users/:id", h.getUser)
+				support.GET("/users/:id/orders", h.getUserOrders)
+				support.GET("/users/:id/withdrawals", h.getUserWithdrawals)
+				support.GET("/users/:id/balance/adjustments", h.getBalanceAdjustments)
+				support.POST("/orders/:number/requeue", h.requeueOrder)
+			}
+
+			admin := staff.Group("/", h.requireRole(model.RoleAdmin))
+			{
+				admin.POST("/users/:id/balance/adjustments", h.adjustBalance)
+				admin.GET("/users/:id/roles", h.getUserRoles)
+				admin.PUT("/users/:id/roles", h.setUserRoles)
+				admin.GET("/users/:id/roles/history", h.getRoleChanges)
+
+				admin.GET("/audit", h.getAuditEvents)
+				admin.GET("/audit/export", h.exportAuditEvents)
+
+				admin.GET("/webhooks", h.getWebhookSubscriptions)
+				admin.POST("/webhooks", h.createWebhookSubscription)
+				admin.DELETE("/webhooks/:id", h.deleteWebhookSubscription)
+				admin.GET("/webhooks/deliveries", h.getWebhookDeliveries)
+				admin.POST("/webhooks/deliveries/:id/replay", h.replayWebhookDelivery)
+
+				admin.GET("/campaigns", h.getCampaigns)
+				admin.POST("/campaigns", h.createCampaign)
+				admin.GET("/campaigns/:id", h.getCampaign)
+				admin.PUT("/campaigns/:id", h.updateCampaign)
+				admin.DELETE("/campaigns/:id", h.deleteCampaign)
+			}
+
+			finance := staff.Group("/", h.requireRole(model.RoleFinance))
+			{
+				finance.GET("/reports/daily", h.getDailyReports)
+				finance.GET("/reports/summary", h.getReportSummary)
 			}
 		}
 	}
@@ -95,6 +224,14 @@ func NewServer(addr string, handler http.Handler) *Server {
 	}
 }
 
+// clearWriteDeadline снимает дедлайн записи ответа для потоковых ответов и выгрузок,
+// которые могут передаваться дольше WriteTimeout сервера. what описывает ответ для лога.
+func clearWriteDeadline(c *gin.Context, what string) {
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Warnf("Не удалось снять дедлайн записи для %s: %s", what, err.Error())
+	}
+}
+
 // Run запускает HTTP-сервер и блокирует выполнение до завершения работы сервера.
 // Возвращает ошибку, если сервер не удалось запустить или произошла ошибка во время работы.
 //
@@ -105,6 +242,14 @@ func (s *Server) Run() error {
 	return s.httpServer.ListenAndServe()
 }
 
+// RegisterOnShutdown регистрирует функцию, вызываемую в начале завершения работы сервера.
+//
+// Параметры:
+//   - f: функция, вызываемая при остановке сервера
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.httpServer.RegisterOnShutdown(f)
+}
+
 // Shutdown выполняет корректное завершение работы HTTP-сервера.
 // Ожидает завершения всех активных соединений или истечения времени контекста.
 //
@@ -1,19 +1,104 @@
 package handler
 
 import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
 	authorizationHeader = "Authorization"
+	requestIDHeader     = "X-Request-ID"
+	tenantHeader        = "X-Tenant"
 	userCtx             = "userID"
+	tenantCtx           = "tenant"
+	staffCtx            = "staff"
+	rolesCtx            = "roles"
 )
 
+// requestMeta сохраняет в контексте запроса IP, user agent и идентификатор запроса для журнала аудита.
+// Идентификатор берется из заголовка X-Request-ID или генерируется и возвращается клиенту.
+func (h *Handler) requestMeta(c *gin.Context) {
+	requestID := c.GetHeader(requestIDHeader)
+	if requestID == "" || len(requestID) > 128 {
+		requestID = newRequestID()
+	}
+	c.Header(requestIDHeader, requestID)
+
+	ctx := service.WithRequestMeta(c.Request.Context(), model.RequestMeta{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: requestID,
+	})
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+}
+
+// resolveTenant определяет арендатора запроса по заголовку X-Tenant или хосту
+// и сохраняет его в контексте, откуда его берут сервисы.
+func (h *Handler) resolveTenant(c *gin.Context) {
+	tenant := model.DefaultTenant
+	if h.services.Tenants != nil {
+		resolved, err := h.services.Tenants.Resolve(c.GetHeader(tenantHeader), c.Request.Host)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		tenant = resolved
+	}
+
+	c.Set(tenantCtx, tenant)
+	c.Request = c.Request.WithContext(service.WithTenant(c.Request.Context(), tenant))
+
+	c.Next()
+}
+
+// validSession отклоняет запрос с токеном удаленной учетной записи: удаление отзывает все ее сессии.
+func (h *Handler) validSession(c *gin.Context, userID int64) bool {
+	if h.services.Accounts == nil {
+		return true
+	}
+
+	err := h.services.Accounts.ValidateSession(c, userID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, customerrors.ErrUserDeleted), errors.Is(err, customerrors.ErrUserNotFound):
+		newErrorResponse(c, http.StatusUnauthorized, "сессия недействительна")
+	default:
+		log.Errorf("Ошибка проверки сессии пользователя %d: %s", userID, err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка проверки сессии")
+	}
+
+	return false
+
+}
+
+// sameTenant сообщает, выдан ли токен пользователя для арендатора текущего запроса.
+func sameTenant(c *gin.Context, identity *model.UserIdentity) bool {
+	return cmp.Or(identity.Tenant, model.DefaultTenant) == c.GetString(tenantCtx)
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
 func (h *Handler) userIdentity(c *gin.Context) {
 	header := c.GetHeader(authorizationHeader)
 	if header == "" {
@@ -29,17 +114,102 @@ func (h *Handler) userIdentity(c *gin.Context) {
 
 	token := headerParts[1]
 
-	userID, err := h.services.Users.ParseToken(token)
+	identity, err := h.services.Users.ParseToken(token)
 	if err != nil {
 		log.Errorf("Ошибка парсинга токена: %s", err.Error())
 		newErrorResponse(c, http.StatusUnauthorized, "неверный токен авторизации")
 		return
 	}
 
-	c.Set(userCtx, userID)
+	if !sameTenant(c, identity) {
+		newErrorResponse(c, http.StatusUnauthorized, "токен выдан для другого арендатора")
+		return
+	}
+
+	if !h.validSession(c, identity.UserID) {
+		return
+	}
+
+	c.Set(userCtx, identity.UserID)
+	c.Set(rolesCtx, identity.Roles)
+	c.Next()
+}
+
+// staffIdentity аутентифицирует сотрудника по служебному токену API либо
+// по JWT пользователя, текущие роли которого проверяются далее через requireRole.
+func (h *Handler) staffIdentity(c *gin.Context) {
+	token, ok := bearerToken(c)
+	if !ok {
+		newErrorResponse(c, http.StatusUnauthorized, "неверный формат заголовка авторизации")
+		return
+	}
+
+	staff, err := h.services.Staff.ParseStaffToken(token)
+	if err == nil {
+		c.Set(staffCtx, staff)
+		c.Set(rolesCtx, []model.Role{staff.Role})
+		c.Next()
+		return
+	}
+
+	identity, userErr := h.services.Users.ParseToken(token)
+	if userErr != nil {
+		log.Errorf("Ошибка проверки токена сотрудника: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "неверный токен сотрудника")
+		return
+	}
+
+	if !sameTenant(c, identity) {
+		newErrorResponse(c, http.StatusUnauthorized, "токен выдан для другого арендатора")
+		return
+	}
+
+	if !h.validSession(c, identity.UserID) {
+		return
+	}
+
+	// Роли берутся из базы, а не из токена, чтобы отзыв роли действовал сразу,
+	// не дожидаясь истечения срока токена.
+	roles, err := h.services.Users.GetRoles(c, identity.UserID)
+	if err != nil {
+		log.Errorf("Ошибка получения ролей пользователя %d: %s", identity.UserID, err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка проверки прав")
+		return
+	}
+
+	c.Set(userCtx, identity.UserID)
+	c.Set(rolesCtx, roles)
+	c.Request = c.Request.WithContext(service.WithTenantBound(c.Request.Context()))
 	c.Next()
 }
 
+// requireRole пропускает запрос дальше, только если у вызывающего есть одна из указанных ролей.
+// Должен устанавливаться после middleware аутентификации, заполняющего роли в контексте.
+func (h *Handler) requireRole(roles ...model.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(rolesCtx)
+		granted, _ := value.([]model.Role)
+
+		for _, role := range granted {
+			if slices.Contains(roles, role) {
+				c.Next()
+				return
+			}
+		}
+
+		newErrorResponse(c, http.StatusForbidden, "недостаточно прав")
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	headerParts := strings.Split(c.GetHeader(authorizationHeader), " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" || headerParts[1] == "" {
+		return "", false
+	}
+
+	return headerParts[1], true
+}
+
 func getUserID(c *gin.Context) (int64, error) {
 	id, ok := c.Get(userCtx)
 	if !ok {
@@ -53,3 +223,22 @@ func getUserID(c *gin.Context) (int64, error) {
 
 	return userID, nil
 }
+
+// getActor возвращает имя того, кто выполняет запрос, для записи в журналы:
+// имя сотрудника для служебного токена или "user:<id>" для пользователя с ролью.
+func getActor(c *gin.Context) (string, error) {
+	if value, ok := c.Get(staffCtx); ok {
+		staff, ok := value.(model.Staff)
+		if !ok {
+			return "", errors.New("неверный тип данных сотрудника")
+		}
+		return staff.Name, nil
+	}
+
+	userID, err := getUserID(c)
+	if err != nil {
+		return "", errors.New("сотрудник не аутентифицирован")
+	}
+
+	return fmt.Sprintf("user:%d", userID), nil
+}
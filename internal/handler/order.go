@@ -1,17 +1,27 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
+// maxBatchBodySize ограничивает тело пакетного запроса с запасом на MaxOrderBatchSize номеров.
+const maxBatchBodySize = 1 << 20
+
 // createOrder обрабатывает запрос на создание нового заказа.
-// Принимает номер заказа в теле запроса в текстовом формате, проверяет его валидность по алгоритму Луна
-// и регистрирует в системе. Метод доступен по пути POST /api/user/orders
+// Принимает номер заказа в теле запроса в текстовом формате, проверяет его правилами проверки номеров
+// заказов (по умолчанию длина и алгоритм Луна) и регистрирует в системе. Метод доступен по пути POST /api/user/orders
 //
 // Коды ответов:
 //   - 200 OK: заказ уже был зарегистрирован ранее этим же пользователем
@@ -19,7 +29,7 @@ import (
 //   - 400 Bad Request: неверный формат запроса или пустой номер заказа
 //   - 401 Unauthorized: пользователь не аутентифицирован
 //   - 409 Conflict: заказ уже зарегистрирован другим пользователем
-//   - 422 Unprocessable Entity: номер заказа не соответствует алгоритму Луна
+//   - 422 Unprocessable Entity: номер заказа не прошел проверку, в ответе указано нарушенное правило
 //   - 500 Internal Server Error: внутренняя ошибка сервера
 func (h *Handler) createOrder(c *gin.Context) {
 	userID, err := getUserID(c)
@@ -52,6 +62,68 @@ func (h *Handler) createOrder(c *gin.Context) {
 	c.Status(code)
 }
 
+// createOrdersBatch регистрирует пакет заказов текущего пользователя.
+// Принимает JSON-массив номеров (Content-Type: application/json) или текст с одним номером на строку.
+// В ответе для каждого номера возвращается результат: accepted, already_yours, conflict, invalid_luhn
+// или invalid_number с причиной в поле reason.
+// Метод доступен по пути POST /api/user/orders/batch
+//
+// Коды ответов:
+//   - 200 OK: пакет обработан, новых заказов нет
+//   - 202 Accepted: пакет обработан, хотя бы один новый заказ принят в обработку
+//   - 400 Bad Request: неверный формат запроса, пустой пакет или слишком много номеров
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) createOrdersBatch(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBatchBodySize))
+	if err != nil {
+		log.Errorf("Ошибка чтения тела запроса: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "ошибка чтения тела запроса")
+		return
+	}
+
+	var numbers []string
+	if c.ContentType() == gin.MIMEJSON {
+		if err := json.Unmarshal(body, &numbers); err != nil {
+			log.Errorf("Ошибка разбора пакета заказов: %s", err.Error())
+			newErrorResponse(c, http.StatusBadRequest, "ожидается JSON-массив номеров заказов")
+			return
+		}
+	} else {
+		numbers = strings.Split(string(body), "\n")
+	}
+
+	results, err := h.services.Orders.CreateOrders(c, userID, numbers)
+	if err != nil {
+		log.Errorf("Ошибка пакетного создания заказов: %s", err.Error())
+
+		if errors.Is(err, customerrors.ErrInvalidBatch) {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка пакетного создания заказов")
+		return
+	}
+
+	code := http.StatusOK
+	for _, result := range results {
+		if result.Result == model.BatchOrderAccepted {
+			code = http.StatusAccepted
+			break
+		}
+	}
+
+	c.JSON(code, results)
+}
+
 // getOrders возвращает список заказов текущего пользователя.
 // Метод доступен по пути GET /api/user/orders
 //
@@ -82,3 +154,107 @@ func (h *Handler) getOrders(c *gin.Context) {
 
 	c.JSON(http.StatusOK, orders)
 }
+
+// getOrder возвращает заказ текущего пользователя с историей смены статусов и исходными
+// статусами системы расчета. Метод доступен по пути GET /api/user/orders/{number}
+//
+// Коды ответов:
+//   - 200 OK: возвращает заказ и историю его статусов в формате JSON
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: заказ не найден у текущего пользователя
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getOrder(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	order, err := h.services.Orders.GetOrder(c, userID, c.Param("number"))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrOrderNotFound) {
+			newErrorResponse(c, http.StatusNotFound, customerrors.ErrOrderNotFound.Error())
+			return
+		}
+
+		log.Errorf("Ошибка получения заказа: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения заказа")
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// streamOrders открывает поток Server-Sent Events с изменениями статусов заказов и баланса пользователя.
+// Поддерживает возобновление потока по заголовку Last-Event-ID. Метод доступен по пути GET /api/user/orders/stream
+//
+// Коды ответов:
+//   - 200 OK: поток событий в формате text/event-stream
+//   - 400 Bad Request: некорректный заголовок Last-Event-ID
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) streamOrders(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	var lastEventID int64
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		lastEventID, err = strconv.ParseInt(header, 10, 64)
+		if err != nil || lastEventID < 0 {
+			newErrorResponse(c, http.StatusBadRequest, "некорректный заголовок Last-Event-ID")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	events, err := h.services.Events.Subscribe(ctx, userID, lastEventID)
+	if err != nil {
+		log.Errorf("Ошибка подписки на события: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка подписки на события")
+		return
+	}
+
+	// Поток живет дольше таймаута записи сервера, поэтому дедлайн для него снимается.
+	clearWriteDeadline(c, "потока событий")
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.streamsDone:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package order_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOrdersBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockOrderService := mockservice.NewMockOrderService(ctrl)
+
+	services := &service.Service{
+		Users:  mockUserService,
+		Orders: mockOrderService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	t.Run("JSONArray", func(t *testing.T) {
+		numbers := []string{"12345678903", "79927398713", "1234"}
+		results := []model.BatchOrderResult{
+			{Number: "12345678903", Result: model.BatchOrderAccepted},
+			{Number: "79927398713", Result: model.BatchOrderConflict},
+			{Number: "1234", Result: model.BatchOrderInvalidLuhn},
+		}
+
+		mockOrderService.EXPECT().
+			CreateOrders(gomock.Any(), userID, numbers).
+			Return(results, nil)
+
+		body, _ := json.Marshal(numbers)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders/batch", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		var response []model.BatchOrderResult
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, results, response)
+	})
+
+	t.Run("PlainTextNothingNew", func(t *testing.T) {
+		mockOrderService.EXPECT().
+			CreateOrders(gomock.Any(), userID, []string{"12345678903", "79927398713", ""}).
+			Return([]model.BatchOrderResult{
+				{Number: "12345678903", Result: model.BatchOrderAlreadyYours},
+				{Number: "79927398713", Result: model.BatchOrderAlreadyYours},
+			}, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders/batch", bytes.NewBufferString("12345678903\n79927398713\n"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders/batch", bytes.NewBufferString(`{"number": "1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("EmptyBatch", func(t *testing.T) {
+		mockOrderService.EXPECT().
+			CreateOrders(gomock.Any(), userID, []string{}).
+			Return(nil, fmt.Errorf("%w: пакет не содержит номеров заказов", customerrors.ErrInvalidBatch))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders/batch", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
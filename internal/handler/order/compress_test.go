@@ -0,0 +1,136 @@
+package order_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockOrderService := mockservice.NewMockOrderService(ctrl)
+
+	services := &service.Service{
+		Users:  mockUserService,
+		Orders: mockOrderService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	t.Run("GzipRequestBody", func(t *testing.T) {
+		orderNumber := "12345678903"
+
+		mockOrderService.EXPECT().
+			CreateOrder(gomock.Any(), userID, orderNumber).
+			Return(http.StatusAccepted, nil)
+
+		var body bytes.Buffer
+		gz := gzip.NewWriter(&body)
+		_, _ = gz.Write([]byte(orderNumber))
+		_ = gz.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders", &body)
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("CorruptedGzipRequestBody", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/orders", bytes.NewBufferString("12345678903"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("LargeResponseCompressed", func(t *testing.T) {
+		orders := make([]model.OrderResponse, 0, 100)
+		for i := 0; i < 100; i++ {
+			orders = append(orders, model.OrderResponse{
+				Number:     fmt.Sprintf("%011d", i),
+				Status:     model.OrderStatusProcessed,
+				Accrual:    500,
+				UploadedAt: time.Now(),
+			})
+		}
+
+		mockOrderService.EXPECT().
+			GetOrdersByUserID(gomock.Any(), userID).
+			Return(orders, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders", nil)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+		gz, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		data, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+
+		var response []model.OrderResponse
+		assert.NoError(t, json.Unmarshal(data, &response))
+		assert.Len(t, response, len(orders))
+	})
+
+	t.Run("SmallResponseNotCompressed", func(t *testing.T) {
+		orders := []model.OrderResponse{{Number: "12345678903", Status: model.OrderStatusNew}}
+
+		mockOrderService.EXPECT().
+			GetOrdersByUserID(gomock.Any(), userID).
+			Return(orders, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+		var response []model.OrderResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	})
+}
@@ -0,0 +1,114 @@
+package order_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockOrderService := mockservice.NewMockOrderService(ctrl)
+
+	services := &service.Service{
+		Users:  mockUserService,
+		Orders: mockOrderService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	t.Run("Success", func(t *testing.T) {
+		uploadedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		checkedAt := uploadedAt.Add(2 * time.Minute)
+		detail := &model.OrderDetailResponse{
+			Number:        "12345678903",
+			Status:        model.OrderStatusProcessed,
+			Accrual:       500,
+			UploadedAt:    uploadedAt,
+			LastCheckedAt: &checkedAt,
+			History: []model.OrderStatusChange{
+				{Status: model.OrderStatusNew, ChangedAt: uploadedAt},
+				{Status: model.OrderStatusProcessing, AccrualStatus: model.AccrualStatusProcessing, ChangedAt: uploadedAt.Add(time.Minute)},
+				{Status: model.OrderStatusProcessed, AccrualStatus: model.AccrualStatusProcessed, Accrual: 500, ChangedAt: checkedAt},
+			},
+		}
+
+		mockOrderService.EXPECT().
+			GetOrder(gomock.Any(), userID, "12345678903").
+			Return(detail, nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/12345678903", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.OrderDetailResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, *detail, response)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mockOrderService.EXPECT().
+			GetOrder(gomock.Any(), userID, "79927398713").
+			Return(nil, fmt.Errorf("%w", customerrors.ErrOrderNotFound))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/79927398713", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("InternalError", func(t *testing.T) {
+		mockOrderService.EXPECT().
+			GetOrder(gomock.Any(), userID, "12345678903").
+			Return(nil, fmt.Errorf("ошибка базы данных"))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/12345678903", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/12345678903", nil)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
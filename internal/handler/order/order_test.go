@@ -47,7 +47,7 @@ func TestCreateOrder(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	t.Run("SuccessfulOrderCreation", func(t *testing.T) {
@@ -141,7 +141,7 @@ func TestGetOrders(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	t.Run("SuccessfulOrdersRetrieval", func(t *testing.T) {
@@ -193,6 +193,71 @@ func TestGetOrders(t *testing.T) {
 	})
 }
 
+func TestStreamOrders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockOrderService := mockservice.NewMockOrderService(ctrl)
+	mockBalanceService := mockservice.NewMockBalanceService(ctrl)
+	mockEventService := mockservice.NewMockEventService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Orders:   mockOrderService,
+		Balances: mockBalanceService,
+		Events:   mockEventService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	t.Run("StreamResumesFromLastEventID", func(t *testing.T) {
+		events := make(chan *model.UserEvent, 1)
+		events <- &model.UserEvent{
+			ID:      42,
+			UserID:  userID,
+			Type:    model.UserEventOrder,
+			Payload: json.RawMessage(`{"number":"1234567890","status":"PROCESSED","accrual":500}`),
+		}
+		close(events)
+
+		mockEventService.EXPECT().
+			Subscribe(gomock.Any(), userID, int64(41)).
+			Return((<-chan *model.UserEvent)(events), nil)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/stream", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		req.Header.Set("Last-Event-ID", "41")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "id: 42\nevent: order\ndata: {\"number\":\"1234567890\"")
+	})
+
+	t.Run("InvalidLastEventID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/user/orders/stream", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		req.Header.Set("Last-Event-ID", "abc")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
 type MockAccrualService struct {
 	OrderStatuses map[string]model.AccrualResponse
 }
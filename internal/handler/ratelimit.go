@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// rateLimit ограничивает частоту запросов по правилу rule для ключа, который возвращает key.
+// В ответ добавляются заголовки RateLimit-Limit, RateLimit-Remaining и RateLimit-Reset,
+// а при превышении лимита возвращается 429 с заголовком Retry-After. Если сервис
+// ограничений не настроен или недоступен, запросы пропускаются.
+func (h *Handler) rateLimit(rule string, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.services.RateLimits == nil {
+			c.Next()
+			return
+		}
+
+		result, err := h.services.RateLimits.Allow(c, rule, key(c))
+		if err != nil {
+			log.Errorf("Ошибка проверки ограничения запросов %s: %s", rule, err.Error())
+			c.Next()
+			return
+		}
+
+		if result.Limit == 0 {
+			c.Next()
+			return
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(time.Until(result.ResetAt).Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			c.Header("Retry-After", reset)
+			newErrorResponse(c, http.StatusTooManyRequests, "превышен лимит запросов")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func clientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+func userIDKey(c *gin.Context) string {
+	userID, err := getUserID(c)
+	if err != nil {
+		return clientIPKey(c)
+	}
+	return "user:" + strconv.FormatInt(userID, 10)
+}
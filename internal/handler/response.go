@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -12,3 +16,17 @@ func newErrorResponse(c *gin.Context, statusCode int, message string) {
 		Error: message,
 	})
 }
+
+// newWithdrawalRuleResponse отвечает 422 с описанием нарушенного правила списания.
+func newWithdrawalRuleResponse(c *gin.Context, err error) {
+	response := model.ErrorResponse{Error: err.Error()}
+
+	var ruleErr *customerrors.WithdrawalRuleError
+	if errors.As(err, &ruleErr) {
+		response.Error = ruleErr.Error()
+		response.Rule = ruleErr.Rule
+	}
+
+	log.Error(response.Error)
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, response)
+}
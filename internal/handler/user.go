@@ -1,20 +1,25 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
 // registerUser обрабатывает запрос на регистрацию нового пользователя.
-// Принимает JSON с логином и паролем, создает нового пользователя и возвращает JWT токен.
+// Принимает JSON с логином, паролем и, опционально, реферальным кодом referral_code,
+// создает нового пользователя и возвращает JWT токен.
 // Метод доступен по пути POST /api/user/register
 //
 // Коды ответов:
 //   - 200 OK: пользователь успешно зарегистрирован, в заголовке Authorization возвращается токен
-//   - 400 Bad Request: неверный формат запроса или пустые логин/пароль
+//   - 400 Bad Request: неверный формат запроса, пустые логин/пароль или неверный реферальный код
 //   - 409 Conflict: пользователь с таким логином уже существует
 //   - 500 Internal Server Error: внутренняя ошибка сервера
 func (h *Handler) registerUser(c *gin.Context) {
@@ -31,10 +36,15 @@ func (h *Handler) registerUser(c *gin.Context) {
 		return
 	}
 
-	token, err := h.services.Users.RegisterUser(c, input.Login, input.Password)
+	token, err := h.services.Users.RegisterUser(c, input)
 	if err != nil {
 		log.Errorf("Ошибка регистрации пользователя: %s", err.Error())
 
+		if errors.Is(err, customerrors.ErrInvalidReferralCode) {
+			newErrorResponse(c, http.StatusBadRequest, customerrors.ErrInvalidReferralCode.Error())
+			return
+		}
+
 		if err.Error() == "пользователь с логином "+input.Login+" уже существует" {
 			newErrorResponse(c, http.StatusConflict, err.Error())
 			return
@@ -81,3 +91,128 @@ func (h *Handler) loginUser(c *gin.Context) {
 	c.Header("Authorization", "Bearer "+token)
 	c.Status(http.StatusOK)
 }
+
+// getProfile возвращает профиль пользователя с текущим уровнем лояльности, суммой начислений
+// за последние 12 месяцев, порогом следующего уровня и историей уровней.
+// Метод доступен по пути GET /api/user/profile
+//
+// Коды ответов:
+//   - 200 OK: возвращает профиль в формате JSON; поле tier отсутствует, если уровни лояльности не настроены
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getProfile(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	profile, err := h.services.Tiers.GetProfile(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения профиля: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения профиля")
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// getReferrals возвращает реферальный код пользователя и приглашенных им пользователей со статусом
+// приглашения и начисленным бонусом. Код выдается при первом обращении.
+// Метод доступен по пути GET /api/user/referrals
+//
+// Коды ответов:
+//   - 200 OK: возвращает код и список приглашений в формате JSON
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getReferrals(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	referrals, err := h.services.Referrals.GetReferrals(c, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения приглашений: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения приглашений")
+		return
+	}
+
+	c.JSON(http.StatusOK, referrals)
+}
+
+// exportUserData выгружает персональные данные пользователя: профиль, баланс, заказы, списания
+// и историю движения баллов. Формат задается параметром format: json (по умолчанию) или zip
+// с отдельным JSON-файлом на каждый раздел.
+// Метод доступен по пути GET /api/user/export
+//
+// Коды ответов:
+//   - 200 OK: возвращает выгрузку в виде файла
+//   - 400 Bad Request: неподдерживаемый формат выгрузки
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) exportUserData(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	format := model.ExportFormat(c.DefaultQuery("format", string(model.ExportJSON)))
+
+	var contentType string
+	switch format {
+	case model.ExportJSON:
+		contentType = "application/json; charset=utf-8"
+	case model.ExportZIP:
+		contentType = "application/zip"
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "неподдерживаемый формат выгрузки")
+		return
+	}
+
+	// Сбор выгрузки с полной историей может занять больше таймаута записи сервера.
+	clearWriteDeadline(c, "выгрузки данных пользователя")
+
+	// Выгрузка одного пользователя невелика, поэтому собирается целиком до отправки,
+	// чтобы ошибка вернулась клиенту кодом ответа, а не оборванным файлом.
+	var buf bytes.Buffer
+	if err := h.services.Accounts.Export(c, userID, format, &buf); err != nil {
+		log.Errorf("Ошибка выгрузки данных пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка выгрузки данных")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="gophermart-export.%s"`, format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// deleteAccount закрывает учетную запись пользователя: логин обезличивается, пароль сбрасывается,
+// все выданные токены перестают действовать. Заказы, баланс, списания и журнал движения баллов
+// сохраняются для отчетности.
+// Метод доступен по пути DELETE /api/user
+//
+// Коды ответов:
+//   - 204 No Content: учетная запись удалена
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) deleteAccount(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		log.Errorf("Ошибка получения ID пользователя: %s", err.Error())
+		newErrorResponse(c, http.StatusUnauthorized, "пользователь не аутентифицирован")
+		return
+	}
+
+	if err := h.services.Accounts.DeleteAccount(c, userID); err != nil {
+		log.Errorf("Ошибка удаления учетной записи: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка удаления учетной записи")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,123 @@
+package user_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockAccountService := mockservice.NewMockAccountService(ctrl)
+
+	services := &service.Service{
+		Users:    mockUserService,
+		Accounts: mockAccountService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("ExportJSON", func(t *testing.T) {
+		mockAccountService.EXPECT().ValidateSession(gomock.Any(), userID).Return(nil)
+		mockAccountService.EXPECT().
+			Export(gomock.Any(), userID, model.ExportJSON, gomock.Any()).
+			DoAndReturn(func(_ any, _ int64, _ model.ExportFormat, w io.Writer) error {
+				_, err := io.WriteString(w, `{"profile":{"login":"alice"}}`)
+				return err
+			})
+
+		w := do("GET", "/api/user/export")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "gophermart-export.json")
+		assert.JSONEq(t, `{"profile":{"login":"alice"}}`, w.Body.String())
+	})
+
+	t.Run("ExportZIP", func(t *testing.T) {
+		mockAccountService.EXPECT().ValidateSession(gomock.Any(), userID).Return(nil)
+		mockAccountService.EXPECT().
+			Export(gomock.Any(), userID, model.ExportZIP, gomock.Any()).
+			Return(nil)
+
+		w := do("GET", "/api/user/export?format=zip")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("ExportUnsupportedFormat", func(t *testing.T) {
+		mockAccountService.EXPECT().ValidateSession(gomock.Any(), userID).Return(nil)
+
+		w := do("GET", "/api/user/export?format=pdf")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("ExportError", func(t *testing.T) {
+		mockAccountService.EXPECT().ValidateSession(gomock.Any(), userID).Return(nil)
+		mockAccountService.EXPECT().
+			Export(gomock.Any(), userID, model.ExportJSON, gomock.Any()).
+			Return(errors.New("ошибка базы данных"))
+
+		w := do("GET", "/api/user/export")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockAccountService.EXPECT().ValidateSession(gomock.Any(), userID).Return(nil)
+		mockAccountService.EXPECT().DeleteAccount(gomock.Any(), userID).Return(nil)
+
+		w := do("DELETE", "/api/user")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("RevokedSession", func(t *testing.T) {
+		mockAccountService.EXPECT().
+			ValidateSession(gomock.Any(), userID).
+			Return(fmt.Errorf("%w", customerrors.ErrUserDeleted))
+
+		w := do("GET", "/api/user/export")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("SessionCheckError", func(t *testing.T) {
+		mockAccountService.EXPECT().
+			ValidateSession(gomock.Any(), userID).
+			Return(errors.New("ошибка базы данных"))
+
+		w := do("DELETE", "/api/user")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,83 @@
+package user_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockTierService := mockservice.NewMockTierService(ctrl)
+
+	services := &service.Service{
+		Users: mockUserService,
+		Tiers: mockTierService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	do := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/user/profile", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		profile := &model.ProfileResponse{
+			Login: "alice",
+			Tier: &model.TierResponse{
+				Name:             "SILVER",
+				Multiplier:       1.1,
+				Points:           1200,
+				NextTier:         "GOLD",
+				PointsToNextTier: 3800,
+			},
+		}
+
+		mockTierService.EXPECT().
+			GetProfile(gomock.Any(), userID).
+			Return(profile, nil)
+
+		w := do()
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ProfileResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, *profile, response)
+	})
+
+	t.Run("ServiceError", func(t *testing.T) {
+		mockTierService.EXPECT().
+			GetProfile(gomock.Any(), userID).
+			Return(nil, errors.New("ошибка базы данных"))
+
+		w := do()
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
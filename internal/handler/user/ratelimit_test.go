@@ -0,0 +1,126 @@
+package user_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+
+	cfg := &config.Config{
+		RateLimits: map[string]config.RateLimit{
+			config.RateLimitLogin: {Limit: 2, Window: time.Hour},
+		},
+	}
+
+	services := &service.Service{
+		Users:      mockUserService,
+		RateLimits: service.NewRateLimitService(repository.NewMemoryRateLimitStore(), cfg),
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	creds := model.UserCredentials{Login: "testuser", Password: "wrong"}
+	mockUserService.EXPECT().
+		LoginUser(gomock.Any(), creds.Login, creds.Password).
+		Return("", errors.New("неверный логин или пароль")).
+		Times(2)
+
+	loginVia := func(router http.Handler, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(creds)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		req.RemoteAddr = remoteAddr
+
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		return loginVia(router, remoteAddr, "")
+	}
+
+	w := login("10.0.0.1:1234")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	w = login("10.0.0.1:1234")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = login("10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+
+	t.Run("ForgedForwardedForIgnored", func(t *testing.T) {
+		w := loginVia(router, "10.0.0.1:1234", "203.0.113.7")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	})
+
+	t.Run("ForwardedForFromTrustedProxy", func(t *testing.T) {
+		mockUserService.EXPECT().
+			LoginUser(gomock.Any(), creds.Login, creds.Password).
+			Return("", errors.New("неверный логин или пароль"))
+
+		proxied := handler.NewHandler(services)
+		proxied.SetTrustedProxies([]string{"10.0.0.0/24"})
+
+		w := loginVia(proxied.InitRoutes(), "10.0.0.1:1234", "203.0.113.7")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("OtherClientNotAffected", func(t *testing.T) {
+		mockUserService.EXPECT().
+			LoginUser(gomock.Any(), creds.Login, creds.Password).
+			Return("valid_token", nil)
+
+		w := login("10.0.0.2:1234")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("UnlimitedRouteHasNoHeaders", func(t *testing.T) {
+		mockUserService.EXPECT().
+			RegisterUser(gomock.Any(), creds).
+			Return("valid_token", nil)
+
+		body, _ := json.Marshal(creds)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/user/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	})
+}
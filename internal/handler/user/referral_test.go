@@ -0,0 +1,102 @@
+package user_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserService := mockservice.NewMockUserService(ctrl)
+	mockReferralService := mockservice.NewMockReferralService(ctrl)
+
+	services := &service.Service{
+		Users:     mockUserService,
+		Referrals: mockReferralService,
+	}
+
+	h := handler.NewHandler(services)
+	router := h.InitRoutes()
+
+	userID := int64(1)
+	mockUserService.EXPECT().
+		ParseToken(gomock.Any()).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
+		AnyTimes()
+
+	getReferrals := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/user/referrals", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("GetReferrals", func(t *testing.T) {
+		mockReferralService.EXPECT().
+			GetReferrals(gomock.Any(), userID).
+			Return(&model.ReferralsResponse{
+				Code: "ABCD2345",
+				Referrals: []*model.Referral{
+					{RefereeLogin: "bob", Status: model.ReferralStatusRewarded, ReferrerBonus: 100},
+				},
+			}, nil)
+
+		w := getReferrals()
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response model.ReferralsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "ABCD2345", response.Code)
+		require.Len(t, response.Referrals, 1)
+		assert.Equal(t, "bob", response.Referrals[0].RefereeLogin)
+		assert.Equal(t, 100.0, response.Referrals[0].ReferrerBonus)
+	})
+
+	t.Run("GetReferralsError", func(t *testing.T) {
+		mockReferralService.EXPECT().
+			GetReferrals(gomock.Any(), userID).
+			Return(nil, errors.New("ошибка базы данных"))
+
+		w := getReferrals()
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("RegisterWithInvalidCode", func(t *testing.T) {
+		creds := model.UserCredentials{Login: "carol", Password: "password", ReferralCode: "UNKNOWN"}
+
+		mockUserService.EXPECT().
+			RegisterUser(gomock.Any(), creds).
+			Return("", fmt.Errorf("ошибка проверки реферального кода: %w", customerrors.ErrInvalidReferralCode))
+
+		body, _ := json.Marshal(creds)
+		req, _ := http.NewRequest("POST", "/api/user/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Authorization"))
+	})
+}
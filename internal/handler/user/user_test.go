@@ -50,7 +50,7 @@ func TestRegister(t *testing.T) {
 		}
 
 		mockUserService.EXPECT().
-			RegisterUser(gomock.Any(), creds.Login, creds.Password).
+			RegisterUser(gomock.Any(), creds).
 			Return("valid_token", nil)
 
 		requestBody, _ := json.Marshal(creds)
@@ -71,7 +71,7 @@ func TestRegister(t *testing.T) {
 		}
 
 		mockUserService.EXPECT().
-			RegisterUser(gomock.Any(), creds.Login, creds.Password).
+			RegisterUser(gomock.Any(), creds).
 			Return("", fmt.Errorf("пользователь с логином %s уже существует", creds.Login))
 
 		requestBody, _ := json.Marshal(creds)
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// createWebhookSubscription создает подписку на исходящие вебхуки.
+// Принимает JSON с адресом получателя, типами событий и, опционально, секретом и арендатором.
+// Метод доступен по пути POST /api/admin/webhooks
+//
+// Коды ответов:
+//   - 201 Created: подписка создана, в ответе возвращается секрет для проверки подписи
+//   - 400 Bad Request: неверный формат запроса или некорректные параметры подписки
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) createWebhookSubscription(c *gin.Context) {
+	var input model.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Errorf("Ошибка разбора запроса на создание подписки: %s", err.Error())
+		newErrorResponse(c, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	sub, err := h.services.Webhooks.CreateSubscription(c, input)
+	if err != nil {
+		log.Errorf("Ошибка создания подписки на вебхуки: %s", err.Error())
+
+		if errors.Is(err, customerrors.ErrInvalidWebhook) {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка создания подписки")
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// getWebhookSubscriptions возвращает список подписок на вебхуки без секретов.
+// Метод доступен по пути GET /api/admin/webhooks
+//
+// Коды ответов:
+//   - 200 OK: возвращает список подписок в формате JSON
+//   - 204 No Content: подписок нет
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getWebhookSubscriptions(c *gin.Context) {
+	subscriptions, err := h.services.Webhooks.GetSubscriptions(c)
+	if err != nil {
+		log.Errorf("Ошибка получения подписок на вебхуки: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения подписок")
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// deleteWebhookSubscription удаляет подписку на вебхуки.
+// Метод доступен по пути DELETE /api/admin/webhooks/{id}
+//
+// Коды ответов:
+//   - 204 No Content: подписка удалена
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: подписка не найдена
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) deleteWebhookSubscription(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "некорректный идентификатор подписки")
+		return
+	}
+
+	if err := h.services.Webhooks.DeleteSubscription(c, id); err != nil {
+		log.Errorf("Ошибка удаления подписки на вебхуки: %s", err.Error())
+
+		if errors.Is(err, customerrors.ErrWebhookNotFound) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка удаления подписки")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getWebhookDeliveries возвращает последние доставки вебхуков.
+// Параметр запроса status (PENDING, DELIVERED, DEAD) ограничивает выборку одним статусом.
+// Метод доступен по пути GET /api/admin/webhooks/deliveries
+//
+// Коды ответов:
+//   - 200 OK: возвращает список доставок в формате JSON
+//   - 204 No Content: доставок нет
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	status := model.WebhookDeliveryStatus(c.Query("status"))
+
+	deliveries, err := h.services.Webhooks.GetDeliveries(c, status)
+	if err != nil {
+		log.Errorf("Ошибка получения доставок вебхуков: %s", err.Error())
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка получения доставок")
+		return
+	}
+
+	if len(deliveries) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// replayWebhookDelivery ставит доставку вебхука в очередь на повторную отправку.
+// Метод доступен по пути POST /api/admin/webhooks/deliveries/{id}/replay
+//
+// Коды ответов:
+//   - 202 Accepted: доставка поставлена в очередь
+//   - 400 Bad Request: некорректный идентификатор
+//   - 401 Unauthorized: неверный токен сотрудника
+//   - 403 Forbidden: недостаточно прав
+//   - 404 Not Found: доставка не найдена
+//   - 500 Internal Server Error: внутренняя ошибка сервера
+func (h *Handler) replayWebhookDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "некорректный идентификатор доставки")
+		return
+	}
+
+	if err := h.services.Webhooks.ReplayDelivery(c, id); err != nil {
+		log.Errorf("Ошибка повторной отправки вебхука: %s", err.Error())
+
+		if errors.Is(err, customerrors.ErrWebhookNotFound) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, "ошибка повторной отправки")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
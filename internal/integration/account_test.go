@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountExportAndDeletion(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	cfg := &config.Config{JWTSigningKey: "secret"}
+	audit := service.NewAuditService(repos.Audit)
+	users := service.NewUserService(repos.Users, audit, nil, nil, cfg)
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, cfg)
+	accounts := service.NewAccountService(repos.Users, repos.Orders, repos.Balances, audit)
+
+	_, err := users.LoginUser(ctx, "alice", "password")
+	require.Error(t, err)
+	_, err = users.RegisterUser(ctx, model.UserCredentials{Login: "alice", Password: "password"})
+	require.NoError(t, err)
+	alice, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "alice")
+	require.NoError(t, err)
+	bobID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+
+	_, err = repos.Orders.CreateOrder(ctx, model.DefaultTenant, alice.ID, "12345678903")
+	require.NoError(t, err)
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(alice.ID, 500, "12345678903")
+	require.NoError(t, balances.Withdraw(ctx, alice.ID, model.WithdrawRequest{Order: "2377225624", Sum: 100}))
+	_, err = balances.Transfer(ctx, alice.ID, model.TransferRequest{Recipient: "bob", Sum: 50}, "key-1")
+	require.NoError(t, err)
+
+	t.Run("ExportJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, accounts.Export(ctx, alice.ID, model.ExportJSON, &buf))
+
+		var export model.UserExport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+		assert.Equal(t, "alice", export.Profile.Login)
+		assert.Equal(t, []model.Role{model.RoleCustomer}, export.Profile.Roles)
+		assert.Equal(t, 350.0, export.Balance.Current)
+		require.Len(t, export.Orders, 1)
+		assert.Equal(t, "12345678903", export.Orders[0].Number)
+		require.Len(t, export.Withdrawals, 1)
+		assert.Equal(t, 100.0, export.Withdrawals[0].Sum)
+
+		operations := make(map[string]float64)
+		for _, entry := range export.BalanceHistory {
+			operations[entry.Operation] += entry.Amount
+		}
+		assert.Equal(t, map[string]float64{
+			string(model.LedgerAccrual):     500,
+			"withdrawal":                    -100,
+			string(model.LedgerTransferOut): -50,
+		}, operations)
+		assert.NotContains(t, buf.String(), "password")
+	})
+
+	t.Run("ExportZIP", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, accounts.Export(ctx, alice.ID, model.ExportZIP, &buf))
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		files := make(map[string][]byte)
+		for _, file := range archive.File {
+			r, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			r.Close()
+			files[file.Name] = data
+		}
+
+		assert.Len(t, files, 5)
+		var profile model.ExportProfile
+		require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+		assert.Equal(t, "alice", profile.Login)
+
+		var orders []model.OrderResponse
+		require.NoError(t, json.Unmarshal(files["orders.json"], &orders))
+		assert.Len(t, orders, 1)
+	})
+
+	t.Run("ExportUnsupportedFormat", func(t *testing.T) {
+		err := accounts.Export(ctx, alice.ID, model.ExportCSV, io.Discard)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidExportFormat)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repos.Referrals.CreateReferral(ctx, &model.Referral{ReferrerID: bobID, RefereeID: alice.ID, IP: "10.0.0.1"}))
+		_, err := balances.Reserve(ctx, alice.ID, model.ReservationRequest{Order: "79927398713", Sum: 30})
+		require.NoError(t, err)
+
+		require.NoError(t, accounts.ValidateSession(ctx, alice.ID))
+		require.NoError(t, accounts.DeleteAccount(ctx, alice.ID))
+
+		assert.ErrorIs(t, accounts.ValidateSession(ctx, alice.ID), customerrors.ErrUserDeleted)
+		assert.ErrorIs(t, accounts.DeleteAccount(ctx, alice.ID), customerrors.ErrUserNotFound)
+
+		deleted, err := repos.Users.GetUserByID(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, "alice", deleted.Login)
+		assert.Empty(t, deleted.PasswordHash)
+
+		events, err := repos.Audit.GetAuditEvents(ctx, model.AuditFilter{Limit: 100})
+		require.NoError(t, err)
+		redacted := 0
+		for _, event := range events {
+			assert.NotEqual(t, "alice", event.Target)
+			assert.NotContains(t, string(event.After), `"alice"`)
+			if event.Target == deleted.Login || strings.Contains(string(event.After), deleted.Login) {
+				redacted++
+			}
+		}
+		assert.Equal(t, 2, redacted)
+
+		referrals, err := repos.Referrals.GetReferrals(ctx, bobID)
+		require.NoError(t, err)
+		require.Len(t, referrals, 1)
+		assert.Empty(t, referrals[0].IP)
+
+		reservations, err := balances.GetReservations(ctx, alice.ID)
+		require.NoError(t, err)
+		require.Len(t, reservations, 1)
+		assert.Equal(t, model.ReservationStatusCancelled, reservations[0].Status)
+
+		_, err = users.LoginUser(ctx, "alice", "password")
+		assert.Error(t, err)
+
+		balance, err := balances.GetBalance(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 350.0, balance.Current)
+
+		withdrawals, err := balances.GetWithdrawals(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Len(t, withdrawals, 1)
+
+		_, err = balances.Transfer(ctx, bobID, model.TransferRequest{Recipient: deleted.Login, Sum: 10}, "key-2")
+		assert.ErrorIs(t, err, customerrors.ErrUserNotFound)
+
+		_, err = users.RegisterUser(ctx, model.UserCredentials{Login: "alice", Password: "password"})
+		assert.NoError(t, err)
+	})
+}
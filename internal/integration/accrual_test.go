@@ -96,7 +96,7 @@ func TestAccrualIntegration(t *testing.T) {
 	userID := int64(1)
 	mockUserService.EXPECT().
 		ParseToken(gomock.Any()).
-		Return(userID, nil).
+		Return(&model.UserIdentity{UserID: userID, Roles: []model.Role{model.RoleCustomer}}, nil).
 		AnyTimes()
 
 	accrualService := NewMockAccrualService()
@@ -176,7 +176,7 @@ func TestAccrualIntegration(t *testing.T) {
 		withdrawOrder := "9876543210"
 
 		mockBalanceService.EXPECT().
-			Withdraw(gomock.Any(), userID, withdrawOrder, withdrawAmount).
+			Withdraw(gomock.Any(), userID, model.WithdrawRequest{Order: withdrawOrder, Sum: withdrawAmount}).
 			Return(nil)
 
 		withdrawRequest := map[string]interface{}{
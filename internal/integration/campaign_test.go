@@ -0,0 +1,143 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaigns(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	campaigns := service.NewCampaignService(repos.Campaigns, repos.Orders, nil)
+	users := service.NewUserService(repos.Users, nil, campaigns, nil, &config.Config{JWTSigningKey: "secret"})
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{})
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	welcome, err := campaigns.CreateCampaign(ctx, model.CampaignRequest{
+		Name:   "Приветственный бонус",
+		Type:   model.CampaignWelcomeBonus,
+		Amount: 100,
+	}, "admin")
+	require.NoError(t, err)
+
+	_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{
+		Name:       "Двойные баллы",
+		Type:       model.CampaignAccrualMultiplier,
+		Multiplier: 2,
+		StartsAt:   &past,
+		EndsAt:     &future,
+	}, "admin")
+	require.NoError(t, err)
+
+	_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{
+		Name:       "Бонус за второй заказ",
+		Type:       model.CampaignNthOrder,
+		Amount:     50,
+		OrderIndex: 2,
+	}, "admin")
+	require.NoError(t, err)
+
+	inactive := false
+	_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{
+		Name:   "Отключенный бонус",
+		Type:   model.CampaignWelcomeBonus,
+		Amount: 1000,
+		Active: &inactive,
+	}, "admin")
+	require.NoError(t, err)
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := campaigns.CreateCampaign(ctx, model.CampaignRequest{Name: "x", Type: model.CampaignAccrualMultiplier, Multiplier: 1}, "admin")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidCampaign)
+
+		_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{Name: "x", Type: model.CampaignNthOrder, Amount: 10}, "admin")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidCampaign)
+
+		_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{Name: "x", Type: "unknown", Amount: 10}, "admin")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidCampaign)
+
+		_, err = campaigns.CreateCampaign(ctx, model.CampaignRequest{Name: "x", Type: model.CampaignWelcomeBonus, Amount: 10, StartsAt: &future, EndsAt: &past}, "admin")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidCampaign)
+	})
+
+	t.Run("WelcomeBonus", func(t *testing.T) {
+		_, err := users.RegisterUser(ctx, model.UserCredentials{Login: "alice", Password: "password"})
+		require.NoError(t, err)
+
+		alice, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "alice")
+		require.NoError(t, err)
+
+		balance, err := balances.GetBalance(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, balance.Current)
+
+		campaigns.OnRegistration(ctx, alice.ID)
+		balance, _ = balances.GetBalance(ctx, alice.ID)
+		assert.Equal(t, 100.0, balance.Current)
+
+		_, err = users.RegisterUser(service.WithTenant(ctx, "shop2"), model.UserCredentials{Login: "bob", Password: "password"})
+		require.NoError(t, err)
+		bob, err := repos.Users.GetUserByLogin(ctx, "shop2", "bob")
+		require.NoError(t, err)
+
+		bonuses, err := campaigns.GetBonuses(ctx, bob.ID)
+		require.NoError(t, err)
+		assert.Empty(t, bonuses)
+	})
+
+	t.Run("OrderBonuses", func(t *testing.T) {
+		alice, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "alice")
+		require.NoError(t, err)
+
+		var orders []*model.Order
+		for _, number := range []string{"2377225624", "12345678903"} {
+			_, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, alice.ID, number)
+			require.NoError(t, err)
+			order, err := repos.Orders.GetOrderByNumber(ctx, model.DefaultTenant, number)
+			require.NoError(t, err)
+			orders = append(orders, order)
+			time.Sleep(time.Millisecond)
+		}
+
+		require.NoError(t, campaigns.OnOrderProcessed(ctx, orders[0], 30))
+		require.NoError(t, campaigns.OnOrderProcessed(ctx, orders[1], 20))
+		require.NoError(t, campaigns.OnOrderProcessed(ctx, orders[1], 20))
+
+		balance, err := balances.GetBalance(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0+30+20+50, balance.Current)
+
+		bonuses, err := campaigns.GetBonuses(ctx, alice.ID)
+		require.NoError(t, err)
+		require.Len(t, bonuses, 4)
+		assert.Equal(t, "Бонус за второй заказ", bonuses[0].CampaignName)
+		assert.Equal(t, "12345678903", bonuses[0].OrderNumber)
+		assert.Equal(t, welcome.ID, bonuses[3].CampaignID)
+	})
+
+	t.Run("DeleteKeepsBonuses", func(t *testing.T) {
+		require.NoError(t, campaigns.DeleteCampaign(ctx, welcome.ID, "admin"))
+
+		_, err := campaigns.GetCampaign(ctx, welcome.ID)
+		assert.ErrorIs(t, err, customerrors.ErrCampaignNotFound)
+
+		alice, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "alice")
+		require.NoError(t, err)
+		bonuses, err := campaigns.GetBonuses(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Приветственный бонус", bonuses[3].CampaignName)
+	})
+}
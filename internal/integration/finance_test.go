@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFinanceReports(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+	cfg := &config.Config{}
+
+	finance := service.NewFinanceService(repos.Finance, cfg)
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, cfg)
+
+	count, err := finance.MaterializeReports(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	aliceID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "alice", "hash")
+	require.NoError(t, err)
+	_, err = repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+	carolID, err := repos.Users.CreateUser(ctx, "shop2", "carol", "hash")
+	require.NoError(t, err)
+
+	processedID, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, aliceID, "12345678903")
+	require.NoError(t, err)
+	require.NoError(t, repos.Orders.UpdateOrderAccrual(ctx, processedID, 500))
+	require.NoError(t, repos.Orders.RecordOrderCheck(ctx, processedID, model.AccrualStatusProcessed))
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(aliceID, 500, "12345678903")
+
+	invalidID, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, aliceID, "79927398713")
+	require.NoError(t, err)
+	require.NoError(t, repos.Orders.UpdateOrderStatus(ctx, invalidID, model.OrderStatusInvalid))
+	require.NoError(t, repos.Orders.RecordOrderCheck(ctx, invalidID, model.AccrualStatusInvalid))
+
+	_, err = repos.Orders.CreateOrder(ctx, model.DefaultTenant, aliceID, "4561261212345467")
+	require.NoError(t, err)
+
+	require.NoError(t, balances.Withdraw(ctx, aliceID, model.WithdrawRequest{Order: "2377225624", Sum: 120}))
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(carolID, 80, "")
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	count, err = finance.MaterializeReports(ctx, tomorrow)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = finance.MaterializeReports(ctx, tomorrow)
+	require.NoError(t, err)
+	assert.Zero(t, count, "рассчитанные сутки не пересчитываются")
+
+	t.Run("Daily", func(t *testing.T) {
+		reports, err := finance.GetDailyReports(ctx, today, today)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+
+		report := reports[0]
+		assert.Equal(t, today.Format(time.DateOnly), report.Day)
+		assert.Equal(t, model.DefaultTenant, report.Tenant)
+		assert.Equal(t, 500.0, report.Accrued)
+		assert.Equal(t, 120.0, report.Withdrawn)
+		assert.Equal(t, 2, report.NewUsers)
+		assert.Equal(t, 1, report.OrdersProcessed)
+		assert.Equal(t, 1, report.OrdersInvalid)
+		assert.Zero(t, report.OrdersUnregistered)
+		require.NotNil(t, report.Liability)
+		assert.Equal(t, 380.0, *report.Liability)
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		reports, err := finance.GetDailyReports(service.WithTenant(ctx, "shop2"), today, today)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+
+		assert.Equal(t, 1, reports[0].NewUsers)
+		assert.Zero(t, reports[0].Accrued)
+		assert.Equal(t, 80.0, *reports[0].Liability)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		summary, err := finance.GetSummary(ctx, today.AddDate(0, 0, -6), today)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, summary.Days)
+		assert.Equal(t, 500.0, summary.Accrued)
+		assert.Equal(t, 120.0, summary.Withdrawn)
+		assert.Equal(t, 2, summary.NewUsers)
+		assert.Equal(t, 380.0, *summary.Liability)
+		assert.Equal(t, today.Format(time.DateOnly), summary.LiabilityDay)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, finance.ExportDailyReports(ctx, today, today, model.ExportCSV, &buf))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "liability", records[0][8])
+		assert.Equal(t, []string{today.Format(time.DateOnly), model.DefaultTenant, "500.00", "120.00", "2", "1", "1", "0", "380.00"}, records[1])
+
+		err = finance.ExportDailyReports(ctx, today, today, model.ExportXLSX, &buf)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidExportFormat)
+	})
+
+	t.Run("InvalidPeriod", func(t *testing.T) {
+		_, err := finance.GetDailyReports(ctx, today, today.AddDate(0, 0, -1))
+		assert.ErrorIs(t, err, customerrors.ErrInvalidPeriod)
+
+		_, err = finance.GetSummary(ctx, today.AddDate(-2, 0, 0), today)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidPeriod)
+	})
+	t.Run("ScheduledRunClaimed", func(t *testing.T) {
+		runCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		finance.RunReportMaterialization(runCtx)
+
+		// Экземпляр, проснувшийся следом, расчет не запускает.
+		started, err := repos.Finance.StartReportMaterialization(ctx, time.Hour)
+		require.NoError(t, err)
+		assert.False(t, started)
+	})
+}
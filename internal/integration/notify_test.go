@@ -0,0 +1,243 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	mockservice "github.com/Gerfey/gophermart/internal/tests/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOrderNotificationTriggersImmediateCheck(t *testing.T) {
+	orderNumber := "4561261212345467"
+
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(model.AccrualResponse{
+			Order:   orderNumber,
+			Status:  model.AccrualStatusProcessed,
+			Accrual: 729.98,
+		})
+	}))
+	defer accrualServer.Close()
+
+	repos := repository.NewRepositoriesForTests()
+	listener := repos.Notifications.(*repository.NotificationListenerMock)
+
+	cfg := &config.Config{
+		AccrualSystemAddress: accrualServer.URL,
+		WorkerID:             "test-worker",
+	}
+	orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, nil, nil, nil, nil, service.NewAccrualClient(cfg), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		orders.ProcessOrdersBackground(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	userID := int64(1)
+	_, err := repos.Orders.CreateOrder(context.Background(), model.DefaultTenant, userID, orderNumber)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		listener.Notify(repository.NewOrdersChannel, orderNumber)
+
+		balance, err := repos.Balances.GetBalance(context.Background(), userID)
+		return err == nil && balance.Current > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	order, err := repos.Orders.GetOrderByNumber(context.Background(), model.DefaultTenant, orderNumber)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusProcessed, order.Status)
+	assert.InDelta(t, 729.98, order.Accrual, 0.001)
+
+	before, err := repos.Balances.GetBalance(context.Background(), userID)
+	require.NoError(t, err)
+
+	err = repos.Orders.ProcessOrderAccrual(context.Background(), order.ID, 729.98, nil, time.Minute)
+	assert.ErrorIs(t, err, customerrors.ErrOrderProcessed)
+
+	after, err := repos.Balances.GetBalance(context.Background(), userID)
+	require.NoError(t, err)
+	assert.InDelta(t, before.Current, after.Current, 0.001)
+}
+
+func TestUnregisteredOrderAging(t *testing.T) {
+	orderNumber := "4561261212345467"
+
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer accrualServer.Close()
+
+	run := func(t *testing.T, ttl time.Duration) *repository.Repository {
+		repos := repository.NewRepositoriesForTests()
+		listener := repos.Notifications.(*repository.NotificationListenerMock)
+
+		cfg := &config.Config{
+			AccrualSystemAddress:        accrualServer.URL,
+			WorkerID:                    "test-worker",
+			UnregisteredOrderTTL:        ttl,
+			UnregisteredOrderMaxBackoff: time.Hour,
+		}
+		orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, nil, nil, nil, nil, service.NewAccrualClient(cfg), cfg)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			orders.ProcessOrdersBackground(ctx)
+			close(done)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+
+		orderID, err := repos.Orders.CreateOrder(context.Background(), model.DefaultTenant, 1, orderNumber)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			listener.Notify(repository.NewOrdersChannel, orderNumber)
+
+			history, err := repos.Orders.GetOrderHistory(context.Background(), orderID)
+			return err == nil && len(history) > 1
+		}, 2*time.Second, 20*time.Millisecond)
+
+		return repos
+	}
+
+	t.Run("BackoffBeforeTTL", func(t *testing.T) {
+		repos := run(t, time.Hour)
+
+		order, err := repos.Orders.GetOrderByNumber(context.Background(), model.DefaultTenant, orderNumber)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderStatusNew, order.Status)
+		assert.NotNil(t, order.LastCheckedAt)
+
+		history, err := repos.Orders.GetOrderHistory(context.Background(), order.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.AccrualStatusNotRegistered, history[len(history)-1].AccrualStatus)
+
+		claimed, err := repos.Orders.ClaimOrdersForCheck(context.Background(), "other-worker", 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, claimed)
+	})
+
+	t.Run("ExpiresAfterTTL", func(t *testing.T) {
+		repos := run(t, time.Nanosecond)
+
+		order, err := repos.Orders.GetOrderByNumber(context.Background(), model.DefaultTenant, orderNumber)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderStatusUnregistered, order.Status)
+
+		history, err := repos.Orders.GetOrderHistory(context.Background(), order.ID)
+		require.NoError(t, err)
+		last := history[len(history)-1]
+		assert.Equal(t, model.OrderStatusUnregistered, last.Status)
+		assert.Equal(t, model.AccrualStatusNotRegistered, last.AccrualStatus)
+
+		require.NoError(t, repos.Orders.RequeueOrder(context.Background(), model.DefaultTenant, orderNumber))
+
+		order, err = repos.Orders.GetOrderByNumber(context.Background(), model.DefaultTenant, orderNumber)
+		require.NoError(t, err)
+		assert.Equal(t, model.OrderStatusNew, order.Status)
+	})
+}
+
+func TestClaimOrdersPrefersUncheckedOrders(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	oldID, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, 1, "12345678903")
+	require.NoError(t, err)
+	newID, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, 1, "79927398713")
+	require.NoError(t, err)
+
+	require.NoError(t, repos.Orders.RecordOrderCheck(ctx, oldID, model.AccrualStatusRegistered))
+
+	claimed, err := repos.Orders.ClaimOrdersForCheck(ctx, "test-worker", 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, newID, claimed[0].ID)
+}
+
+func TestOrderBonusesRetriedAfterFailure(t *testing.T) {
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(model.AccrualResponse{
+			Order:   path.Base(r.URL.Path),
+			Status:  model.AccrualStatusProcessed,
+			Accrual: 100,
+		})
+	}))
+	defer accrualServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	campaigns := mockservice.NewMockCampaignService(ctrl)
+	gomock.InOrder(
+		campaigns.EXPECT().OnOrderProcessed(gomock.Any(), gomock.Any(), 100.0).Return(errors.New("база недоступна")),
+		campaigns.EXPECT().OnOrderProcessed(gomock.Any(), gomock.Any(), 100.0).Return(nil),
+	)
+
+	repos := repository.NewRepositoriesForTests()
+	listener := repos.Notifications.(*repository.NotificationListenerMock)
+	orderRepo := repos.Orders.(*repository.OrderRepoMock)
+
+	cfg := &config.Config{
+		AccrualSystemAddress: accrualServer.URL,
+		WorkerID:             "test-worker",
+	}
+	orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, nil, campaigns, nil, nil, service.NewAccrualClient(cfg), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		orders.ProcessOrdersBackground(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, number := range []string{"12345678903", "79927398713"} {
+		_, err := repos.Orders.CreateOrder(context.Background(), model.DefaultTenant, 1, number)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			listener.Notify(repository.NewOrdersChannel, number)
+
+			order, err := repos.Orders.GetOrderByNumber(context.Background(), model.DefaultTenant, number)
+			return err == nil && order.Status == model.OrderStatusProcessed
+		}, 2*time.Second, 20*time.Millisecond)
+	}
+
+	orderRepo.ExpireOrderBonusClaims()
+	tasks, err := repos.Orders.ClaimOrderBonuses(context.Background(), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "12345678903", tasks[0].Order.Number)
+	assert.InDelta(t, 100, tasks[0].Accrual, 0.001)
+	assert.Equal(t, 1, tasks[0].Attempts)
+}
@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliation(t *testing.T) {
+	responses := map[string]model.AccrualResponse{
+		"12345678903":      {Status: model.AccrualStatusProcessed, Accrual: 100},
+		"79927398713":      {Status: model.AccrualStatusProcessed, Accrual: 150},
+		"4561261212345467": {Status: model.AccrualStatusInvalid},
+		"5062821234567892": {Status: model.AccrualStatusProcessed, Accrual: 60},
+	}
+
+	var throttled atomic.Bool
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		number := r.URL.Path[len("/api/orders/"):]
+		if number == "12345678903" && throttled.CompareAndSwap(false, true) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		response, ok := responses[number]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		response.Order = number
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer accrualServer.Close()
+
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+	balances := repos.Balances.(*repository.BalanceRepoMock)
+	reports := repos.Reconciliation.(*repository.ReconciliationRepoMock)
+
+	aliceID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "alice", "hash")
+	require.NoError(t, err)
+	bobID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+	carolID, err := repos.Users.CreateUser(ctx, "shop2", "carol", "hash")
+	require.NoError(t, err)
+
+	process := func(number string, accrual float64, credit bool) int64 {
+		orderID, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, aliceID, number)
+		require.NoError(t, err)
+		require.NoError(t, repos.Orders.UpdateOrderAccrual(ctx, orderID, accrual))
+		if credit {
+			require.NoError(t, repos.Balances.AddAccrual(ctx, aliceID, orderID, accrual, nil))
+		}
+		return orderID
+	}
+
+	process("12345678903", 100, true)
+	mismatchedID := process("79927398713", 100, true)
+	process("4561261212345467", 80, true)
+	process("2377225624", 40, true)
+	process("5062821234567892", 60, false)
+	require.NoError(t, repos.Balances.Withdraw(ctx, aliceID, 20, "9278923470", nil))
+
+	balances.SetBalance(bobID, 999, 0)
+	balances.SetBalance(carolID, 0, 30)
+
+	cfg := &config.Config{AccrualSystemAddress: accrualServer.URL}
+	accrual := service.NewAccrualClient(cfg)
+	reconciliation := service.NewReconciliationService(repos.Reconciliation, nil, accrual, cfg)
+
+	kinds := func(discrepancies []*model.ReconciliationDiscrepancy) map[string]model.ReconciliationKind {
+		result := make(map[string]model.ReconciliationKind)
+		for _, discrepancy := range discrepancies {
+			key := discrepancy.OrderNumber
+			if key == "" {
+				key = string(discrepancy.Kind) + "/" + discrepancy.Tenant
+			}
+			result[key] = discrepancy.Kind
+		}
+		return result
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		run, err := reconciliation.Reconcile(ctx, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, 5, run.OrdersChecked)
+		assert.Zero(t, run.OrdersFailed, "после ответа 429 запрос повторяется")
+		assert.Equal(t, 3, run.BalancesChecked)
+		assert.Equal(t, 6, run.Discrepancies)
+		assert.Zero(t, run.Corrected)
+		assert.NotNil(t, run.FinishedAt)
+
+		discrepancies := reports.Discrepancies(run.ID)
+		assert.Equal(t, map[string]model.ReconciliationKind{
+			"79927398713":             model.ReconciliationOrderAccrual,
+			"4561261212345467":        model.ReconciliationOrderStatus,
+			"2377225624":              model.ReconciliationOrderStatus,
+			"5062821234567892":        model.ReconciliationOrderLedger,
+			"balance_current/default": model.ReconciliationBalanceCurrent,
+			"balance_withdrawn/shop2": model.ReconciliationBalanceWithdrawn,
+		}, kinds(discrepancies))
+
+		for _, discrepancy := range discrepancies {
+			switch discrepancy.Kind {
+			case model.ReconciliationOrderAccrual:
+				assert.Equal(t, 150.0, discrepancy.Expected)
+				assert.Equal(t, 100.0, discrepancy.Actual)
+			case model.ReconciliationOrderLedger:
+				assert.Equal(t, 60.0, discrepancy.Expected)
+				assert.Zero(t, discrepancy.Actual)
+			case model.ReconciliationBalanceCurrent:
+				assert.Equal(t, bobID, discrepancy.UserID)
+				assert.Zero(t, discrepancy.Expected)
+				assert.Equal(t, 999.0, discrepancy.Actual)
+			}
+			assert.False(t, discrepancy.Corrected)
+		}
+
+		balance, err := repos.Balances.GetBalance(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, 300.0, balance.Current)
+	})
+
+	t.Run("AutoFix", func(t *testing.T) {
+		run, err := reconciliation.Reconcile(ctx, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, 6, run.Discrepancies)
+		assert.Equal(t, 3, run.Corrected)
+
+		for _, discrepancy := range reports.Discrepancies(run.ID) {
+			switch discrepancy.Kind {
+			case model.ReconciliationOrderAccrual:
+				assert.True(t, discrepancy.Corrected)
+				require.NotNil(t, discrepancy.AdjustmentID)
+			case model.ReconciliationBalanceCurrent, model.ReconciliationBalanceWithdrawn:
+				assert.True(t, discrepancy.Corrected)
+				require.NotNil(t, discrepancy.AdjustmentID)
+			default:
+				assert.False(t, discrepancy.Corrected, "расхождение %s исправляется только вручную", discrepancy.Kind)
+			}
+		}
+
+		balance, err := repos.Balances.GetBalance(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, 350.0, balance.Current)
+
+		adjustments, err := repos.Balances.GetAdjustments(ctx, aliceID)
+		require.NoError(t, err)
+		require.Len(t, adjustments, 1)
+		assert.Equal(t, 50.0, adjustments[0].Amount)
+		assert.Equal(t, model.ReconciliationActor, adjustments[0].Actor)
+		assert.Contains(t, adjustments[0].Reason, "79927398713")
+
+		orders, err := repos.Orders.GetOrdersByUserID(ctx, aliceID)
+		require.NoError(t, err)
+		for _, order := range orders {
+			if order.ID == mismatchedID {
+				assert.Equal(t, 150.0, order.Accrual)
+			}
+		}
+
+		bob, err := repos.Balances.GetBalance(ctx, bobID)
+		require.NoError(t, err)
+		assert.Zero(t, bob.Current)
+
+		bobAdjustments, err := repos.Balances.GetAdjustments(ctx, bobID)
+		require.NoError(t, err)
+		require.Len(t, bobAdjustments, 1)
+		assert.True(t, bobAdjustments[0].Drift)
+		assert.Equal(t, -999.0, bobAdjustments[0].Amount)
+		assert.Equal(t, model.ReconciliationActor, bobAdjustments[0].Actor)
+
+		bobHistory, err := repos.Balances.GetBalanceAt(ctx, bobID, time.Now().Add(time.Second))
+		require.NoError(t, err)
+		assert.Zero(t, bobHistory, "исправление расхождения не входит в историю движения баллов")
+
+		carol, err := repos.Balances.GetBalance(ctx, carolID)
+		require.NoError(t, err)
+		assert.Zero(t, carol.Withdrawn)
+
+		carolAdjustments, err := repos.Balances.GetAdjustments(ctx, carolID)
+		require.NoError(t, err)
+		require.Len(t, carolAdjustments, 1)
+		assert.Zero(t, carolAdjustments[0].Amount)
+		assert.Equal(t, -30.0, carolAdjustments[0].Withdrawn)
+	})
+
+	t.Run("AfterFix", func(t *testing.T) {
+		run, err := reconciliation.Reconcile(ctx, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]model.ReconciliationKind{
+			"4561261212345467": model.ReconciliationOrderStatus,
+			"2377225624":       model.ReconciliationOrderStatus,
+			"5062821234567892": model.ReconciliationOrderLedger,
+		}, kinds(reports.Discrepancies(run.ID)))
+		assert.Zero(t, run.Corrected)
+
+		adjustments, err := repos.Balances.GetAdjustments(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Len(t, adjustments, 1, "исправленное начисление не корректируется повторно")
+	})
+
+	t.Run("Sample", func(t *testing.T) {
+		sampled := service.NewReconciliationService(repos.Reconciliation, nil, accrual, &config.Config{
+			ReconciliationSampleSize: 2,
+		})
+
+		run, err := sampled.Reconcile(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 2, run.OrdersChecked)
+	})
+}
@@ -0,0 +1,194 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrals(t *testing.T) {
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(model.AccrualResponse{
+			Status:  model.AccrualStatusProcessed,
+			Accrual: 10,
+		})
+	}))
+	defer accrualServer.Close()
+
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+	listener := repos.Notifications.(*repository.NotificationListenerMock)
+
+	cfg := &config.Config{
+		AccrualSystemAddress:  accrualServer.URL,
+		WorkerID:              "test-worker",
+		JWTSigningKey:         "secret",
+		ReferralReferrerBonus: 100,
+		ReferralRefereeBonus:  50,
+		ReferralMaxRewards:    1,
+		ReferralMaxPerIP:      2,
+	}
+	referrals := service.NewReferralService(repos.Referrals, cfg)
+	users := service.NewUserService(repos.Users, nil, nil, referrals, cfg)
+	orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, nil, nil, referrals, nil, service.NewAccrualClient(cfg), cfg)
+
+	fromIP := func(ip string) context.Context {
+		return service.WithRequestMeta(ctx, model.RequestMeta{IP: ip})
+	}
+
+	register := func(ip, login, code string) (int64, error) {
+		_, err := users.RegisterUser(fromIP(ip), model.UserCredentials{Login: login, Password: "password", ReferralCode: code})
+		if err != nil {
+			return 0, err
+		}
+		user, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, login)
+		require.NoError(t, err)
+		return user.ID, nil
+	}
+
+	balance := func(userID int64) float64 {
+		balance, err := repos.Balances.GetBalance(ctx, userID)
+		require.NoError(t, err)
+		return balance.Current
+	}
+
+	processOrder := func(userID int64, number string) {
+		processCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			orders.ProcessOrdersBackground(processCtx)
+			close(done)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		_, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, userID, number)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			listener.Notify(repository.NewOrdersChannel, number)
+
+			order, err := repos.Orders.GetOrderByNumber(ctx, model.DefaultTenant, number)
+			return err == nil && order.Status == model.OrderStatusProcessed
+		}, 2*time.Second, 20*time.Millisecond)
+	}
+
+	aliceID, err := register("10.0.0.1", "alice", "")
+	require.NoError(t, err)
+
+	response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+	require.NoError(t, err)
+	require.Len(t, response.Code, 8)
+	assert.Empty(t, response.Referrals)
+	code := response.Code
+
+	again, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, code, again.Code)
+
+	t.Run("InvalidCode", func(t *testing.T) {
+		_, err := register("10.0.0.2", "mallory", "NOSUCHCODE")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidReferralCode)
+
+		_, err = repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "mallory")
+		assert.Error(t, err)
+
+		_, err = users.RegisterUser(service.WithTenant(fromIP("10.0.0.2"), "shop2"),
+			model.UserCredentials{Login: "mallory", Password: "password", ReferralCode: code})
+		assert.ErrorIs(t, err, customerrors.ErrInvalidReferralCode)
+	})
+
+	t.Run("RewardOnFirstProcessedOrder", func(t *testing.T) {
+		bobID, err := register("10.0.0.2", "bob", code)
+		require.NoError(t, err)
+
+		processOrder(bobID, "12345678903")
+		assert.Equal(t, 100.0, balance(aliceID))
+		assert.Equal(t, 10.0+50, balance(bobID))
+
+		processOrder(bobID, "2377225624")
+		assert.Equal(t, 100.0, balance(aliceID))
+		assert.Equal(t, 10.0+50+10, balance(bobID))
+
+		response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+		require.NoError(t, err)
+		require.Len(t, response.Referrals, 1)
+		assert.Equal(t, "bob", response.Referrals[0].RefereeLogin)
+		assert.Equal(t, model.ReferralStatusRewarded, response.Referrals[0].Status)
+		assert.NotNil(t, response.Referrals[0].RewardedAt)
+	})
+
+	t.Run("SelfReferralByIP", func(t *testing.T) {
+		alice2ID, err := register("10.0.0.1", "alice2", code)
+		require.NoError(t, err)
+
+		processOrder(alice2ID, "4561261212345467")
+		assert.Equal(t, 10.0, balance(alice2ID))
+
+		response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ReferralStatusRejected, response.Referrals[0].Status)
+		assert.Equal(t, model.ReferralRejectSameIP, response.Referrals[0].RejectReason)
+	})
+
+	t.Run("ReferrerLimit", func(t *testing.T) {
+		carolID, err := register("10.0.0.3", "carol", code)
+		require.NoError(t, err)
+
+		processOrder(carolID, "79927398713")
+		assert.Equal(t, 100.0, balance(aliceID))
+		assert.Equal(t, 10.0, balance(carolID))
+
+		response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ReferralRejectReferrerLimit, response.Referrals[0].RejectReason)
+	})
+
+	t.Run("IPLimit", func(t *testing.T) {
+		_, err := register("10.0.0.3", "dave", code)
+		require.NoError(t, err)
+		_, err = register("10.0.0.3", "erin", code)
+		require.NoError(t, err)
+
+		response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, "erin", response.Referrals[0].RefereeLogin)
+		assert.Equal(t, model.ReferralRejectIPLimit, response.Referrals[0].RejectReason)
+		assert.Equal(t, model.ReferralStatusPending, response.Referrals[1].Status)
+	})
+
+	t.Run("ForgedForwardedForIgnored", func(t *testing.T) {
+		router := handler.NewHandler(&service.Service{Users: users, Referrals: referrals}).InitRoutes()
+
+		body := `{"login":"frank","password":"password","referral_code":"` + code + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		req.RemoteAddr = "10.0.0.1:5555"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		response, err := referrals.GetReferrals(fromIP("10.0.0.1"), aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, "frank", response.Referrals[0].RefereeLogin)
+		assert.Equal(t, "10.0.0.1", response.Referrals[0].IP)
+		assert.Equal(t, model.ReferralRejectSameIP, response.Referrals[0].RejectReason)
+	})
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceReservations(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	userID := int64(1)
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(userID, 500, "")
+
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{BalanceReservationTTL: time.Hour})
+
+	t.Run("ConfirmAndCancel", func(t *testing.T) {
+		first, err := balances.Reserve(ctx, userID, model.ReservationRequest{Order: "2377225624", Sum: 100})
+		require.NoError(t, err)
+		second, err := balances.Reserve(ctx, userID, model.ReservationRequest{Order: "12345678903", Sum: 50})
+		require.NoError(t, err)
+
+		balance, _ := balances.GetBalance(ctx, userID)
+		assert.Equal(t, model.BalanceResponse{Current: 350, Withdrawn: 0, Reserved: 150}, balance)
+
+		confirmed, err := balances.ConfirmReservation(ctx, userID, first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ReservationStatusConfirmed, confirmed.Status)
+
+		_, err = balances.ConfirmReservation(ctx, userID, first.ID)
+		assert.NoError(t, err)
+
+		_, err = balances.CancelReservation(ctx, userID, first.ID)
+		assert.ErrorIs(t, err, customerrors.ErrReservationClosed)
+
+		_, err = balances.CancelReservation(ctx, userID, second.ID)
+		require.NoError(t, err)
+
+		balance, _ = balances.GetBalance(ctx, userID)
+		assert.Equal(t, model.BalanceResponse{Current: 400, Withdrawn: 100, Reserved: 0}, balance)
+
+		withdrawals, err := balances.GetWithdrawals(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, withdrawals, 1)
+		assert.Equal(t, "2377225624", withdrawals[0].Order)
+
+		_, err = balances.CancelReservation(ctx, 2, second.ID)
+		assert.ErrorIs(t, err, customerrors.ErrReservationNotFound)
+	})
+
+	t.Run("InsufficientFunds", func(t *testing.T) {
+		_, err := balances.Reserve(ctx, userID, model.ReservationRequest{Order: "2377225624", Sum: 1000})
+		assert.ErrorIs(t, err, customerrors.ErrInsufficientFunds)
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		expiring := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{BalanceReservationTTL: time.Millisecond})
+
+		stale, err := expiring.Reserve(ctx, userID, model.ReservationRequest{Order: "2377225624", Sum: 100})
+		require.NoError(t, err)
+		swept, err := expiring.Reserve(ctx, userID, model.ReservationRequest{Order: "12345678903", Sum: 200})
+		require.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+
+		_, err = expiring.ConfirmReservation(ctx, userID, stale.ID)
+		assert.ErrorIs(t, err, customerrors.ErrReservationClosed)
+
+		expiring.ExpireReservations(ctx)
+
+		reservations, err := expiring.GetReservations(ctx, userID)
+		require.NoError(t, err)
+		require.NotEmpty(t, reservations)
+		assert.Equal(t, swept.ID, reservations[0].ID)
+		assert.Equal(t, model.ReservationStatusExpired, reservations[0].Status)
+
+		balance, _ := expiring.GetBalance(ctx, userID)
+		assert.Equal(t, model.BalanceResponse{Current: 400, Withdrawn: 100, Reserved: 0}, balance)
+	})
+}
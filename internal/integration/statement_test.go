@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceStatement(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	aliceID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "alice", "hash")
+	require.NoError(t, err)
+	_, err = repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{})
+
+	start := time.Now()
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(aliceID, 500, "12345678903")
+	require.NoError(t, balances.Withdraw(ctx, aliceID, model.WithdrawRequest{Order: "2377225624", Sum: 120.5}))
+	middle := time.Now()
+	_, err = balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "bob", Sum: 50}, "key-1")
+	require.NoError(t, err)
+	_, err = repos.Balances.AdjustBalance(ctx, aliceID, 10.25, "компенсация", "staff:admin")
+	require.NoError(t, err)
+
+	readCSV := func(t *testing.T, from, to time.Time) [][]string {
+		var buf bytes.Buffer
+		require.NoError(t, balances.Statement(ctx, aliceID, from, to, model.ExportCSV, &buf))
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		return records
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		records := readCSV(t, start, time.Time{})
+		require.Len(t, records, 7)
+
+		assert.Equal(t, []string{"date", "operation", "order", "counterparty", "reason", "amount", "balance"}, records[0])
+		assert.Equal(t, []string{model.StatementOpeningBalance, "0.00"}, []string{records[1][1], records[1][6]})
+		assert.Equal(t, []string{string(model.LedgerAccrual), "500.00", "500.00"}, []string{records[2][1], records[2][5], records[2][6]})
+		assert.Equal(t, []string{model.BalanceHistoryWithdrawal, "2377225624", "-120.50", "379.50"},
+			[]string{records[3][1], records[3][2], records[3][5], records[3][6]})
+		assert.Equal(t, []string{string(model.LedgerTransferOut), "-50.00", "329.50"}, []string{records[4][1], records[4][5], records[4][6]})
+		assert.Equal(t, []string{model.BalanceHistoryAdjustment, "компенсация", "10.25", "339.75"},
+			[]string{records[5][1], records[5][4], records[5][5], records[5][6]})
+		assert.Equal(t, []string{model.StatementClosingBalance, "339.75"}, []string{records[6][1], records[6][6]})
+
+		balance, err := balances.GetBalance(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, 339.75, balance.Current)
+	})
+
+	t.Run("OpeningBalance", func(t *testing.T) {
+		records := readCSV(t, middle, time.Time{})
+		require.Len(t, records, 5)
+
+		assert.Equal(t, "379.50", records[1][6])
+		assert.Equal(t, string(model.LedgerTransferOut), records[2][1])
+		assert.Equal(t, "339.75", records[4][6])
+	})
+
+	t.Run("EmptyPeriod", func(t *testing.T) {
+		records := readCSV(t, start.Add(-time.Hour), start)
+		require.Len(t, records, 3)
+
+		assert.Equal(t, "0.00", records[1][6])
+		assert.Equal(t, "0.00", records[2][6])
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, balances.Statement(ctx, aliceID, start, time.Time{}, model.ExportXLSX, &buf))
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		files := make(map[string][]byte)
+		for _, file := range archive.File {
+			r, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			r.Close()
+			files[file.Name] = data
+		}
+
+		for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+			assert.Contains(t, files, name)
+		}
+
+		var sheet struct {
+			Rows []struct {
+				Cells []struct {
+					Type   string `xml:"t,attr"`
+					Value  string `xml:"v"`
+					Inline string `xml:"is>t"`
+				} `xml:"c"`
+			} `xml:"sheetData>row"`
+		}
+		require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+		require.Len(t, sheet.Rows, 7)
+
+		withdrawal := sheet.Rows[3].Cells
+		assert.Equal(t, model.BalanceHistoryWithdrawal, withdrawal[1].Inline)
+		assert.Equal(t, "-120.5", withdrawal[5].Value)
+		assert.Equal(t, "компенсация", sheet.Rows[5].Cells[4].Inline)
+		assert.Equal(t, "339.75", sheet.Rows[6].Cells[6].Value)
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, balances.Statement(ctx, aliceID, start, time.Time{}, model.ExportPDF, &buf))
+
+		doc := buf.Bytes()
+		require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+		require.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+
+		// Смещения из таблицы перекрестных ссылок указывают на начало объектов.
+		trailer := doc[bytes.LastIndex(doc, []byte("startxref\n"))+len("startxref\n"):]
+		xref, err := strconv.Atoi(string(trailer[:bytes.IndexByte(trailer, '\n')]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n")))
+
+		entries := strings.Split(string(doc[xref:]), "\n")[3:]
+		for id := 1; !strings.HasPrefix(entries[id-1], "trailer"); id++ {
+			offset, err := strconv.Atoi(entries[id-1][:10])
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", id))), "объект %d", id)
+		}
+
+		assert.Contains(t, string(doc), "/Count 1")
+		assert.Contains(t, string(doc), model.BalanceHistoryWithdrawal)
+		assert.Contains(t, string(doc), "-120.50")
+		assert.Contains(t, string(doc), "339.75")
+		// "компенсация" в кодировке шрифта Windows-1251.
+		assert.Contains(t, string(doc), "\xea\xee\xec\xef\xe5\xed\xf1\xe0\xf6\xe8\xff")
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := balances.Statement(ctx, aliceID, time.Time{}, time.Time{}, "ods", &buf)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidExportFormat)
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("InvalidPeriod", func(t *testing.T) {
+		err := balances.Statement(ctx, aliceID, time.Now().Add(time.Hour), time.Time{}, model.ExportCSV, io.Discard)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidPeriod)
+	})
+}
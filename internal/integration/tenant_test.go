@@ -0,0 +1,260 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/handler"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgOrderRepo повторяет поведение репозитория PostgreSQL, который для отсутствующего
+// заказа возвращает nil без ошибки.
+type pgOrderRepo struct {
+	repository.OrderRepository
+}
+
+func (r pgOrderRepo) GetOrderByNumber(ctx context.Context, tenant, number string) (*model.Order, error) {
+	order, err := r.OrderRepository.GetOrderByNumber(ctx, tenant, number)
+	if errors.Is(err, customerrors.ErrOrderNotFound) {
+		return nil, nil
+	}
+	return order, err
+}
+
+func TestTenantIsolation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		JWTSigningKey: "test_key",
+		AdminAPIToken: "staff_token",
+		Tenants: []config.Tenant{
+			{Name: "shop2", Hosts: []string{"shop2.example.com"}},
+		},
+	}
+	repos := repository.NewRepositoriesForTests()
+	repos.Orders = pgOrderRepo{repos.Orders}
+	router := handler.NewHandler(service.NewService(repos, cfg)).InitRoutes()
+
+	do := func(method, path, body, tenant, host, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		if host != "" {
+			req.Host = host
+		}
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	credentials := `{"login":"alice","password":"secret"}`
+
+	w := do("POST", "/api/user/register", credentials, "", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	defaultToken := w.Header().Get("Authorization")
+
+	w = do("POST", "/api/user/register", credentials, "shop2", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	shopToken := w.Header().Get("Authorization")
+
+	t.Run("SameLoginPerTenant", func(t *testing.T) {
+		w := do("POST", "/api/user/register", credentials, "shop2", "", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = do("POST", "/api/user/login", credentials, "", "shop2.example.com", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("SameOrderNumberPerTenant", func(t *testing.T) {
+		orderNumber := "12345678903"
+
+		w := do("POST", "/api/user/orders", orderNumber, "", "", defaultToken)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		w = do("POST", "/api/user/orders", orderNumber, "shop2", "", shopToken)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		for _, tc := range []struct{ tenant, token string }{{"", defaultToken}, {"shop2", shopToken}} {
+			w = do("GET", "/api/user/orders", "", tc.tenant, "", tc.token)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var orders []model.OrderResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &orders))
+			require.Len(t, orders, 1)
+			assert.Equal(t, orderNumber, orders[0].Number)
+		}
+	})
+
+	t.Run("CrossTenantToken", func(t *testing.T) {
+		w := do("GET", "/api/user/balance", "", "", "", shopToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = do("GET", "/api/user/balance", "", "shop2", "", defaultToken)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("HostResolution", func(t *testing.T) {
+		w := do("GET", "/api/user/balance", "", "", "shop2.example.com:8080", shopToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = do("GET", "/api/user/balance", "", "", "unknown.example.com", defaultToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("AdminScopedToTenant", func(t *testing.T) {
+		ctx := context.Background()
+		defaultUser, err := repos.Users.GetUserByLogin(ctx, model.DefaultTenant, "alice")
+		require.NoError(t, err)
+		shopUser, err := repos.Users.GetUserByLogin(ctx, "shop2", "alice")
+		require.NoError(t, err)
+		require.NoError(t, repos.Users.SetUserRoles(ctx, shopUser.ID, []model.Role{model.RoleAdmin}, "test"))
+
+		w := do("POST", "/api/user/login", credentials, "shop2", "", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		shopAdmin := w.Header().Get("Authorization")
+
+		foreign := fmt.Sprintf("/api/admin/users/%d", defaultUser.ID)
+		for _, tc := range []struct{ method, path, body string }{
+			{"GET", foreign, ""},
+			{"GET", foreign + "/orders", ""},
+			{"POST", foreign + "/balance/adjustments", `{"amount":100,"reason":"test"}`},
+			{"PUT", foreign + "/roles", `{"roles":["admin"]}`},
+		} {
+			w := do(tc.method, tc.path, tc.body, "shop2", "", shopAdmin)
+			assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", tc.method, tc.path)
+		}
+
+		roles, err := repos.Users.GetUserRoles(ctx, defaultUser.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []model.Role{model.RoleCustomer}, roles)
+
+		w = do("GET", "/api/admin/users?login=alice", "", "shop2", "", shopAdmin)
+		require.Equal(t, http.StatusOK, w.Code)
+		var users []*model.UserSummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &users))
+		require.Len(t, users, 1)
+		assert.Equal(t, shopUser.ID, users[0].ID)
+
+		w = do("GET", fmt.Sprintf("/api/admin/users/%d", shopUser.ID), "", "shop2", "", shopAdmin)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = do("GET", foreign, "", "shop2", "", "Bearer staff_token")
+		assert.Equal(t, http.StatusOK, w.Code, "служебный токен действует во всех арендаторах")
+
+		w = do("GET", "/api/admin/users?login=alice", "", "shop2", "", "Bearer staff_token")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &users))
+		assert.Len(t, users, 2)
+	})
+
+	t.Run("UnknownTenant", func(t *testing.T) {
+		w := do("POST", "/api/user/login", credentials, "shop3", "", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestTenantBoundStaffScope(t *testing.T) {
+	repos := repository.NewRepositoriesForTests()
+	audit := service.NewAuditService(repos.Audit)
+	campaigns := service.NewCampaignService(repos.Campaigns, repos.Orders, audit)
+	webhooks := service.NewWebhookService(repos.Webhooks)
+
+	ctx := context.Background()
+	bound := service.WithTenantBound(service.WithTenant(ctx, "acme"))
+
+	other, err := campaigns.CreateCampaign(ctx, model.CampaignRequest{
+		Tenant: "globex",
+		Name:   "Бонус globex",
+		Type:   model.CampaignWelcomeBonus,
+		Amount: 10,
+	}, "staff:root")
+	require.NoError(t, err)
+
+	own, err := campaigns.CreateCampaign(bound, model.CampaignRequest{
+		Tenant: "globex",
+		Name:   "Бонус acme",
+		Type:   model.CampaignWelcomeBonus,
+		Amount: 10,
+	}, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", own.Tenant)
+
+	otherSub, err := webhooks.CreateSubscription(ctx, model.WebhookSubscriptionRequest{
+		Tenant:     "globex",
+		URL:        "https://globex.example/hook",
+		EventTypes: []model.WebhookEventType{model.WebhookEventOrderProcessed},
+	})
+	require.NoError(t, err)
+
+	ownSub, err := webhooks.CreateSubscription(bound, model.WebhookSubscriptionRequest{
+		Tenant:     "globex",
+		URL:        "https://acme.example/hook",
+		EventTypes: []model.WebhookEventType{model.WebhookEventOrderProcessed},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", ownSub.Tenant)
+
+	t.Run("Campaigns", func(t *testing.T) {
+		list, err := campaigns.GetCampaigns(bound)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, own.ID, list[0].ID)
+
+		_, err = campaigns.GetCampaign(bound, other.ID)
+		assert.ErrorIs(t, err, customerrors.ErrCampaignNotFound)
+
+		_, err = campaigns.UpdateCampaign(bound, other.ID, model.CampaignRequest{Name: "x", Type: model.CampaignWelcomeBonus, Amount: 1}, "user:1")
+		assert.ErrorIs(t, err, customerrors.ErrCampaignNotFound)
+
+		assert.ErrorIs(t, campaigns.DeleteCampaign(bound, other.ID, "user:1"), customerrors.ErrCampaignNotFound)
+
+		all, err := campaigns.GetCampaigns(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		subs, err := webhooks.GetSubscriptions(bound)
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, ownSub.ID, subs[0].ID)
+
+		assert.ErrorIs(t, webhooks.DeleteSubscription(bound, otherSub.ID), customerrors.ErrWebhookNotFound)
+		require.NoError(t, webhooks.DeleteSubscription(ctx, otherSub.ID))
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		events, err := audit.GetEvents(bound, model.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "acme", events[0].Tenant)
+		assert.Equal(t, model.AuditCampaignCreated, events[0].Action)
+
+		events, err = audit.GetEvents(ctx, model.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, "globex", events[0].Tenant)
+
+		var buf bytes.Buffer
+		require.NoError(t, audit.Export(bound, model.AuditFilter{}, model.ExportJSONL, &buf))
+		assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+	})
+}
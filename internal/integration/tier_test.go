@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoyaltyTiers(t *testing.T) {
+	orderNumber := "4561261212345467"
+
+	accrualServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(model.AccrualResponse{
+			Order:   orderNumber,
+			Status:  model.AccrualStatusProcessed,
+			Accrual: 100,
+		})
+	}))
+	defer accrualServer.Close()
+
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+	listener := repos.Notifications.(*repository.NotificationListenerMock)
+
+	aliceID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "alice", "hash")
+	require.NoError(t, err)
+	bobID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(aliceID, 1200, "")
+
+	cfg := &config.Config{
+		AccrualSystemAddress: accrualServer.URL,
+		WorkerID:             "test-worker",
+		LoyaltyTiers: []config.LoyaltyTier{
+			{Name: "BRONZE", Threshold: 0, Multiplier: 1},
+			{Name: "SILVER", Threshold: 1000, Multiplier: 1.1, Bonus: 5},
+			{Name: "GOLD", Threshold: 5000, Multiplier: 1.25},
+		},
+		TierRecalcInterval: time.Hour,
+	}
+	tiers := service.NewTierService(repos.Tiers, repos.Users, cfg)
+
+	t.Run("Recalculation", func(t *testing.T) {
+		assert.Equal(t, 2, tiers.RecalculateTiers(ctx))
+		assert.Equal(t, 0, tiers.RecalculateTiers(ctx))
+
+		profile, err := tiers.GetProfile(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", profile.Login)
+		require.NotNil(t, profile.Tier)
+		assert.Equal(t, "SILVER", profile.Tier.Name)
+		assert.Equal(t, 1200.0, profile.Tier.Points)
+		assert.Equal(t, "GOLD", profile.Tier.NextTier)
+		assert.Equal(t, 3800.0, profile.Tier.PointsToNextTier)
+		require.Len(t, profile.TierHistory, 1)
+		assert.Equal(t, "SILVER", profile.TierHistory[0].To)
+
+		profile, err = tiers.GetProfile(ctx, bobID)
+		require.NoError(t, err)
+		assert.Equal(t, "BRONZE", profile.Tier.Name)
+	})
+
+	t.Run("AccrualBonus", func(t *testing.T) {
+		orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, tiers, nil, nil, nil, service.NewAccrualClient(cfg), cfg)
+
+		processCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			orders.ProcessOrdersBackground(processCtx)
+			close(done)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		_, err := repos.Orders.CreateOrder(ctx, model.DefaultTenant, aliceID, orderNumber)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			listener.Notify(repository.NewOrdersChannel, orderNumber)
+
+			balance, err := repos.Balances.GetBalance(ctx, aliceID)
+			return err == nil && balance.Current > 1200
+		}, 2*time.Second, 20*time.Millisecond)
+
+		balance, err := repos.Balances.GetBalance(ctx, aliceID)
+		require.NoError(t, err)
+		assert.InDelta(t, 1200+100+15, balance.Current, 0.001)
+
+		order, err := repos.Orders.GetOrderByNumber(ctx, model.DefaultTenant, orderNumber)
+		require.NoError(t, err)
+		assert.InDelta(t, 100, order.Accrual, 0.001)
+	})
+
+	t.Run("BonusNotCountedTowardsTier", func(t *testing.T) {
+		tiers.RecalculateTiers(ctx)
+
+		profile, err := tiers.GetProfile(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Equal(t, 1300.0, profile.Tier.Points)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		disabled := service.NewTierService(repos.Tiers, repos.Users, &config.Config{})
+
+		profile, err := disabled.GetProfile(ctx, aliceID)
+		require.NoError(t, err)
+		assert.Nil(t, profile.Tier)
+
+		bonus, err := disabled.AccrualBonus(ctx, aliceID, 100)
+		require.NoError(t, err)
+		assert.Nil(t, bonus)
+	})
+	t.Run("ScheduledRunClaimedByOtherInstance", func(t *testing.T) {
+		carolID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "carol", "hash")
+		require.NoError(t, err)
+		repos.Balances.(*repository.BalanceRepoMock).AddPoints(carolID, 6000, "")
+
+		// Другой экземпляр только что отметил плановый пересчет.
+		started, err := repos.Tiers.StartTierRecalculation(ctx, cfg.TierRecalcInterval/2)
+		require.NoError(t, err)
+		require.True(t, started)
+
+		runCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		tiers.RunTierRecalculation(runCtx)
+
+		profile, err := tiers.GetProfile(ctx, carolID)
+		require.NoError(t, err)
+		assert.Empty(t, profile.TierHistory)
+
+		started, err = repos.Tiers.StartTierRecalculation(ctx, cfg.TierRecalcInterval/2)
+		require.NoError(t, err)
+		assert.False(t, started)
+	})
+}
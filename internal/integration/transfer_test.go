@@ -0,0 +1,88 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceTransfer(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	aliceID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "alice", "hash")
+	require.NoError(t, err)
+	bobID, err := repos.Users.CreateUser(ctx, model.DefaultTenant, "bob", "hash")
+	require.NoError(t, err)
+	_, err = repos.Users.CreateUser(ctx, "shop2", "carol", "hash")
+	require.NoError(t, err)
+
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(aliceID, 500, "")
+
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{TransferDailyLimit: 300})
+
+	t.Run("Idempotent", func(t *testing.T) {
+		request := model.TransferRequest{Recipient: "bob", Sum: 100}
+
+		first, err := balances.Transfer(ctx, aliceID, request, "key-1")
+		require.NoError(t, err)
+
+		second, err := balances.Transfer(ctx, aliceID, request, "key-1")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+
+		aliceBalance, _ := balances.GetBalance(ctx, aliceID)
+		bobBalance, _ := balances.GetBalance(ctx, bobID)
+		assert.Equal(t, 400.0, aliceBalance.Current)
+		assert.Equal(t, 100.0, bobBalance.Current)
+
+		_, err = balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "bob", Sum: 50}, "key-1")
+		assert.ErrorIs(t, err, customerrors.ErrIdempotencyConflict)
+	})
+
+	t.Run("DailyLimit", func(t *testing.T) {
+		_, err := balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "bob", Sum: 250}, "key-2")
+		assert.ErrorIs(t, err, customerrors.ErrTransferLimit)
+
+		_, err = balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "bob", Sum: 200}, "key-3")
+		assert.NoError(t, err)
+	})
+
+	t.Run("InsufficientFunds", func(t *testing.T) {
+		unlimited := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{})
+
+		_, err := unlimited.Transfer(ctx, bobID, model.TransferRequest{Recipient: "alice", Sum: 1000}, "key-1")
+		assert.ErrorIs(t, err, customerrors.ErrInsufficientFunds)
+	})
+
+	t.Run("InvalidRecipient", func(t *testing.T) {
+		_, err := balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "alice", Sum: 1}, "key-4")
+		assert.ErrorIs(t, err, customerrors.ErrTransferToSelf)
+
+		_, err = balances.Transfer(ctx, aliceID, model.TransferRequest{Recipient: "carol", Sum: 1}, "key-5")
+		assert.ErrorIs(t, err, customerrors.ErrUserNotFound)
+	})
+
+	t.Run("HistoryOnBothSides", func(t *testing.T) {
+		aliceTransfers, err := balances.GetTransfers(ctx, aliceID)
+		require.NoError(t, err)
+		require.Len(t, aliceTransfers, 2)
+		assert.Equal(t, model.TransferDirectionOut, aliceTransfers[0].Direction)
+		assert.Equal(t, "bob", aliceTransfers[0].Counterparty)
+		assert.Equal(t, 200.0, aliceTransfers[0].Sum)
+
+		bobTransfers, err := balances.GetTransfers(ctx, bobID)
+		require.NoError(t, err)
+		require.Len(t, bobTransfers, 2)
+		assert.Equal(t, model.TransferDirectionIn, bobTransfers[0].Direction)
+		assert.Equal(t, "alice", bobTransfers[0].Counterparty)
+		assert.Equal(t, aliceTransfers[0].ID, bobTransfers[0].ID)
+	})
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderNumberValidators(t *testing.T) {
+	validators := service.NewOrderNumberValidators(map[string][]config.OrderNumberRule{
+		"shop2": {
+			{Type: config.OrderNumberRulePrefix, Prefix: "SH"},
+			{Type: config.OrderNumberRuleRegex, Pattern: `^SH[0-9A-Z]{8}$`},
+		},
+	})
+
+	t.Run("DefaultRules", func(t *testing.T) {
+		assert.NoError(t, validators.Validate(model.DefaultTenant, "4561261212345467"))
+
+		err := validators.Validate(model.DefaultTenant, "")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidOrderNumber)
+
+		err = validators.Validate(model.DefaultTenant, "4561261212345468")
+		assert.ErrorIs(t, err, customerrors.ErrInvalidOrderNumber)
+		assert.ErrorIs(t, err, customerrors.ErrInvalidLuhn)
+
+		var numberErr *customerrors.OrderNumberError
+		require.True(t, errors.As(validators.Validate(model.DefaultTenant, "4561261212345467456126121234546700"), &numberErr))
+		assert.Equal(t, config.OrderNumberRuleLength, numberErr.Rule)
+	})
+
+	t.Run("TenantRules", func(t *testing.T) {
+		assert.NoError(t, validators.Validate("shop2", "SH12AB34CD"))
+
+		var numberErr *customerrors.OrderNumberError
+		require.True(t, errors.As(validators.Validate("shop2", "XX12AB34CD"), &numberErr))
+		assert.Equal(t, config.OrderNumberRulePrefix, numberErr.Rule)
+
+		require.True(t, errors.As(validators.Validate("shop2", "SH12ab34cd"), &numberErr))
+		assert.Equal(t, config.OrderNumberRuleRegex, numberErr.Rule)
+		assert.NotErrorIs(t, numberErr, customerrors.ErrInvalidLuhn)
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		validators.Register("shop3", service.LengthValidator{Min: 4})
+
+		assert.NoError(t, validators.Validate("shop3", "ABCD"))
+		assert.ErrorIs(t, validators.Validate("shop3", "ABC"), customerrors.ErrInvalidOrderNumber)
+	})
+}
+
+func TestCreateOrdersBatchValidation(t *testing.T) {
+	repos := repository.NewRepositoriesForTests()
+	validators := service.NewOrderNumberValidators(map[string][]config.OrderNumberRule{
+		model.DefaultTenant: {
+			{Type: config.OrderNumberRuleLength, Min: 10, Max: 16},
+			{Type: config.OrderNumberRuleLuhn},
+		},
+	})
+	cfg := &config.Config{}
+	orders := service.NewOrderService(repos.Orders, repos.Balances, repos.Notifications, nil, nil, nil, nil, nil, validators, service.NewAccrualClient(cfg), cfg)
+
+	results, err := orders.CreateOrders(context.Background(), 1, []string{"4561261212345467", "4561261212345468", "12345"})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, model.BatchOrderAccepted, results[0].Result)
+	assert.Equal(t, model.BatchOrderInvalidLuhn, results[1].Result)
+	assert.NotEmpty(t, results[1].Reason)
+	assert.Equal(t, model.BatchOrderInvalidNumber, results[2].Result)
+	assert.Contains(t, results[2].Reason, "от 10 до 16")
+}
@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	"github.com/Gerfey/gophermart/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithdrawalRules(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewRepositoriesForTests()
+
+	userID := int64(1)
+	newcomerID := int64(2)
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(userID, 1000, "")
+	repos.Balances.(*repository.BalanceRepoMock).AddPoints(newcomerID, 1000, "")
+
+	balances := service.NewBalanceService(repos.Balances, repos.Users, nil, nil, &config.Config{
+		WithdrawalRules: map[string][]config.WithdrawalRule{
+			model.DefaultTenant: {
+				{Type: config.WithdrawalRuleMinAmount, Amount: 10},
+				{Type: config.WithdrawalRuleMaxPerOrder, Amount: 250},
+				{Type: config.WithdrawalRuleDailyCap, Amount: 300},
+			},
+			"shop2": {
+				{Type: config.WithdrawalRuleCoolingOff, Period: "1h"},
+			},
+		},
+	})
+
+	assertRule := func(t *testing.T, err error, rule string) {
+		t.Helper()
+
+		require.ErrorIs(t, err, customerrors.ErrWithdrawalRejected)
+		var ruleErr *customerrors.WithdrawalRuleError
+		require.True(t, errors.As(err, &ruleErr))
+		assert.Equal(t, rule, ruleErr.Rule)
+	}
+
+	t.Run("MinAmount", func(t *testing.T) {
+		err := balances.Withdraw(ctx, userID, model.WithdrawRequest{Order: "2377225624", Sum: 5})
+		assertRule(t, err, config.WithdrawalRuleMinAmount)
+	})
+
+	t.Run("MaxPerOrder", func(t *testing.T) {
+		err := balances.Withdraw(ctx, userID, model.WithdrawRequest{Order: "2377225624", Sum: 260})
+		assertRule(t, err, config.WithdrawalRuleMaxPerOrder)
+
+		require.NoError(t, balances.Withdraw(ctx, userID, model.WithdrawRequest{Order: "2377225624", Sum: 50}))
+
+		_, err = balances.Reserve(ctx, userID, model.ReservationRequest{Order: "2377225624", Sum: 210})
+		assertRule(t, err, config.WithdrawalRuleMaxPerOrder)
+	})
+
+	t.Run("DailyCapCountsReservations", func(t *testing.T) {
+		_, err := balances.Reserve(ctx, userID, model.ReservationRequest{Order: "12345678903", Sum: 200})
+		require.NoError(t, err)
+
+		err = balances.Withdraw(ctx, userID, model.WithdrawRequest{Order: "79927398713", Sum: 100})
+		assertRule(t, err, config.WithdrawalRuleDailyCap)
+
+		require.NoError(t, balances.Withdraw(ctx, userID, model.WithdrawRequest{Order: "79927398713", Sum: 50}))
+	})
+
+	t.Run("CoolingOff", func(t *testing.T) {
+		tenantCtx := service.WithTenant(ctx, "shop2")
+
+		err := balances.Withdraw(tenantCtx, newcomerID, model.WithdrawRequest{Order: "2377225624", Sum: 10})
+		assertRule(t, err, config.WithdrawalRuleCoolingOff)
+	})
+
+	balance, err := balances.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BalanceResponse{Current: 700, Withdrawn: 100, Reserved: 200}, balance)
+}
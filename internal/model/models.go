@@ -1,14 +1,17 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type User struct {
-	ID           int64     `db:"id"`
-	Login        string    `db:"login"`
-	PasswordHash string    `db:"password_hash"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID           int64      `db:"id"`
+	Tenant       string     `db:"tenant"`
+	Login        string     `db:"login"`
+	PasswordHash string     `db:"password_hash"`
+	CreatedAt    time.Time  `db:"created_at"`
+	DeletedAt    *time.Time `db:"deleted_at"`
 }
 
 type OrderStatus string
@@ -18,6 +21,9 @@ const (
 	OrderStatusProcessing OrderStatus = "PROCESSING"
 	OrderStatusInvalid    OrderStatus = "INVALID"
 	OrderStatusProcessed  OrderStatus = "PROCESSED"
+	// OrderStatusUnregistered конечный статус заказа, который система расчета так и не
+	// зарегистрировала за отведенное время. Может быть возвращен в обработку администратором.
+	OrderStatusUnregistered OrderStatus = "UNREGISTERED"
 )
 
 type AccrualSystemStatus string
@@ -27,15 +33,19 @@ const (
 	AccrualStatusInvalid    AccrualSystemStatus = "INVALID"
 	AccrualStatusProcessing AccrualSystemStatus = "PROCESSING"
 	AccrualStatusProcessed  AccrualSystemStatus = "PROCESSED"
+	// AccrualStatusNotRegistered фиксирует ответ 204: система расчета не знает о заказе.
+	AccrualStatusNotRegistered AccrualSystemStatus = "NOT_REGISTERED"
 )
 
 type Order struct {
-	ID         int64       `db:"id"`
-	UserID     int64       `db:"user_id"`
-	Number     string      `db:"number"`
-	Status     OrderStatus `db:"status"`
-	Accrual    float64     `db:"accrual"`
-	UploadedAt time.Time   `db:"uploaded_at"`
+	ID            int64       `db:"id"`
+	Tenant        string      `db:"tenant"`
+	UserID        int64       `db:"user_id"`
+	Number        string      `db:"number"`
+	Status        OrderStatus `db:"status"`
+	Accrual       float64     `db:"accrual"`
+	UploadedAt    time.Time   `db:"uploaded_at"`
+	LastCheckedAt *time.Time  `db:"last_checked_at"`
 }
 
 type OrderResponse struct {
@@ -45,6 +55,31 @@ type OrderResponse struct {
 	UploadedAt time.Time   `json:"uploaded_at"`
 }
 
+// OrderStatusChange запись истории статусов заказа вместе с исходным статусом системы расчета.
+type OrderStatusChange struct {
+	Status        OrderStatus         `json:"status"`
+	AccrualStatus AccrualSystemStatus `json:"accrual_status,omitempty"`
+	Accrual       float64             `json:"accrual,omitempty"`
+	ChangedAt     time.Time           `json:"changed_at"`
+}
+
+// OrderBonusTask начисление бонусов кампаний и реферальной программы за обработанный заказ.
+// Задача ставится в очередь в транзакции зачисления начисления и повторяется до успешного выполнения.
+type OrderBonusTask struct {
+	Order    Order
+	Accrual  float64
+	Attempts int
+}
+
+type OrderDetailResponse struct {
+	Number        string              `json:"number"`
+	Status        OrderStatus         `json:"status"`
+	Accrual       float64             `json:"accrual,omitempty"`
+	UploadedAt    time.Time           `json:"uploaded_at"`
+	LastCheckedAt *time.Time          `json:"last_checked_at,omitempty"`
+	History       []OrderStatusChange `json:"history"`
+}
+
 type Withdrawal struct {
 	ID          int64     `db:"id"`
 	UserID      int64     `db:"user_id"`
@@ -59,15 +94,19 @@ type WithdrawalResponse struct {
 	ProcessedAt time.Time `json:"processed_at"`
 }
 
+// Balance баланс пользователя. Current доступен для списания, Reserved удерживается
+// активными резервами и в Current не входит.
 type Balance struct {
 	UserID    int64   `db:"user_id"`
 	Current   float64 `db:"current"`
 	Withdrawn float64 `db:"withdrawn"`
+	Reserved  float64 `db:"reserved"`
 }
 
 type BalanceResponse struct {
 	Current   float64 `json:"current"`
 	Withdrawn float64 `json:"withdrawn"`
+	Reserved  float64 `json:"reserved,omitempty"`
 }
 
 type WithdrawRequest struct {
@@ -75,9 +114,411 @@ type WithdrawRequest struct {
 	Sum   float64 `json:"sum" binding:"required,gt=0"`
 }
 
+// WithdrawalStats данные для проверки списания правилами, собранные в транзакции списания
+// после блокировки баланса. Суммы учитывают выполненные списания и активные резервы.
+type WithdrawalStats struct {
+	UserID             int64
+	OrderNumber        string
+	Amount             float64
+	WithdrawnToday     float64
+	WithdrawnThisMonth float64
+	WithdrawnForOrder  float64
+	FirstAccrualAt     *time.Time
+	Now                time.Time
+}
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "ACTIVE"
+	ReservationStatusConfirmed ReservationStatus = "CONFIRMED"
+	ReservationStatusCancelled ReservationStatus = "CANCELLED"
+	ReservationStatusExpired   ReservationStatus = "EXPIRED"
+)
+
+// Reservation резерв баллов под заказ. Активный резерв уменьшает доступный баланс,
+// подтверждение превращает его в списание, а отмена или истечение срока возвращают баллы.
+type Reservation struct {
+	ID          int64             `db:"id" json:"id"`
+	UserID      int64             `db:"user_id" json:"-"`
+	OrderNumber string            `db:"order_number" json:"order"`
+	Amount      float64           `db:"amount" json:"sum"`
+	Status      ReservationStatus `db:"status" json:"status"`
+	ExpiresAt   time.Time         `db:"expires_at" json:"expires_at"`
+	CreatedAt   time.Time         `db:"created_at" json:"created_at"`
+	ResolvedAt  *time.Time        `db:"resolved_at" json:"resolved_at,omitempty"`
+	// Replayed отмечает резерв, уже переведенный в запрошенный статус ранее.
+	Replayed bool `db:"-" json:"-"`
+}
+
+type ReservationRequest struct {
+	Order string  `json:"order" binding:"required"`
+	Sum   float64 `json:"sum" binding:"required,gt=0"`
+}
+
+type TransferRequest struct {
+	Recipient string  `json:"recipient" binding:"required"`
+	Sum       float64 `json:"sum" binding:"required,gt=0"`
+}
+
+// Transfer перевод баллов между пользователями одного арендатора. Ключ идемпотентности
+// уникален в пределах отправителя.
+type Transfer struct {
+	ID             int64     `db:"id"`
+	SenderID       int64     `db:"sender_id"`
+	RecipientID    int64     `db:"recipient_id"`
+	Amount         float64   `db:"amount"`
+	IdempotencyKey string    `db:"idempotency_key"`
+	CreatedAt      time.Time `db:"created_at"`
+	// Replayed отмечает перевод, выполненный ранее с тем же ключом идемпотентности.
+	Replayed bool `db:"-"`
+}
+
+type TransferDirection string
+
+const (
+	TransferDirectionOut TransferDirection = "out"
+	TransferDirectionIn  TransferDirection = "in"
+)
+
+type TransferResponse struct {
+	ID           int64             `json:"id"`
+	Direction    TransferDirection `json:"direction"`
+	Counterparty string            `json:"counterparty"`
+	Sum          float64           `json:"sum"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+type LedgerOperation string
+
+const (
+	LedgerTransferOut LedgerOperation = "transfer_out"
+	LedgerTransferIn  LedgerOperation = "transfer_in"
+	LedgerAccrual     LedgerOperation = "accrual"
+	LedgerTierBonus   LedgerOperation = "tier_bonus"
+	LedgerCampaign    LedgerOperation = "campaign_bonus"
+	LedgerReferral    LedgerOperation = "referral_bonus"
+)
+
+// LedgerEntry запись журнала движения баллов. Amount положителен для зачислений и
+// отрицателен для списаний; Counterparty содержит логин второй стороны перевода,
+// OrderNumber — номер заказа, за который начислены баллы.
+type LedgerEntry struct {
+	ID           int64           `db:"id"`
+	UserID       int64           `db:"user_id"`
+	Amount       float64         `db:"amount"`
+	Operation    LedgerOperation `db:"operation"`
+	TransferID   *int64          `db:"transfer_id"`
+	Counterparty string          `db:"counterparty"`
+	OrderNumber  string          `db:"order_number"`
+	CreatedAt    time.Time       `db:"created_at"`
+}
+
+// CampaignType тип промо-кампании.
+type CampaignType string
+
+const (
+	// CampaignWelcomeBonus начисляет Amount при регистрации пользователя.
+	CampaignWelcomeBonus CampaignType = "welcome_bonus"
+	// CampaignAccrualMultiplier начисляет к заказу, загруженному в период кампании,
+	// дополнительно (Multiplier - 1) от начисления системы расчета.
+	CampaignAccrualMultiplier CampaignType = "accrual_multiplier"
+	// CampaignNthOrder начисляет Amount за OrderIndex-й загруженный пользователем заказ.
+	CampaignNthOrder CampaignType = "nth_order"
+)
+
+// Campaign промо-кампания арендатора. Кампания действует, пока она активна и текущий момент
+// (для заказов — момент загрузки заказа) попадает в интервал [StartsAt, EndsAt); пустые границы не ограничивают.
+type Campaign struct {
+	ID         int64        `db:"id" json:"id"`
+	Tenant     string       `db:"tenant" json:"tenant"`
+	Name       string       `db:"name" json:"name"`
+	Type       CampaignType `db:"type" json:"type"`
+	Amount     float64      `db:"amount" json:"amount,omitempty"`
+	Multiplier float64      `db:"multiplier" json:"multiplier,omitempty"`
+	OrderIndex int          `db:"order_index" json:"order_index,omitempty"`
+	StartsAt   *time.Time   `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt     *time.Time   `db:"ends_at" json:"ends_at,omitempty"`
+	Active     bool         `db:"active" json:"active"`
+	CreatedAt  time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+// ActiveAt сообщает, действует ли кампания в момент at.
+func (c *Campaign) ActiveAt(at time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if c.StartsAt != nil && at.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !at.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// CampaignRequest запрос на создание или изменение кампании. Пустой Active означает активную кампанию.
+type CampaignRequest struct {
+	Tenant     string       `json:"tenant"`
+	Name       string       `json:"name" binding:"required"`
+	Type       CampaignType `json:"type" binding:"required"`
+	Amount     float64      `json:"amount"`
+	Multiplier float64      `json:"multiplier"`
+	OrderIndex int          `json:"order_index"`
+	StartsAt   *time.Time   `json:"starts_at"`
+	EndsAt     *time.Time   `json:"ends_at"`
+	Active     *bool        `json:"active"`
+}
+
+// CampaignBonus начисление по промо-кампании. Reference определяет, за что начислен бонус
+// (регистрация или заказ), и не дает начислить бонус кампании за одно и то же дважды.
+type CampaignBonus struct {
+	ID           int64     `db:"id" json:"id"`
+	CampaignID   int64     `db:"campaign_id" json:"campaign_id"`
+	CampaignName string    `db:"campaign_name" json:"campaign"`
+	UserID       int64     `db:"user_id" json:"-"`
+	OrderID      int64     `db:"order_id" json:"-"`
+	OrderNumber  string    `db:"order_number" json:"order,omitempty"`
+	Reference    string    `db:"reference" json:"-"`
+	Amount       float64   `db:"amount" json:"sum"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// TierBonus надбавка уровня лояльности Tier к начислению системы расчета.
+type TierBonus struct {
+	Tier   string
+	Amount float64
+}
+
+// UserTier текущий уровень лояльности пользователя и сумма его начислений за последние 12 месяцев,
+// по которой уровень был определен при последнем пересчете.
+type UserTier struct {
+	UserID    int64     `db:"user_id"`
+	Tier      string    `db:"tier"`
+	Points    float64   `db:"points"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// TierPoints сумма начислений пользователя за период пересчета уровней.
+type TierPoints struct {
+	UserID int64
+	Points float64
+}
+
+// TierChange запись истории уровней лояльности пользователя. From пуст при первом назначении уровня.
+type TierChange struct {
+	ID        int64     `json:"-" db:"id"`
+	UserID    int64     `json:"-" db:"user_id"`
+	From      string    `json:"from,omitempty" db:"tier_from"`
+	To        string    `json:"to" db:"tier_to"`
+	Points    float64   `json:"points" db:"points"`
+	ChangedAt time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// TierResponse уровень лояльности пользователя в профиле.
+type TierResponse struct {
+	Name             string     `json:"name"`
+	Multiplier       float64    `json:"multiplier"`
+	Bonus            float64    `json:"bonus,omitempty"`
+	Points           float64    `json:"points"`
+	NextTier         string     `json:"next_tier,omitempty"`
+	PointsToNextTier float64    `json:"points_to_next_tier,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// ProfileResponse профиль пользователя. Tier отсутствует, если уровни лояльности не настроены.
+type ProfileResponse struct {
+	Login       string        `json:"login"`
+	Tier        *TierResponse `json:"tier,omitempty"`
+	TierHistory []*TierChange `json:"tier_history,omitempty"`
+}
+
+// UserCredentials логин и пароль пользователя. ReferralCode учитывается только при регистрации.
 type UserCredentials struct {
-	Login    string `json:"login" binding:"required,min=1"`
-	Password string `json:"password" binding:"required,min=1"`
+	Login        string `json:"login" binding:"required,min=1"`
+	Password     string `json:"password" binding:"required,min=1"`
+	ReferralCode string `json:"referral_code,omitempty"`
+}
+
+// UserExport персональные данные пользователя, выгружаемые по его запросу.
+type UserExport struct {
+	ExportedAt     time.Time             `json:"exported_at"`
+	Profile        ExportProfile         `json:"profile"`
+	Balance        BalanceResponse       `json:"balance"`
+	Orders         []OrderResponse       `json:"orders"`
+	Withdrawals    []WithdrawalResponse  `json:"withdrawals"`
+	BalanceHistory []BalanceHistoryEntry `json:"balance_history"`
+}
+
+// ExportProfile учетные данные пользователя в выгрузке, без хеша пароля.
+type ExportProfile struct {
+	ID        int64     `json:"id"`
+	Login     string    `json:"login"`
+	Tenant    string    `json:"tenant"`
+	Roles     []Role    `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BalanceHistoryEntry движение баллов пользователя: операция журнала, списание (withdrawal)
+// или корректировка сотрудником (adjustment). Amount отрицателен для списаний.
+type BalanceHistoryEntry struct {
+	Operation    string    `json:"operation"`
+	Amount       float64   `json:"amount"`
+	Order        string    `json:"order,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Операции истории баллов, которые хранятся вне журнала движения баллов.
+const (
+	BalanceHistoryWithdrawal = "withdrawal"
+	BalanceHistoryAdjustment = "adjustment"
+)
+
+// Строки выписки с остатками на начало и конец периода.
+const (
+	StatementOpeningBalance = "opening_balance"
+	StatementClosingBalance = "closing_balance"
+)
+
+// ReferralStatus статус приглашения пользователя по реферальному коду.
+type ReferralStatus string
+
+const (
+	// ReferralStatusPending приглашенный зарегистрирован, но его первый заказ еще не обработан.
+	ReferralStatusPending ReferralStatus = "PENDING"
+	// ReferralStatusRewarded бонусы начислены обеим сторонам.
+	ReferralStatusRewarded ReferralStatus = "REWARDED"
+	// ReferralStatusRejected бонусы не начисляются; причина указана в RejectReason.
+	ReferralStatusRejected ReferralStatus = "REJECTED"
+)
+
+// Причины отказа в реферальных бонусах.
+const (
+	ReferralRejectSameIP        = "same_ip"
+	ReferralRejectIPLimit       = "ip_limit"
+	ReferralRejectReferrerLimit = "referrer_limit"
+)
+
+// Referral приглашение пользователя RefereeID пользователем ReferrerID. IP — адрес,
+// с которого приглашенный зарегистрировался.
+type Referral struct {
+	ID            int64          `db:"id" json:"-"`
+	ReferrerID    int64          `db:"referrer_id" json:"-"`
+	RefereeID     int64          `db:"referee_id" json:"-"`
+	RefereeLogin  string         `db:"referee_login" json:"login"`
+	IP            string         `db:"ip" json:"-"`
+	Status        ReferralStatus `db:"status" json:"status"`
+	RejectReason  string         `db:"reject_reason" json:"reject_reason,omitempty"`
+	ReferrerBonus float64        `db:"referrer_bonus" json:"bonus,omitempty"`
+	RefereeBonus  float64        `db:"referee_bonus" json:"-"`
+	CreatedAt     time.Time      `db:"created_at" json:"registered_at"`
+	RewardedAt    *time.Time     `db:"rewarded_at" json:"rewarded_at,omitempty"`
+}
+
+// ReferralCode реферальный код пользователя. IP — адрес последнего запроса владельца к своим приглашениям,
+// регистрации с него по этому коду считаются саморефералом.
+type ReferralCode struct {
+	UserID int64  `db:"user_id"`
+	Tenant string `db:"tenant"`
+	Code   string `db:"code"`
+	IP     string `db:"ip"`
+}
+
+// ReferralsResponse реферальный код пользователя и приглашенные им пользователи, начиная с последнего.
+type ReferralsResponse struct {
+	Code      string      `json:"code"`
+	Referrals []*Referral `json:"referrals"`
+}
+
+// FinanceDailyReport суточные финансовые показатели арендатора. Сутки считаются по UTC.
+// Liability — сумма доступных баллов пользователей на момент расчета отчета; для суток,
+// рассчитанных задним числом, она неизвестна и равна nil.
+type FinanceDailyReport struct {
+	Day                string    `db:"day" json:"day"`
+	Tenant             string    `db:"tenant" json:"tenant"`
+	Accrued            float64   `db:"accrued" json:"accrued"`
+	Withdrawn          float64   `db:"withdrawn" json:"withdrawn"`
+	NewUsers           int       `db:"new_users" json:"new_users"`
+	OrdersProcessed    int       `db:"orders_processed" json:"orders_processed"`
+	OrdersInvalid      int       `db:"orders_invalid" json:"orders_invalid"`
+	OrdersUnregistered int       `db:"orders_unregistered" json:"orders_unregistered"`
+	Liability          *float64  `db:"liability" json:"liability"`
+	ComputedAt         time.Time `db:"computed_at" json:"computed_at"`
+}
+
+// FinanceSummary итоги финансовых отчетов за период. Liability берется из последних суток периода,
+// для которых она известна.
+type FinanceSummary struct {
+	From               string   `json:"from"`
+	To                 string   `json:"to"`
+	Days               int      `json:"days"`
+	Accrued            float64  `json:"accrued"`
+	Withdrawn          float64  `json:"withdrawn"`
+	NewUsers           int      `json:"new_users"`
+	OrdersProcessed    int      `json:"orders_processed"`
+	OrdersInvalid      int      `json:"orders_invalid"`
+	OrdersUnregistered int      `json:"orders_unregistered"`
+	Liability          *float64 `json:"liability"`
+	LiabilityDay       string   `json:"liability_day,omitempty"`
+}
+
+// ReconciliationKind вид расхождения, найденного сверкой.
+type ReconciliationKind string
+
+const (
+	// ReconciliationOrderAccrual начисление заказа не совпадает с ответом системы расчета.
+	ReconciliationOrderAccrual ReconciliationKind = "order_accrual"
+	// ReconciliationOrderStatus система расчета не подтверждает статус PROCESSED заказа.
+	ReconciliationOrderStatus ReconciliationKind = "order_status"
+	// ReconciliationOrderLedger начисление заказа не совпадает с зачислениями по нему в журнале движения баллов.
+	ReconciliationOrderLedger ReconciliationKind = "order_ledger"
+	// ReconciliationBalanceCurrent текущий остаток не совпадает с пересчитанным по истории операций.
+	ReconciliationBalanceCurrent ReconciliationKind = "balance_current"
+	// ReconciliationBalanceWithdrawn сумма списаний баланса не совпадает с историей списаний.
+	ReconciliationBalanceWithdrawn ReconciliationKind = "balance_withdrawn"
+)
+
+const (
+	// ReconciliationActor автор корректировок, записанных сверкой.
+	ReconciliationActor = "reconciliation"
+	// ReconciliationTolerance наименьшее расхождение сумм баллов, которое сверка считает ошибкой,
+	// а не погрешностью округления.
+	ReconciliationTolerance = 0.005
+)
+
+// ReconciliationRun запуск сверки с итогами проверки.
+type ReconciliationRun struct {
+	ID              int64      `db:"id" json:"id"`
+	AutoFix         bool       `db:"auto_fix" json:"auto_fix"`
+	OrdersChecked   int        `db:"orders_checked" json:"orders_checked"`
+	OrdersFailed    int        `db:"orders_failed" json:"orders_failed"`
+	BalancesChecked int        `db:"balances_checked" json:"balances_checked"`
+	Discrepancies   int        `db:"discrepancies" json:"discrepancies"`
+	Corrected       int        `db:"corrected" json:"corrected"`
+	StartedAt       time.Time  `db:"started_at" json:"started_at"`
+	FinishedAt      *time.Time `db:"finished_at" json:"finished_at,omitempty"`
+}
+
+// ReconciliationDiscrepancy расхождение, найденное сверкой. Expected — значение по источнику истины
+// (системе расчета или истории операций), Actual — сохраненное значение. Для расхождений заказов
+// заполнены OrderID и OrderNumber; AdjustmentID указывает на корректировку баланса, если она записана.
+type ReconciliationDiscrepancy struct {
+	ID           int64              `db:"id" json:"id"`
+	RunID        int64              `db:"run_id" json:"run_id"`
+	Kind         ReconciliationKind `db:"kind" json:"kind"`
+	Tenant       string             `db:"tenant" json:"tenant"`
+	UserID       int64              `db:"user_id" json:"user_id"`
+	OrderID      int64              `db:"order_id" json:"order_id,omitempty"`
+	OrderNumber  string             `db:"order_number" json:"order_number,omitempty"`
+	Expected     float64            `db:"expected" json:"expected"`
+	Actual       float64            `db:"actual" json:"actual"`
+	Details      string             `db:"details" json:"details,omitempty"`
+	Corrected    bool               `db:"corrected" json:"corrected"`
+	AdjustmentID *int64             `db:"adjustment_id" json:"adjustment_id,omitempty"`
+	CreatedAt    time.Time          `db:"created_at" json:"created_at"`
 }
 
 type AccrualResponse struct {
@@ -86,6 +527,259 @@ type AccrualResponse struct {
 	Accrual float64             `json:"accrual,omitempty"`
 }
 
+type UserEventType string
+
+const (
+	UserEventOrder   UserEventType = "order"
+	UserEventBalance UserEventType = "balance"
+)
+
+type UserEvent struct {
+	ID        int64           `db:"id"`
+	UserID    int64           `db:"user_id"`
+	Type      UserEventType   `db:"type"`
+	Payload   json.RawMessage `db:"payload"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+type WebhookEventType string
+
+const (
+	WebhookEventOrderProcessed     WebhookEventType = "order.processed"
+	WebhookEventOrderInvalid       WebhookEventType = "order.invalid"
+	WebhookEventBalanceWithdrawn   WebhookEventType = "balance.withdrawn"
+	WebhookEventBalanceTransferred WebhookEventType = "balance.transferred"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "DEAD"
+)
+
+// DefaultTenant арендатор, к которому относятся запросы без явного указания витрины.
+const DefaultTenant = "default"
+
+type WebhookSubscription struct {
+	ID         int64              `db:"id" json:"id"`
+	Tenant     string             `db:"tenant" json:"tenant"`
+	URL        string             `db:"url" json:"url"`
+	Secret     string             `db:"secret" json:"secret,omitempty"`
+	EventTypes []WebhookEventType `db:"event_types" json:"event_types"`
+	Active     bool               `db:"active" json:"active"`
+	CreatedAt  time.Time          `db:"created_at" json:"created_at"`
+}
+
+type WebhookSubscriptionRequest struct {
+	Tenant     string             `json:"tenant"`
+	URL        string             `json:"url" binding:"required"`
+	Secret     string             `json:"secret"`
+	EventTypes []WebhookEventType `json:"event_types" binding:"required,min=1"`
+}
+
+type WebhookDelivery struct {
+	ID             int64                 `db:"id" json:"id"`
+	SubscriptionID int64                 `db:"subscription_id" json:"subscription_id"`
+	EventID        int64                 `db:"outbox_id" json:"event_id"`
+	EventType      WebhookEventType      `db:"event_type" json:"event_type"`
+	Status         WebhookDeliveryStatus `db:"status" json:"status"`
+	Attempts       int                   `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError      string                `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time             `db:"created_at" json:"created_at"`
+
+	URL       string          `json:"-"`
+	Secret    string          `json:"-"`
+	Payload   json.RawMessage `json:"-"`
+	EventTime time.Time       `json:"-"`
+}
+
+type WebhookOrderPayload struct {
+	UserID  int64       `json:"user_id"`
+	Order   string      `json:"order"`
+	Status  OrderStatus `json:"status"`
+	Accrual float64     `json:"accrual,omitempty"`
+}
+
+type WebhookWithdrawalPayload struct {
+	UserID int64   `json:"user_id"`
+	Order  string  `json:"order"`
+	Sum    float64 `json:"sum"`
+}
+
+type WebhookTransferPayload struct {
+	TransferID  int64   `json:"transfer_id"`
+	SenderID    int64   `json:"sender_id"`
+	RecipientID int64   `json:"recipient_id"`
+	Sum         float64 `json:"sum"`
+}
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleSupport  Role = "support"
+	RoleFinance  Role = "finance"
+	RoleAdmin    Role = "admin"
+)
+
+// Roles перечисляет все известные роли в порядке возрастания привилегий.
+var Roles = []Role{RoleCustomer, RoleSupport, RoleFinance, RoleAdmin}
+
+type UserIdentity struct {
+	UserID int64
+	Tenant string
+	Roles  []Role
+}
+
+type RoleChangeAction string
+
+const (
+	RoleChangeGrant  RoleChangeAction = "GRANT"
+	RoleChangeRevoke RoleChangeAction = "REVOKE"
+)
+
+type RoleChange struct {
+	ID        int64            `db:"id" json:"id"`
+	UserID    int64            `db:"user_id" json:"user_id"`
+	Role      Role             `db:"role" json:"role"`
+	Action    RoleChangeAction `db:"action" json:"action"`
+	Actor     string           `db:"actor" json:"actor"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+}
+
+type UserRolesRequest struct {
+	Roles []Role `json:"roles" binding:"required"`
+}
+
+type Staff struct {
+	Name string
+	Role Role
+}
+
+type UserSummary struct {
+	ID        int64     `db:"id" json:"id"`
+	Tenant    string    `db:"tenant" json:"tenant"`
+	Login     string    `db:"login" json:"login"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Current   float64   `db:"current" json:"current"`
+	Withdrawn float64   `db:"withdrawn" json:"withdrawn"`
+}
+
+type BalanceAdjustment struct {
+	ID     int64   `db:"id" json:"id"`
+	UserID int64   `db:"user_id" json:"user_id"`
+	Amount float64 `db:"amount" json:"amount"`
+	// Withdrawn изменение суммы списаний; бывает только у исправлений расхождений сверкой.
+	Withdrawn float64 `db:"withdrawn" json:"withdrawn,omitempty"`
+	// Drift отмечает исправление сверкой расхождения баланса с историей операций. Такая корректировка
+	// приводит сохраненный баланс к истории и поэтому сама в историю движения баллов не входит.
+	Drift     bool      `db:"drift" json:"drift,omitempty"`
+	Reason    string    `db:"reason" json:"reason"`
+	Actor     string    `db:"actor" json:"actor"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type BalanceAdjustmentRequest struct {
+	Amount float64 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type AuditAction string
+
+const (
+	AuditUserRegistered    AuditAction = "user.registered"
+	AuditUserLoggedIn      AuditAction = "user.logged_in"
+	AuditUserLoginFailed   AuditAction = "user.login_failed"
+	AuditUserRolesChanged  AuditAction = "user.roles_changed"
+	AuditUserExported      AuditAction = "user.exported"
+	AuditUserDeleted       AuditAction = "user.deleted"
+	AuditOrderUploaded     AuditAction = "order.uploaded"
+	AuditOrdersBatch       AuditAction = "order.batch_uploaded"
+	AuditOrderRequeued     AuditAction = "order.requeued"
+	AuditBalanceWithdrawn  AuditAction = "balance.withdrawn"
+	AuditBalanceAdjusted   AuditAction = "balance.adjusted"
+	AuditBalanceReconciled AuditAction = "balance.reconciled"
+	AuditBalanceTransfer   AuditAction = "balance.transferred"
+	AuditBalanceReserved   AuditAction = "balance.reserved"
+	AuditReserveConfirmed  AuditAction = "balance.reservation_confirmed"
+	AuditReserveCancelled  AuditAction = "balance.reservation_cancelled"
+	AuditCampaignCreated   AuditAction = "campaign.created"
+	AuditCampaignUpdated   AuditAction = "campaign.updated"
+	AuditCampaignDeleted   AuditAction = "campaign.deleted"
+)
+
+// RequestMeta данные входящего запроса, сохраняемые в журнале аудита.
+type RequestMeta struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type AuditEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	Tenant    string          `db:"tenant" json:"tenant"`
+	Actor     string          `db:"actor" json:"actor"`
+	Action    AuditAction     `db:"action" json:"action"`
+	Target    string          `db:"target" json:"target"`
+	IP        string          `db:"ip" json:"ip,omitempty"`
+	UserAgent string          `db:"user_agent" json:"user_agent,omitempty"`
+	RequestID string          `db:"request_id" json:"request_id,omitempty"`
+	Before    json.RawMessage `db:"before" json:"before,omitempty"`
+	After     json.RawMessage `db:"after" json:"after,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+type AuditFilter struct {
+	Tenant  string
+	Actor   string
+	Action  AuditAction
+	Target  string
+	From    time.Time
+	To      time.Time
+	AfterID int64
+	Limit   int
+}
+
+type ExportFormat string
+
+const (
+	ExportCSV   ExportFormat = "csv"
+	ExportJSONL ExportFormat = "jsonl"
+	ExportJSON  ExportFormat = "json"
+	ExportZIP   ExportFormat = "zip"
+	ExportXLSX  ExportFormat = "xlsx"
+	ExportPDF   ExportFormat = "pdf"
+)
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+type BatchOrderStatus string
+
+const (
+	BatchOrderAccepted     BatchOrderStatus = "accepted"
+	BatchOrderAlreadyYours BatchOrderStatus = "already_yours"
+	BatchOrderConflict     BatchOrderStatus = "conflict"
+	BatchOrderInvalidLuhn  BatchOrderStatus = "invalid_luhn"
+	// BatchOrderInvalidNumber номер нарушает правило проверки, отличное от алгоритма Луна.
+	BatchOrderInvalidNumber BatchOrderStatus = "invalid_number"
+)
+
+type BatchOrderResult struct {
+	Number string           `json:"number"`
+	Result BatchOrderStatus `json:"result"`
+	Reason string           `json:"reason,omitempty"`
+}
+
+// ErrorResponse ответ с ошибкой. Rule заполняется, если запрос нарушил правило проверки.
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Rule  string `json:"rule,omitempty"`
 }
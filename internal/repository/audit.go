@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepo(db *pgxpool.Pool) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+func (r *AuditRepo) AppendAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (tenant, actor, action, target, ip, user_agent, request_id, before, after) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		event.Tenant,
+		event.Actor,
+		event.Action,
+		event.Target,
+		event.IP,
+		event.UserAgent,
+		event.RequestID,
+		nullableJSON(event.Before),
+		nullableJSON(event.After),
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи события аудита: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditEvents возвращает события аудита по фильтру в порядке возрастания идентификатора.
+// Постраничная выборка выполняется по AfterID, поэтому выгрузка не держит в памяти весь журнал.
+func (r *AuditRepo) GetAuditEvents(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error) {
+	conditions := []string{"id > $1"}
+	args := []any{filter.AfterID}
+
+	addCondition := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Tenant != "" {
+		addCondition("tenant = $%d", filter.Tenant)
+	}
+	if filter.Actor != "" {
+		addCondition("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.Target != "" {
+		addCondition("target = $%d", filter.Target)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+		SELECT id, tenant, actor, action, target, ip, user_agent, request_id, before, after, created_at 
+		FROM audit_events 
+		WHERE %s 
+		ORDER BY id 
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения событий аудита: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		var event model.AuditEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Tenant,
+			&event.Actor,
+			&event.Action,
+			&event.Target,
+			&event.IP,
+			&event.UserAgent,
+			&event.RequestID,
+			&event.Before,
+			&event.After,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования события аудита: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по событиям аудита: %w", err)
+	}
+
+	return events, nil
+}
+
+func nullableJSON(value []byte) any {
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
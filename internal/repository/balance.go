@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	stderrors "errors"
 	"github.com/Gerfey/gophermart/internal/errors"
@@ -22,7 +23,7 @@ func NewBalanceRepo(db *pgxpool.Pool) *BalanceRepo {
 func (r *BalanceRepo) GetBalance(ctx context.Context, userID int64) (*model.Balance, error) {
 	var balance model.Balance
 	query := `
-		SELECT user_id, current, withdrawn 
+		SELECT user_id, current, withdrawn, reserved 
 		FROM balances 
 		WHERE user_id = $1
 	`
@@ -31,6 +32,7 @@ func (r *BalanceRepo) GetBalance(ctx context.Context, userID int64) (*model.Bala
 		&balance.UserID,
 		&balance.Current,
 		&balance.Withdrawn,
+		&balance.Reserved,
 	)
 
 	if err != nil {
@@ -58,13 +60,34 @@ func (r *BalanceRepo) CreateBalance(ctx context.Context, userID int64) error {
 	return nil
 }
 
-func (r *BalanceRepo) AddAccrual(ctx context.Context, userID int64, amount float64) error {
+// AddAccrual зачисляет начисление по заказу вместе с надбавкой уровня лояльности, если она задана,
+// и записывает обе операции в журнал движения баллов. Нулевой orderID означает начисление без заказа.
+func (r *BalanceRepo) AddAccrual(ctx context.Context, userID, orderID int64, amount float64, bonus *model.TierBonus) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	if err := creditAccrual(ctx, tx, userID, orderID, amount, bonus); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// creditAccrual зачисляет начисление и надбавку уровня лояльности на баланс и записывает
+// обе операции в журнал движения баллов в рамках транзакции tx.
+func creditAccrual(ctx context.Context, tx pgx.Tx, userID, orderID int64, amount float64, bonus *model.TierBonus) error {
+	total := amount
+	if bonus != nil {
+		total += bonus.Amount
+	}
+
 	upsertQuery := `
 		INSERT INTO balances (user_id, current, withdrawn) 
 		VALUES ($1, $2, 0)
@@ -72,39 +95,59 @@ func (r *BalanceRepo) AddAccrual(ctx context.Context, userID int64, amount float
 		SET current = balances.current + $2
 	`
 
-	if _, err := tx.Exec(ctx, upsertQuery, userID, amount); err != nil {
+	if _, err := tx.Exec(ctx, upsertQuery, userID, total); err != nil {
 		return fmt.Errorf("ошибка обновления баланса: %w", err)
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	ledgerQuery := `
+		INSERT INTO balance_ledger (user_id, amount, operation, order_id) 
+		VALUES ($1, $2, $3, NULLIF($4, 0))
+	`
+	if _, err := tx.Exec(ctx, ledgerQuery, userID, amount, model.LedgerAccrual, orderID); err != nil {
+		return fmt.Errorf("ошибка записи журнала движения баллов: %w", err)
+	}
+
+	if bonus != nil && bonus.Amount > 0 {
+		if _, err := tx.Exec(ctx, ledgerQuery, userID, bonus.Amount, model.LedgerTierBonus, orderID); err != nil {
+			return fmt.Errorf("ошибка записи надбавки уровня лояльности: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func (r *BalanceRepo) Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string) error {
+// Withdraw списывает баллы по заказу. Непустой check вызывается после блокировки баланса
+// и до проверки достаточности средств.
+func (r *BalanceRepo) Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string, check WithdrawalCheck) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	var currentBalance float64
+	var (
+		currentBalance float64
+		tenant         string
+	)
 
 	balanceQuery := `
-		SELECT current 
-		FROM balances 
-		WHERE user_id = $1
-		FOR UPDATE
+		SELECT b.current, u.tenant 
+		FROM balances b 
+		JOIN users u ON u.id = b.user_id 
+		WHERE b.user_id = $1
+		FOR UPDATE OF b
 	`
-	if err := tx.QueryRow(ctx, balanceQuery, userID).Scan(&currentBalance); err != nil {
+	if err := tx.QueryRow(ctx, balanceQuery, userID).Scan(&currentBalance, &tenant); err != nil {
 		if stderrors.Is(err, pgx.ErrNoRows) {
 			return fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
 		}
 		return fmt.Errorf("ошибка получения текущего баланса: %w", err)
 	}
 
+	if err := checkWithdrawal(ctx, tx, userID, amount, orderNumber, check); err != nil {
+		return err
+	}
+
 	if currentBalance < amount {
 		return fmt.Errorf("%w", errors.ErrInsufficientFunds)
 	}
@@ -119,13 +162,22 @@ func (r *BalanceRepo) Withdraw(ctx context.Context, userID int64, amount float64
 	}
 
 	withdrawalQuery := `
-		INSERT INTO withdrawals (user_id, order_number, amount) 
-		VALUES ($1, $2, $3)
+		INSERT INTO withdrawals (tenant, user_id, order_number, amount) 
+		VALUES ($1, $2, $3, $4)
 	`
-	if _, err := tx.Exec(ctx, withdrawalQuery, userID, orderNumber, amount); err != nil {
+	if _, err := tx.Exec(ctx, withdrawalQuery, tenant, userID, orderNumber, amount); err != nil {
 		return fmt.Errorf("ошибка создания записи о списании: %w", err)
 	}
 
+	payload := model.WebhookWithdrawalPayload{
+		UserID: userID,
+		Order:  orderNumber,
+		Sum:    amount,
+	}
+	if err := insertOutbox(ctx, tx, tenant, model.WebhookEventBalanceWithdrawn, payload); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
 	}
@@ -168,3 +220,462 @@ func (r *BalanceRepo) GetWithdrawals(ctx context.Context, userID int64) ([]*mode
 
 	return withdrawals, nil
 }
+
+// AdjustBalance применяет ручную корректировку баланса и сохраняет ее с причиной и автором.
+// Отрицательная корректировка не может сделать баланс отрицательным.
+func (r *BalanceRepo) AdjustBalance(ctx context.Context, userID int64, amount float64, reason, actor string) (*model.BalanceAdjustment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var currentBalance float64
+	balanceQuery := `
+		SELECT current 
+		FROM balances 
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+
+	if err := tx.QueryRow(ctx, balanceQuery, userID).Scan(&currentBalance); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения текущего баланса: %w", err)
+	}
+
+	if currentBalance+amount < 0 {
+		return nil, fmt.Errorf("%w", errors.ErrInsufficientFunds)
+	}
+
+	updateBalanceQuery := `
+		UPDATE balances 
+		SET current = current + $1 
+		WHERE user_id = $2
+	`
+
+	if _, err := tx.Exec(ctx, updateBalanceQuery, amount, userID); err != nil {
+		return nil, fmt.Errorf("ошибка корректировки баланса: %w", err)
+	}
+
+	adjustment := &model.BalanceAdjustment{
+		UserID: userID,
+		Amount: amount,
+		Reason: reason,
+		Actor:  actor,
+	}
+
+	adjustmentQuery := `
+		INSERT INTO balance_adjustments (user_id, amount, reason, actor) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id, created_at
+	`
+
+	if err := tx.QueryRow(ctx, adjustmentQuery, userID, amount, reason, actor).Scan(&adjustment.ID, &adjustment.CreatedAt); err != nil {
+		return nil, fmt.Errorf("ошибка создания записи о корректировке: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+func (r *BalanceRepo) GetAdjustments(ctx context.Context, userID int64) ([]*model.BalanceAdjustment, error) {
+	query := `
+		SELECT id, user_id, amount, withdrawn, drift, reason, actor, created_at 
+		FROM balance_adjustments 
+		WHERE user_id = $1 
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения корректировок баланса: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []*model.BalanceAdjustment
+	for rows.Next() {
+		var adjustment model.BalanceAdjustment
+		if err := rows.Scan(
+			&adjustment.ID,
+			&adjustment.UserID,
+			&adjustment.Amount,
+			&adjustment.Withdrawn,
+			&adjustment.Drift,
+			&adjustment.Reason,
+			&adjustment.Actor,
+			&adjustment.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки корректировки: %w", err)
+		}
+		adjustments = append(adjustments, &adjustment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по корректировкам: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+// Transfer переводит баллы между пользователями в одной транзакции. Балансы обеих сторон
+// блокируются в порядке возрастания ID пользователя, чтобы встречные переводы не приводили
+// к взаимоблокировке. Повторный запрос с тем же ключом идемпотентности возвращает ранее
+// выполненный перевод. Положительный dailyLimit ограничивает сумму переводов отправителя
+// за текущие сутки.
+func (r *BalanceRepo) Transfer(ctx context.Context, transfer *model.Transfer, dailyLimit float64) (*model.Transfer, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	createRecipientBalanceQuery := `
+		INSERT INTO balances (user_id, current, withdrawn) 
+		VALUES ($1, 0, 0) 
+		ON CONFLICT (user_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, createRecipientBalanceQuery, transfer.RecipientID); err != nil {
+		return nil, fmt.Errorf("ошибка создания баланса получателя: %w", err)
+	}
+
+	lockQuery := `
+		SELECT b.user_id, b.current, u.tenant 
+		FROM balances b 
+		JOIN users u ON u.id = b.user_id 
+		WHERE b.user_id = ANY($1) 
+		ORDER BY b.user_id
+		FOR UPDATE OF b
+	`
+	rows, err := tx.Query(ctx, lockQuery, []int64{transfer.SenderID, transfer.RecipientID})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки балансов: %w", err)
+	}
+
+	var (
+		senderBalance float64
+		tenant        string
+		senderFound   bool
+	)
+	for rows.Next() {
+		var (
+			userID  int64
+			current float64
+			owner   string
+		)
+		if err := rows.Scan(&userID, &current, &owner); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования баланса: %w", err)
+		}
+		if userID == transfer.SenderID {
+			senderBalance, tenant, senderFound = current, owner, true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка блокировки балансов: %w", err)
+	}
+	if !senderFound {
+		return nil, fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
+	}
+
+	// Баланс отправителя уже заблокирован, поэтому параллельные запросы с одним ключом
+	// проверяются последовательно.
+	existing := &model.Transfer{SenderID: transfer.SenderID, IdempotencyKey: transfer.IdempotencyKey}
+	existingQuery := `
+		SELECT id, recipient_id, amount, created_at 
+		FROM balance_transfers 
+		WHERE sender_id = $1 AND idempotency_key = $2
+	`
+	err = tx.QueryRow(ctx, existingQuery, transfer.SenderID, transfer.IdempotencyKey).
+		Scan(&existing.ID, &existing.RecipientID, &existing.Amount, &existing.CreatedAt)
+	switch {
+	case err == nil:
+		if existing.RecipientID != transfer.RecipientID || existing.Amount != transfer.Amount {
+			return nil, fmt.Errorf("%w", errors.ErrIdempotencyConflict)
+		}
+		existing.Replayed = true
+		return existing, nil
+	case !stderrors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("ошибка проверки ключа идемпотентности: %w", err)
+	}
+
+	if dailyLimit > 0 {
+		var transferred float64
+		dailyQuery := `
+			SELECT COALESCE(SUM(amount), 0) 
+			FROM balance_transfers 
+			WHERE sender_id = $1 AND created_at >= date_trunc('day', NOW())
+		`
+		if err := tx.QueryRow(ctx, dailyQuery, transfer.SenderID).Scan(&transferred); err != nil {
+			return nil, fmt.Errorf("ошибка подсчета переводов за сутки: %w", err)
+		}
+		if transferred+transfer.Amount > dailyLimit {
+			return nil, fmt.Errorf("%w", errors.ErrTransferLimit)
+		}
+	}
+
+	if senderBalance < transfer.Amount {
+		return nil, fmt.Errorf("%w", errors.ErrInsufficientFunds)
+	}
+
+	updateBalanceQuery := `
+		UPDATE balances 
+		SET current = current + $1 
+		WHERE user_id = $2
+	`
+	if _, err := tx.Exec(ctx, updateBalanceQuery, -transfer.Amount, transfer.SenderID); err != nil {
+		return nil, fmt.Errorf("ошибка списания средств отправителя: %w", err)
+	}
+	if _, err := tx.Exec(ctx, updateBalanceQuery, transfer.Amount, transfer.RecipientID); err != nil {
+		return nil, fmt.Errorf("ошибка зачисления средств получателю: %w", err)
+	}
+
+	created := *transfer
+	transferQuery := `
+		INSERT INTO balance_transfers (tenant, sender_id, recipient_id, amount, idempotency_key) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, transferQuery, tenant, transfer.SenderID, transfer.RecipientID, transfer.Amount, transfer.IdempotencyKey).
+		Scan(&created.ID, &created.CreatedAt); err != nil {
+		return nil, fmt.Errorf("ошибка создания записи о переводе: %w", err)
+	}
+
+	ledgerQuery := `
+		INSERT INTO balance_ledger (user_id, amount, operation, transfer_id, created_at) 
+		VALUES ($1, $2, $3, $7, $8), ($4, $5, $6, $7, $8)
+	`
+	if _, err := tx.Exec(ctx, ledgerQuery,
+		transfer.SenderID, -transfer.Amount, model.LedgerTransferOut,
+		transfer.RecipientID, transfer.Amount, model.LedgerTransferIn,
+		created.ID, created.CreatedAt,
+	); err != nil {
+		return nil, fmt.Errorf("ошибка записи журнала движения баллов: %w", err)
+	}
+
+	payload := model.WebhookTransferPayload{
+		TransferID:  created.ID,
+		SenderID:    created.SenderID,
+		RecipientID: created.RecipientID,
+		Sum:         created.Amount,
+	}
+	if err := insertOutbox(ctx, tx, tenant, model.WebhookEventBalanceTransferred, payload); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetTransfers возвращает записи журнала движения баллов пользователя, относящиеся к переводам,
+// вместе с логином второй стороны перевода.
+func (r *BalanceRepo) GetTransfers(ctx context.Context, userID int64) ([]*model.LedgerEntry, error) {
+	query := `
+		SELECT l.id, l.user_id, l.amount, l.operation, l.transfer_id, u.login, l.created_at 
+		FROM balance_ledger l 
+		JOIN balance_transfers t ON t.id = l.transfer_id 
+		JOIN users u ON u.id = CASE WHEN t.sender_id = l.user_id THEN t.recipient_id ELSE t.sender_id END 
+		WHERE l.user_id = $1 
+		ORDER BY l.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории переводов: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*model.LedgerEntry
+	for rows.Next() {
+		var entry model.LedgerEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Amount,
+			&entry.Operation,
+			&entry.TransferID,
+			&entry.Counterparty,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки перевода: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по переводам: %w", err)
+	}
+
+	return entries, nil
+}
+
+// checkWithdrawal собирает статистику списаний пользователя и передает ее проверке check.
+// Баланс пользователя должен быть заблокирован в транзакции tx.
+func checkWithdrawal(ctx context.Context, tx pgx.Tx, userID int64, amount float64, orderNumber string, check WithdrawalCheck) error {
+	if check == nil {
+		return nil
+	}
+
+	stats := model.WithdrawalStats{
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+	}
+
+	statsQuery := `
+		SELECT
+			COALESCE(SUM(w.amount) FILTER (WHERE w.at >= date_trunc('day', NOW())), 0),
+			COALESCE(SUM(w.amount) FILTER (WHERE w.at >= date_trunc('month', NOW())), 0),
+			COALESCE(SUM(w.amount) FILTER (WHERE w.order_number = $2), 0),
+			(
+				SELECT MIN(COALESCE(h.created_at, o.uploaded_at)) 
+				FROM orders o 
+				LEFT JOIN order_status_history h ON h.order_id = o.id AND h.status = $4 
+				WHERE o.user_id = $1 AND o.status = $4 AND o.accrual > 0
+			),
+			NOW()
+		FROM (
+			SELECT amount, processed_at AS at, order_number FROM withdrawals WHERE user_id = $1
+			UNION ALL
+			SELECT amount, created_at, order_number FROM balance_reservations WHERE user_id = $1 AND status = $3
+		) w
+	`
+	if err := tx.QueryRow(ctx, statsQuery, userID, orderNumber, model.ReservationStatusActive, model.OrderStatusProcessed).Scan(
+		&stats.WithdrawnToday,
+		&stats.WithdrawnThisMonth,
+		&stats.WithdrawnForOrder,
+		&stats.FirstAccrualAt,
+		&stats.Now,
+	); err != nil {
+		return fmt.Errorf("ошибка получения статистики списаний: %w", err)
+	}
+
+	return check(stats)
+}
+
+// GetLedger возвращает все записи журнала движения баллов пользователя, начиная с последней,
+// с логином второй стороны перевода и номером заказа начисления.
+func (r *BalanceRepo) GetLedger(ctx context.Context, userID int64) ([]*model.LedgerEntry, error) {
+	query := `
+		SELECT l.id, l.user_id, l.amount, l.operation, l.transfer_id, COALESCE(u.login, ''), COALESCE(o.number, ''), l.created_at 
+		FROM balance_ledger l 
+		LEFT JOIN balance_transfers t ON t.id = l.transfer_id 
+		LEFT JOIN users u ON u.id = CASE WHEN t.sender_id = l.user_id THEN t.recipient_id ELSE t.sender_id END 
+		LEFT JOIN orders o ON o.id = l.order_id 
+		WHERE l.user_id = $1 
+		ORDER BY l.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала движения баллов: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*model.LedgerEntry
+	for rows.Next() {
+		var entry model.LedgerEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.Amount,
+			&entry.Operation,
+			&entry.TransferID,
+			&entry.Counterparty,
+			&entry.OrderNumber,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки журнала: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по журналу движения баллов: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetBalanceAt возвращает баллы пользователя на момент at, включая зарезервированные:
+// сумму записей журнала движения баллов и корректировок за вычетом списаний до этого момента.
+// Исправления расхождений сверкой в историю не входят.
+func (r *BalanceRepo) GetBalanceAt(ctx context.Context, userID int64, at time.Time) (float64, error) {
+	query := `
+		SELECT
+			COALESCE((SELECT SUM(amount) FROM balance_ledger WHERE user_id = $1 AND created_at < $2), 0)
+			- COALESCE((SELECT SUM(amount) FROM withdrawals WHERE user_id = $1 AND processed_at < $2), 0)
+			+ COALESCE((SELECT SUM(amount) FROM balance_adjustments WHERE user_id = $1 AND NOT drift AND created_at < $2), 0)
+	`
+
+	var balance float64
+	if err := r.db.QueryRow(ctx, query, userID, at).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("ошибка получения остатка баллов: %w", err)
+	}
+
+	return balance, nil
+}
+
+// StreamStatement вызывает fn для каждого движения баллов пользователя за период [from, to)
+// в порядке времени: записей журнала, списаний и корректировок. Строки читаются из базы
+// по мере обработки, ошибка fn прерывает выборку.
+func (r *BalanceRepo) StreamStatement(ctx context.Context, userID int64, from, to time.Time, fn func(*model.BalanceHistoryEntry) error) error {
+	query := `
+		SELECT operation, amount, order_number, counterparty, reason, created_at 
+		FROM (
+			SELECT l.operation::text AS operation, l.amount, COALESCE(o.number, '') AS order_number, 
+				COALESCE(u.login, '') AS counterparty, '' AS reason, l.created_at, l.id, 0 AS source 
+			FROM balance_ledger l 
+			LEFT JOIN balance_transfers t ON t.id = l.transfer_id 
+			LEFT JOIN users u ON u.id = CASE WHEN t.sender_id = l.user_id THEN t.recipient_id ELSE t.sender_id END 
+			LEFT JOIN orders o ON o.id = l.order_id 
+			WHERE l.user_id = $1 AND l.created_at >= $2 AND l.created_at < $3 
+			UNION ALL 
+			SELECT $4::text, -amount, order_number, '', '', processed_at, id, 1 
+			FROM withdrawals 
+			WHERE user_id = $1 AND processed_at >= $2 AND processed_at < $3 
+			UNION ALL 
+			SELECT $5::text, amount, '', '', reason, created_at, id, 2 
+			FROM balance_adjustments 
+			WHERE user_id = $1 AND NOT drift AND created_at >= $2 AND created_at < $3
+		) movements 
+		ORDER BY created_at, source, id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, from, to, model.BalanceHistoryWithdrawal, model.BalanceHistoryAdjustment)
+	if err != nil {
+		return fmt.Errorf("ошибка получения движения баллов: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry model.BalanceHistoryEntry
+		if err := rows.Scan(
+			&entry.Operation,
+			&entry.Amount,
+			&entry.Order,
+			&entry.Counterparty,
+			&entry.Reason,
+			&entry.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("ошибка сканирования строки движения баллов: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по движению баллов: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	stderrors "errors"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const campaignColumns = `id, tenant, name, type, amount, multiplier, order_index, starts_at, ends_at, active, created_at, updated_at`
+
+type CampaignRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCampaignRepo(db *pgxpool.Pool) *CampaignRepo {
+	return &CampaignRepo{db: db}
+}
+
+func scanCampaign(row pgx.Row) (*model.Campaign, error) {
+	var campaign model.Campaign
+	err := row.Scan(
+		&campaign.ID,
+		&campaign.Tenant,
+		&campaign.Name,
+		&campaign.Type,
+		&campaign.Amount,
+		&campaign.Multiplier,
+		&campaign.OrderIndex,
+		&campaign.StartsAt,
+		&campaign.EndsAt,
+		&campaign.Active,
+		&campaign.CreatedAt,
+		&campaign.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *CampaignRepo) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	query := `
+		INSERT INTO campaigns (tenant, name, type, amount, multiplier, order_index, starts_at, ends_at, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		campaign.Tenant,
+		campaign.Name,
+		campaign.Type,
+		campaign.Amount,
+		campaign.Multiplier,
+		campaign.OrderIndex,
+		campaign.StartsAt,
+		campaign.EndsAt,
+		campaign.Active,
+	).Scan(&campaign.ID, &campaign.CreatedAt, &campaign.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания кампании: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CampaignRepo) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	query := `
+		UPDATE campaigns
+		SET tenant = $1, name = $2, type = $3, amount = $4, multiplier = $5, order_index = $6,
+			starts_at = $7, ends_at = $8, active = $9, updated_at = NOW()
+		WHERE id = $10
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		campaign.Tenant,
+		campaign.Name,
+		campaign.Type,
+		campaign.Amount,
+		campaign.Multiplier,
+		campaign.OrderIndex,
+		campaign.StartsAt,
+		campaign.EndsAt,
+		campaign.Active,
+		campaign.ID,
+	).Scan(&campaign.CreatedAt, &campaign.UpdatedAt)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w", errors.ErrCampaignNotFound)
+		}
+		return fmt.Errorf("ошибка изменения кампании: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCampaign удаляет кампанию. Начисленные по ней бонусы сохраняются вместе с названием кампании.
+func (r *CampaignRepo) DeleteCampaign(ctx context.Context, id int64) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM campaigns WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления кампании: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w", errors.ErrCampaignNotFound)
+	}
+
+	return nil
+}
+
+func (r *CampaignRepo) GetCampaign(ctx context.Context, id int64) (*model.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE id = $1`
+
+	campaign, err := scanCampaign(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrCampaignNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения кампании: %w", err)
+	}
+
+	return campaign, nil
+}
+
+// GetCampaigns возвращает кампании арендатора, начиная с последней. Пустой арендатор — кампании всех арендаторов.
+func (r *CampaignRepo) GetCampaigns(ctx context.Context, tenant string) ([]*model.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE $1 = '' OR tenant = $1 ORDER BY id DESC`
+
+	return r.queryCampaigns(ctx, query, tenant)
+}
+
+// GetActiveCampaigns возвращает активные кампании арендатора указанного типа без учета периода действия.
+func (r *CampaignRepo) GetActiveCampaigns(ctx context.Context, tenant string, campaignType model.CampaignType) ([]*model.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns WHERE tenant = $1 AND type = $2 AND active ORDER BY id`
+
+	return r.queryCampaigns(ctx, query, tenant, campaignType)
+}
+
+func (r *CampaignRepo) queryCampaigns(ctx context.Context, query string, args ...any) ([]*model.Campaign, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения кампаний: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*model.Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки кампании: %w", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по кампаниям: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// CreditCampaignBonus начисляет бонус кампании и записывает его в журнал движения баллов.
+// Если бонус кампании с той же Reference уже начислен, баланс не меняется и возвращается false.
+func (r *CampaignRepo) CreditCampaignBonus(ctx context.Context, bonus *model.CampaignBonus) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	bonusQuery := `
+		INSERT INTO campaign_bonuses (campaign_id, campaign_name, user_id, order_id, reference, amount)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+		ON CONFLICT (campaign_id, reference) DO NOTHING
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, bonusQuery,
+		bonus.CampaignID,
+		bonus.CampaignName,
+		bonus.UserID,
+		bonus.OrderID,
+		bonus.Reference,
+		bonus.Amount,
+	).Scan(&bonus.ID, &bonus.CreatedAt)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ошибка записи бонуса кампании: %w", err)
+	}
+
+	balanceQuery := `
+		INSERT INTO balances (user_id, current, withdrawn)
+		VALUES ($1, $2, 0)
+		ON CONFLICT (user_id) DO UPDATE
+		SET current = balances.current + $2
+	`
+	if _, err := tx.Exec(ctx, balanceQuery, bonus.UserID, bonus.Amount); err != nil {
+		return false, fmt.Errorf("ошибка обновления баланса: %w", err)
+	}
+
+	ledgerQuery := `
+		INSERT INTO balance_ledger (user_id, amount, operation, order_id, campaign_bonus_id)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5)
+	`
+	if _, err := tx.Exec(ctx, ledgerQuery, bonus.UserID, bonus.Amount, model.LedgerCampaign, bonus.OrderID, bonus.ID); err != nil {
+		return false, fmt.Errorf("ошибка записи журнала движения баллов: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *CampaignRepo) GetCampaignBonuses(ctx context.Context, userID int64) ([]*model.CampaignBonus, error) {
+	query := `
+		SELECT b.id, COALESCE(b.campaign_id, 0), b.campaign_name, b.user_id, COALESCE(b.order_id, 0),
+			COALESCE(o.number, ''), b.reference, b.amount, b.created_at
+		FROM campaign_bonuses b
+		LEFT JOIN orders o ON o.id = b.order_id
+		WHERE b.user_id = $1
+		ORDER BY b.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения бонусов кампаний: %w", err)
+	}
+	defer rows.Close()
+
+	var bonuses []*model.CampaignBonus
+	for rows.Next() {
+		var bonus model.CampaignBonus
+		if err := rows.Scan(
+			&bonus.ID,
+			&bonus.CampaignID,
+			&bonus.CampaignName,
+			&bonus.UserID,
+			&bonus.OrderID,
+			&bonus.OrderNumber,
+			&bonus.Reference,
+			&bonus.Amount,
+			&bonus.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки бонуса: %w", err)
+		}
+		bonuses = append(bonuses, &bonus)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по бонусам: %w", err)
+	}
+
+	return bonuses, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserEventsChannel канал уведомлений Postgres, в который публикуется идентификатор
+// пользователя при появлении у него нового события.
+const UserEventsChannel = "gophermart_user_events"
+
+type EventRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewEventRepo(db *pgxpool.Pool) *EventRepo {
+	return &EventRepo{db: db}
+}
+
+func (r *EventRepo) AppendEvent(ctx context.Context, userID int64, eventType model.UserEventType, payload []byte) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	query := `
+		INSERT INTO user_events (user_id, type, payload) 
+		VALUES ($1, $2, $3) 
+		RETURNING id
+	`
+
+	if err := tx.QueryRow(ctx, query, userID, eventType, payload).Scan(&id); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения события пользователя: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", UserEventsChannel, strconv.FormatInt(userID, 10)); err != nil {
+		return 0, fmt.Errorf("ошибка отправки уведомления о событии: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *EventRepo) GetEventsAfter(ctx context.Context, userID int64, afterID int64, limit int) ([]*model.UserEvent, error) {
+	query := `
+		SELECT id, user_id, type, payload, created_at 
+		FROM user_events 
+		WHERE user_id = $1 AND id > $2 
+		ORDER BY id 
+		LIMIT $3
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения событий пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.UserEvent
+	for rows.Next() {
+		var event model.UserEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Type,
+			&event.Payload,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки события: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по событиям: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *EventRepo) GetLastEventID(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	query := `
+		SELECT COALESCE(MAX(id), 0) 
+		FROM user_events 
+		WHERE user_id = $1
+	`
+
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("ошибка получения последнего события пользователя: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *EventRepo) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM user_events 
+		WHERE created_at < $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления устаревших событий: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
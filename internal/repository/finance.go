@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FinanceRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewFinanceRepo(db *pgxpool.Pool) *FinanceRepo {
+	return &FinanceRepo{db: db}
+}
+
+// GetNextReportDay возвращает первые сутки, за которые еще не рассчитан отчет: следующие за последними
+// рассчитанными или, если отчетов нет, сутки регистрации первого пользователя. Возвращает nil,
+// если пользователей еще нет.
+func (r *FinanceRepo) GetNextReportDay(ctx context.Context) (*time.Time, error) {
+	query := `
+		SELECT COALESCE(
+			(SELECT to_char(MAX(day) + 1, 'YYYY-MM-DD') FROM finance_daily_reports),
+			(SELECT to_char(MIN(created_at) AT TIME ZONE 'UTC', 'YYYY-MM-DD') FROM users)
+		)
+	`
+
+	var value *string
+	if err := r.db.QueryRow(ctx, query).Scan(&value); err != nil {
+		return nil, fmt.Errorf("ошибка получения даты следующего отчета: %w", err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	day, err := time.Parse(time.DateOnly, *value)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора даты следующего отчета: %w", err)
+	}
+
+	return &day, nil
+}
+
+// MaterializeReport рассчитывает показатели всех арендаторов за сутки day (UTC) и сохраняет их,
+// заменяя ранее рассчитанные. Заказы учитываются по дате перехода в конечный статус, начисления —
+// по дате перехода в PROCESSED. Если snapshotLiability, текущая сумма доступных баллов сохраняется
+// как обязательства на эти сутки, но только если они еще не зафиксированы.
+func (r *FinanceRepo) MaterializeReport(ctx context.Context, day time.Time, snapshotLiability bool) error {
+	query := `
+		WITH tenants AS (
+			SELECT DISTINCT tenant FROM users WHERE created_at < $2
+		),
+		final_orders AS (
+			SELECT o.tenant,
+				COUNT(*) FILTER (WHERE f.status = $5) AS processed,
+				SUM(o.accrual) FILTER (WHERE f.status = $5) AS accrued,
+				COUNT(*) FILTER (WHERE f.status = $6) AS invalid,
+				COUNT(*) FILTER (WHERE f.status = $7) AS unregistered
+			FROM (
+				SELECT DISTINCT order_id, status 
+				FROM order_status_history 
+				WHERE status IN ($5, $6, $7) AND created_at >= $1 AND created_at < $2
+			) f
+			JOIN orders o ON o.id = f.order_id
+			GROUP BY o.tenant
+		),
+		withdrawn AS (
+			SELECT tenant, SUM(amount) AS amount 
+			FROM withdrawals 
+			WHERE processed_at >= $1 AND processed_at < $2 
+			GROUP BY tenant
+		),
+		new_users AS (
+			SELECT tenant, COUNT(*) AS count 
+			FROM users 
+			WHERE created_at >= $1 AND created_at < $2 
+			GROUP BY tenant
+		),
+		liability AS (
+			SELECT u.tenant, SUM(b.current) AS amount 
+			FROM balances b 
+			JOIN users u ON u.id = b.user_id 
+			WHERE $4 
+			GROUP BY u.tenant
+		)
+		INSERT INTO finance_daily_reports (tenant, day, accrued, withdrawn, new_users, 
+			orders_processed, orders_invalid, orders_unregistered, liability, computed_at)
+		SELECT t.tenant, $3::date, COALESCE(f.accrued, 0), COALESCE(w.amount, 0), COALESCE(n.count, 0), 
+			COALESCE(f.processed, 0), COALESCE(f.invalid, 0), COALESCE(f.unregistered, 0), 
+			CASE WHEN $4 THEN COALESCE(l.amount, 0) END, NOW()
+		FROM tenants t
+		LEFT JOIN final_orders f ON f.tenant = t.tenant
+		LEFT JOIN withdrawn w ON w.tenant = t.tenant
+		LEFT JOIN new_users n ON n.tenant = t.tenant
+		LEFT JOIN liability l ON l.tenant = t.tenant
+		ON CONFLICT (tenant, day) DO UPDATE
+		SET accrued = EXCLUDED.accrued, 
+			withdrawn = EXCLUDED.withdrawn, 
+			new_users = EXCLUDED.new_users, 
+			orders_processed = EXCLUDED.orders_processed, 
+			orders_invalid = EXCLUDED.orders_invalid, 
+			orders_unregistered = EXCLUDED.orders_unregistered, 
+			liability = COALESCE(finance_daily_reports.liability, EXCLUDED.liability), 
+			computed_at = EXCLUDED.computed_at
+	`
+
+	from := day.UTC()
+	_, err := r.db.Exec(ctx, query,
+		from,
+		from.AddDate(0, 0, 1),
+		from.Format(time.DateOnly),
+		snapshotLiability,
+		model.OrderStatusProcessed,
+		model.OrderStatusInvalid,
+		model.OrderStatusUnregistered,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка расчета финансового отчета за %s: %w", from.Format(time.DateOnly), err)
+	}
+
+	return nil
+}
+
+// GetReports возвращает суточные отчеты арендатора с from по to включительно в порядке дат.
+func (r *FinanceRepo) GetReports(ctx context.Context, tenant string, from, to time.Time) ([]*model.FinanceDailyReport, error) {
+	query := `
+		SELECT to_char(day, 'YYYY-MM-DD'), tenant, accrued, withdrawn, new_users, 
+			orders_processed, orders_invalid, orders_unregistered, liability, computed_at 
+		FROM finance_daily_reports 
+		WHERE tenant = $1 AND day >= $2::date AND day <= $3::date 
+		ORDER BY day
+	`
+
+	rows, err := r.db.Query(ctx, query, tenant, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения финансовых отчетов: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*model.FinanceDailyReport
+	for rows.Next() {
+		var report model.FinanceDailyReport
+		if err := rows.Scan(
+			&report.Day,
+			&report.Tenant,
+			&report.Accrued,
+			&report.Withdrawn,
+			&report.NewUsers,
+			&report.OrdersProcessed,
+			&report.OrdersInvalid,
+			&report.OrdersUnregistered,
+			&report.Liability,
+			&report.ComputedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки финансового отчета: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по финансовым отчетам: %w", err)
+	}
+
+	return reports, nil
+}
+
+// StartReportMaterialization отмечает плановый расчет отчетов. Возвращает false, если за minInterval
+// расчет уже запускал другой экземпляр сервиса.
+func (r *FinanceRepo) StartReportMaterialization(ctx context.Context, minInterval time.Duration) (bool, error) {
+	return claimJobRun(ctx, r.db, jobReportMaterialization, minInterval)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	stderrors "errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Фоновые задачи, запуск которых отмечается в job_runs.
+const (
+	jobTierRecalculation     = "tier_recalculation"
+	jobReportMaterialization = "finance_reports"
+)
+
+// claimJobRun отмечает запуск фоновой задачи job и возвращает true, если за minInterval ее не запускал
+// ни один экземпляр сервиса. Отметка обновляется одним запросом под блокировкой строки, поэтому из
+// одновременно проснувшихся экземпляров задачу получает только один.
+func claimJobRun(ctx context.Context, db *pgxpool.Pool, job string, minInterval time.Duration) (bool, error) {
+	query := `
+		INSERT INTO job_runs (job, started_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (job) DO UPDATE
+		SET started_at = NOW()
+		WHERE job_runs.started_at <= NOW() - make_interval(secs => $2::float8)
+		RETURNING started_at
+	`
+
+	var startedAt time.Time
+	if err := db.QueryRow(ctx, query, job, minInterval.Seconds()).Scan(&startedAt); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("ошибка отметки запуска задачи %s: %w", job, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// NewOrdersChannel канал уведомлений Postgres, в который публикуются новые заказы
+	// в формате "арендатор:номер".
+	NewOrdersChannel = "gophermart_new_orders"
+
+	listenReconnectDelay = 5 * time.Second
+	notificationsBuffer  = 64
+)
+
+// NewOrderPayload формирует полезную нагрузку уведомления о новом заказе.
+func NewOrderPayload(tenant, number string) string {
+	return tenant + ":" + number
+}
+
+// ParseNewOrderPayload разбирает уведомление о новом заказе. Уведомления без арендатора,
+// отправленные предыдущими версиями сервиса, относятся к арендатору по умолчанию.
+func ParseNewOrderPayload(payload string) (string, string) {
+	tenant, number, ok := strings.Cut(payload, ":")
+	if !ok {
+		return model.DefaultTenant, payload
+	}
+	return tenant, number
+}
+
+type PgListener struct {
+	db *pgxpool.Pool
+}
+
+func NewPgListener(db *pgxpool.Pool) *PgListener {
+	return &PgListener{db: db}
+}
+
+// Listen подписывается на канал уведомлений Postgres на выделенном соединении и возвращает
+// канал с полезной нагрузкой уведомлений. При потере соединения подписка восстанавливается.
+// Возвращаемый канал закрывается после отмены контекста.
+func (l *PgListener) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	conn, err := l.listen(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan string, notificationsBuffer)
+
+	go func() {
+		defer close(out)
+
+		for {
+			err := l.wait(ctx, conn, out)
+			l.release(conn)
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Errorf("Подписка на канал %s прервана: %s", channel, err.Error())
+
+			for conn = nil; conn == nil; {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(listenReconnectDelay):
+				}
+
+				conn, err = l.listen(ctx, channel)
+				if err != nil {
+					log.Errorf("Ошибка повторной подписки на канал %s: %s", channel, err.Error())
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (l *PgListener) listen(ctx context.Context, channel string) (*pgxpool.Conn, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения соединения для подписки: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("ошибка подписки на канал %s: %w", channel, err)
+	}
+
+	return conn, nil
+}
+
+func (l *PgListener) wait(ctx context.Context, conn *pgxpool.Conn, out chan<- string) error {
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case out <- notification.Payload:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *PgListener) release(conn *pgxpool.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultConnTimeout)
+	defer cancel()
+
+	// Соединение возвращается в пул, поэтому подписка должна быть снята.
+	if _, err := conn.Exec(ctx, "UNLISTEN *"); err != nil {
+		conn.Conn().Close(ctx)
+	}
+	conn.Release()
+}
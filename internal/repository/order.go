@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,37 +20,142 @@ func NewOrderRepo(db *pgxpool.Pool) *OrderRepo {
 	return &OrderRepo{db: db}
 }
 
-func (r *OrderRepo) CreateOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (r *OrderRepo) CreateOrder(ctx context.Context, tenant string, userID int64, number string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var id int64
 	query := `
-		INSERT INTO orders (user_id, number, status) 
-		VALUES ($1, $2, $3) 
+		INSERT INTO orders (tenant, user_id, number, status) 
+		VALUES ($1, $2, $3, $4) 
 		RETURNING id
 	`
 
-	err := r.db.QueryRow(ctx, query, userID, number, model.OrderStatusNew).Scan(&id)
+	err = tx.QueryRow(ctx, query, tenant, userID, number, model.OrderStatusNew).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("ошибка создания заказа: %w", err)
 	}
 
+	historyQuery := `
+		INSERT INTO order_status_history (order_id, status) 
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(ctx, historyQuery, id, model.OrderStatusNew); err != nil {
+		return 0, fmt.Errorf("ошибка записи истории статусов заказа: %w", err)
+	}
+
+	// Уведомление доставляется подписчикам только после фиксации транзакции.
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", NewOrdersChannel, NewOrderPayload(tenant, number)); err != nil {
+		return 0, fmt.Errorf("ошибка отправки уведомления о новом заказе: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
 	return id, nil
 }
 
-func (r *OrderRepo) GetOrderByNumber(ctx context.Context, number string) (*model.Order, error) {
+// CreateOrders регистрирует пакет заказов одним запросом. Уже существующие номера
+// пропускаются через ON CONFLICT и сопоставляются с владельцем. Номера должны быть уникальны.
+func (r *OrderRepo) CreateOrders(ctx context.Context, tenant string, userID int64, numbers []string) ([]model.BatchOrderResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Подзапрос к orders видит снимок до вставки, поэтому владелец
+	// находится только у номеров, зарегистрированных ранее.
+	query := `
+		WITH input AS (
+			SELECT number, ord FROM unnest($3::text[]) WITH ORDINALITY AS t(number, ord)
+		), inserted AS (
+			INSERT INTO orders (tenant, user_id, number, status) 
+			SELECT $1, $2, number, $4 FROM input 
+			ON CONFLICT (tenant, number) DO NOTHING 
+			RETURNING id, number, status
+		), history AS (
+			INSERT INTO order_status_history (order_id, status) 
+			SELECT id, status FROM inserted
+		)
+		SELECT i.number, ins.number IS NOT NULL, o.user_id 
+		FROM input i 
+		LEFT JOIN inserted ins ON ins.number = i.number 
+		LEFT JOIN orders o ON o.tenant = $1 AND o.number = i.number 
+		ORDER BY i.ord
+	`
+
+	rows, err := tx.Query(ctx, query, tenant, userID, numbers, model.OrderStatusNew)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка пакетного создания заказов: %w", err)
+	}
+
+	results := make([]model.BatchOrderResult, 0, len(numbers))
+	var accepted []string
+	for rows.Next() {
+		var (
+			number   string
+			inserted bool
+			ownerID  *int64
+		)
+		if err := rows.Scan(&number, &inserted, &ownerID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования результата пакета: %w", err)
+		}
+
+		result := model.BatchOrderResult{Number: number}
+		switch {
+		case inserted:
+			result.Result = model.BatchOrderAccepted
+			accepted = append(accepted, number)
+		case ownerID != nil && *ownerID == userID:
+			result.Result = model.BatchOrderAlreadyYours
+		default:
+			result.Result = model.BatchOrderConflict
+		}
+		results = append(results, result)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по результатам пакета: %w", err)
+	}
+
+	if len(accepted) > 0 {
+		notifyQuery := `SELECT pg_notify($1, $2 || ':' || number) FROM unnest($3::text[]) AS number`
+		if _, err := tx.Exec(ctx, notifyQuery, NewOrdersChannel, tenant, accepted); err != nil {
+			return nil, fmt.Errorf("ошибка отправки уведомлений о новых заказах: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return results, nil
+}
+
+func (r *OrderRepo) GetOrderByNumber(ctx context.Context, tenant, number string) (*model.Order, error) {
 	var order model.Order
 	query := `
-		SELECT id, user_id, number, status, accrual, uploaded_at 
+		SELECT id, tenant, user_id, number, status, accrual, uploaded_at, last_checked_at 
 		FROM orders 
-		WHERE number = $1
+		WHERE tenant = $1 AND number = $2
 	`
 
-	err := r.db.QueryRow(ctx, query, number).Scan(
+	err := r.db.QueryRow(ctx, query, tenant, number).Scan(
 		&order.ID,
+		&order.Tenant,
 		&order.UserID,
 		&order.Number,
 		&order.Status,
 		&order.Accrual,
 		&order.UploadedAt,
+		&order.LastCheckedAt,
 	)
 
 	if err != nil {
@@ -63,7 +170,7 @@ func (r *OrderRepo) GetOrderByNumber(ctx context.Context, number string) (*model
 
 func (r *OrderRepo) GetOrdersByUserID(ctx context.Context, userID int64) ([]*model.Order, error) {
 	query := `
-		SELECT id, user_id, number, status, accrual, uploaded_at 
+		SELECT id, tenant, user_id, number, status, accrual, uploaded_at 
 		FROM orders 
 		WHERE user_id = $1 
 		ORDER BY uploaded_at DESC
@@ -80,6 +187,7 @@ func (r *OrderRepo) GetOrdersByUserID(ctx context.Context, userID int64) ([]*mod
 		var order model.Order
 		if err := rows.Scan(
 			&order.ID,
+			&order.Tenant,
 			&order.UserID,
 			&order.Number,
 			&order.Status,
@@ -99,45 +207,215 @@ func (r *OrderRepo) GetOrdersByUserID(ctx context.Context, userID int64) ([]*mod
 }
 
 func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, orderID int64, status model.OrderStatus) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		tenant  string
+		payload model.WebhookOrderPayload
+	)
 	query := `
 		UPDATE orders 
 		SET status = $1 
-		WHERE id = $2
+		WHERE id = $2 
+		RETURNING tenant, user_id, number, status
 	`
 
-	_, err := r.db.Exec(ctx, query, status, orderID)
+	err = tx.QueryRow(ctx, query, status, orderID).Scan(&tenant, &payload.UserID, &payload.Order, &payload.Status)
 	if err != nil {
 		return fmt.Errorf("ошибка обновления статуса заказа: %w", err)
 	}
 
+	if status == model.OrderStatusInvalid {
+		if err := insertOutbox(ctx, tx, tenant, model.WebhookEventOrderInvalid, payload); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
 	return nil
 }
 
 func (r *OrderRepo) UpdateOrderAccrual(ctx context.Context, orderID int64, accrual float64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := markOrderProcessed(ctx, tx, orderID, accrual); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessOrderAccrual в одной транзакции переводит заказ в PROCESSED, зачисляет начисление
+// вместе с надбавкой уровня лояльности на баланс владельца, ставит событие в очередь вебхуков
+// и задачу начисления бонусов за заказ в очередь бонусов. Задача достается вызывающему на lease,
+// а если он не завершит ее через CompleteOrderBonuses, ее подхватит ClaimOrderBonuses.
+// Сбой на любом шаге не оставляет заказ обработанным без зачисления. Для заказа, уже получившего
+// конечный статус, возвращает ErrOrderProcessed, и повторный ответ системы расчета ничего не зачисляет.
+func (r *OrderRepo) ProcessOrderAccrual(ctx context.Context, orderID int64, accrual float64, bonus *model.TierBonus, lease time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	userID, err := markOrderProcessed(ctx, tx, orderID, accrual)
+	if err != nil {
+		return err
+	}
+
+	if err := creditAccrual(ctx, tx, userID, orderID, accrual, bonus); err != nil {
+		return err
+	}
+
+	queueQuery := `
+		INSERT INTO order_bonus_queue (order_id, accrual, next_attempt_at) 
+		VALUES ($1, $2, NOW() + make_interval(secs => $3))
+	`
+	if _, err := tx.Exec(ctx, queueQuery, orderID, accrual, lease.Seconds()); err != nil {
+		return fmt.Errorf("ошибка постановки бонусов за заказ в очередь: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// markOrderProcessed записывает начисление и статус PROCESSED заказа и добавляет событие
+// в очередь вебхуков в рамках транзакции tx. Возвращает идентификатор владельца заказа или
+// ErrOrderProcessed, если заказ уже не ожидает проверки.
+func markOrderProcessed(ctx context.Context, tx pgx.Tx, orderID int64, accrual float64) (int64, error) {
+	var (
+		tenant  string
+		payload model.WebhookOrderPayload
+	)
 	query := `
 		UPDATE orders 
 		SET accrual = $1, status = $2 
-		WHERE id = $3
+		WHERE id = $3 AND status IN ($4, $5) 
+		RETURNING tenant, user_id, number, status, accrual
+	`
+
+	err := tx.QueryRow(ctx, query, accrual, model.OrderStatusProcessed, orderID, model.OrderStatusNew, model.OrderStatusProcessing).Scan(
+		&tenant,
+		&payload.UserID,
+		&payload.Order,
+		&payload.Status,
+		&payload.Accrual,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("%w", customerrors.ErrOrderProcessed)
+		}
+		return 0, fmt.Errorf("ошибка обновления начисления заказа: %w", err)
+	}
+
+	if err := insertOutbox(ctx, tx, tenant, model.WebhookEventOrderProcessed, payload); err != nil {
+		return 0, err
+	}
+
+	return payload.UserID, nil
+}
+
+// ClaimOrderBonuses захватывает задачи начисления бонусов, время попытки которых наступило,
+// откладывая их следующую попытку на время аренды, и увеличивает счетчик попыток.
+func (r *OrderRepo) ClaimOrderBonuses(ctx context.Context, limit int, lease time.Duration) ([]*model.OrderBonusTask, error) {
+	query := `
+		UPDATE order_bonus_queue q 
+		SET attempts = q.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2) 
+		FROM orders o 
+		WHERE q.order_id IN (
+			SELECT order_id 
+			FROM order_bonus_queue 
+			WHERE next_attempt_at <= NOW() 
+			ORDER BY next_attempt_at 
+			LIMIT $1 
+			FOR UPDATE SKIP LOCKED
+		) 
+			AND o.id = q.order_id 
+		RETURNING o.id, o.tenant, o.user_id, o.number, o.status, o.accrual, o.uploaded_at, q.accrual, q.attempts
 	`
 
-	_, err := r.db.Exec(ctx, query, accrual, model.OrderStatusProcessed, orderID)
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds())
 	if err != nil {
-		return fmt.Errorf("ошибка обновления начисления заказа: %w", err)
+		return nil, fmt.Errorf("ошибка захвата бонусов за заказы: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*model.OrderBonusTask
+	for rows.Next() {
+		var task model.OrderBonusTask
+		if err := rows.Scan(
+			&task.Order.ID,
+			&task.Order.Tenant,
+			&task.Order.UserID,
+			&task.Order.Number,
+			&task.Order.Status,
+			&task.Order.Accrual,
+			&task.Order.UploadedAt,
+			&task.Accrual,
+			&task.Attempts,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования бонусов за заказ: %w", err)
+		}
+		tasks = append(tasks, &task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по бонусам за заказы: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// CompleteOrderBonuses удаляет из очереди выполненную задачу начисления бонусов за заказ.
+func (r *OrderRepo) CompleteOrderBonuses(ctx context.Context, orderID int64) error {
+	if _, err := r.db.Exec(ctx, "DELETE FROM order_bonus_queue WHERE order_id = $1", orderID); err != nil {
+		return fmt.Errorf("ошибка удаления бонусов за заказ из очереди: %w", err)
 	}
 
 	return nil
 }
 
-func (r *OrderRepo) GetNewOrProcessingOrders(ctx context.Context) ([]*model.Order, error) {
+// ClaimOrdersForCheck захватывает до limit заказов, ожидающих проверки. Первыми берутся
+// заказы, которые еще не проверялись или проверялись раньше остальных, чтобы давно
+// загруженные заказы не вытесняли новые.
+func (r *OrderRepo) ClaimOrdersForCheck(ctx context.Context, workerID string, limit int, lease time.Duration) ([]*model.Order, error) {
 	query := `
-		SELECT id, user_id, number, status, accrual, uploaded_at 
-		FROM orders 
-		WHERE status = $1 OR status = $2
+		UPDATE orders 
+		SET claimed_by = $1, claimed_until = NOW() + make_interval(secs => $2) 
+		WHERE id IN (
+			SELECT id 
+			FROM orders 
+			WHERE (status = $3 OR status = $4) 
+				AND (claimed_until IS NULL OR claimed_until < NOW()) 
+				AND (next_check_at IS NULL OR next_check_at <= NOW()) 
+			ORDER BY last_checked_at NULLS FIRST, uploaded_at 
+			LIMIT $5 
+			FOR UPDATE SKIP LOCKED
+		) 
+		RETURNING id, tenant, user_id, number, status, accrual, uploaded_at
 	`
 
-	rows, err := r.db.Query(ctx, query, model.OrderStatusNew, model.OrderStatusProcessing)
+	rows, err := r.db.Query(ctx, query, workerID, lease.Seconds(), model.OrderStatusNew, model.OrderStatusProcessing, limit)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения заказов для проверки: %w", err)
+		return nil, fmt.Errorf("ошибка захвата заказов для проверки: %w", err)
 	}
 	defer rows.Close()
 
@@ -146,6 +424,7 @@ func (r *OrderRepo) GetNewOrProcessingOrders(ctx context.Context) ([]*model.Orde
 		var order model.Order
 		if err := rows.Scan(
 			&order.ID,
+			&order.Tenant,
 			&order.UserID,
 			&order.Number,
 			&order.Status,
@@ -163,3 +442,258 @@ func (r *OrderRepo) GetNewOrProcessingOrders(ctx context.Context) ([]*model.Orde
 
 	return orders, nil
 }
+
+func (r *OrderRepo) ClaimOrderByNumber(ctx context.Context, workerID string, tenant, number string, lease time.Duration) (*model.Order, error) {
+	var order model.Order
+	query := `
+		UPDATE orders 
+		SET claimed_by = $1, claimed_until = NOW() + make_interval(secs => $2) 
+		WHERE id = (
+			SELECT id 
+			FROM orders 
+			WHERE tenant = $3 AND number = $4 
+				AND (status = $5 OR status = $6) 
+				AND (claimed_until IS NULL OR claimed_until < NOW()) 
+			FOR UPDATE SKIP LOCKED
+		) 
+		RETURNING id, tenant, user_id, number, status, accrual, uploaded_at
+	`
+
+	err := r.db.QueryRow(ctx, query, workerID, lease.Seconds(), tenant, number, model.OrderStatusNew, model.OrderStatusProcessing).Scan(
+		&order.ID,
+		&order.Tenant,
+		&order.UserID,
+		&order.Number,
+		&order.Status,
+		&order.Accrual,
+		&order.UploadedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка захвата заказа для проверки: %w", err)
+	}
+
+	return &order, nil
+}
+
+// ExtendOrderClaim продлевает захват заказа обработчиком workerID на lease от текущего момента.
+// Возвращает false, если заказ уже не захвачен этим обработчиком.
+func (r *OrderRepo) ExtendOrderClaim(ctx context.Context, orderID int64, workerID string, lease time.Duration) (bool, error) {
+	query := `
+		UPDATE orders 
+		SET claimed_until = NOW() + make_interval(secs => $3) 
+		WHERE id = $1 AND claimed_by = $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, orderID, workerID, lease.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("ошибка продления захвата заказа: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *OrderRepo) ReleaseOrder(ctx context.Context, orderID int64, workerID string) error {
+	query := `
+		UPDATE orders 
+		SET claimed_by = NULL, claimed_until = NULL 
+		WHERE id = $1 AND claimed_by = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, orderID, workerID)
+	if err != nil {
+		return fmt.Errorf("ошибка освобождения заказа: %w", err)
+	}
+
+	return nil
+}
+
+// RequeueOrder возвращает заказ в статус NEW, снимает захват и уведомляет обработчики
+// о необходимости немедленной проверки. Обработанные заказы повторно не проверяются,
+// чтобы исключить повторное начисление.
+func (r *OrderRepo) RequeueOrder(ctx context.Context, tenant, number string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status model.OrderStatus
+	statusQuery := `
+		SELECT status 
+		FROM orders 
+		WHERE tenant = $1 AND number = $2 
+		FOR UPDATE
+	`
+
+	if err := tx.QueryRow(ctx, statusQuery, tenant, number).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w", customerrors.ErrOrderNotFound)
+		}
+		return fmt.Errorf("ошибка получения заказа: %w", err)
+	}
+
+	if status == model.OrderStatusProcessed {
+		return fmt.Errorf("%w", customerrors.ErrOrderNotRequeueable)
+	}
+
+	requeueQuery := `
+		UPDATE orders 
+		SET status = $1, claimed_by = NULL, claimed_until = NULL, 
+			unregistered_since = NULL, unregistered_checks = 0, next_check_at = NULL 
+		WHERE tenant = $2 AND number = $3 
+		RETURNING id
+	`
+
+	var orderID int64
+	if err := tx.QueryRow(ctx, requeueQuery, model.OrderStatusNew, tenant, number).Scan(&orderID); err != nil {
+		return fmt.Errorf("ошибка повторной постановки заказа: %w", err)
+	}
+
+	historyQuery := `
+		INSERT INTO order_status_history (order_id, status) 
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(ctx, historyQuery, orderID, model.OrderStatusNew); err != nil {
+		return fmt.Errorf("ошибка записи истории статусов заказа: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", NewOrdersChannel, NewOrderPayload(tenant, number)); err != nil {
+		return fmt.Errorf("ошибка отправки уведомления о заказе: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// RecordOrderCheck отмечает время последней проверки заказа и добавляет запись в историю,
+// если текущий статус заказа или исходный статус системы расчета отличаются от последней записи.
+// Любой ответ, кроме NOT_REGISTERED, сбрасывает отсчет времени незарегистрированного заказа.
+func (r *OrderRepo) RecordOrderCheck(ctx context.Context, orderID int64, accrualStatus model.AccrualSystemStatus) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	checkedQuery := `
+		UPDATE orders 
+		SET last_checked_at = NOW(), 
+			unregistered_since = CASE WHEN $2 THEN unregistered_since END, 
+			unregistered_checks = CASE WHEN $2 THEN unregistered_checks ELSE 0 END, 
+			next_check_at = CASE WHEN $2 THEN next_check_at END 
+		WHERE id = $1
+	`
+
+	notRegistered := accrualStatus == model.AccrualStatusNotRegistered
+	if _, err := tx.Exec(ctx, checkedQuery, orderID, notRegistered); err != nil {
+		return fmt.Errorf("ошибка отметки проверки заказа: %w", err)
+	}
+
+	historyQuery := `
+		INSERT INTO order_status_history (order_id, status, accrual_status, accrual) 
+		SELECT o.id, o.status, $2, o.accrual 
+		FROM orders o 
+		WHERE o.id = $1 
+			AND NOT EXISTS (
+				SELECT 1 
+				FROM (
+					SELECT status, accrual_status 
+					FROM order_status_history 
+					WHERE order_id = $1 
+					ORDER BY id DESC 
+					LIMIT 1
+				) last 
+				WHERE last.status = o.status AND last.accrual_status = $2
+			)
+	`
+
+	if _, err := tx.Exec(ctx, historyQuery, orderID, accrualStatus); err != nil {
+		return fmt.Errorf("ошибка записи истории статусов заказа: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// MarkOrderUnregistered фиксирует очередной ответ 204 системы расчета и возвращает время,
+// с которого заказ считается незарегистрированным, и число таких ответов подряд.
+func (r *OrderRepo) MarkOrderUnregistered(ctx context.Context, orderID int64) (time.Time, int, error) {
+	var (
+		since  time.Time
+		checks int
+	)
+	query := `
+		UPDATE orders 
+		SET unregistered_since = COALESCE(unregistered_since, NOW()), 
+			unregistered_checks = unregistered_checks + 1 
+		WHERE id = $1 
+		RETURNING unregistered_since, unregistered_checks
+	`
+
+	if err := r.db.QueryRow(ctx, query, orderID).Scan(&since, &checks); err != nil {
+		return time.Time{}, 0, fmt.Errorf("ошибка отметки незарегистрированного заказа: %w", err)
+	}
+
+	return since, checks, nil
+}
+
+// DeferOrderCheck откладывает следующую периодическую проверку заказа до момента at.
+func (r *OrderRepo) DeferOrderCheck(ctx context.Context, orderID int64, at time.Time) error {
+	query := `
+		UPDATE orders 
+		SET next_check_at = $1 
+		WHERE id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, at, orderID); err != nil {
+		return fmt.Errorf("ошибка переноса проверки заказа: %w", err)
+	}
+
+	return nil
+}
+
+func (r *OrderRepo) GetOrderHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusChange, error) {
+	query := `
+		SELECT status, accrual_status, accrual, created_at 
+		FROM order_status_history 
+		WHERE order_id = $1 
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории статусов заказа: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*model.OrderStatusChange
+	for rows.Next() {
+		var change model.OrderStatusChange
+		if err := rows.Scan(
+			&change.Status,
+			&change.AccrualStatus,
+			&change.Accrual,
+			&change.ChangedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки истории статусов: %w", err)
+		}
+		history = append(history, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по истории статусов: %w", err)
+	}
+
+	return history, nil
+}
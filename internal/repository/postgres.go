@@ -11,6 +11,8 @@ import (
 const (
 	defaultConnTimeout = 5 * time.Second
 	defaultMaxPoolSize = 10
+
+	schemaLockKey = 7301001
 )
 
 func NewPostgresDB(databaseURI string) (*pgxpool.Pool, error) {
@@ -76,26 +78,471 @@ func initDB(ctx context.Context, pool *pgxpool.Pool) error {
 		processed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 	);`
 
+	addOrdersClaimColumns := `
+	ALTER TABLE orders
+		ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255),
+		ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP WITH TIME ZONE;`
+
+	createOrdersStatusIndex := `
+	CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status);`
+
+	createUserEventsTable := `
+	CREATE TABLE IF NOT EXISTS user_events (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id),
+		type VARCHAR(50) NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS user_events_user_id_idx ON user_events (user_id, id);`
+
+	createWebhookTables := `
+	CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id SERIAL PRIMARY KEY,
+		tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+		url TEXT NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		event_types TEXT[] NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS webhook_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+		event_type VARCHAR(50) NOT NULL,
+		payload JSONB NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		dispatched_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS webhook_outbox_pending_idx ON webhook_outbox (id) WHERE dispatched_at IS NULL;
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		outbox_id BIGINT NOT NULL REFERENCES webhook_outbox(id),
+		subscription_id INT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		last_error TEXT NOT NULL DEFAULT '',
+		delivered_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';`
+
+	createBalanceAdjustmentsTable := `
+	CREATE TABLE IF NOT EXISTS balance_adjustments (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id),
+		amount FLOAT NOT NULL,
+		reason TEXT NOT NULL,
+		actor VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);`
+
+	createUserRolesTables := `
+	CREATE TABLE IF NOT EXISTS user_roles (
+		user_id INT NOT NULL REFERENCES users(id),
+		role VARCHAR(32) NOT NULL,
+		PRIMARY KEY (user_id, role)
+	);
+	CREATE TABLE IF NOT EXISTS user_role_changes (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id),
+		role VARCHAR(32) NOT NULL,
+		action VARCHAR(10) NOT NULL,
+		actor VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	INSERT INTO user_roles (user_id, role)
+	SELECT u.id, 'customer' FROM users u
+	WHERE NOT EXISTS (SELECT 1 FROM user_roles r WHERE r.user_id = u.id);`
+
+	createAuditEventsTable := `
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id BIGSERIAL PRIMARY KEY,
+		actor VARCHAR(255) NOT NULL,
+		action VARCHAR(64) NOT NULL,
+		target VARCHAR(255) NOT NULL DEFAULT '',
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		request_id VARCHAR(128) NOT NULL DEFAULT '',
+		before JSONB,
+		after JSONB,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor, id);
+	CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+	CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_events допускает только добавление записей';
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+	CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+		FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();`
+
+	// Счетчики ограничений запросов не нужно восстанавливать после сбоя,
+	// поэтому таблица не журналируется ради скорости записи.
+	createRateLimitsTable := `
+	CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+		key VARCHAR(255) PRIMARY KEY,
+		window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+		hits INT NOT NULL
+	);`
+
+	createOrderStatusHistoryTable := `
+	ALTER TABLE orders
+		ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP WITH TIME ZONE;
+	CREATE TABLE IF NOT EXISTS order_status_history (
+		id BIGSERIAL PRIMARY KEY,
+		order_id INT NOT NULL REFERENCES orders(id),
+		status VARCHAR(50) NOT NULL,
+		accrual_status VARCHAR(50) NOT NULL DEFAULT '',
+		accrual FLOAT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id, id);`
+
+	addOrdersUnregisteredColumns := `
+	ALTER TABLE orders
+		ADD COLUMN IF NOT EXISTS unregistered_since TIMESTAMP WITH TIME ZONE,
+		ADD COLUMN IF NOT EXISTS unregistered_checks INT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS next_check_at TIMESTAMP WITH TIME ZONE;`
+
+	// Логины и номера заказов уникальны в пределах арендатора, поэтому глобальные
+	// ограничения уникальности заменяются составными.
+	addTenantColumns := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT 'default';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT 'default';
+	ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT 'default';
+	DO $$
+	DECLARE
+		c RECORD;
+	BEGIN
+		FOR c IN
+			SELECT con.conrelid::regclass AS tbl, con.conname
+			FROM pg_constraint con
+			JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = con.conkey[1]
+			WHERE con.contype = 'u' AND array_length(con.conkey, 1) = 1
+				AND ((con.conrelid = 'users'::regclass AND a.attname = 'login')
+					OR (con.conrelid = 'orders'::regclass AND a.attname = 'number'))
+		LOOP
+			EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+		END LOOP;
+	END;
+	$$;
+	CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_login_idx ON users (tenant, login);
+	CREATE UNIQUE INDEX IF NOT EXISTS orders_tenant_number_idx ON orders (tenant, number);`
+
+	// Каждый перевод отражается в журнале движения баллов двумя записями: списанием
+	// у отправителя и зачислением получателю.
+	createBalanceTransfersTables := `
+	CREATE TABLE IF NOT EXISTS balance_transfers (
+		id BIGSERIAL PRIMARY KEY,
+		tenant VARCHAR(64) NOT NULL,
+		sender_id INT NOT NULL REFERENCES users(id),
+		recipient_id INT NOT NULL REFERENCES users(id),
+		amount FLOAT NOT NULL CHECK (amount > 0),
+		idempotency_key VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		UNIQUE (sender_id, idempotency_key)
+	);
+	CREATE INDEX IF NOT EXISTS balance_transfers_sender_created_idx ON balance_transfers (sender_id, created_at);
+	CREATE TABLE IF NOT EXISTS balance_ledger (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id),
+		amount FLOAT NOT NULL,
+		operation VARCHAR(32) NOT NULL,
+		transfer_id BIGINT REFERENCES balance_transfers(id),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS balance_ledger_user_id_idx ON balance_ledger (user_id, id);`
+
+	// Зарезервированные баллы переносятся из current в reserved и возвращаются
+	// обратно при отмене или истечении срока резерва.
+	createBalanceReservationsTable := `
+	ALTER TABLE balances ADD COLUMN IF NOT EXISTS reserved FLOAT NOT NULL DEFAULT 0;
+	CREATE TABLE IF NOT EXISTS balance_reservations (
+		id BIGSERIAL PRIMARY KEY,
+		tenant VARCHAR(64) NOT NULL,
+		user_id INT NOT NULL REFERENCES users(id),
+		order_number VARCHAR(255) NOT NULL,
+		amount FLOAT NOT NULL CHECK (amount > 0),
+		status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE',
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		resolved_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS balance_reservations_user_id_idx ON balance_reservations (user_id, id);
+	CREATE INDEX IF NOT EXISTS balance_reservations_active_idx ON balance_reservations (expires_at)
+		WHERE status = 'ACTIVE';`
+
+	// Начисления по заказам и надбавки уровней лояльности записываются в журнал движения баллов,
+	// по нему пересчитываются уровни. Начисления, выполненные до появления журнала, переносятся из заказов.
+	createLoyaltyTiersTables := `
+	ALTER TABLE balance_ledger ADD COLUMN IF NOT EXISTS order_id INT REFERENCES orders(id);
+	CREATE INDEX IF NOT EXISTS balance_ledger_operation_idx ON balance_ledger (operation, created_at);
+	INSERT INTO balance_ledger (user_id, amount, operation, order_id, created_at)
+	SELECT o.user_id, o.accrual, 'accrual', o.id, COALESCE(
+		(SELECT MIN(h.created_at) FROM order_status_history h WHERE h.order_id = o.id AND h.status = 'PROCESSED'),
+		o.uploaded_at
+	)
+	FROM orders o
+	WHERE o.status = 'PROCESSED' AND o.accrual > 0
+		AND NOT EXISTS (SELECT 1 FROM balance_ledger l WHERE l.order_id = o.id AND l.operation = 'accrual');
+	CREATE TABLE IF NOT EXISTS user_tiers (
+		user_id INT PRIMARY KEY REFERENCES users(id),
+		tier VARCHAR(64) NOT NULL,
+		points FLOAT NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS user_tier_history (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id),
+		tier_from VARCHAR(64) NOT NULL DEFAULT '',
+		tier_to VARCHAR(64) NOT NULL,
+		points FLOAT NOT NULL DEFAULT 0,
+		changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS user_tier_history_user_id_idx ON user_tier_history (user_id, id);`
+
+	// Бонус кампании за одно и то же событие начисляется один раз: повторная обработка
+	// заказа или регистрации не проходит ограничение уникальности.
+	createCampaignsTables := `
+	CREATE TABLE IF NOT EXISTS campaigns (
+		id SERIAL PRIMARY KEY,
+		tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+		name VARCHAR(255) NOT NULL,
+		type VARCHAR(32) NOT NULL,
+		amount FLOAT NOT NULL DEFAULT 0,
+		multiplier FLOAT NOT NULL DEFAULT 0,
+		order_index INT NOT NULL DEFAULT 0,
+		starts_at TIMESTAMP WITH TIME ZONE,
+		ends_at TIMESTAMP WITH TIME ZONE,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS campaigns_tenant_type_idx ON campaigns (tenant, type) WHERE active;
+	CREATE TABLE IF NOT EXISTS campaign_bonuses (
+		id BIGSERIAL PRIMARY KEY,
+		campaign_id INT REFERENCES campaigns(id) ON DELETE SET NULL,
+		campaign_name VARCHAR(255) NOT NULL,
+		user_id INT NOT NULL REFERENCES users(id),
+		order_id INT REFERENCES orders(id),
+		reference VARCHAR(64) NOT NULL,
+		amount FLOAT NOT NULL CHECK (amount > 0),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		UNIQUE (campaign_id, reference)
+	);
+	CREATE INDEX IF NOT EXISTS campaign_bonuses_user_id_idx ON campaign_bonuses (user_id, id);
+	ALTER TABLE balance_ledger ADD COLUMN IF NOT EXISTS campaign_bonus_id BIGINT REFERENCES campaign_bonuses(id);`
+
+	createReferralsTables := `
+	CREATE TABLE IF NOT EXISTS referral_codes (
+		user_id INT PRIMARY KEY REFERENCES users(id),
+		tenant VARCHAR(64) NOT NULL DEFAULT 'default',
+		code VARCHAR(32) NOT NULL,
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		UNIQUE (tenant, code)
+	);
+	CREATE TABLE IF NOT EXISTS referrals (
+		id BIGSERIAL PRIMARY KEY,
+		referrer_id INT NOT NULL REFERENCES users(id),
+		referee_id INT NOT NULL UNIQUE REFERENCES users(id),
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+		reject_reason VARCHAR(32) NOT NULL DEFAULT '',
+		referrer_bonus FLOAT NOT NULL DEFAULT 0,
+		referee_bonus FLOAT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		rewarded_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE INDEX IF NOT EXISTS referrals_referrer_id_idx ON referrals (referrer_id, id);
+	CREATE INDEX IF NOT EXISTS referrals_ip_idx ON referrals (ip, created_at);
+	ALTER TABLE balance_ledger ADD COLUMN IF NOT EXISTS referral_id BIGINT REFERENCES referrals(id);`
+
+	addUsersDeletedAt := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;`
+
+	// Суточные финансовые отчеты рассчитываются фоновой задачей; индексы ускоряют выборку
+	// событий за сутки из истории статусов заказов, списаний и регистраций.
+	createFinanceReportsTable := `
+	CREATE TABLE IF NOT EXISTS finance_daily_reports (
+		tenant VARCHAR(64) NOT NULL,
+		day DATE NOT NULL,
+		accrued FLOAT NOT NULL DEFAULT 0,
+		withdrawn FLOAT NOT NULL DEFAULT 0,
+		new_users INT NOT NULL DEFAULT 0,
+		orders_processed INT NOT NULL DEFAULT 0,
+		orders_invalid INT NOT NULL DEFAULT 0,
+		orders_unregistered INT NOT NULL DEFAULT 0,
+		liability FLOAT,
+		computed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		PRIMARY KEY (tenant, day)
+	);
+	CREATE INDEX IF NOT EXISTS order_status_history_status_created_idx ON order_status_history (status, created_at);
+	CREATE INDEX IF NOT EXISTS withdrawals_processed_at_idx ON withdrawals (processed_at);
+	CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at);`
+
+	// Сверка сохраняет итоги каждого запуска и найденные расхождения. Корректировки начислений
+	// по заказам ссылаются на заказ, чтобы сверка журнала с заказами учитывала их.
+	createReconciliationTables := `
+	CREATE TABLE IF NOT EXISTS reconciliation_runs (
+		id BIGSERIAL PRIMARY KEY,
+		auto_fix BOOLEAN NOT NULL DEFAULT FALSE,
+		orders_checked INT NOT NULL DEFAULT 0,
+		orders_failed INT NOT NULL DEFAULT 0,
+		balances_checked INT NOT NULL DEFAULT 0,
+		discrepancies INT NOT NULL DEFAULT 0,
+		corrected INT NOT NULL DEFAULT 0,
+		started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		finished_at TIMESTAMP WITH TIME ZONE
+	);
+	CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+		id BIGSERIAL PRIMARY KEY,
+		run_id BIGINT NOT NULL REFERENCES reconciliation_runs(id),
+		kind VARCHAR(32) NOT NULL,
+		tenant VARCHAR(64) NOT NULL,
+		user_id INT NOT NULL REFERENCES users(id),
+		order_id INT REFERENCES orders(id),
+		order_number VARCHAR(255) NOT NULL DEFAULT '',
+		expected FLOAT NOT NULL,
+		actual FLOAT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		corrected BOOLEAN NOT NULL DEFAULT FALSE,
+		adjustment_id INT REFERENCES balance_adjustments(id),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS reconciliation_discrepancies_run_id_idx ON reconciliation_discrepancies (run_id, id);
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS order_id INT REFERENCES orders(id);
+	CREATE INDEX IF NOT EXISTS balance_adjustments_user_id_idx ON balance_adjustments (user_id);
+	CREATE INDEX IF NOT EXISTS withdrawals_user_id_idx ON withdrawals (user_id);
+	CREATE INDEX IF NOT EXISTS balance_ledger_order_id_idx ON balance_ledger (order_id) WHERE order_id IS NOT NULL;`
+
+	addRateLimitsExpiresAt := `
+	ALTER TABLE rate_limits
+		ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+	CREATE INDEX IF NOT EXISTS rate_limits_expires_at_idx ON rate_limits (expires_at);`
+
+	addBalanceAdjustmentsDrift := `
+	ALTER TABLE balance_adjustments
+		ADD COLUMN IF NOT EXISTS withdrawn FLOAT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS drift BOOLEAN NOT NULL DEFAULT FALSE;`
+
+	// Плановые задачи запускаются на каждом экземпляре сервиса, а выполняет их тот,
+	// кто первым отметил запуск.
+	createJobRunsTable := `
+	CREATE TABLE IF NOT EXISTS job_runs (
+		job VARCHAR(64) PRIMARY KEY,
+		started_at TIMESTAMP WITH TIME ZONE NOT NULL
+	);`
+
+	// Журнал аудита остается только для добавления, но при удалении учетной записи логин
+	// в событиях обезличивается: транзакция удаления включает gophermart.audit_redaction,
+	// и тогда разрешается изменить цель и данные события, не трогая автора, действие и время.
+	allowAuditRedaction := `
+	CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'UPDATE' AND current_setting('gophermart.audit_redaction', true) = 'on'
+			AND NEW.id = OLD.id AND NEW.actor = OLD.actor AND NEW.action = OLD.action
+			AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at THEN
+			RETURN NEW;
+		END IF;
+		RAISE EXCEPTION 'audit_events допускает только добавление записей';
+	END;
+	$$ LANGUAGE plpgsql;`
+
+	// События аудита относятся к арендатору, чтобы сотрудник, вошедший по JWT, видел
+	// только журнал своего арендатора. Уже записанные события при добавлении колонки
+	// переносятся к арендатору пользователя или кампании, к которым они относятся.
+	addAuditEventsTenant := `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 
+			FROM information_schema.columns 
+			WHERE table_name = 'audit_events' AND column_name = 'tenant'
+		) THEN
+			ALTER TABLE audit_events ADD COLUMN tenant VARCHAR(64) NOT NULL DEFAULT 'default';
+			PERFORM set_config('gophermart.audit_redaction', 'on', true);
+			UPDATE audit_events e 
+			SET tenant = u.tenant 
+			FROM users u 
+			WHERE u.tenant <> 'default' AND (e.actor = 'user:' || u.id OR e.target = 'user:' || u.id);
+			UPDATE audit_events e 
+			SET tenant = c.tenant 
+			FROM campaigns c 
+			WHERE c.tenant <> 'default' AND e.target = 'campaign:' || c.id;
+			PERFORM set_config('gophermart.audit_redaction', 'off', true);
+		END IF;
+	END;
+	$$;
+	CREATE INDEX IF NOT EXISTS audit_events_tenant_idx ON audit_events (tenant, id);`
+
+	// Бонусы кампаний и реферальной программы за обработанный заказ начисляются после
+	// зачисления начисления; очередь хранит задачу, пока начисление бонусов не выполнено.
+	createOrderBonusQueueTable := `
+	CREATE TABLE IF NOT EXISTS order_bonus_queue (
+		order_id INT PRIMARY KEY REFERENCES orders(id),
+		accrual FLOAT NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS order_bonus_queue_due_idx ON order_bonus_queue (next_attempt_at);`
+
+	migrations := []struct {
+		query  string
+		errMsg string
+	}{
+		{createUsersTable, "ошибка создания таблицы пользователей"},
+		{createOrdersTable, "ошибка создания таблицы заказов"},
+		{createBalanceTable, "ошибка создания таблицы баланса"},
+		{createWithdrawalsTable, "ошибка создания таблицы операций снятия"},
+		{addOrdersClaimColumns, "ошибка добавления колонок захвата заказов"},
+		{createOrdersStatusIndex, "ошибка создания индекса статусов заказов"},
+		{createUserEventsTable, "ошибка создания таблицы событий пользователей"},
+		{createWebhookTables, "ошибка создания таблиц вебхуков"},
+		{createBalanceAdjustmentsTable, "ошибка создания таблицы корректировок баланса"},
+		{createUserRolesTables, "ошибка создания таблиц ролей пользователей"},
+		{createAuditEventsTable, "ошибка создания журнала аудита"},
+		{createRateLimitsTable, "ошибка создания таблицы ограничений запросов"},
+		{createOrderStatusHistoryTable, "ошибка создания истории статусов заказов"},
+		{addOrdersUnregisteredColumns, "ошибка добавления колонок незарегистрированных заказов"},
+		{addTenantColumns, "ошибка добавления арендаторов"},
+		{createBalanceTransfersTables, "ошибка создания таблиц переводов баллов"},
+		{createBalanceReservationsTable, "ошибка создания таблицы резервов баллов"},
+		{createLoyaltyTiersTables, "ошибка создания таблиц уровней лояльности"},
+		{createCampaignsTables, "ошибка создания таблиц промо-кампаний"},
+		{createReferralsTables, "ошибка создания таблиц реферальной программы"},
+		{addUsersDeletedAt, "ошибка добавления отметки удаления пользователей"},
+		{createFinanceReportsTable, "ошибка создания таблицы финансовых отчетов"},
+		{createReconciliationTables, "ошибка создания таблиц сверки"},
+		{addRateLimitsExpiresAt, "ошибка добавления срока действия счетчиков запросов"},
+		{addBalanceAdjustmentsDrift, "ошибка добавления исправлений расхождений баланса"},
+		{allowAuditRedaction, "ошибка разрешения обезличивания журнала аудита"},
+		{createJobRunsTable, "ошибка создания таблицы запусков плановых задач"},
+		{addAuditEventsTenant, "ошибка добавления арендатора в журнал аудита"},
+		{createOrderBonusQueueTable, "ошибка создания очереди бонусов за заказы"},
+	}
+
 	tx, err := pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	if _, err := tx.Exec(ctx, createUsersTable); err != nil {
-		return fmt.Errorf("ошибка создания таблицы пользователей: %w", err)
-	}
-
-	if _, err := tx.Exec(ctx, createOrdersTable); err != nil {
-		return fmt.Errorf("ошибка создания таблицы заказов: %w", err)
-	}
-
-	if _, err := tx.Exec(ctx, createBalanceTable); err != nil {
-		return fmt.Errorf("ошибка создания таблицы баланса: %w", err)
+	// Несколько экземпляров сервиса могут стартовать одновременно,
+	// поэтому инициализация схемы выполняется под advisory-блокировкой.
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", schemaLockKey); err != nil {
+		return fmt.Errorf("ошибка получения блокировки схемы: %w", err)
 	}
 
-	if _, err := tx.Exec(ctx, createWithdrawalsTable); err != nil {
-		return fmt.Errorf("ошибка создания таблицы операций снятия: %w", err)
+	for _, m := range migrations {
+		if _, err := tx.Exec(ctx, m.query); err != nil {
+			return fmt.Errorf("%s: %w", m.errMsg, err)
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
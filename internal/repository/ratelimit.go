@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	memoryRateLimitSweepInterval = time.Minute
+	pgRateLimitSweepInterval     = time.Minute
+)
+
+// PgRateLimitStore хранит счетчики фиксированных окон в Postgres, поэтому
+// ограничения действуют согласованно для всех экземпляров сервиса.
+type PgRateLimitStore struct {
+	db *pgxpool.Pool
+
+	mutex     sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPgRateLimitStore(db *pgxpool.Pool) *PgRateLimitStore {
+	return &PgRateLimitStore{db: db, lastSweep: time.Now()}
+}
+
+func (s *PgRateLimitStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	expiresAt := windowStart.Add(window)
+
+	if err := s.sweep(ctx, now); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	query := `
+		INSERT INTO rate_limits (key, window_start, hits, expires_at) 
+		VALUES ($1, $2, 1, $3) 
+		ON CONFLICT (key) DO UPDATE 
+		SET hits = CASE WHEN rate_limits.window_start = EXCLUDED.window_start THEN rate_limits.hits + 1 ELSE 1 END, 
+			window_start = EXCLUDED.window_start, 
+			expires_at = EXCLUDED.expires_at 
+		RETURNING hits
+	`
+
+	var hits int
+	if err := s.db.QueryRow(ctx, query, key, windowStart, expiresAt).Scan(&hits); err != nil {
+		return 0, time.Time{}, fmt.Errorf("ошибка учета запроса: %w", err)
+	}
+
+	return hits, expiresAt, nil
+}
+
+// sweep не чаще раза в pgRateLimitSweepInterval удаляет счетчики закончившихся окон,
+// иначе таблица растет с каждым новым IP и пользователем.
+func (s *PgRateLimitStore) sweep(ctx context.Context, now time.Time) error {
+	s.mutex.Lock()
+	if now.Sub(s.lastSweep) < pgRateLimitSweepInterval {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.lastSweep = now
+	s.mutex.Unlock()
+
+	if _, err := s.db.Exec(ctx, "DELETE FROM rate_limits WHERE expires_at <= NOW()"); err != nil {
+		return fmt.Errorf("ошибка очистки счетчиков запросов: %w", err)
+	}
+
+	return nil
+}
+
+type memoryRateLimitEntry struct {
+	windowStart time.Time
+	expiresAt   time.Time
+	hits        int
+}
+
+// MemoryRateLimitStore хранит счетчики фиксированных окон в памяти процесса.
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries:   make(map[string]*memoryRateLimitEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now.Sub(s.lastSweep) >= memoryRateLimitSweepInterval {
+		for k, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	entry, ok := s.entries[key]
+	if !ok || !entry.windowStart.Equal(windowStart) {
+		entry = &memoryRateLimitEntry{
+			windowStart: windowStart,
+			expiresAt:   windowStart.Add(window),
+		}
+		s.entries[key] = entry
+	}
+	entry.hits++
+
+	return entry.hits, entry.expiresAt, nil
+}
@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	stderrors "errors"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// reconciliationLockKey ключ advisory-блокировки, под которой экземпляры сервиса решают, кто выполняет сверку.
+const reconciliationLockKey = 7301002
+
+// balanceExpectationQuery пересчитывает остаток и сумму списаний баланса по истории операций:
+// журналу движения баллов, списаниям, корректировкам и активным резервам. Исправления расхождений
+// в историю не входят: они только приводят к ней сохраненный баланс.
+const balanceExpectationQuery = `
+	SELECT u.tenant, b.user_id, b.current, b.withdrawn,
+		COALESCE((SELECT SUM(l.amount) FROM balance_ledger l WHERE l.user_id = b.user_id), 0)
+			- COALESCE((SELECT SUM(w.amount) FROM withdrawals w WHERE w.user_id = b.user_id), 0)
+			+ COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.user_id = b.user_id AND NOT a.drift), 0)
+			- COALESCE((SELECT SUM(r.amount) FROM balance_reservations r WHERE r.user_id = b.user_id AND r.status = 'ACTIVE'), 0)
+			AS expected_current,
+		COALESCE((SELECT SUM(w.amount) FROM withdrawals w WHERE w.user_id = b.user_id), 0) AS expected_withdrawn
+	FROM balances b
+	JOIN users u ON u.id = b.user_id
+`
+
+type ReconciliationRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReconciliationRepo(db *pgxpool.Pool) *ReconciliationRepo {
+	return &ReconciliationRepo{db: db}
+}
+
+// StartRun регистрирует запуск сверки. Если minInterval больше нуля и за это время уже был запуск,
+// возвращает nil: плановую сверку выполняет только один экземпляр сервиса.
+func (r *ReconciliationRepo) StartRun(ctx context.Context, autoFix bool, minInterval time.Duration) (*model.ReconciliationRun, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", reconciliationLockKey); err != nil {
+		return nil, fmt.Errorf("ошибка получения блокировки сверки: %w", err)
+	}
+
+	query := `
+		INSERT INTO reconciliation_runs (auto_fix)
+		SELECT $1
+		WHERE $2::float8 <= 0 OR NOT EXISTS (
+			SELECT 1 FROM reconciliation_runs WHERE started_at > NOW() - make_interval(secs => $2::float8)
+		)
+		RETURNING id, auto_fix, started_at
+	`
+
+	var run model.ReconciliationRun
+	if err := tx.QueryRow(ctx, query, autoFix, minInterval.Seconds()).Scan(&run.ID, &run.AutoFix, &run.StartedAt); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка регистрации запуска сверки: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return &run, nil
+}
+
+// FinishRun сохраняет итоги запуска сверки и время его окончания.
+func (r *ReconciliationRepo) FinishRun(ctx context.Context, run *model.ReconciliationRun) error {
+	query := `
+		UPDATE reconciliation_runs
+		SET orders_checked = $2, orders_failed = $3, balances_checked = $4, discrepancies = $5, corrected = $6, finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at
+	`
+
+	err := r.db.QueryRow(ctx, query, run.ID, run.OrdersChecked, run.OrdersFailed, run.BalancesChecked, run.Discrepancies, run.Corrected).
+		Scan(&run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения итогов сверки: %w", err)
+	}
+
+	return nil
+}
+
+// GetProcessedOrders возвращает случайную выборку из limit обработанных заказов, которые сейчас
+// не проверяются обработчиком. Нулевой limit означает все обработанные заказы.
+func (r *ReconciliationRepo) GetProcessedOrders(ctx context.Context, limit int) ([]*model.Order, error) {
+	query := `
+		SELECT id, tenant, user_id, number, status, accrual, uploaded_at
+		FROM orders
+		WHERE status = $1 AND (claimed_until IS NULL OR claimed_until < NOW())
+		ORDER BY random()
+		LIMIT NULLIF($2, 0)
+	`
+
+	rows, err := r.db.Query(ctx, query, model.OrderStatusProcessed, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заказов для сверки: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*model.Order
+	for rows.Next() {
+		var order model.Order
+		if err := rows.Scan(
+			&order.ID,
+			&order.Tenant,
+			&order.UserID,
+			&order.Number,
+			&order.Status,
+			&order.Accrual,
+			&order.UploadedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки заказа: %w", err)
+		}
+		orders = append(orders, &order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по заказам: %w", err)
+	}
+
+	return orders, nil
+}
+
+// FindOrderLedgerDiscrepancies находит обработанные заказы, начисление которых не совпадает с суммой
+// зачислений по ним в журнале движения баллов и корректировок сверки. Заказы, которые сейчас
+// проверяются обработчиком, пропускаются: начисление по ним может быть зачислено в этот момент.
+func (r *ReconciliationRepo) FindOrderLedgerDiscrepancies(ctx context.Context) ([]*model.ReconciliationDiscrepancy, error) {
+	query := `
+		SELECT o.tenant, o.user_id, o.id, o.number, o.accrual, c.credited
+		FROM orders o
+		CROSS JOIN LATERAL (
+			SELECT COALESCE((SELECT SUM(l.amount) FROM balance_ledger l WHERE l.order_id = o.id AND l.operation = $2), 0)
+				+ COALESCE((SELECT SUM(a.amount) FROM balance_adjustments a WHERE a.order_id = o.id), 0) AS credited
+		) c
+		WHERE o.status = $3 AND (o.claimed_until IS NULL OR o.claimed_until < NOW())
+			AND ABS(o.accrual - c.credited) >= $1
+		ORDER BY o.id
+	`
+
+	rows, err := r.db.Query(ctx, query, model.ReconciliationTolerance, model.LedgerAccrual, model.OrderStatusProcessed)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сверки заказов с журналом движения баллов: %w", err)
+	}
+	defer rows.Close()
+
+	var discrepancies []*model.ReconciliationDiscrepancy
+	for rows.Next() {
+		discrepancy := model.ReconciliationDiscrepancy{Kind: model.ReconciliationOrderLedger}
+		if err := rows.Scan(
+			&discrepancy.Tenant,
+			&discrepancy.UserID,
+			&discrepancy.OrderID,
+			&discrepancy.OrderNumber,
+			&discrepancy.Expected,
+			&discrepancy.Actual,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования расхождения заказа: %w", err)
+		}
+		discrepancies = append(discrepancies, &discrepancy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по расхождениям заказов: %w", err)
+	}
+
+	return discrepancies, nil
+}
+
+// FindBalanceDiscrepancies пересчитывает все балансы по истории операций и возвращает число
+// проверенных балансов и найденные расхождения остатка и суммы списаний.
+func (r *ReconciliationRepo) FindBalanceDiscrepancies(ctx context.Context) (int, []*model.ReconciliationDiscrepancy, error) {
+	var checked int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM balances").Scan(&checked); err != nil {
+		return 0, nil, fmt.Errorf("ошибка подсчета балансов: %w", err)
+	}
+
+	query := `
+		SELECT tenant, user_id, current, withdrawn, expected_current, expected_withdrawn
+		FROM (` + balanceExpectationQuery + `) AS s
+		WHERE ABS(current - expected_current) >= $1 OR ABS(withdrawn - expected_withdrawn) >= $1
+		ORDER BY user_id
+	`
+
+	rows, err := r.db.Query(ctx, query, model.ReconciliationTolerance)
+	if err != nil {
+		return 0, nil, fmt.Errorf("ошибка пересчета балансов: %w", err)
+	}
+	defer rows.Close()
+
+	var discrepancies []*model.ReconciliationDiscrepancy
+	for rows.Next() {
+		var (
+			tenant                                                 string
+			userID                                                 int64
+			current, withdrawn, expectedCurrent, expectedWithdrawn float64
+		)
+		if err := rows.Scan(&tenant, &userID, &current, &withdrawn, &expectedCurrent, &expectedWithdrawn); err != nil {
+			return 0, nil, fmt.Errorf("ошибка сканирования пересчитанного баланса: %w", err)
+		}
+
+		discrepancies = append(discrepancies, balanceDiscrepancies(tenant, userID, current, withdrawn, expectedCurrent, expectedWithdrawn)...)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("ошибка итерации по балансам: %w", err)
+	}
+
+	return checked, discrepancies, nil
+}
+
+// balanceDiscrepancies сравнивает сохраненные остаток и сумму списаний с пересчитанными.
+func balanceDiscrepancies(tenant string, userID int64, current, withdrawn, expectedCurrent, expectedWithdrawn float64) []*model.ReconciliationDiscrepancy {
+	var discrepancies []*model.ReconciliationDiscrepancy
+	if math.Abs(current-expectedCurrent) >= model.ReconciliationTolerance {
+		discrepancies = append(discrepancies, &model.ReconciliationDiscrepancy{
+			Kind:     model.ReconciliationBalanceCurrent,
+			Tenant:   tenant,
+			UserID:   userID,
+			Expected: expectedCurrent,
+			Actual:   current,
+		})
+	}
+	if math.Abs(withdrawn-expectedWithdrawn) >= model.ReconciliationTolerance {
+		discrepancies = append(discrepancies, &model.ReconciliationDiscrepancy{
+			Kind:     model.ReconciliationBalanceWithdrawn,
+			Tenant:   tenant,
+			UserID:   userID,
+			Expected: expectedWithdrawn,
+			Actual:   withdrawn,
+		})
+	}
+	return discrepancies
+}
+
+// CorrectOrderAccrual заменяет начисление обработанного заказа на accrual и зачисляет разницу
+// корректировкой баланса, связанной с заказом. Если начисление или статус заказа изменились после
+// сверки, возвращает ErrReconciliationStale; отрицательная корректировка не может сделать баланс отрицательным.
+func (r *ReconciliationRepo) CorrectOrderAccrual(ctx context.Context, order *model.Order, accrual float64, reason string) (*model.BalanceAdjustment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		status model.OrderStatus
+		stored float64
+	)
+	orderQuery := `
+		SELECT status, accrual
+		FROM orders
+		WHERE id = $1
+		FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, orderQuery, order.ID).Scan(&status, &stored); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrOrderNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения заказа: %w", err)
+	}
+	if status != model.OrderStatusProcessed || math.Abs(stored-order.Accrual) >= model.ReconciliationTolerance {
+		return nil, fmt.Errorf("%w", errors.ErrReconciliationStale)
+	}
+
+	var currentBalance float64
+	balanceQuery := `
+		SELECT current
+		FROM balances
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, balanceQuery, order.UserID).Scan(&currentBalance); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения текущего баланса: %w", err)
+	}
+
+	amount := accrual - stored
+	if currentBalance+amount < 0 {
+		return nil, fmt.Errorf("%w", errors.ErrInsufficientFunds)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE orders SET accrual = $1 WHERE id = $2", accrual, order.ID); err != nil {
+		return nil, fmt.Errorf("ошибка обновления начисления заказа: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE balances SET current = current + $1 WHERE user_id = $2", amount, order.UserID); err != nil {
+		return nil, fmt.Errorf("ошибка корректировки баланса: %w", err)
+	}
+
+	adjustment := &model.BalanceAdjustment{
+		UserID: order.UserID,
+		Amount: amount,
+		Reason: reason,
+		Actor:  model.ReconciliationActor,
+	}
+
+	adjustmentQuery := `
+		INSERT INTO balance_adjustments (user_id, amount, reason, actor, order_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, adjustmentQuery, order.UserID, amount, reason, model.ReconciliationActor, order.ID).
+		Scan(&adjustment.ID, &adjustment.CreatedAt); err != nil {
+		return nil, fmt.Errorf("ошибка создания записи о корректировке: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+// CorrectBalance пересчитывает баланс пользователя по истории операций под блокировкой, записывает
+// пересчитанные остаток и сумму списаний и сохраняет разницу корректировкой с отметкой drift.
+// Возвращает nil, если баланс уже совпадает с историей.
+func (r *ReconciliationRepo) CorrectBalance(ctx context.Context, userID int64, reason string) (*model.BalanceAdjustment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Баланс блокируется отдельным запросом, чтобы пересчет видел операции,
+	// зафиксированные транзакциями, которые удерживали блокировку до этого.
+	var id int64
+	if err := tx.QueryRow(ctx, "SELECT user_id FROM balances WHERE user_id = $1 FOR UPDATE", userID).Scan(&id); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
+		}
+		return nil, fmt.Errorf("ошибка блокировки баланса: %w", err)
+	}
+
+	var (
+		tenant                                                 string
+		current, withdrawn, expectedCurrent, expectedWithdrawn float64
+	)
+	if err := tx.QueryRow(ctx, balanceExpectationQuery+" WHERE b.user_id = $1", userID).
+		Scan(&tenant, &id, &current, &withdrawn, &expectedCurrent, &expectedWithdrawn); err != nil {
+		return nil, fmt.Errorf("ошибка пересчета баланса: %w", err)
+	}
+
+	if len(balanceDiscrepancies(tenant, userID, current, withdrawn, expectedCurrent, expectedWithdrawn)) == 0 {
+		return nil, nil
+	}
+	if expectedCurrent < 0 {
+		return nil, fmt.Errorf("%w", errors.ErrInsufficientFunds)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE balances SET current = $1, withdrawn = $2 WHERE user_id = $3", expectedCurrent, expectedWithdrawn, userID); err != nil {
+		return nil, fmt.Errorf("ошибка исправления баланса: %w", err)
+	}
+
+	adjustment := &model.BalanceAdjustment{
+		UserID:    userID,
+		Amount:    expectedCurrent - current,
+		Withdrawn: expectedWithdrawn - withdrawn,
+		Drift:     true,
+		Reason:    reason,
+		Actor:     model.ReconciliationActor,
+	}
+
+	adjustmentQuery := `
+		INSERT INTO balance_adjustments (user_id, amount, withdrawn, drift, reason, actor)
+		VALUES ($1, $2, $3, TRUE, $4, $5)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRow(ctx, adjustmentQuery, userID, adjustment.Amount, adjustment.Withdrawn, reason, model.ReconciliationActor).
+		Scan(&adjustment.ID, &adjustment.CreatedAt); err != nil {
+		return nil, fmt.Errorf("ошибка создания записи о корректировке: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return adjustment, nil
+}
+
+// SaveDiscrepancy записывает расхождение в отчет сверки.
+func (r *ReconciliationRepo) SaveDiscrepancy(ctx context.Context, discrepancy *model.ReconciliationDiscrepancy) error {
+	query := `
+		INSERT INTO reconciliation_discrepancies
+			(run_id, kind, tenant, user_id, order_id, order_number, expected, actual, details, corrected, adjustment_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		discrepancy.RunID,
+		discrepancy.Kind,
+		discrepancy.Tenant,
+		discrepancy.UserID,
+		discrepancy.OrderID,
+		discrepancy.OrderNumber,
+		discrepancy.Expected,
+		discrepancy.Actual,
+		discrepancy.Details,
+		discrepancy.Corrected,
+		discrepancy.AdjustmentID,
+	).Scan(&discrepancy.ID, &discrepancy.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи расхождения: %w", err)
+	}
+
+	return nil
+}
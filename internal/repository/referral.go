@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	stderrors "errors"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReferralRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewReferralRepo(db *pgxpool.Pool) *ReferralRepo {
+	return &ReferralRepo{db: db}
+}
+
+// GetReferralCode возвращает реферальный код пользователя или nil, если код еще не выдан.
+func (r *ReferralRepo) GetReferralCode(ctx context.Context, userID int64) (*model.ReferralCode, error) {
+	query := `SELECT user_id, tenant, code, ip FROM referral_codes WHERE user_id = $1`
+
+	var code model.ReferralCode
+	err := r.db.QueryRow(ctx, query, userID).Scan(&code.UserID, &code.Tenant, &code.Code, &code.IP)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения реферального кода: %w", err)
+	}
+
+	return &code, nil
+}
+
+// SaveReferralCode выдает пользователю реферальный код или, если код уже выдан, обновляет IP владельца.
+// В code.Code возвращается действующий код пользователя.
+func (r *ReferralRepo) SaveReferralCode(ctx context.Context, code *model.ReferralCode) error {
+	query := `
+		INSERT INTO referral_codes (user_id, tenant, code, ip)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET ip = EXCLUDED.ip
+		RETURNING code
+	`
+
+	if err := r.db.QueryRow(ctx, query, code.UserID, code.Tenant, code.Code, code.IP).Scan(&code.Code); err != nil {
+		return fmt.Errorf("ошибка сохранения реферального кода: %w", err)
+	}
+
+	return nil
+}
+
+// FindReferralCode ищет реферальный код арендатора. Если кода нет, возвращает ErrInvalidReferralCode.
+func (r *ReferralRepo) FindReferralCode(ctx context.Context, tenant, code string) (*model.ReferralCode, error) {
+	query := `SELECT user_id, tenant, code, ip FROM referral_codes WHERE tenant = $1 AND code = $2`
+
+	var referralCode model.ReferralCode
+	err := r.db.QueryRow(ctx, query, tenant, code).Scan(&referralCode.UserID, &referralCode.Tenant, &referralCode.Code, &referralCode.IP)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrInvalidReferralCode)
+		}
+		return nil, fmt.Errorf("ошибка поиска реферального кода: %w", err)
+	}
+
+	return &referralCode, nil
+}
+
+func (r *ReferralRepo) CreateReferral(ctx context.Context, referral *model.Referral) error {
+	query := `
+		INSERT INTO referrals (referrer_id, referee_id, ip, status, reject_reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		referral.ReferrerID,
+		referral.RefereeID,
+		referral.IP,
+		referral.Status,
+		referral.RejectReason,
+	).Scan(&referral.ID, &referral.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи приглашения: %w", err)
+	}
+
+	return nil
+}
+
+// CountReferralsByIP возвращает число регистраций по реферальным кодам с адреса ip начиная с момента since.
+func (r *ReferralRepo) CountReferralsByIP(ctx context.Context, ip string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM referrals WHERE ip = $1 AND created_at >= $2", ip, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка подсчета приглашений по IP: %w", err)
+	}
+	return count, nil
+}
+
+// RewardReferral начисляет бонусы по ожидающему приглашению пользователя refereeID за обработанный заказ orderID.
+// Если пригласивший уже получил бонусы за maxRewards приглашений (0 — без ограничения), приглашение
+// отклоняется без начисления. Возвращает nil, если ожидающего приглашения нет.
+func (r *ReferralRepo) RewardReferral(ctx context.Context, refereeID, orderID int64, referrerBonus, refereeBonus float64, maxRewards int) (*model.Referral, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var referral model.Referral
+	err = tx.QueryRow(ctx, `
+		SELECT id, referrer_id, referee_id
+		FROM referrals
+		WHERE referee_id = $1 AND status = $2
+		FOR UPDATE
+	`, refereeID, model.ReferralStatusPending).Scan(&referral.ID, &referral.ReferrerID, &referral.RefereeID)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения приглашения: %w", err)
+	}
+
+	// Блокировка баланса пригласившего упорядочивает одновременные начисления по его приглашениям,
+	// чтобы ограничение maxRewards не было превышено. Оба баланса блокируются по возрастанию
+	// ID пользователя, как в Transfer, иначе встречные операции могут взаимно заблокироваться.
+	createBalancesQuery := `
+		INSERT INTO balances (user_id, current, withdrawn) 
+		SELECT user_id, 0, 0 FROM unnest($1::int[]) AS user_id ORDER BY user_id 
+		ON CONFLICT (user_id) DO NOTHING
+	`
+	userIDs := []int64{referral.ReferrerID, referral.RefereeID}
+	if _, err := tx.Exec(ctx, createBalancesQuery, userIDs); err != nil {
+		return nil, fmt.Errorf("ошибка создания балансов: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM balances WHERE user_id = ANY($1) ORDER BY user_id FOR UPDATE", userIDs); err != nil {
+		return nil, fmt.Errorf("ошибка блокировки балансов: %w", err)
+	}
+
+	var rewarded int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND status = $2",
+		referral.ReferrerID, model.ReferralStatusRewarded).Scan(&rewarded)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета приглашений: %w", err)
+	}
+
+	if maxRewards > 0 && rewarded >= maxRewards {
+		referral.Status = model.ReferralStatusRejected
+		referral.RejectReason = model.ReferralRejectReferrerLimit
+	} else {
+		referral.Status = model.ReferralStatusRewarded
+		referral.ReferrerBonus = referrerBonus
+		referral.RefereeBonus = refereeBonus
+
+		credits := []struct {
+			userID int64
+			amount float64
+		}{
+			{referral.ReferrerID, referrerBonus},
+			{referral.RefereeID, refereeBonus},
+		}
+		for _, credit := range credits {
+			if credit.amount <= 0 {
+				continue
+			}
+
+			balanceQuery := `UPDATE balances SET current = current + $2 WHERE user_id = $1`
+			if _, err := tx.Exec(ctx, balanceQuery, credit.userID, credit.amount); err != nil {
+				return nil, fmt.Errorf("ошибка обновления баланса: %w", err)
+			}
+
+			ledgerQuery := `
+				INSERT INTO balance_ledger (user_id, amount, operation, order_id, referral_id)
+				VALUES ($1, $2, $3, NULLIF($4, 0), $5)
+			`
+			if _, err := tx.Exec(ctx, ledgerQuery, credit.userID, credit.amount, model.LedgerReferral, orderID, referral.ID); err != nil {
+				return nil, fmt.Errorf("ошибка записи журнала движения баллов: %w", err)
+			}
+		}
+	}
+
+	updateQuery := `
+		UPDATE referrals
+		SET status = $1, reject_reason = $2, referrer_bonus = $3, referee_bonus = $4,
+			rewarded_at = CASE WHEN $1 = 'REWARDED' THEN NOW() END
+		WHERE id = $5
+		RETURNING ip, created_at, rewarded_at
+	`
+	err = tx.QueryRow(ctx, updateQuery,
+		referral.Status,
+		referral.RejectReason,
+		referral.ReferrerBonus,
+		referral.RefereeBonus,
+		referral.ID,
+	).Scan(&referral.IP, &referral.CreatedAt, &referral.RewardedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления приглашения: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return &referral, nil
+}
+
+func (r *ReferralRepo) GetReferrals(ctx context.Context, referrerID int64) ([]*model.Referral, error) {
+	query := `
+		SELECT r.id, r.referrer_id, r.referee_id, u.login, r.ip, r.status, r.reject_reason,
+			r.referrer_bonus, r.referee_bonus, r.created_at, r.rewarded_at
+		FROM referrals r
+		JOIN users u ON u.id = r.referee_id
+		WHERE r.referrer_id = $1
+		ORDER BY r.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, referrerID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения приглашений: %w", err)
+	}
+	defer rows.Close()
+
+	var referrals []*model.Referral
+	for rows.Next() {
+		var referral model.Referral
+		if err := rows.Scan(
+			&referral.ID,
+			&referral.ReferrerID,
+			&referral.RefereeID,
+			&referral.RefereeLogin,
+			&referral.IP,
+			&referral.Status,
+			&referral.RejectReason,
+			&referral.ReferrerBonus,
+			&referral.RefereeBonus,
+			&referral.CreatedAt,
+			&referral.RewardedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки приглашения: %w", err)
+		}
+		referrals = append(referrals, &referral)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по приглашениям: %w", err)
+	}
+
+	return referrals, nil
+}
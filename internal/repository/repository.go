@@ -2,44 +2,179 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type UserRepository interface {
-	CreateUser(ctx context.Context, login, passwordHash string) (int64, error)
-	GetUserByLogin(ctx context.Context, login string) (*model.User, error)
+	CreateUser(ctx context.Context, tenant, login, passwordHash string) (int64, error)
+	GetUserByLogin(ctx context.Context, tenant, login string) (*model.User, error)
 	GetUserByID(ctx context.Context, id int64) (*model.User, error)
+	SearchUsers(ctx context.Context, tenant, loginQuery string, limit int) ([]*model.UserSummary, error)
+	GetUserRoles(ctx context.Context, userID int64) ([]model.Role, error)
+	SetUserRoles(ctx context.Context, userID int64, roles []model.Role, actor string) error
+	GetRoleChanges(ctx context.Context, userID int64) ([]*model.RoleChange, error)
+	DeleteUser(ctx context.Context, userID int64, anonymizedLogin string) error
 }
 
 type OrderRepository interface {
-	CreateOrder(ctx context.Context, userID int64, number string) (int64, error)
-	GetOrderByNumber(ctx context.Context, number string) (*model.Order, error)
+	CreateOrder(ctx context.Context, tenant string, userID int64, number string) (int64, error)
+	CreateOrders(ctx context.Context, tenant string, userID int64, numbers []string) ([]model.BatchOrderResult, error)
+	GetOrderByNumber(ctx context.Context, tenant, number string) (*model.Order, error)
 	GetOrdersByUserID(ctx context.Context, userID int64) ([]*model.Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID int64, status model.OrderStatus) error
 	UpdateOrderAccrual(ctx context.Context, orderID int64, accrual float64) error
-	GetNewOrProcessingOrders(ctx context.Context) ([]*model.Order, error)
+	ProcessOrderAccrual(ctx context.Context, orderID int64, accrual float64, bonus *model.TierBonus, lease time.Duration) error
+	ClaimOrderBonuses(ctx context.Context, limit int, lease time.Duration) ([]*model.OrderBonusTask, error)
+	CompleteOrderBonuses(ctx context.Context, orderID int64) error
+	ClaimOrdersForCheck(ctx context.Context, workerID string, limit int, lease time.Duration) ([]*model.Order, error)
+	ClaimOrderByNumber(ctx context.Context, workerID string, tenant, number string, lease time.Duration) (*model.Order, error)
+	ExtendOrderClaim(ctx context.Context, orderID int64, workerID string, lease time.Duration) (bool, error)
+	ReleaseOrder(ctx context.Context, orderID int64, workerID string) error
+	RequeueOrder(ctx context.Context, tenant, number string) error
+	RecordOrderCheck(ctx context.Context, orderID int64, accrualStatus model.AccrualSystemStatus) error
+	MarkOrderUnregistered(ctx context.Context, orderID int64) (time.Time, int, error)
+	DeferOrderCheck(ctx context.Context, orderID int64, at time.Time) error
+	GetOrderHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusChange, error)
 }
 
+// WithdrawalCheck проверяет списание по данным, собранным в транзакции списания.
+// Ошибка проверки отменяет списание.
+type WithdrawalCheck func(stats model.WithdrawalStats) error
+
 type BalanceRepository interface {
 	GetBalance(ctx context.Context, userID int64) (*model.Balance, error)
 	CreateBalance(ctx context.Context, userID int64) error
-	AddAccrual(ctx context.Context, userID int64, amount float64) error
-	Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string) error
+	AddAccrual(ctx context.Context, userID, orderID int64, amount float64, bonus *model.TierBonus) error
+	Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string, check WithdrawalCheck) error
 	GetWithdrawals(ctx context.Context, userID int64) ([]*model.Withdrawal, error)
+	AdjustBalance(ctx context.Context, userID int64, amount float64, reason, actor string) (*model.BalanceAdjustment, error)
+	GetAdjustments(ctx context.Context, userID int64) ([]*model.BalanceAdjustment, error)
+	Transfer(ctx context.Context, transfer *model.Transfer, dailyLimit float64) (*model.Transfer, error)
+	GetTransfers(ctx context.Context, userID int64) ([]*model.LedgerEntry, error)
+	GetLedger(ctx context.Context, userID int64) ([]*model.LedgerEntry, error)
+	GetBalanceAt(ctx context.Context, userID int64, at time.Time) (float64, error)
+	StreamStatement(ctx context.Context, userID int64, from, to time.Time, fn func(*model.BalanceHistoryEntry) error) error
+	Reserve(ctx context.Context, userID int64, amount float64, orderNumber string, ttl time.Duration, check WithdrawalCheck) (*model.Reservation, error)
+	ConfirmReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error)
+	CancelReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error)
+	GetReservations(ctx context.Context, userID int64) ([]*model.Reservation, error)
+	ExpireReservations(ctx context.Context, limit int) (int, error)
+}
+
+type TierRepository interface {
+	GetUserTier(ctx context.Context, userID int64) (*model.UserTier, error)
+	GetTierHistory(ctx context.Context, userID int64) ([]*model.TierChange, error)
+	GetTierPoints(ctx context.Context, since time.Time, afterUserID int64, limit int) ([]model.TierPoints, error)
+	SetUserTier(ctx context.Context, userID int64, tier string, points float64) (bool, error)
+	StartTierRecalculation(ctx context.Context, minInterval time.Duration) (bool, error)
+}
+
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign *model.Campaign) error
+	UpdateCampaign(ctx context.Context, campaign *model.Campaign) error
+	DeleteCampaign(ctx context.Context, id int64) error
+	GetCampaign(ctx context.Context, id int64) (*model.Campaign, error)
+	GetCampaigns(ctx context.Context, tenant string) ([]*model.Campaign, error)
+	GetActiveCampaigns(ctx context.Context, tenant string, campaignType model.CampaignType) ([]*model.Campaign, error)
+	CreditCampaignBonus(ctx context.Context, bonus *model.CampaignBonus) (bool, error)
+	GetCampaignBonuses(ctx context.Context, userID int64) ([]*model.CampaignBonus, error)
+}
+
+type ReferralRepository interface {
+	GetReferralCode(ctx context.Context, userID int64) (*model.ReferralCode, error)
+	SaveReferralCode(ctx context.Context, code *model.ReferralCode) error
+	FindReferralCode(ctx context.Context, tenant, code string) (*model.ReferralCode, error)
+	CreateReferral(ctx context.Context, referral *model.Referral) error
+	CountReferralsByIP(ctx context.Context, ip string, since time.Time) (int, error)
+	RewardReferral(ctx context.Context, refereeID, orderID int64, referrerBonus, refereeBonus float64, maxRewards int) (*model.Referral, error)
+	GetReferrals(ctx context.Context, referrerID int64) ([]*model.Referral, error)
+}
+
+type FinanceRepository interface {
+	GetNextReportDay(ctx context.Context) (*time.Time, error)
+	MaterializeReport(ctx context.Context, day time.Time, snapshotLiability bool) error
+	GetReports(ctx context.Context, tenant string, from, to time.Time) ([]*model.FinanceDailyReport, error)
+	StartReportMaterialization(ctx context.Context, minInterval time.Duration) (bool, error)
+}
+
+type ReconciliationRepository interface {
+	StartRun(ctx context.Context, autoFix bool, minInterval time.Duration) (*model.ReconciliationRun, error)
+	FinishRun(ctx context.Context, run *model.ReconciliationRun) error
+	GetProcessedOrders(ctx context.Context, limit int) ([]*model.Order, error)
+	FindOrderLedgerDiscrepancies(ctx context.Context) ([]*model.ReconciliationDiscrepancy, error)
+	FindBalanceDiscrepancies(ctx context.Context) (int, []*model.ReconciliationDiscrepancy, error)
+	CorrectOrderAccrual(ctx context.Context, order *model.Order, accrual float64, reason string) (*model.BalanceAdjustment, error)
+	CorrectBalance(ctx context.Context, userID int64, reason string) (*model.BalanceAdjustment, error)
+	SaveDiscrepancy(ctx context.Context, discrepancy *model.ReconciliationDiscrepancy) error
+}
+
+type EventRepository interface {
+	AppendEvent(ctx context.Context, userID int64, eventType model.UserEventType, payload []byte) (int64, error)
+	GetEventsAfter(ctx context.Context, userID int64, afterID int64, limit int) ([]*model.UserEvent, error)
+	GetLastEventID(ctx context.Context, userID int64) (int64, error)
+	DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (int64, error)
+	GetSubscriptions(ctx context.Context, tenant string) ([]*model.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, tenant string, id int64) error
+	FanOutOutbox(ctx context.Context, limit int) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string, dead bool) error
+	GetDeliveries(ctx context.Context, tenant string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, tenant string, id int64) error
+}
+
+type NotificationListener interface {
+	Listen(ctx context.Context, channel string) (<-chan string, error)
+}
+
+type AuditRepository interface {
+	AppendAuditEvent(ctx context.Context, event *model.AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error)
+}
+
+// RateLimitStore учитывает запрос в текущем фиксированном окне и возвращает
+// число запросов в окне вместе с моментом его окончания.
+type RateLimitStore interface {
+	Hit(ctx context.Context, key string, window time.Duration) (int, time.Time, error)
 }
 
 type Repository struct {
-	Users    UserRepository
-	Orders   OrderRepository
-	Balances BalanceRepository
+	Users          UserRepository
+	Orders         OrderRepository
+	Balances       BalanceRepository
+	Tiers          TierRepository
+	Campaigns      CampaignRepository
+	Referrals      ReferralRepository
+	Finance        FinanceRepository
+	Reconciliation ReconciliationRepository
+	Events         EventRepository
+	Webhooks       WebhookRepository
+	Audit          AuditRepository
+	RateLimits     RateLimitStore
+	Notifications  NotificationListener
 }
 
 func NewRepository(db *pgxpool.Pool) *Repository {
 	return &Repository{
-		Users:    NewUserRepo(db),
-		Orders:   NewOrderRepo(db),
-		Balances: NewBalanceRepo(db),
+		Users:          NewUserRepo(db),
+		Orders:         NewOrderRepo(db),
+		Balances:       NewBalanceRepo(db),
+		Tiers:          NewTierRepo(db),
+		Campaigns:      NewCampaignRepo(db),
+		Referrals:      NewReferralRepo(db),
+		Finance:        NewFinanceRepo(db),
+		Reconciliation: NewReconciliationRepo(db),
+		Events:         NewEventRepo(db),
+		Webhooks:       NewWebhookRepo(db),
+		Audit:          NewAuditRepo(db),
+		RateLimits:     NewPgRateLimitStore(db),
+		Notifications:  NewPgListener(db),
 	}
 }
@@ -1,8 +1,14 @@
 package repository
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,23 +18,65 @@ import (
 
 var (
 	ErrUserExists             = errors.New("пользователь с таким логином уже существует")
-	ErrUserNotFound           = errors.New("пользователь не найден")
-	ErrOrderNotFound          = errors.New("заказ не найден")
+	ErrUserNotFound           = customerrors.ErrUserNotFound
+	ErrOrderNotFound          = customerrors.ErrOrderNotFound
 	ErrOrderAlreadyExists     = errors.New("заказ уже зарегистрирован этим пользователем")
 	ErrOrderBelongsToAnotherUser = errors.New("заказ уже зарегистрирован другим пользователем")
 	ErrInsufficientFunds      = customerrors.ErrInsufficientFunds
 )
 
 func NewRepositoriesForTests() *Repository {
+	listener := NewNotificationListenerMock()
+	users := NewUserRepoMock()
+	balances := NewBalanceRepoMock()
+	balances.users = users
+	tiers := NewTierRepoMock()
+	tiers.users = users
+	tiers.balances = balances
+	campaigns := NewCampaignRepoMock()
+	campaigns.balances = balances
+	referrals := NewReferralRepoMock()
+	referrals.users = users
+	referrals.balances = balances
+	orders := NewOrderRepoMock()
+	orders.balances = balances
+	finance := NewFinanceRepoMock()
+	finance.users = users
+	finance.orders = orders
+	finance.balances = balances
+	reconciliation := NewReconciliationRepoMock()
+	reconciliation.users = users
+	reconciliation.orders = orders
+	reconciliation.balances = balances
+	audit := NewAuditRepoMock()
+	users.audit = audit
+	users.referrals = referrals
+	users.balances = balances
+	
 	return &Repository{
-		Users:    NewUserRepoMock(),
-		Orders:   NewOrderRepoMock(),
-		Balances: NewBalanceRepoMock(),
+		Users:    users,
+		Orders:   orders,
+		Balances: balances,
+		Tiers:    tiers,
+		Campaigns: campaigns,
+		Referrals: referrals,
+		Finance:   finance,
+		Reconciliation: reconciliation,
+		Events:   NewEventRepoMock(listener),
+		Webhooks: NewWebhookRepoMock(),
+		Audit:    audit,
+		RateLimits:    NewMemoryRateLimitStore(),
+		Notifications: listener,
 	}
 }
 
 type UserRepoMock struct {
 	users map[int64]*model.User
+	roles map[int64][]model.Role
+	roleChanges []*model.RoleChange
+	audit *AuditRepoMock
+	referrals *ReferralRepoMock
+	balances *BalanceRepoMock
 	mutex sync.RWMutex
 	lastID int64
 }
@@ -36,16 +84,17 @@ type UserRepoMock struct {
 func NewUserRepoMock() *UserRepoMock {
 	return &UserRepoMock{
 		users: make(map[int64]*model.User),
+		roles: make(map[int64][]model.Role),
 		lastID: 0,
 	}
 }
 
-func (r *UserRepoMock) CreateUser(ctx context.Context, login, passwordHash string) (int64, error) {
+func (r *UserRepoMock) CreateUser(ctx context.Context, tenant, login, passwordHash string) (int64, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
 	for _, user := range r.users {
-		if user.Login == login {
+		if user.Tenant == tenant && user.Login == login {
 			return 0, ErrUserExists
 		}
 	}
@@ -55,19 +104,22 @@ func (r *UserRepoMock) CreateUser(ctx context.Context, login, passwordHash strin
 	
 	r.users[userID] = &model.User{
 		ID:           userID,
+		Tenant:       tenant,
 		Login:        login,
 		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
 	}
+	r.roles[userID] = []model.Role{model.RoleCustomer}
 	
 	return userID, nil
 }
 
-func (r *UserRepoMock) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+func (r *UserRepoMock) GetUserByLogin(ctx context.Context, tenant, login string) (*model.User, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	for _, user := range r.users {
-		if user.Login == login {
+		if user.Tenant == tenant && user.Login == login {
 			return user, nil
 		}
 	}
@@ -87,8 +139,130 @@ func (r *UserRepoMock) GetUserByID(ctx context.Context, id int64) (*model.User,
 	return user, nil
 }
 
+func (r *UserRepoMock) SearchUsers(ctx context.Context, tenant, loginQuery string, limit int) ([]*model.UserSummary, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.UserSummary
+	
+	for _, user := range r.users {
+		if len(result) >= limit {
+			break
+		}
+		if tenant != "" && user.Tenant != tenant {
+			continue
+		}
+		if strings.Contains(strings.ToLower(user.Login), strings.ToLower(loginQuery)) {
+			result = append(result, &model.UserSummary{
+				ID:        user.ID,
+				Tenant:    user.Tenant,
+				Login:     user.Login,
+				CreatedAt: user.CreatedAt,
+			})
+		}
+	}
+	
+	return result, nil
+}
+
+func (r *UserRepoMock) GetUserRoles(ctx context.Context, userID int64) ([]model.Role, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	return append([]model.Role(nil), r.roles[userID]...), nil
+}
+
+func (r *UserRepoMock) SetUserRoles(ctx context.Context, userID int64, roles []model.Role, actor string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if _, exists := r.users[userID]; !exists {
+		return ErrUserNotFound
+	}
+	
+	current := r.roles[userID]
+	for _, role := range roles {
+		if !slices.Contains(current, role) {
+			r.appendRoleChange(userID, role, model.RoleChangeGrant, actor)
+		}
+	}
+	for _, role := range current {
+		if !slices.Contains(roles, role) {
+			r.appendRoleChange(userID, role, model.RoleChangeRevoke, actor)
+		}
+	}
+	
+	r.roles[userID] = append([]model.Role(nil), roles...)
+	
+	return nil
+}
+
+func (r *UserRepoMock) appendRoleChange(userID int64, role model.Role, action model.RoleChangeAction, actor string) {
+	r.roleChanges = append(r.roleChanges, &model.RoleChange{
+		ID:        int64(len(r.roleChanges) + 1),
+		UserID:    userID,
+		Role:      role,
+		Action:    action,
+		Actor:     actor,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (r *UserRepoMock) DeleteUser(ctx context.Context, userID int64, anonymizedLogin string) error {
+	r.mutex.Lock()
+	
+	user, exists := r.users[userID]
+	if !exists || user.DeletedAt != nil {
+		r.mutex.Unlock()
+		return ErrUserNotFound
+	}
+	
+	login := user.Login
+	now := time.Now()
+	user.Login = anonymizedLogin
+	user.PasswordHash = ""
+	user.DeletedAt = &now
+	delete(r.roles, userID)
+	r.mutex.Unlock()
+	
+	// Связанные хранилища обновляются после снятия блокировки: они сами обращаются к пользователям.
+	if r.referrals != nil {
+		r.referrals.redactIP(userID)
+	}
+	if r.balances != nil {
+		r.balances.cancelReservations(userID)
+	}
+	if r.audit != nil {
+		r.audit.redactLogin(userID, login, anonymizedLogin)
+	}
+	
+	return nil
+}
+
+func (r *UserRepoMock) GetRoleChanges(ctx context.Context, userID int64) ([]*model.RoleChange, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.RoleChange
+	for i := len(r.roleChanges) - 1; i >= 0; i-- {
+		if r.roleChanges[i].UserID == userID {
+			result = append(result, r.roleChanges[i])
+		}
+	}
+	
+	return result, nil
+}
+
 type OrderRepoMock struct {
 	orders map[int64]*model.Order
+	claims map[int64]string
+	history map[int64][]*model.OrderStatusChange
+	unregisteredSince map[int64]time.Time
+	unregisteredChecks map[int64]int
+	nextCheckAt map[int64]time.Time
+	bonusQueue map[int64]*model.OrderBonusTask
+	bonusDueAt map[int64]time.Time
+	balances *BalanceRepoMock
 	mutex sync.RWMutex
 	lastID int64
 }
@@ -96,16 +270,23 @@ type OrderRepoMock struct {
 func NewOrderRepoMock() *OrderRepoMock {
 	return &OrderRepoMock{
 		orders: make(map[int64]*model.Order),
+		claims: make(map[int64]string),
+		history: make(map[int64][]*model.OrderStatusChange),
+		unregisteredSince: make(map[int64]time.Time),
+		unregisteredChecks: make(map[int64]int),
+		nextCheckAt: make(map[int64]time.Time),
+		bonusQueue: make(map[int64]*model.OrderBonusTask),
+		bonusDueAt: make(map[int64]time.Time),
 		lastID: 0,
 	}
 }
 
-func (r *OrderRepoMock) CreateOrder(ctx context.Context, userID int64, number string) (int64, error) {
+func (r *OrderRepoMock) CreateOrder(ctx context.Context, tenant string, userID int64, number string) (int64, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
 	for _, order := range r.orders {
-		if order.Number == number {
+		if order.Tenant == tenant && order.Number == number {
 			if order.UserID == userID {
 				return order.ID, ErrOrderAlreadyExists
 			}
@@ -118,21 +299,48 @@ func (r *OrderRepoMock) CreateOrder(ctx context.Context, userID int64, number st
 	
 	r.orders[orderID] = &model.Order{
 		ID:         orderID,
+		Tenant:     tenant,
 		UserID:     userID,
 		Number:     number,
 		Status:     model.OrderStatusNew,
 		UploadedAt: time.Now(),
 	}
+	r.history[orderID] = append(r.history[orderID], &model.OrderStatusChange{
+		Status:    model.OrderStatusNew,
+		ChangedAt: time.Now(),
+	})
 	
 	return orderID, nil
 }
 
-func (r *OrderRepoMock) GetOrderByNumber(ctx context.Context, number string) (*model.Order, error) {
+func (r *OrderRepoMock) CreateOrders(ctx context.Context, tenant string, userID int64, numbers []string) ([]model.BatchOrderResult, error) {
+	results := make([]model.BatchOrderResult, 0, len(numbers))
+	
+	for _, number := range numbers {
+		result := model.BatchOrderResult{Number: number, Result: model.BatchOrderAccepted}
+		
+		_, err := r.CreateOrder(ctx, tenant, userID, number)
+		switch {
+		case errors.Is(err, ErrOrderAlreadyExists):
+			result.Result = model.BatchOrderAlreadyYours
+		case errors.Is(err, ErrOrderBelongsToAnotherUser):
+			result.Result = model.BatchOrderConflict
+		case err != nil:
+			return nil, err
+		}
+		
+		results = append(results, result)
+	}
+	
+	return results, nil
+}
+
+func (r *OrderRepoMock) GetOrderByNumber(ctx context.Context, tenant, number string) (*model.Order, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	for _, order := range r.orders {
-		if order.Number == number {
+		if order.Tenant == tenant && order.Number == number {
 			return order, nil
 		}
 	}
@@ -176,30 +384,271 @@ func (r *OrderRepoMock) UpdateOrderAccrual(ctx context.Context, orderID int64, a
 	if !exists {
 		return ErrOrderNotFound
 	}
+	if order.Status != model.OrderStatusNew && order.Status != model.OrderStatusProcessing {
+		return customerrors.ErrOrderProcessed
+	}
+	
+	order.Accrual = accrual
+	order.Status = model.OrderStatusProcessed
+	return nil
+}
+
+func (r *OrderRepoMock) ProcessOrderAccrual(ctx context.Context, orderID int64, accrual float64, bonus *model.TierBonus, lease time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	order, exists := r.orders[orderID]
+	if !exists {
+		return ErrOrderNotFound
+	}
+	if order.Status != model.OrderStatusNew && order.Status != model.OrderStatusProcessing {
+		return customerrors.ErrOrderProcessed
+	}
+	
+	if r.balances != nil {
+		if err := r.balances.AddAccrual(ctx, order.UserID, orderID, accrual, bonus); err != nil {
+			return err
+		}
+	}
 	
 	order.Accrual = accrual
 	order.Status = model.OrderStatusProcessed
+	r.bonusQueue[orderID] = &model.OrderBonusTask{Order: *order, Accrual: accrual}
+	r.bonusDueAt[orderID] = time.Now().Add(lease)
 	return nil
 }
 
-func (r *OrderRepoMock) GetNewOrProcessingOrders(ctx context.Context) ([]*model.Order, error) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
+func (r *OrderRepoMock) ClaimOrderBonuses(ctx context.Context, limit int, lease time.Duration) ([]*model.OrderBonusTask, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var result []*model.OrderBonusTask
+	
+	for orderID, task := range r.bonusQueue {
+		if len(result) >= limit {
+			break
+		}
+		if r.bonusDueAt[orderID].After(time.Now()) {
+			continue
+		}
+		task.Attempts++
+		r.bonusDueAt[orderID] = time.Now().Add(lease)
+		claimed := *task
+		result = append(result, &claimed)
+	}
+	
+	return result, nil
+}
+
+// ExpireOrderBonusClaims делает задачи очереди бонусов доступными для захвата, как по истечении аренды.
+func (r *OrderRepoMock) ExpireOrderBonusClaims() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for orderID := range r.bonusDueAt {
+		r.bonusDueAt[orderID] = time.Now()
+	}
+}
+
+func (r *OrderRepoMock) CompleteOrderBonuses(ctx context.Context, orderID int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	delete(r.bonusQueue, orderID)
+	delete(r.bonusDueAt, orderID)
+	return nil
+}
+
+func (r *OrderRepoMock) ClaimOrdersForCheck(ctx context.Context, workerID string, limit int, lease time.Duration) ([]*model.Order, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	
 	var result []*model.Order
 	
 	for _, order := range r.orders {
-		if order.Status == model.OrderStatusNew || order.Status == model.OrderStatusProcessing {
-			result = append(result, order)
+		if order.Status != model.OrderStatusNew && order.Status != model.OrderStatusProcessing {
+			continue
+		}
+		if _, claimed := r.claims[order.ID]; claimed {
+			continue
+		}
+		if at, deferred := r.nextCheckAt[order.ID]; deferred && at.After(time.Now()) {
+			continue
+		}
+		result = append(result, order)
+	}
+	
+	slices.SortFunc(result, func(a, b *model.Order) int {
+		switch {
+		case a.LastCheckedAt == nil && b.LastCheckedAt != nil:
+			return -1
+		case a.LastCheckedAt != nil && b.LastCheckedAt == nil:
+			return 1
+		case a.LastCheckedAt != nil && !a.LastCheckedAt.Equal(*b.LastCheckedAt):
+			return a.LastCheckedAt.Compare(*b.LastCheckedAt)
 		}
+		return a.UploadedAt.Compare(b.UploadedAt)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	for _, order := range result {
+		r.claims[order.ID] = workerID
 	}
 	
 	return result, nil
 }
 
+func (r *OrderRepoMock) ClaimOrderByNumber(ctx context.Context, workerID string, tenant, number string, lease time.Duration) (*model.Order, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for _, order := range r.orders {
+		if order.Tenant != tenant || order.Number != number {
+			continue
+		}
+		if order.Status != model.OrderStatusNew && order.Status != model.OrderStatusProcessing {
+			return nil, nil
+		}
+		if _, claimed := r.claims[order.ID]; claimed {
+			return nil, nil
+		}
+		r.claims[order.ID] = workerID
+		return order, nil
+	}
+	
+	return nil, nil
+}
+
+func (r *OrderRepoMock) ExtendOrderClaim(ctx context.Context, orderID int64, workerID string, lease time.Duration) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	return r.claims[orderID] == workerID, nil
+}
+
+func (r *OrderRepoMock) ReleaseOrder(ctx context.Context, orderID int64, workerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if r.claims[orderID] == workerID {
+		delete(r.claims, orderID)
+	}
+	
+	return nil
+}
+
+func (r *OrderRepoMock) RequeueOrder(ctx context.Context, tenant, number string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for _, order := range r.orders {
+		if order.Tenant != tenant || order.Number != number {
+			continue
+		}
+		if order.Status == model.OrderStatusProcessed {
+			return customerrors.ErrOrderNotRequeueable
+		}
+		order.Status = model.OrderStatusNew
+		delete(r.claims, order.ID)
+		r.resetUnregistered(order.ID)
+		r.history[order.ID] = append(r.history[order.ID], &model.OrderStatusChange{
+			Status:    model.OrderStatusNew,
+			ChangedAt: time.Now(),
+		})
+		return nil
+	}
+	
+	return ErrOrderNotFound
+}
+
+func (r *OrderRepoMock) RecordOrderCheck(ctx context.Context, orderID int64, accrualStatus model.AccrualSystemStatus) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	order, exists := r.orders[orderID]
+	if !exists {
+		return ErrOrderNotFound
+	}
+	
+	now := time.Now()
+	order.LastCheckedAt = &now
+	if accrualStatus != model.AccrualStatusNotRegistered {
+		r.resetUnregistered(orderID)
+	}
+	
+	history := r.history[orderID]
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.Status == order.Status && last.AccrualStatus == accrualStatus {
+			return nil
+		}
+	}
+	
+	r.history[orderID] = append(history, &model.OrderStatusChange{
+		Status:        order.Status,
+		AccrualStatus: accrualStatus,
+		Accrual:       order.Accrual,
+		ChangedAt:     now,
+	})
+	return nil
+}
+
+func (r *OrderRepoMock) MarkOrderUnregistered(ctx context.Context, orderID int64) (time.Time, int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if _, exists := r.orders[orderID]; !exists {
+		return time.Time{}, 0, ErrOrderNotFound
+	}
+	
+	since, exists := r.unregisteredSince[orderID]
+	if !exists {
+		since = time.Now()
+		r.unregisteredSince[orderID] = since
+	}
+	r.unregisteredChecks[orderID]++
+	
+	return since, r.unregisteredChecks[orderID], nil
+}
+
+func (r *OrderRepoMock) DeferOrderCheck(ctx context.Context, orderID int64, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if _, exists := r.orders[orderID]; !exists {
+		return ErrOrderNotFound
+	}
+	
+	r.nextCheckAt[orderID] = at
+	return nil
+}
+
+func (r *OrderRepoMock) resetUnregistered(orderID int64) {
+	delete(r.unregisteredSince, orderID)
+	delete(r.unregisteredChecks, orderID)
+	delete(r.nextCheckAt, orderID)
+}
+
+func (r *OrderRepoMock) GetOrderHistory(ctx context.Context, orderID int64) ([]*model.OrderStatusChange, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	return r.history[orderID], nil
+}
+
 type BalanceRepoMock struct {
 	balances   map[int64]*model.Balance
 	withdrawals map[int64][]*model.Withdrawal
+	adjustments map[int64][]*model.BalanceAdjustment
+	transfers  []*model.Transfer
+	ledger     map[int64][]*model.LedgerEntry
+	reservations []*model.Reservation
+	firstAccrual map[int64]time.Time
+	accruals   map[int64][]*model.LedgerEntry
+	orderAccruals map[int64]float64
+	orderAdjustments map[int64]float64
+	users      *UserRepoMock
 	mutex      sync.RWMutex
 	lastID     int64
 }
@@ -208,6 +657,12 @@ func NewBalanceRepoMock() *BalanceRepoMock {
 	return &BalanceRepoMock{
 		balances:   make(map[int64]*model.Balance),
 		withdrawals: make(map[int64][]*model.Withdrawal),
+		adjustments: make(map[int64][]*model.BalanceAdjustment),
+		ledger:     make(map[int64][]*model.LedgerEntry),
+		firstAccrual: make(map[int64]time.Time),
+		accruals:   make(map[int64][]*model.LedgerEntry),
+		orderAccruals: make(map[int64]float64),
+		orderAdjustments: make(map[int64]float64),
 		lastID:     0,
 	}
 }
@@ -241,10 +696,25 @@ func (r *BalanceRepoMock) CreateBalance(ctx context.Context, userID int64) error
 	return nil
 }
 
-func (r *BalanceRepoMock) AddAccrual(ctx context.Context, userID int64, amount float64) error {
+func (r *BalanceRepoMock) AddAccrual(ctx context.Context, userID, orderID int64, amount float64, bonus *model.TierBonus) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
+	r.recordAccrual(userID, amount)
+	if orderID != 0 {
+		r.orderAccruals[orderID] += amount
+	}
+	
+	if bonus != nil && bonus.Amount > 0 {
+		r.accruals[userID] = append(r.accruals[userID], &model.LedgerEntry{
+			UserID:    userID,
+			Amount:    bonus.Amount,
+			Operation: model.LedgerTierBonus,
+			CreatedAt: time.Now(),
+		})
+		amount += bonus.Amount
+	}
+	
 	balance, exists := r.balances[userID]
 	if !exists {
 		r.balances[userID] = &model.Balance{
@@ -259,10 +729,14 @@ func (r *BalanceRepoMock) AddAccrual(ctx context.Context, userID int64, amount f
 	return nil
 }
 
-func (r *BalanceRepoMock) Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string) error {
+func (r *BalanceRepoMock) Withdraw(ctx context.Context, userID int64, amount float64, orderNumber string, check WithdrawalCheck) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
+	if err := r.checkWithdrawal(userID, amount, orderNumber, check); err != nil {
+		return err
+	}
+	
 	balance, exists := r.balances[userID]
 	if !exists {
 		return customerrors.ErrInsufficientFunds
@@ -299,19 +773,1693 @@ func (r *BalanceRepoMock) GetWithdrawals(ctx context.Context, userID int64) ([]*
 	return withdrawals, nil
 }
 
-func (r *BalanceRepoMock) AddPoints(userID int64, amount float64, orderNumber string) {
+func (r *BalanceRepoMock) AdjustBalance(ctx context.Context, userID int64, amount float64, reason, actor string) (*model.BalanceAdjustment, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
 	balance, exists := r.balances[userID]
 	if !exists {
-		r.balances[userID] = &model.Balance{
-			UserID:    userID,
-			Current:   amount,
-			Withdrawn: 0,
-		}
-		return
+		return nil, customerrors.ErrUserBalanceNotFound
+	}
+	
+	if balance.Current+amount < 0 {
+		return nil, customerrors.ErrInsufficientFunds
 	}
 	
 	balance.Current += amount
+	
+	r.lastID++
+	adjustment := &model.BalanceAdjustment{
+		ID:        r.lastID,
+		UserID:    userID,
+		Amount:    amount,
+		Reason:    reason,
+		Actor:     actor,
+		CreatedAt: time.Now(),
+	}
+	
+	r.adjustments[userID] = append(r.adjustments[userID], adjustment)
+	
+	return adjustment, nil
+}
+
+func (r *BalanceRepoMock) GetAdjustments(ctx context.Context, userID int64) ([]*model.BalanceAdjustment, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	return r.adjustments[userID], nil
+}
+
+func (r *BalanceRepoMock) Transfer(ctx context.Context, transfer *model.Transfer, dailyLimit float64) (*model.Transfer, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var transferred float64
+	today := time.Now().Truncate(24 * time.Hour)
+	for _, existing := range r.transfers {
+		if existing.SenderID != transfer.SenderID {
+			continue
+		}
+		if existing.IdempotencyKey == transfer.IdempotencyKey {
+			if existing.RecipientID != transfer.RecipientID || existing.Amount != transfer.Amount {
+				return nil, customerrors.ErrIdempotencyConflict
+			}
+			replayed := *existing
+			replayed.Replayed = true
+			return &replayed, nil
+		}
+		if !existing.CreatedAt.Before(today) {
+			transferred += existing.Amount
+		}
+	}
+	
+	if dailyLimit > 0 && transferred+transfer.Amount > dailyLimit {
+		return nil, customerrors.ErrTransferLimit
+	}
+	
+	sender, exists := r.balances[transfer.SenderID]
+	if !exists || sender.Current < transfer.Amount {
+		return nil, customerrors.ErrInsufficientFunds
+	}
+	
+	recipient, exists := r.balances[transfer.RecipientID]
+	if !exists {
+		recipient = &model.Balance{UserID: transfer.RecipientID}
+		r.balances[transfer.RecipientID] = recipient
+	}
+	
+	sender.Current -= transfer.Amount
+	recipient.Current += transfer.Amount
+	
+	r.lastID++
+	created := *transfer
+	created.ID = r.lastID
+	created.CreatedAt = time.Now()
+	r.transfers = append(r.transfers, &created)
+	
+	transferID := created.ID
+	r.ledger[created.SenderID] = append(r.ledger[created.SenderID], &model.LedgerEntry{
+		UserID:       created.SenderID,
+		Amount:       -created.Amount,
+		Operation:    model.LedgerTransferOut,
+		TransferID:   &transferID,
+		Counterparty: r.login(ctx, created.RecipientID),
+		CreatedAt:    created.CreatedAt,
+	})
+	r.ledger[created.RecipientID] = append(r.ledger[created.RecipientID], &model.LedgerEntry{
+		UserID:       created.RecipientID,
+		Amount:       created.Amount,
+		Operation:    model.LedgerTransferIn,
+		TransferID:   &transferID,
+		Counterparty: r.login(ctx, created.SenderID),
+		CreatedAt:    created.CreatedAt,
+	})
+	
+	result := created
+	return &result, nil
+}
+
+func (r *BalanceRepoMock) login(ctx context.Context, userID int64) string {
+	if r.users == nil {
+		return ""
+	}
+	
+	user, err := r.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	
+	return user.Login
+}
+
+func (r *BalanceRepoMock) GetTransfers(ctx context.Context, userID int64) ([]*model.LedgerEntry, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	entries := r.ledger[userID]
+	result := make([]*model.LedgerEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		result = append(result, entries[i])
+	}
+	
+	return result, nil
+}
+
+func (r *BalanceRepoMock) GetLedger(ctx context.Context, userID int64) ([]*model.LedgerEntry, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var entries []*model.LedgerEntry
+	entries = append(entries, r.ledger[userID]...)
+	entries = append(entries, r.accruals[userID]...)
+	slices.SortStableFunc(entries, func(a, b *model.LedgerEntry) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	
+	return entries, nil
+}
+
+func (r *BalanceRepoMock) GetBalanceAt(ctx context.Context, userID int64, at time.Time) (float64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var balance float64
+	for _, entry := range r.movements(userID) {
+		if entry.CreatedAt.Before(at) {
+			balance += entry.Amount
+		}
+	}
+	
+	return balance, nil
+}
+
+func (r *BalanceRepoMock) StreamStatement(ctx context.Context, userID int64, from, to time.Time, fn func(*model.BalanceHistoryEntry) error) error {
+	r.mutex.RLock()
+	entries := r.movements(userID)
+	r.mutex.RUnlock()
+	
+	for _, entry := range entries {
+		if entry.CreatedAt.Before(from) || !entry.CreatedAt.Before(to) {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// movements возвращает записи журнала, списания и корректировки пользователя в порядке времени.
+func (r *BalanceRepoMock) movements(userID int64) []*model.BalanceHistoryEntry {
+	var entries []*model.BalanceHistoryEntry
+	for _, ledger := range [][]*model.LedgerEntry{r.ledger[userID], r.accruals[userID]} {
+		for _, entry := range ledger {
+			entries = append(entries, &model.BalanceHistoryEntry{
+				Operation:    string(entry.Operation),
+				Amount:       entry.Amount,
+				Order:        entry.OrderNumber,
+				Counterparty: entry.Counterparty,
+				CreatedAt:    entry.CreatedAt,
+			})
+		}
+	}
+	for _, withdrawal := range r.withdrawals[userID] {
+		entries = append(entries, &model.BalanceHistoryEntry{
+			Operation: model.BalanceHistoryWithdrawal,
+			Amount:    -withdrawal.Amount,
+			Order:     withdrawal.OrderNumber,
+			CreatedAt: withdrawal.ProcessedAt,
+		})
+	}
+	for _, adjustment := range r.adjustments[userID] {
+		if adjustment.Drift {
+			continue
+		}
+		entries = append(entries, &model.BalanceHistoryEntry{
+			Operation: model.BalanceHistoryAdjustment,
+			Amount:    adjustment.Amount,
+			Reason:    adjustment.Reason,
+			CreatedAt: adjustment.CreatedAt,
+		})
+	}
+	
+	slices.SortStableFunc(entries, func(a, b *model.BalanceHistoryEntry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	
+	return entries
+}
+
+func (r *BalanceRepoMock) Reserve(ctx context.Context, userID int64, amount float64, orderNumber string, ttl time.Duration, check WithdrawalCheck) (*model.Reservation, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if err := r.checkWithdrawal(userID, amount, orderNumber, check); err != nil {
+		return nil, err
+	}
+	
+	balance, exists := r.balances[userID]
+	if !exists || balance.Current < amount {
+		return nil, customerrors.ErrInsufficientFunds
+	}
+	
+	balance.Current -= amount
+	balance.Reserved += amount
+	
+	r.lastID++
+	now := time.Now()
+	reservation := &model.Reservation{
+		ID:          r.lastID,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		Status:      model.ReservationStatusActive,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+	r.reservations = append(r.reservations, reservation)
+	
+	result := *reservation
+	return &result, nil
+}
+
+func (r *BalanceRepoMock) ConfirmReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	return r.resolveReservation(userID, reservationID, model.ReservationStatusConfirmed)
+}
+
+func (r *BalanceRepoMock) CancelReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	return r.resolveReservation(userID, reservationID, model.ReservationStatusCancelled)
+}
+
+func (r *BalanceRepoMock) resolveReservation(userID, reservationID int64, target model.ReservationStatus) (*model.Reservation, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var reservation *model.Reservation
+	for _, candidate := range r.reservations {
+		if candidate.ID == reservationID && candidate.UserID == userID {
+			reservation = candidate
+			break
+		}
+	}
+	if reservation == nil {
+		return nil, customerrors.ErrReservationNotFound
+	}
+	
+	if reservation.Status == target {
+		result := *reservation
+		result.Replayed = true
+		return &result, nil
+	}
+	if reservation.Status != model.ReservationStatusActive {
+		return nil, customerrors.ErrReservationClosed
+	}
+	
+	now := time.Now()
+	status := target
+	if target == model.ReservationStatusConfirmed && !reservation.ExpiresAt.After(now) {
+		status = model.ReservationStatusExpired
+	}
+	
+	balance := r.balances[userID]
+	balance.Reserved -= reservation.Amount
+	if status == model.ReservationStatusConfirmed {
+		balance.Withdrawn += reservation.Amount
+		r.withdrawals[userID] = append(r.withdrawals[userID], &model.Withdrawal{
+			UserID:      userID,
+			OrderNumber: reservation.OrderNumber,
+			Amount:      reservation.Amount,
+			ProcessedAt: now,
+		})
+	} else {
+		balance.Current += reservation.Amount
+	}
+	
+	reservation.Status = status
+	reservation.ResolvedAt = &now
+	
+	if status != target {
+		return nil, customerrors.ErrReservationClosed
+	}
+	
+	result := *reservation
+	return &result, nil
+}
+
+func (r *BalanceRepoMock) cancelReservations(userID int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	for _, reservation := range r.reservations {
+		if reservation.UserID != userID || reservation.Status != model.ReservationStatusActive {
+			continue
+		}
+		balance := r.balances[userID]
+		balance.Reserved -= reservation.Amount
+		balance.Current += reservation.Amount
+		reservation.Status = model.ReservationStatusCancelled
+		reservation.ResolvedAt = &now
+	}
+}
+
+func (r *BalanceRepoMock) GetReservations(ctx context.Context, userID int64) ([]*model.Reservation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.Reservation
+	for i := len(r.reservations) - 1; i >= 0; i-- {
+		if r.reservations[i].UserID == userID {
+			reservation := *r.reservations[i]
+			result = append(result, &reservation)
+		}
+	}
+	
+	return result, nil
+}
+
+func (r *BalanceRepoMock) ExpireReservations(ctx context.Context, limit int) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	expired := 0
+	for _, reservation := range r.reservations {
+		if expired == limit {
+			break
+		}
+		if reservation.Status != model.ReservationStatusActive || reservation.ExpiresAt.After(now) {
+			continue
+		}
+		
+		balance := r.balances[reservation.UserID]
+		balance.Reserved -= reservation.Amount
+		balance.Current += reservation.Amount
+		
+		reservation.Status = model.ReservationStatusExpired
+		reservation.ResolvedAt = &now
+		expired++
+	}
+	
+	return expired, nil
+}
+
+func (r *BalanceRepoMock) recordAccrual(userID int64, amount float64) {
+	if _, exists := r.firstAccrual[userID]; !exists && amount > 0 {
+		r.firstAccrual[userID] = time.Now()
+	}
+	
+	r.accruals[userID] = append(r.accruals[userID], &model.LedgerEntry{
+		UserID:    userID,
+		Amount:    amount,
+		Operation: model.LedgerAccrual,
+		CreatedAt: time.Now(),
+	})
+}
+
+// AccrualPoints возвращает сумму начислений пользователя по заказам с момента since без надбавок уровней.
+func (r *BalanceRepoMock) AccrualPoints(userID int64, since time.Time) float64 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var points float64
+	for _, entry := range r.accruals[userID] {
+		if entry.Operation == model.LedgerAccrual && !entry.CreatedAt.Before(since) {
+			points += entry.Amount
+		}
+	}
+	return points
+}
+
+func (r *BalanceRepoMock) checkWithdrawal(userID int64, amount float64, orderNumber string, check WithdrawalCheck) error {
+	if check == nil {
+		return nil
+	}
+	
+	now := time.Now()
+	year, month, day := now.Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+	
+	stats := model.WithdrawalStats{
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		Now:         now,
+	}
+	
+	add := func(at time.Time, order string, sum float64) {
+		if !at.Before(startOfDay) {
+			stats.WithdrawnToday += sum
+		}
+		if !at.Before(startOfMonth) {
+			stats.WithdrawnThisMonth += sum
+		}
+		if order == orderNumber {
+			stats.WithdrawnForOrder += sum
+		}
+	}
+	for _, withdrawal := range r.withdrawals[userID] {
+		add(withdrawal.ProcessedAt, withdrawal.OrderNumber, withdrawal.Amount)
+	}
+	for _, reservation := range r.reservations {
+		if reservation.UserID == userID && reservation.Status == model.ReservationStatusActive {
+			add(reservation.CreatedAt, reservation.OrderNumber, reservation.Amount)
+		}
+	}
+	
+	if firstAccrual, exists := r.firstAccrual[userID]; exists {
+		stats.FirstAccrualAt = &firstAccrual
+	}
+	
+	return check(stats)
+}
+
+func (r *BalanceRepoMock) AddPoints(userID int64, amount float64, orderNumber string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.recordAccrual(userID, amount)
+	
+	balance, exists := r.balances[userID]
+	if !exists {
+		r.balances[userID] = &model.Balance{
+			UserID:    userID,
+			Current:   amount,
+			Withdrawn: 0,
+		}
+		return
+	}
+	
+	balance.Current += amount
+}
+
+type TierRepoMock struct {
+	tiers    map[int64]*model.UserTier
+	history  []*model.TierChange
+	users    *UserRepoMock
+	balances *BalanceRepoMock
+	lastRun  time.Time
+	mutex    sync.RWMutex
+	lastID   int64
+}
+
+func NewTierRepoMock() *TierRepoMock {
+	return &TierRepoMock{
+		tiers: make(map[int64]*model.UserTier),
+	}
+}
+
+func (r *TierRepoMock) GetUserTier(ctx context.Context, userID int64) (*model.UserTier, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	tier, exists := r.tiers[userID]
+	if !exists {
+		return nil, nil
+	}
+	
+	tierCopy := *tier
+	return &tierCopy, nil
+}
+
+func (r *TierRepoMock) GetTierHistory(ctx context.Context, userID int64) ([]*model.TierChange, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var history []*model.TierChange
+	for i := len(r.history) - 1; i >= 0; i-- {
+		if r.history[i].UserID == userID {
+			history = append(history, r.history[i])
+		}
+	}
+	return history, nil
+}
+
+func (r *TierRepoMock) GetTierPoints(ctx context.Context, since time.Time, afterUserID int64, limit int) ([]model.TierPoints, error) {
+	r.users.mutex.RLock()
+	userIDs := make([]int64, 0, len(r.users.users))
+	for id := range r.users.users {
+		if id > afterUserID {
+			userIDs = append(userIDs, id)
+		}
+	}
+	r.users.mutex.RUnlock()
+	
+	slices.Sort(userIDs)
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	
+	points := make([]model.TierPoints, 0, len(userIDs))
+	for _, id := range userIDs {
+		points = append(points, model.TierPoints{UserID: id, Points: r.balances.AccrualPoints(id, since)})
+	}
+	return points, nil
+}
+
+func (r *TierRepoMock) StartTierRecalculation(ctx context.Context, minInterval time.Duration) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	if !r.lastRun.IsZero() && r.lastRun.After(now.Add(-minInterval)) {
+		return false, nil
+	}
+	
+	r.lastRun = now
+	return true, nil
+}
+
+func (r *TierRepoMock) SetUserTier(ctx context.Context, userID int64, tier string, points float64) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var current string
+	if existing, exists := r.tiers[userID]; exists {
+		current = existing.Tier
+	}
+	
+	now := time.Now()
+	r.tiers[userID] = &model.UserTier{UserID: userID, Tier: tier, Points: points, UpdatedAt: now}
+	
+	if current == tier {
+		return false, nil
+	}
+	
+	r.lastID++
+	r.history = append(r.history, &model.TierChange{
+		ID:        r.lastID,
+		UserID:    userID,
+		From:      current,
+		To:        tier,
+		Points:    points,
+		ChangedAt: now,
+	})
+	return true, nil
+}
+
+type CampaignRepoMock struct {
+	campaigns map[int64]*model.Campaign
+	bonuses   []*model.CampaignBonus
+	balances  *BalanceRepoMock
+	mutex     sync.RWMutex
+	lastID    int64
+	lastBonusID int64
+}
+
+func NewCampaignRepoMock() *CampaignRepoMock {
+	return &CampaignRepoMock{
+		campaigns: make(map[int64]*model.Campaign),
+	}
+}
+
+func (r *CampaignRepoMock) CreateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.lastID++
+	campaign.ID = r.lastID
+	campaign.CreatedAt = time.Now()
+	campaign.UpdatedAt = campaign.CreatedAt
+	
+	campaignCopy := *campaign
+	r.campaigns[campaign.ID] = &campaignCopy
+	return nil
+}
+
+func (r *CampaignRepoMock) UpdateCampaign(ctx context.Context, campaign *model.Campaign) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	existing, exists := r.campaigns[campaign.ID]
+	if !exists {
+		return customerrors.ErrCampaignNotFound
+	}
+	
+	campaign.CreatedAt = existing.CreatedAt
+	campaign.UpdatedAt = time.Now()
+	
+	campaignCopy := *campaign
+	r.campaigns[campaign.ID] = &campaignCopy
+	return nil
+}
+
+func (r *CampaignRepoMock) DeleteCampaign(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if _, exists := r.campaigns[id]; !exists {
+		return customerrors.ErrCampaignNotFound
+	}
+	
+	delete(r.campaigns, id)
+	for _, bonus := range r.bonuses {
+		if bonus.CampaignID == id {
+			bonus.CampaignID = 0
+		}
+	}
+	return nil
+}
+
+func (r *CampaignRepoMock) GetCampaign(ctx context.Context, id int64) (*model.Campaign, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	campaign, exists := r.campaigns[id]
+	if !exists {
+		return nil, customerrors.ErrCampaignNotFound
+	}
+	
+	campaignCopy := *campaign
+	return &campaignCopy, nil
+}
+
+func (r *CampaignRepoMock) GetCampaigns(ctx context.Context, tenant string) ([]*model.Campaign, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var campaigns []*model.Campaign
+	for _, campaign := range r.campaigns {
+		if tenant != "" && campaign.Tenant != tenant {
+			continue
+		}
+		campaignCopy := *campaign
+		campaigns = append(campaigns, &campaignCopy)
+	}
+	slices.SortFunc(campaigns, func(a, b *model.Campaign) int {
+		return int(b.ID - a.ID)
+	})
+	return campaigns, nil
+}
+
+func (r *CampaignRepoMock) GetActiveCampaigns(ctx context.Context, tenant string, campaignType model.CampaignType) ([]*model.Campaign, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var campaigns []*model.Campaign
+	for _, campaign := range r.campaigns {
+		if campaign.Tenant == tenant && campaign.Type == campaignType && campaign.Active {
+			campaignCopy := *campaign
+			campaigns = append(campaigns, &campaignCopy)
+		}
+	}
+	slices.SortFunc(campaigns, func(a, b *model.Campaign) int {
+		return int(a.ID - b.ID)
+	})
+	return campaigns, nil
+}
+
+func (r *CampaignRepoMock) CreditCampaignBonus(ctx context.Context, bonus *model.CampaignBonus) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for _, existing := range r.bonuses {
+		if existing.CampaignID == bonus.CampaignID && existing.Reference == bonus.Reference {
+			return false, nil
+		}
+	}
+	
+	r.lastBonusID++
+	bonus.ID = r.lastBonusID
+	bonus.CreatedAt = time.Now()
+	
+	bonusCopy := *bonus
+	r.bonuses = append(r.bonuses, &bonusCopy)
+	
+	r.balances.mutex.Lock()
+	defer r.balances.mutex.Unlock()
+	
+	balance, exists := r.balances.balances[bonus.UserID]
+	if !exists {
+		balance = &model.Balance{UserID: bonus.UserID}
+		r.balances.balances[bonus.UserID] = balance
+	}
+	balance.Current += bonus.Amount
+	
+	return true, nil
+}
+
+func (r *CampaignRepoMock) GetCampaignBonuses(ctx context.Context, userID int64) ([]*model.CampaignBonus, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var bonuses []*model.CampaignBonus
+	for i := len(r.bonuses) - 1; i >= 0; i-- {
+		if r.bonuses[i].UserID == userID {
+			bonusCopy := *r.bonuses[i]
+			bonuses = append(bonuses, &bonusCopy)
+		}
+	}
+	return bonuses, nil
+}
+
+type FinanceRepoMock struct {
+	reports  map[string]*model.FinanceDailyReport
+	users    *UserRepoMock
+	orders   *OrderRepoMock
+	balances *BalanceRepoMock
+	lastRun  time.Time
+	mutex    sync.RWMutex
+}
+
+func NewFinanceRepoMock() *FinanceRepoMock {
+	return &FinanceRepoMock{
+		reports: make(map[string]*model.FinanceDailyReport),
+	}
+}
+
+func (r *FinanceRepoMock) StartReportMaterialization(ctx context.Context, minInterval time.Duration) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	if !r.lastRun.IsZero() && r.lastRun.After(now.Add(-minInterval)) {
+		return false, nil
+	}
+	
+	r.lastRun = now
+	return true, nil
+}
+
+func (r *FinanceRepoMock) GetNextReportDay(ctx context.Context) (*time.Time, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var last time.Time
+	for _, report := range r.reports {
+		day, _ := time.Parse(time.DateOnly, report.Day)
+		if day.After(last) {
+			last = day
+		}
+	}
+	if !last.IsZero() {
+		next := last.AddDate(0, 0, 1)
+		return &next, nil
+	}
+	
+	r.users.mutex.RLock()
+	defer r.users.mutex.RUnlock()
+	
+	var first *time.Time
+	for _, user := range r.users.users {
+		day := user.CreatedAt.UTC().Truncate(24 * time.Hour)
+		if first == nil || day.Before(*first) {
+			first = &day
+		}
+	}
+	
+	return first, nil
+}
+
+func (r *FinanceRepoMock) MaterializeReport(ctx context.Context, day time.Time, snapshotLiability bool) error {
+	from := day.UTC()
+	to := from.AddDate(0, 0, 1)
+	inDay := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(to)
+	}
+	
+	reports := make(map[string]*model.FinanceDailyReport)
+	tenants := make(map[int64]string)
+	
+	r.users.mutex.RLock()
+	for _, user := range r.users.users {
+		if !user.CreatedAt.Before(to) {
+			continue
+		}
+		tenants[user.ID] = user.Tenant
+		if reports[user.Tenant] == nil {
+			reports[user.Tenant] = &model.FinanceDailyReport{Tenant: user.Tenant, Day: from.Format(time.DateOnly)}
+		}
+		if inDay(user.CreatedAt) {
+			reports[user.Tenant].NewUsers++
+		}
+	}
+	r.users.mutex.RUnlock()
+	
+	r.orders.mutex.RLock()
+	for orderID, history := range r.orders.history {
+		order := r.orders.orders[orderID]
+		report := reports[order.Tenant]
+		if report == nil {
+			continue
+		}
+		seen := make(map[model.OrderStatus]bool)
+		for _, change := range history {
+			if !inDay(change.ChangedAt) || seen[change.Status] {
+				continue
+			}
+			seen[change.Status] = true
+			switch change.Status {
+			case model.OrderStatusProcessed:
+				report.OrdersProcessed++
+				report.Accrued += order.Accrual
+			case model.OrderStatusInvalid:
+				report.OrdersInvalid++
+			case model.OrderStatusUnregistered:
+				report.OrdersUnregistered++
+			}
+		}
+	}
+	r.orders.mutex.RUnlock()
+	
+	r.balances.mutex.RLock()
+	for userID, withdrawals := range r.balances.withdrawals {
+		report := reports[tenants[userID]]
+		if report == nil {
+			continue
+		}
+		for _, withdrawal := range withdrawals {
+			if inDay(withdrawal.ProcessedAt) {
+				report.Withdrawn += withdrawal.Amount
+			}
+		}
+	}
+	if snapshotLiability {
+		for _, report := range reports {
+			report.Liability = new(float64)
+		}
+		for userID, balance := range r.balances.balances {
+			if report := reports[tenants[userID]]; report != nil {
+				*report.Liability += balance.Current
+			}
+		}
+	}
+	r.balances.mutex.RUnlock()
+	
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for tenant, report := range reports {
+		key := tenant + "/" + report.Day
+		if existing := r.reports[key]; existing != nil && existing.Liability != nil {
+			report.Liability = existing.Liability
+		}
+		report.ComputedAt = time.Now()
+		r.reports[key] = report
+	}
+	
+	return nil
+}
+
+func (r *FinanceRepoMock) GetReports(ctx context.Context, tenant string, from, to time.Time) ([]*model.FinanceDailyReport, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	fromDay, toDay := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	
+	var reports []*model.FinanceDailyReport
+	for _, report := range r.reports {
+		if report.Tenant == tenant && report.Day >= fromDay && report.Day <= toDay {
+			copied := *report
+			reports = append(reports, &copied)
+		}
+	}
+	slices.SortFunc(reports, func(a, b *model.FinanceDailyReport) int {
+		return strings.Compare(a.Day, b.Day)
+	})
+	
+	return reports, nil
+}
+
+type ReconciliationRepoMock struct {
+	runs          []*model.ReconciliationRun
+	discrepancies []*model.ReconciliationDiscrepancy
+	users         *UserRepoMock
+	orders        *OrderRepoMock
+	balances      *BalanceRepoMock
+	mutex         sync.RWMutex
+}
+
+func NewReconciliationRepoMock() *ReconciliationRepoMock {
+	return &ReconciliationRepoMock{}
+}
+
+func (r *ReconciliationRepoMock) StartRun(ctx context.Context, autoFix bool, minInterval time.Duration) (*model.ReconciliationRun, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	if minInterval > 0 && len(r.runs) > 0 && r.runs[len(r.runs)-1].StartedAt.After(now.Add(-minInterval)) {
+		return nil, nil
+	}
+	
+	run := &model.ReconciliationRun{
+		ID:        int64(len(r.runs) + 1),
+		AutoFix:   autoFix,
+		StartedAt: now,
+	}
+	r.runs = append(r.runs, run)
+	
+	copied := *run
+	return &copied, nil
+}
+
+func (r *ReconciliationRepoMock) FinishRun(ctx context.Context, run *model.ReconciliationRun) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	now := time.Now()
+	run.FinishedAt = &now
+	
+	copied := *run
+	r.runs[run.ID-1] = &copied
+	return nil
+}
+
+func (r *ReconciliationRepoMock) GetProcessedOrders(ctx context.Context, limit int) ([]*model.Order, error) {
+	r.orders.mutex.RLock()
+	defer r.orders.mutex.RUnlock()
+	
+	var orders []*model.Order
+	for id, order := range r.orders.orders {
+		if order.Status != model.OrderStatusProcessed || r.orders.claims[id] != "" {
+			continue
+		}
+		copied := *order
+		orders = append(orders, &copied)
+	}
+	slices.SortFunc(orders, func(a, b *model.Order) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	
+	if limit > 0 && len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders, nil
+}
+
+func (r *ReconciliationRepoMock) FindOrderLedgerDiscrepancies(ctx context.Context) ([]*model.ReconciliationDiscrepancy, error) {
+	r.orders.mutex.RLock()
+	defer r.orders.mutex.RUnlock()
+	r.balances.mutex.RLock()
+	defer r.balances.mutex.RUnlock()
+	
+	var discrepancies []*model.ReconciliationDiscrepancy
+	for id, order := range r.orders.orders {
+		if order.Status != model.OrderStatusProcessed || r.orders.claims[id] != "" {
+			continue
+		}
+		credited := r.balances.orderAccruals[id] + r.balances.orderAdjustments[id]
+		if math.Abs(order.Accrual-credited) >= model.ReconciliationTolerance {
+			discrepancies = append(discrepancies, &model.ReconciliationDiscrepancy{
+				Kind:        model.ReconciliationOrderLedger,
+				Tenant:      order.Tenant,
+				UserID:      order.UserID,
+				OrderID:     id,
+				OrderNumber: order.Number,
+				Expected:    order.Accrual,
+				Actual:      credited,
+			})
+		}
+	}
+	slices.SortFunc(discrepancies, func(a, b *model.ReconciliationDiscrepancy) int {
+		return cmp.Compare(a.OrderID, b.OrderID)
+	})
+	
+	return discrepancies, nil
+}
+
+func (r *ReconciliationRepoMock) FindBalanceDiscrepancies(ctx context.Context) (int, []*model.ReconciliationDiscrepancy, error) {
+	tenants := r.tenants()
+	
+	r.balances.mutex.RLock()
+	defer r.balances.mutex.RUnlock()
+	
+	var discrepancies []*model.ReconciliationDiscrepancy
+	for userID, balance := range r.balances.balances {
+		expectedCurrent, expectedWithdrawn := r.expectedBalance(userID)
+		discrepancies = append(discrepancies, balanceDiscrepancies(tenants[userID], userID, balance.Current, balance.Withdrawn, expectedCurrent, expectedWithdrawn)...)
+	}
+	slices.SortStableFunc(discrepancies, func(a, b *model.ReconciliationDiscrepancy) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	
+	return len(r.balances.balances), discrepancies, nil
+}
+
+// expectedBalance пересчитывает остаток и сумму списаний пользователя по истории операций.
+// Вызывается под блокировкой балансов.
+func (r *ReconciliationRepoMock) expectedBalance(userID int64) (float64, float64) {
+	var current, withdrawn float64
+	for _, ledger := range [][]*model.LedgerEntry{r.balances.ledger[userID], r.balances.accruals[userID]} {
+		for _, entry := range ledger {
+			current += entry.Amount
+		}
+	}
+	for _, withdrawal := range r.balances.withdrawals[userID] {
+		withdrawn += withdrawal.Amount
+	}
+	for _, adjustment := range r.balances.adjustments[userID] {
+		if !adjustment.Drift {
+			current += adjustment.Amount
+		}
+	}
+	for _, reservation := range r.balances.reservations {
+		if reservation.UserID == userID && reservation.Status == model.ReservationStatusActive {
+			current -= reservation.Amount
+		}
+	}
+	return current - withdrawn, withdrawn
+}
+
+func (r *ReconciliationRepoMock) tenants() map[int64]string {
+	r.users.mutex.RLock()
+	defer r.users.mutex.RUnlock()
+	
+	tenants := make(map[int64]string, len(r.users.users))
+	for _, user := range r.users.users {
+		tenants[user.ID] = user.Tenant
+	}
+	return tenants
+}
+
+func (r *ReconciliationRepoMock) CorrectOrderAccrual(ctx context.Context, order *model.Order, accrual float64, reason string) (*model.BalanceAdjustment, error) {
+	r.orders.mutex.Lock()
+	defer r.orders.mutex.Unlock()
+	r.balances.mutex.Lock()
+	defer r.balances.mutex.Unlock()
+	
+	stored, exists := r.orders.orders[order.ID]
+	if !exists {
+		return nil, customerrors.ErrOrderNotFound
+	}
+	if stored.Status != model.OrderStatusProcessed || math.Abs(stored.Accrual-order.Accrual) >= model.ReconciliationTolerance {
+		return nil, customerrors.ErrReconciliationStale
+	}
+	
+	balance, exists := r.balances.balances[order.UserID]
+	if !exists {
+		return nil, customerrors.ErrUserBalanceNotFound
+	}
+	
+	amount := accrual - stored.Accrual
+	if balance.Current+amount < 0 {
+		return nil, customerrors.ErrInsufficientFunds
+	}
+	
+	stored.Accrual = accrual
+	balance.Current += amount
+	
+	r.balances.lastID++
+	adjustment := &model.BalanceAdjustment{
+		ID:        r.balances.lastID,
+		UserID:    order.UserID,
+		Amount:    amount,
+		Reason:    reason,
+		Actor:     model.ReconciliationActor,
+		CreatedAt: time.Now(),
+	}
+	r.balances.adjustments[order.UserID] = append(r.balances.adjustments[order.UserID], adjustment)
+	r.balances.orderAdjustments[order.ID] += amount
+	
+	return adjustment, nil
+}
+
+func (r *ReconciliationRepoMock) CorrectBalance(ctx context.Context, userID int64, reason string) (*model.BalanceAdjustment, error) {
+	r.balances.mutex.Lock()
+	defer r.balances.mutex.Unlock()
+	
+	balance, exists := r.balances.balances[userID]
+	if !exists {
+		return nil, customerrors.ErrUserBalanceNotFound
+	}
+	
+	expectedCurrent, expectedWithdrawn := r.expectedBalance(userID)
+	if len(balanceDiscrepancies("", userID, balance.Current, balance.Withdrawn, expectedCurrent, expectedWithdrawn)) == 0 {
+		return nil, nil
+	}
+	if expectedCurrent < 0 {
+		return nil, customerrors.ErrInsufficientFunds
+	}
+	
+	r.balances.lastID++
+	adjustment := &model.BalanceAdjustment{
+		ID:        r.balances.lastID,
+		UserID:    userID,
+		Amount:    expectedCurrent - balance.Current,
+		Withdrawn: expectedWithdrawn - balance.Withdrawn,
+		Drift:     true,
+		Reason:    reason,
+		Actor:     model.ReconciliationActor,
+		CreatedAt: time.Now(),
+	}
+	r.balances.adjustments[userID] = append(r.balances.adjustments[userID], adjustment)
+	
+	balance.Current = expectedCurrent
+	balance.Withdrawn = expectedWithdrawn
+	return adjustment, nil
+}
+
+func (r *ReconciliationRepoMock) SaveDiscrepancy(ctx context.Context, discrepancy *model.ReconciliationDiscrepancy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	discrepancy.ID = int64(len(r.discrepancies) + 1)
+	discrepancy.CreatedAt = time.Now()
+	
+	copied := *discrepancy
+	r.discrepancies = append(r.discrepancies, &copied)
+	return nil
+}
+
+// Discrepancies возвращает расхождения, записанные запуском сверки runID.
+func (r *ReconciliationRepoMock) Discrepancies(runID int64) []*model.ReconciliationDiscrepancy {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var discrepancies []*model.ReconciliationDiscrepancy
+	for _, discrepancy := range r.discrepancies {
+		if discrepancy.RunID == runID {
+			copied := *discrepancy
+			discrepancies = append(discrepancies, &copied)
+		}
+	}
+	return discrepancies
+}
+
+// SetBalance заменяет сохраненные остаток и сумму списаний пользователя, не записывая операций.
+func (r *BalanceRepoMock) SetBalance(userID int64, current, withdrawn float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.balances[userID] = &model.Balance{
+		UserID:    userID,
+		Current:   current,
+		Withdrawn: withdrawn,
+	}
+}
+
+type ReferralRepoMock struct {
+	codes     map[int64]*model.ReferralCode
+	referrals []*model.Referral
+	users     *UserRepoMock
+	balances  *BalanceRepoMock
+	mutex     sync.RWMutex
+	lastID    int64
+}
+
+func NewReferralRepoMock() *ReferralRepoMock {
+	return &ReferralRepoMock{
+		codes: make(map[int64]*model.ReferralCode),
+	}
+}
+
+func (r *ReferralRepoMock) GetReferralCode(ctx context.Context, userID int64) (*model.ReferralCode, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	code, exists := r.codes[userID]
+	if !exists {
+		return nil, nil
+	}
+	
+	codeCopy := *code
+	return &codeCopy, nil
+}
+
+func (r *ReferralRepoMock) SaveReferralCode(ctx context.Context, code *model.ReferralCode) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if existing, exists := r.codes[code.UserID]; exists {
+		existing.IP = code.IP
+		code.Code = existing.Code
+		return nil
+	}
+	
+	for _, existing := range r.codes {
+		if existing.Tenant == code.Tenant && existing.Code == code.Code {
+			return errors.New("реферальный код уже выдан")
+		}
+	}
+	
+	codeCopy := *code
+	r.codes[code.UserID] = &codeCopy
+	return nil
+}
+
+func (r *ReferralRepoMock) FindReferralCode(ctx context.Context, tenant, code string) (*model.ReferralCode, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	for _, existing := range r.codes {
+		if existing.Tenant == tenant && existing.Code == code {
+			codeCopy := *existing
+			return &codeCopy, nil
+		}
+	}
+	return nil, customerrors.ErrInvalidReferralCode
+}
+
+func (r *ReferralRepoMock) CreateReferral(ctx context.Context, referral *model.Referral) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for _, existing := range r.referrals {
+		if existing.RefereeID == referral.RefereeID {
+			return errors.New("приглашение пользователя уже записано")
+		}
+	}
+	
+	r.lastID++
+	referral.ID = r.lastID
+	referral.CreatedAt = time.Now()
+	
+	referralCopy := *referral
+	r.referrals = append(r.referrals, &referralCopy)
+	return nil
+}
+
+func (r *ReferralRepoMock) redactIP(refereeID int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	for _, referral := range r.referrals {
+		if referral.RefereeID == refereeID {
+			referral.IP = ""
+		}
+	}
+}
+
+func (r *ReferralRepoMock) CountReferralsByIP(ctx context.Context, ip string, since time.Time) (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	count := 0
+	for _, referral := range r.referrals {
+		if referral.IP == ip && !referral.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *ReferralRepoMock) RewardReferral(ctx context.Context, refereeID, orderID int64, referrerBonus, refereeBonus float64, maxRewards int) (*model.Referral, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var referral *model.Referral
+	for _, existing := range r.referrals {
+		if existing.RefereeID == refereeID && existing.Status == model.ReferralStatusPending {
+			referral = existing
+		}
+	}
+	if referral == nil {
+		return nil, nil
+	}
+	
+	rewarded := 0
+	for _, existing := range r.referrals {
+		if existing.ReferrerID == referral.ReferrerID && existing.Status == model.ReferralStatusRewarded {
+			rewarded++
+		}
+	}
+	
+	if maxRewards > 0 && rewarded >= maxRewards {
+		referral.Status = model.ReferralStatusRejected
+		referral.RejectReason = model.ReferralRejectReferrerLimit
+	} else {
+		now := time.Now()
+		referral.Status = model.ReferralStatusRewarded
+		referral.ReferrerBonus = referrerBonus
+		referral.RefereeBonus = refereeBonus
+		referral.RewardedAt = &now
+		
+		r.balances.mutex.Lock()
+		for userID, amount := range map[int64]float64{referral.ReferrerID: referrerBonus, referral.RefereeID: refereeBonus} {
+			if amount <= 0 {
+				continue
+			}
+			balance, exists := r.balances.balances[userID]
+			if !exists {
+				balance = &model.Balance{UserID: userID}
+				r.balances.balances[userID] = balance
+			}
+			balance.Current += amount
+		}
+		r.balances.mutex.Unlock()
+	}
+	
+	referralCopy := *referral
+	return &referralCopy, nil
+}
+
+func (r *ReferralRepoMock) GetReferrals(ctx context.Context, referrerID int64) ([]*model.Referral, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var referrals []*model.Referral
+	for i := len(r.referrals) - 1; i >= 0; i-- {
+		if r.referrals[i].ReferrerID != referrerID {
+			continue
+		}
+		referralCopy := *r.referrals[i]
+		if user, err := r.users.GetUserByID(ctx, referralCopy.RefereeID); err == nil {
+			referralCopy.RefereeLogin = user.Login
+		}
+		referrals = append(referrals, &referralCopy)
+	}
+	return referrals, nil
+}
+
+type EventRepoMock struct {
+	events   []*model.UserEvent
+	listener *NotificationListenerMock
+	mutex    sync.RWMutex
+	lastID   int64
+}
+
+func NewEventRepoMock(listener *NotificationListenerMock) *EventRepoMock {
+	return &EventRepoMock{
+		listener: listener,
+		lastID:   0,
+	}
+}
+
+func (r *EventRepoMock) AppendEvent(ctx context.Context, userID int64, eventType model.UserEventType, payload []byte) (int64, error) {
+	r.mutex.Lock()
+	r.lastID++
+	event := &model.UserEvent{
+		ID:        r.lastID,
+		UserID:    userID,
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	r.events = append(r.events, event)
+	r.mutex.Unlock()
+	
+	r.listener.Notify(UserEventsChannel, strconv.FormatInt(userID, 10))
+	
+	return event.ID, nil
+}
+
+func (r *EventRepoMock) GetEventsAfter(ctx context.Context, userID int64, afterID int64, limit int) ([]*model.UserEvent, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.UserEvent
+	
+	for _, event := range r.events {
+		if len(result) >= limit {
+			break
+		}
+		if event.UserID == userID && event.ID > afterID {
+			result = append(result, event)
+		}
+	}
+	
+	return result, nil
+}
+
+func (r *EventRepoMock) GetLastEventID(ctx context.Context, userID int64) (int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var lastID int64
+	
+	for _, event := range r.events {
+		if event.UserID == userID {
+			lastID = event.ID
+		}
+	}
+	
+	return lastID, nil
+}
+
+func (r *EventRepoMock) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	kept := r.events[:0]
+	var deleted int64
+	
+	for _, event := range r.events {
+		if event.CreatedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	r.events = kept
+	
+	return deleted, nil
+}
+
+type WebhookRepoMock struct {
+	subscriptions map[int64]*model.WebhookSubscription
+	deliveries    map[int64]*model.WebhookDelivery
+	mutex         sync.RWMutex
+	lastID        int64
+}
+
+func NewWebhookRepoMock() *WebhookRepoMock {
+	return &WebhookRepoMock{
+		subscriptions: make(map[int64]*model.WebhookSubscription),
+		deliveries:    make(map[int64]*model.WebhookDelivery),
+		lastID:        0,
+	}
+}
+
+func (r *WebhookRepoMock) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.lastID++
+	sub.ID = r.lastID
+	sub.Active = true
+	sub.CreatedAt = time.Now()
+	
+	stored := *sub
+	r.subscriptions[sub.ID] = &stored
+	
+	return sub.ID, nil
+}
+
+func (r *WebhookRepoMock) GetSubscriptions(ctx context.Context, tenant string) ([]*model.WebhookSubscription, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.WebhookSubscription
+	
+	for _, sub := range r.subscriptions {
+		if tenant != "" && sub.Tenant != tenant {
+			continue
+		}
+		withoutSecret := *sub
+		withoutSecret.Secret = ""
+		result = append(result, &withoutSecret)
+	}
+	
+	return result, nil
+}
+
+func (r *WebhookRepoMock) DeleteSubscription(ctx context.Context, tenant string, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	sub, exists := r.subscriptions[id]
+	if !exists || (tenant != "" && sub.Tenant != tenant) {
+		return customerrors.ErrWebhookNotFound
+	}
+	
+	delete(r.subscriptions, id)
+	return nil
+}
+
+func (r *WebhookRepoMock) FanOutOutbox(ctx context.Context, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (r *WebhookRepoMock) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	var result []*model.WebhookDelivery
+	
+	for _, d := range r.deliveries {
+		if len(result) >= limit {
+			break
+		}
+		if d.Status != model.WebhookDeliveryPending || d.NextAttemptAt.After(time.Now()) {
+			continue
+		}
+		d.Attempts++
+		d.NextAttemptAt = time.Now().Add(lease)
+		claimed := *d
+		result = append(result, &claimed)
+	}
+	
+	return result, nil
+}
+
+func (r *WebhookRepoMock) MarkDelivered(ctx context.Context, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if d, exists := r.deliveries[id]; exists {
+		d.Status = model.WebhookDeliveryDelivered
+		d.LastError = ""
+	}
+	
+	return nil
+}
+
+func (r *WebhookRepoMock) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string, dead bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	if d, exists := r.deliveries[id]; exists {
+		d.NextAttemptAt = nextAttemptAt
+		d.LastError = lastError
+		if dead {
+			d.Status = model.WebhookDeliveryDead
+		}
+	}
+	
+	return nil
+}
+
+func (r *WebhookRepoMock) GetDeliveries(ctx context.Context, tenant string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.WebhookDelivery
+	
+	for _, d := range r.deliveries {
+		if len(result) >= limit {
+			break
+		}
+		if !r.deliveryInTenant(d, tenant) {
+			continue
+		}
+		if status == "" || d.Status == status {
+			result = append(result, d)
+		}
+	}
+	
+	return result, nil
+}
+
+func (r *WebhookRepoMock) ReplayDelivery(ctx context.Context, tenant string, id int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	d, exists := r.deliveries[id]
+	if !exists || !r.deliveryInTenant(d, tenant) {
+		return customerrors.ErrWebhookNotFound
+	}
+	
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	d.LastError = ""
+	return nil
+}
+
+// deliveryInTenant проверяет, что доставка относится к подписке арендатора. Пустой арендатор — любой.
+func (r *WebhookRepoMock) deliveryInTenant(d *model.WebhookDelivery, tenant string) bool {
+	if tenant == "" {
+		return true
+	}
+	sub, exists := r.subscriptions[d.SubscriptionID]
+	return exists && sub.Tenant == tenant
+}
+
+type AuditRepoMock struct {
+	events []*model.AuditEvent
+	mutex  sync.RWMutex
+}
+
+func NewAuditRepoMock() *AuditRepoMock {
+	return &AuditRepoMock{}
+}
+
+func (r *AuditRepoMock) AppendAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	event.ID = int64(len(r.events) + 1)
+	event.CreatedAt = time.Now()
+	
+	stored := *event
+	r.events = append(r.events, &stored)
+	
+	return nil
+}
+
+func (r *AuditRepoMock) redactLogin(userID int64, login, anonymizedLogin string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	target := "user:" + strconv.FormatInt(userID, 10)
+	for _, event := range r.events {
+		switch {
+		case event.Action == model.AuditUserRegistered && event.Target == target:
+			var after map[string]any
+			if json.Unmarshal(event.After, &after) != nil || after["login"] == nil {
+				continue
+			}
+			after["login"] = anonymizedLogin
+			event.After, _ = json.Marshal(after)
+		case event.Action == model.AuditUserLoginFailed && event.Target == login:
+			event.Target = anonymizedLogin
+		}
+	}
+}
+
+func (r *AuditRepoMock) GetAuditEvents(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	var result []*model.AuditEvent
+	
+	for _, event := range r.events {
+		if len(result) >= filter.Limit {
+			break
+		}
+		if event.ID <= filter.AfterID ||
+			(filter.Tenant != "" && event.Tenant != filter.Tenant) ||
+			(filter.Actor != "" && event.Actor != filter.Actor) ||
+			(filter.Action != "" && event.Action != filter.Action) ||
+			(filter.Target != "" && event.Target != filter.Target) ||
+			(!filter.From.IsZero() && event.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !event.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		result = append(result, event)
+	}
+	
+	return result, nil
+}
+
+type NotificationListenerMock struct {
+	mutex       sync.Mutex
+	subscribers map[string][]chan string
+}
+
+func NewNotificationListenerMock() *NotificationListenerMock {
+	return &NotificationListenerMock{
+		subscribers: make(map[string][]chan string),
+	}
+}
+
+func (l *NotificationListenerMock) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	
+	out := make(chan string, 64)
+	l.subscribers[channel] = append(l.subscribers[channel], out)
+	
+	go func() {
+		<-ctx.Done()
+		
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		
+		subscribers := l.subscribers[channel]
+		for i, ch := range subscribers {
+			if ch == out {
+				l.subscribers[channel] = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		close(out)
+	}()
+	
+	return out, nil
+}
+
+func (l *NotificationListenerMock) Notify(channel, payload string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	
+	for _, ch := range l.subscribers[channel] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
 }
@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	stderrors "errors"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// Reserve переносит баллы из доступного баланса в резерв под заказ на время ttl.
+// Непустой check вызывается после блокировки баланса, как и при списании.
+func (r *BalanceRepo) Reserve(ctx context.Context, userID int64, amount float64, orderNumber string, ttl time.Duration, check WithdrawalCheck) (*model.Reservation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		currentBalance float64
+		tenant         string
+	)
+
+	balanceQuery := `
+		SELECT b.current, u.tenant
+		FROM balances b
+		JOIN users u ON u.id = b.user_id
+		WHERE b.user_id = $1
+		FOR UPDATE OF b
+	`
+	if err := tx.QueryRow(ctx, balanceQuery, userID).Scan(&currentBalance, &tenant); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrUserBalanceNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения текущего баланса: %w", err)
+	}
+
+	if err := checkWithdrawal(ctx, tx, userID, amount, orderNumber, check); err != nil {
+		return nil, err
+	}
+
+	if currentBalance < amount {
+		return nil, fmt.Errorf("%w", errors.ErrInsufficientFunds)
+	}
+
+	updateBalanceQuery := `
+		UPDATE balances
+		SET current = current - $1, reserved = reserved + $1
+		WHERE user_id = $2
+	`
+	if _, err := tx.Exec(ctx, updateBalanceQuery, amount, userID); err != nil {
+		return nil, fmt.Errorf("ошибка резервирования средств: %w", err)
+	}
+
+	reservation := &model.Reservation{
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      amount,
+		Status:      model.ReservationStatusActive,
+	}
+
+	reservationQuery := `
+		INSERT INTO balance_reservations (tenant, user_id, order_number, amount, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6))
+		RETURNING id, expires_at, created_at
+	`
+	if err := tx.QueryRow(ctx, reservationQuery, tenant, userID, orderNumber, amount, reservation.Status, ttl.Seconds()).
+		Scan(&reservation.ID, &reservation.ExpiresAt, &reservation.CreatedAt); err != nil {
+		return nil, fmt.Errorf("ошибка создания резерва: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation превращает активный резерв в списание. Резерв с истекшим сроком
+// снимается, а подтверждение отклоняется.
+func (r *BalanceRepo) ConfirmReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	return r.resolveReservation(ctx, userID, reservationID, model.ReservationStatusConfirmed)
+}
+
+// CancelReservation снимает активный резерв и возвращает баллы в доступный баланс.
+func (r *BalanceRepo) CancelReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	return r.resolveReservation(ctx, userID, reservationID, model.ReservationStatusCancelled)
+}
+
+// resolveReservation переводит активный резерв в конечный статус. Повторный перевод в тот же
+// статус возвращает резерв без изменений с отметкой Replayed. Резерв блокируется раньше баланса,
+// как и при снятии просроченных резервов, чтобы не возникало взаимоблокировок.
+func (r *BalanceRepo) resolveReservation(ctx context.Context, userID, reservationID int64, target model.ReservationStatus) (*model.Reservation, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		reservation model.Reservation
+		tenant      string
+		expired     bool
+	)
+
+	reservationQuery := `
+		SELECT id, user_id, order_number, amount, status, expires_at, created_at, resolved_at, tenant, expires_at <= NOW()
+		FROM balance_reservations
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, reservationQuery, reservationID, userID).Scan(
+		&reservation.ID,
+		&reservation.UserID,
+		&reservation.OrderNumber,
+		&reservation.Amount,
+		&reservation.Status,
+		&reservation.ExpiresAt,
+		&reservation.CreatedAt,
+		&reservation.ResolvedAt,
+		&tenant,
+		&expired,
+	); err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w", errors.ErrReservationNotFound)
+		}
+		return nil, fmt.Errorf("ошибка получения резерва: %w", err)
+	}
+
+	if reservation.Status == target {
+		reservation.Replayed = true
+		return &reservation, nil
+	}
+	if reservation.Status != model.ReservationStatusActive {
+		return nil, fmt.Errorf("%w: статус %s", errors.ErrReservationClosed, reservation.Status)
+	}
+
+	status := target
+	if target == model.ReservationStatusConfirmed && expired {
+		status = model.ReservationStatusExpired
+	}
+
+	if status == model.ReservationStatusConfirmed {
+		updateBalanceQuery := `
+			UPDATE balances
+			SET reserved = reserved - $1, withdrawn = withdrawn + $1
+			WHERE user_id = $2
+		`
+		if _, err := tx.Exec(ctx, updateBalanceQuery, reservation.Amount, userID); err != nil {
+			return nil, fmt.Errorf("ошибка списания зарезервированных средств: %w", err)
+		}
+
+		withdrawalQuery := `
+			INSERT INTO withdrawals (tenant, user_id, order_number, amount)
+			VALUES ($1, $2, $3, $4)
+		`
+		if _, err := tx.Exec(ctx, withdrawalQuery, tenant, userID, reservation.OrderNumber, reservation.Amount); err != nil {
+			return nil, fmt.Errorf("ошибка создания записи о списании: %w", err)
+		}
+
+		payload := model.WebhookWithdrawalPayload{
+			UserID: userID,
+			Order:  reservation.OrderNumber,
+			Sum:    reservation.Amount,
+		}
+		if err := insertOutbox(ctx, tx, tenant, model.WebhookEventBalanceWithdrawn, payload); err != nil {
+			return nil, err
+		}
+	} else if err := releaseReserved(ctx, tx, userID, reservation.Amount); err != nil {
+		return nil, err
+	}
+
+	updateReservationQuery := `
+		UPDATE balance_reservations
+		SET status = $1, resolved_at = NOW()
+		WHERE id = $2
+		RETURNING resolved_at
+	`
+	if err := tx.QueryRow(ctx, updateReservationQuery, status, reservation.ID).Scan(&reservation.ResolvedAt); err != nil {
+		return nil, fmt.Errorf("ошибка обновления статуса резерва: %w", err)
+	}
+	reservation.Status = status
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	if status != target {
+		return nil, fmt.Errorf("%w: срок резерва истек", errors.ErrReservationClosed)
+	}
+
+	return &reservation, nil
+}
+
+func (r *BalanceRepo) GetReservations(ctx context.Context, userID int64) ([]*model.Reservation, error) {
+	query := `
+		SELECT id, user_id, order_number, amount, status, expires_at, created_at, resolved_at
+		FROM balance_reservations
+		WHERE user_id = $1
+		ORDER BY id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения резервов: %w", err)
+	}
+	defer rows.Close()
+
+	var reservations []*model.Reservation
+	for rows.Next() {
+		var reservation model.Reservation
+		if err := rows.Scan(
+			&reservation.ID,
+			&reservation.UserID,
+			&reservation.OrderNumber,
+			&reservation.Amount,
+			&reservation.Status,
+			&reservation.ExpiresAt,
+			&reservation.CreatedAt,
+			&reservation.ResolvedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки резерва: %w", err)
+		}
+		reservations = append(reservations, &reservation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по резервам: %w", err)
+	}
+
+	return reservations, nil
+}
+
+// ExpireReservations снимает не более limit резервов с истекшим сроком и возвращает их число.
+// Резервы, заблокированные другими транзакциями, пропускаются, а балансы обновляются в порядке
+// возрастания ID пользователя, поэтому несколько экземпляров могут снимать резервы одновременно.
+func (r *BalanceRepo) ExpireReservations(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	claimQuery := `
+		SELECT id, user_id, amount
+		FROM balance_reservations
+		WHERE status = $1 AND expires_at <= NOW()
+		ORDER BY user_id, id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(ctx, claimQuery, model.ReservationStatusActive, limit)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения просроченных резервов: %w", err)
+	}
+
+	var (
+		ids     []int64
+		userIDs []int64
+		amounts = make(map[int64]float64)
+	)
+	for rows.Next() {
+		var (
+			id     int64
+			userID int64
+			amount float64
+		)
+		if err := rows.Scan(&id, &userID, &amount); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("ошибка сканирования просроченного резерва: %w", err)
+		}
+		ids = append(ids, id)
+		if _, ok := amounts[userID]; !ok {
+			userIDs = append(userIDs, userID)
+		}
+		amounts[userID] += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("ошибка итерации по просроченным резервам: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	expireQuery := `
+		UPDATE balance_reservations
+		SET status = $1, resolved_at = NOW()
+		WHERE id = ANY($2)
+	`
+	if _, err := tx.Exec(ctx, expireQuery, model.ReservationStatusExpired, ids); err != nil {
+		return 0, fmt.Errorf("ошибка снятия просроченных резервов: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := releaseReserved(ctx, tx, userID, amounts[userID]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// releaseReserved возвращает зарезервированные баллы в доступный баланс.
+// cancelUserReservations снимает все активные резервы пользователя в рамках транзакции tx
+// и возвращает баллы в доступный баланс. Резервы блокируются раньше баланса, как и при снятии
+// одного резерва.
+func cancelUserReservations(ctx context.Context, tx pgx.Tx, userID int64) error {
+	query := `
+		UPDATE balance_reservations
+		SET status = $1, resolved_at = NOW()
+		WHERE user_id = $2 AND status = $3
+		RETURNING amount
+	`
+	rows, err := tx.Query(ctx, query, model.ReservationStatusCancelled, userID, model.ReservationStatusActive)
+	if err != nil {
+		return fmt.Errorf("ошибка снятия резервов пользователя: %w", err)
+	}
+
+	var total float64
+	for rows.Next() {
+		var amount float64
+		if err := rows.Scan(&amount); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка сканирования резерва: %w", err)
+		}
+		total += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по резервам: %w", err)
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	return releaseReserved(ctx, tx, userID, total)
+}
+
+func releaseReserved(ctx context.Context, tx pgx.Tx, userID int64, amount float64) error {
+	query := `
+		UPDATE balances
+		SET reserved = reserved - $1, current = current + $1
+		WHERE user_id = $2
+	`
+	if _, err := tx.Exec(ctx, query, amount, userID); err != nil {
+		return fmt.Errorf("ошибка возврата зарезервированных средств: %w", err)
+	}
+	return nil
+}
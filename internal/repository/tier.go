@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	stderrors "errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TierRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewTierRepo(db *pgxpool.Pool) *TierRepo {
+	return &TierRepo{db: db}
+}
+
+// GetUserTier возвращает текущий уровень пользователя или nil, если уровень еще не назначен.
+func (r *TierRepo) GetUserTier(ctx context.Context, userID int64) (*model.UserTier, error) {
+	query := `
+		SELECT user_id, tier, points, updated_at
+		FROM user_tiers
+		WHERE user_id = $1
+	`
+
+	var tier model.UserTier
+	err := r.db.QueryRow(ctx, query, userID).Scan(&tier.UserID, &tier.Tier, &tier.Points, &tier.UpdatedAt)
+	if err != nil {
+		if stderrors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения уровня лояльности: %w", err)
+	}
+
+	return &tier, nil
+}
+
+func (r *TierRepo) GetTierHistory(ctx context.Context, userID int64) ([]*model.TierChange, error) {
+	query := `
+		SELECT id, user_id, tier_from, tier_to, points, changed_at
+		FROM user_tier_history
+		WHERE user_id = $1
+		ORDER BY id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории уровней лояльности: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*model.TierChange
+	for rows.Next() {
+		var change model.TierChange
+		if err := rows.Scan(&change.ID, &change.UserID, &change.From, &change.To, &change.Points, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки истории уровней: %w", err)
+		}
+		history = append(history, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по истории уровней: %w", err)
+	}
+
+	return history, nil
+}
+
+// GetTierPoints возвращает суммы начислений по заказам с момента since для не более limit пользователей
+// с ID больше afterUserID в порядке возрастания ID. Пользователи без начислений возвращаются с нулевой суммой,
+// чтобы их уровень тоже пересчитывался. Надбавки уровней в сумму не входят.
+func (r *TierRepo) GetTierPoints(ctx context.Context, since time.Time, afterUserID int64, limit int) ([]model.TierPoints, error) {
+	query := `
+		SELECT u.id, COALESCE(SUM(l.amount), 0)
+		FROM users u
+		LEFT JOIN balance_ledger l ON l.user_id = u.id AND l.operation = $1 AND l.created_at >= $2
+		WHERE u.id > $3
+		GROUP BY u.id
+		ORDER BY u.id
+		LIMIT $4
+	`
+
+	rows, err := r.db.Query(ctx, query, model.LedgerAccrual, since, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения начислений для пересчета уровней: %w", err)
+	}
+	defer rows.Close()
+
+	var points []model.TierPoints
+	for rows.Next() {
+		var p model.TierPoints
+		if err := rows.Scan(&p.UserID, &p.Points); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования начислений пользователя: %w", err)
+		}
+		points = append(points, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по начислениям пользователей: %w", err)
+	}
+
+	return points, nil
+}
+
+// SetUserTier сохраняет уровень пользователя и сумму начислений, по которой он определен.
+// Смена уровня записывается в историю; возвращает true, если уровень изменился.
+func (r *TierRepo) SetUserTier(ctx context.Context, userID int64, tier string, points float64) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var current string
+	err = tx.QueryRow(ctx, "SELECT tier FROM user_tiers WHERE user_id = $1 FOR UPDATE", userID).Scan(&current)
+	if err != nil && !stderrors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("ошибка получения уровня лояльности: %w", err)
+	}
+
+	upsertQuery := `
+		INSERT INTO user_tiers (user_id, tier, points, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET tier = EXCLUDED.tier, points = EXCLUDED.points, updated_at = EXCLUDED.updated_at
+	`
+	if _, err := tx.Exec(ctx, upsertQuery, userID, tier, points); err != nil {
+		return false, fmt.Errorf("ошибка сохранения уровня лояльности: %w", err)
+	}
+
+	changed := current != tier
+	if changed {
+		historyQuery := `
+			INSERT INTO user_tier_history (user_id, tier_from, tier_to, points)
+			VALUES ($1, $2, $3, $4)
+		`
+		if _, err := tx.Exec(ctx, historyQuery, userID, current, tier, points); err != nil {
+			return false, fmt.Errorf("ошибка записи истории уровней лояльности: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return changed, nil
+}
+
+// StartTierRecalculation отмечает плановый пересчет уровней. Возвращает false, если за minInterval
+// пересчет уже запускал другой экземпляр сервиса.
+func (r *TierRepo) StartTierRecalculation(ctx context.Context, minInterval time.Duration) (bool, error) {
+	return claimJobRun(ctx, r.db, jobTierRecalculation, minInterval)
+}
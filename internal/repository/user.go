@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
+	customerrors "github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -18,15 +20,15 @@ func NewUserRepo(db *pgxpool.Pool) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) CreateUser(ctx context.Context, login, passwordHash string) (int64, error) {
+func (r *UserRepo) CreateUser(ctx context.Context, tenant, login, passwordHash string) (int64, error) {
 	var id int64
 	query := `
-		INSERT INTO users (login, password_hash) 
-		VALUES ($1, $2) 
+		INSERT INTO users (tenant, login, password_hash) 
+		VALUES ($1, $2, $3) 
 		RETURNING id
 	`
 
-	err := r.db.QueryRow(ctx, query, login, passwordHash).Scan(&id)
+	err := r.db.QueryRow(ctx, query, tenant, login, passwordHash).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("ошибка создания пользователя: %w", err)
 	}
@@ -40,27 +42,38 @@ func (r *UserRepo) CreateUser(ctx context.Context, login, passwordHash string) (
 		return 0, fmt.Errorf("ошибка создания баланса пользователя: %w", err)
 	}
 
+	roleQuery := `
+		INSERT INTO user_roles (user_id, role) 
+		VALUES ($1, $2)
+	`
+	_, err = r.db.Exec(ctx, roleQuery, id, model.RoleCustomer)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка назначения роли пользователю: %w", err)
+	}
+
 	return id, nil
 }
 
-func (r *UserRepo) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+func (r *UserRepo) GetUserByLogin(ctx context.Context, tenant, login string) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, login, password_hash, created_at 
+		SELECT id, tenant, login, password_hash, created_at, deleted_at 
 		FROM users 
-		WHERE login = $1
+		WHERE tenant = $1 AND login = $2
 	`
 
-	err := r.db.QueryRow(ctx, query, login).Scan(
+	err := r.db.QueryRow(ctx, query, tenant, login).Scan(
 		&user.ID,
+		&user.Tenant,
 		&user.Login,
 		&user.PasswordHash,
 		&user.CreatedAt,
+		&user.DeletedAt,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("пользователь не найден")
+			return nil, fmt.Errorf("%w", customerrors.ErrUserNotFound)
 		}
 		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
 	}
@@ -71,24 +84,297 @@ func (r *UserRepo) GetUserByLogin(ctx context.Context, login string) (*model.Use
 func (r *UserRepo) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	var user model.User
 	query := `
-		SELECT id, login, password_hash, created_at 
+		SELECT id, tenant, login, password_hash, created_at, deleted_at 
 		FROM users 
 		WHERE id = $1
 	`
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&user.ID,
+		&user.Tenant,
 		&user.Login,
 		&user.PasswordHash,
 		&user.CreatedAt,
+		&user.DeletedAt,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("пользователь не найден")
+			return nil, fmt.Errorf("%w", customerrors.ErrUserNotFound)
 		}
 		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
 	}
 
 	return &user, nil
 }
+
+// SearchUsers ищет пользователей арендатора tenant по подстроке логина; пустой tenant — во всех арендаторах.
+func (r *UserRepo) SearchUsers(ctx context.Context, tenant, loginQuery string, limit int) ([]*model.UserSummary, error) {
+	query := `
+		SELECT u.id, u.tenant, u.login, u.created_at, COALESCE(b.current, 0), COALESCE(b.withdrawn, 0) 
+		FROM users u 
+		LEFT JOIN balances b ON b.user_id = u.id 
+		WHERE u.login ILIKE '%' || $1 || '%' AND ($3 = '' OR u.tenant = $3) 
+		ORDER BY u.id 
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, escapeLike(loginQuery), limit, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*model.UserSummary
+	for rows.Next() {
+		var user model.UserSummary
+		if err := rows.Scan(
+			&user.ID,
+			&user.Tenant,
+			&user.Login,
+			&user.CreatedAt,
+			&user.Current,
+			&user.Withdrawn,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки пользователя: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по пользователям: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepo) GetUserRoles(ctx context.Context, userID int64) ([]model.Role, error) {
+	query := `
+		SELECT role 
+		FROM user_roles 
+		WHERE user_id = $1 
+		ORDER BY role
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []model.Role
+	for rows.Next() {
+		var role model.Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования роли пользователя: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по ролям пользователя: %w", err)
+	}
+
+	return roles, nil
+}
+
+// DeleteUser закрывает учетную запись: логин заменяется на anonymizedLogin, пароль сбрасывается,
+// роли, реферальный код и события пользователя удаляются, активные резервы снимаются, а IP приглашения
+// и логин в журнале аудита обезличиваются. Строка пользователя остается, потому что
+// на нее ссылаются заказы, баланс, списания и журнал движения баллов, которые хранятся для отчетности.
+func (r *UserRepo) DeleteUser(ctx context.Context, userID int64, anonymizedLogin string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var login string
+	err = tx.QueryRow(ctx, "SELECT login FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", userID).Scan(&login)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w", customerrors.ErrUserNotFound)
+		}
+		return fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	query := `
+		UPDATE users 
+		SET login = $2, password_hash = '', deleted_at = NOW() 
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, userID, anonymizedLogin); err != nil {
+		return fmt.Errorf("ошибка обезличивания пользователя: %w", err)
+	}
+
+	cleanup := []struct {
+		query  string
+		errMsg string
+	}{
+		{"DELETE FROM user_roles WHERE user_id = $1", "ошибка удаления ролей пользователя"},
+		{"DELETE FROM referral_codes WHERE user_id = $1", "ошибка удаления реферального кода"},
+		{"DELETE FROM user_events WHERE user_id = $1", "ошибка удаления событий пользователя"},
+		{"UPDATE referrals SET ip = '' WHERE referee_id = $1", "ошибка удаления IP приглашения"},
+	}
+	for _, c := range cleanup {
+		if _, err := tx.Exec(ctx, c.query, userID); err != nil {
+			return fmt.Errorf("%s: %w", c.errMsg, err)
+		}
+	}
+
+	if err := cancelUserReservations(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := redactAuditLogin(ctx, tx, userID, login, anonymizedLogin); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+// redactAuditLogin заменяет логин удаляемого пользователя на обезличенный в журнале аудита:
+// в данных события регистрации и в цели неудачных входов по логину. В журнале нет арендатора,
+// поэтому неудачные входы с тем же логином в других арендаторах тоже обезличиваются.
+func redactAuditLogin(ctx context.Context, tx pgx.Tx, userID int64, login, anonymizedLogin string) error {
+	if _, err := tx.Exec(ctx, "SELECT set_config('gophermart.audit_redaction', 'on', true)"); err != nil {
+		return fmt.Errorf("ошибка разрешения обезличивания журнала аудита: %w", err)
+	}
+
+	query := `
+		UPDATE audit_events 
+		SET after = CASE WHEN action = $4 THEN jsonb_set(after, '{login}', to_jsonb($3::text)) ELSE after END,
+			target = CASE WHEN action = $5 THEN $3 ELSE target END
+		WHERE (action = $4 AND target = $1 AND after ? 'login') 
+			OR (action = $5 AND target = $2)
+	`
+	_, err := tx.Exec(ctx, query, fmt.Sprintf("user:%d", userID), login, anonymizedLogin,
+		model.AuditUserRegistered, model.AuditUserLoginFailed)
+	if err != nil {
+		return fmt.Errorf("ошибка обезличивания журнала аудита: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserRoles заменяет набор ролей пользователя и записывает каждую выданную
+// и отозванную роль в журнал изменений в той же транзакции.
+func (r *UserRepo) SetUserRoles(ctx context.Context, userID int64, roles []model.Role, actor string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	if err := tx.QueryRow(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w", customerrors.ErrUserNotFound)
+		}
+		return fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT role FROM user_roles WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+
+	current := make(map[model.Role]bool)
+	for rows.Next() {
+		var role model.Role
+		if err := rows.Scan(&role); err != nil {
+			rows.Close()
+			return fmt.Errorf("ошибка сканирования роли пользователя: %w", err)
+		}
+		current[role] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка итерации по ролям пользователя: %w", err)
+	}
+
+	wanted := make(map[model.Role]bool, len(roles))
+	for _, role := range roles {
+		wanted[role] = true
+	}
+
+	changeQuery := `
+		INSERT INTO user_role_changes (user_id, role, action, actor) 
+		VALUES ($1, $2, $3, $4)
+	`
+
+	for role := range wanted {
+		if current[role] {
+			continue
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO user_roles (user_id, role) VALUES ($1, $2)", userID, role); err != nil {
+			return fmt.Errorf("ошибка назначения роли пользователю: %w", err)
+		}
+		if _, err := tx.Exec(ctx, changeQuery, userID, role, model.RoleChangeGrant, actor); err != nil {
+			return fmt.Errorf("ошибка записи изменения роли: %w", err)
+		}
+	}
+
+	for role := range current {
+		if wanted[role] {
+			continue
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM user_roles WHERE user_id = $1 AND role = $2", userID, role); err != nil {
+			return fmt.Errorf("ошибка отзыва роли пользователя: %w", err)
+		}
+		if _, err := tx.Exec(ctx, changeQuery, userID, role, model.RoleChangeRevoke, actor); err != nil {
+			return fmt.Errorf("ошибка записи изменения роли: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserRepo) GetRoleChanges(ctx context.Context, userID int64) ([]*model.RoleChange, error) {
+	query := `
+		SELECT id, user_id, role, action, actor, created_at 
+		FROM user_role_changes 
+		WHERE user_id = $1 
+		ORDER BY id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории ролей: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*model.RoleChange
+	for rows.Next() {
+		var change model.RoleChange
+		if err := rows.Scan(
+			&change.ID,
+			&change.UserID,
+			&change.Role,
+			&change.Action,
+			&change.Actor,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования изменения роли: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по истории ролей: %w", err)
+	}
+
+	return changes, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepo(db *pgxpool.Pool) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+// insertOutbox записывает событие вебхука в outbox в рамках переданной транзакции,
+// чтобы событие появилось тогда и только тогда, когда зафиксировано изменение данных.
+func insertOutbox(ctx context.Context, tx pgx.Tx, tenant string, eventType model.WebhookEventType, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события вебхука: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_outbox (tenant, event_type, payload) 
+		VALUES ($1, $2, $3)
+	`
+
+	if _, err := tx.Exec(ctx, query, tenant, eventType, data); err != nil {
+		return fmt.Errorf("ошибка записи события вебхука в outbox: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) (int64, error) {
+	var id int64
+	query := `
+		INSERT INTO webhook_subscriptions (tenant, url, secret, event_types) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING id, active, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, sub.Tenant, sub.URL, sub.Secret, sub.EventTypes).Scan(&id, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания подписки на вебхуки: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetSubscriptions возвращает подписки арендатора. Пустой арендатор — подписки всех арендаторов.
+func (r *WebhookRepo) GetSubscriptions(ctx context.Context, tenant string) ([]*model.WebhookSubscription, error) {
+	query := `
+		SELECT id, tenant, url, event_types, active, created_at 
+		FROM webhook_subscriptions 
+		WHERE $1 = '' OR tenant = $1 
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения подписок на вебхуки: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*model.WebhookSubscription
+	for rows.Next() {
+		var sub model.WebhookSubscription
+		if err := rows.Scan(
+			&sub.ID,
+			&sub.Tenant,
+			&sub.URL,
+			&sub.EventTypes,
+			&sub.Active,
+			&sub.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки подписки: %w", err)
+		}
+		subscriptions = append(subscriptions, &sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по подпискам: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, tenant string, id int64) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1 AND ($2 = '' OR tenant = $2)", id, tenant)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления подписки на вебхуки: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w", errors.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// FanOutOutbox превращает необработанные события outbox в доставки для всех подходящих подписок.
+// Возвращает количество обработанных событий.
+func (r *WebhookRepo) FanOutOutbox(ctx context.Context, limit int) (int64, error) {
+	query := `
+		WITH batch AS (
+			SELECT id, tenant, event_type 
+			FROM webhook_outbox 
+			WHERE dispatched_at IS NULL 
+			ORDER BY id 
+			LIMIT $1 
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (outbox_id, subscription_id) 
+			SELECT b.id, s.id 
+			FROM batch b 
+			JOIN webhook_subscriptions s 
+				ON s.tenant = b.tenant AND s.active AND b.event_type = ANY(s.event_types)
+		) 
+		UPDATE webhook_outbox 
+		SET dispatched_at = NOW() 
+		WHERE id IN (SELECT id FROM batch)
+	`
+
+	tag, err := r.db.Exec(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка распределения событий вебхуков: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDueDeliveries захватывает доставки, время попытки которых наступило, откладывая
+// их следующую попытку на время аренды, и увеличивает счетчик попыток.
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d 
+		SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2) 
+		FROM webhook_subscriptions s, webhook_outbox o 
+		WHERE d.id IN (
+			SELECT id 
+			FROM webhook_deliveries 
+			WHERE status = $3 AND next_attempt_at <= NOW() 
+			ORDER BY next_attempt_at 
+			LIMIT $1 
+			FOR UPDATE SKIP LOCKED
+		) 
+			AND s.id = d.subscription_id 
+			AND o.id = d.outbox_id 
+		RETURNING d.id, d.subscription_id, d.outbox_id, o.event_type, d.status, d.attempts, 
+			d.next_attempt_at, d.last_error, d.created_at, s.url, s.secret, o.payload, o.created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, lease.Seconds(), model.WebhookDeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка захвата доставок вебхуков: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastError,
+			&d.CreatedAt,
+			&d.URL,
+			&d.Secret,
+			&d.Payload,
+			&d.EventTime,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки доставки: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по доставкам: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) MarkDelivered(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_deliveries 
+		SET status = $1, delivered_at = NOW(), last_error = '' 
+		WHERE id = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, model.WebhookDeliveryDelivered, id); err != nil {
+		return fmt.Errorf("ошибка отметки доставки вебхука: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string, dead bool) error {
+	status := model.WebhookDeliveryPending
+	if dead {
+		status = model.WebhookDeliveryDead
+	}
+
+	query := `
+		UPDATE webhook_deliveries 
+		SET status = $1, next_attempt_at = $2, last_error = $3 
+		WHERE id = $4
+	`
+
+	if _, err := r.db.Exec(ctx, query, status, nextAttemptAt, lastError, id); err != nil {
+		return fmt.Errorf("ошибка отметки неудачной доставки вебхука: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает последние доставки арендатора с указанным статусом.
+// Пустые арендатор и статус снимают соответствующее ограничение.
+func (r *WebhookRepo) GetDeliveries(ctx context.Context, tenant string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.subscription_id, d.outbox_id, o.event_type, d.status, d.attempts, 
+			d.next_attempt_at, d.last_error, d.created_at 
+		FROM webhook_deliveries d 
+		JOIN webhook_outbox o ON o.id = d.outbox_id 
+		WHERE ($1 = '' OR d.status = $1) AND ($3 = '' OR o.tenant = $3) 
+		ORDER BY d.id DESC 
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(ctx, query, status, limit, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения доставок вебхуков: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastError,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки доставки: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по доставкам: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) ReplayDelivery(ctx context.Context, tenant string, id int64) error {
+	query := `
+		UPDATE webhook_deliveries d 
+		SET status = $1, attempts = 0, next_attempt_at = NOW(), last_error = '', delivered_at = NULL 
+		FROM webhook_outbox o 
+		WHERE d.id = $2 AND o.id = d.outbox_id AND ($3 = '' OR o.tenant = $3)
+	`
+
+	tag, err := r.db.Exec(ctx, query, model.WebhookDeliveryPending, id, tenant)
+	if err != nil {
+		return fmt.Errorf("ошибка повторной постановки доставки вебхука: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w", errors.ErrWebhookNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+)
+
+type AccountSvc struct {
+	users    repository.UserRepository
+	orders   repository.OrderRepository
+	balances repository.BalanceRepository
+	audit    AuditService
+}
+
+func NewAccountService(users repository.UserRepository, orders repository.OrderRepository, balances repository.BalanceRepository, audit AuditService) *AccountSvc {
+	return &AccountSvc{
+		users:    users,
+		orders:   orders,
+		balances: balances,
+		audit:    audit,
+	}
+}
+
+func (s *AccountSvc) ValidateSession(ctx context.Context, userID int64) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+	if user.DeletedAt != nil {
+		return fmt.Errorf("%w", errors.ErrUserDeleted)
+	}
+	return nil
+}
+
+func (s *AccountSvc) Export(ctx context.Context, userID int64, format model.ExportFormat, w io.Writer) error {
+	if format != model.ExportJSON && format != model.ExportZIP {
+		return fmt.Errorf("%w: %q", errors.ErrInvalidExportFormat, format)
+	}
+
+	export, err := s.collectExport(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if format == model.ExportJSON {
+		if err := writeExportJSON(w, export); err != nil {
+			return fmt.Errorf("ошибка записи выгрузки: %w", err)
+		}
+	} else if err := writeExportZIP(w, export); err != nil {
+		return fmt.Errorf("ошибка записи архива выгрузки: %w", err)
+	}
+
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditUserExported, userActor(userID), nil, map[string]model.ExportFormat{"format": format})
+
+	return nil
+}
+
+// collectExport собирает профиль, баланс, заказы, списания и историю движения баллов пользователя.
+func (s *AccountSvc) collectExport(ctx context.Context, userID int64) (*model.UserExport, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	roles, err := s.users.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+
+	balance, err := s.balances.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения баланса: %w", err)
+	}
+
+	orders, err := s.orders.GetOrdersByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заказов: %w", err)
+	}
+
+	withdrawals, err := s.balances.GetWithdrawals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории списаний: %w", err)
+	}
+
+	ledger, err := s.balances.GetLedger(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала движения баллов: %w", err)
+	}
+
+	adjustments, err := s.balances.GetAdjustments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения корректировок баланса: %w", err)
+	}
+
+	export := &model.UserExport{
+		ExportedAt: time.Now(),
+		Profile: model.ExportProfile{
+			ID:        user.ID,
+			Login:     user.Login,
+			Tenant:    cmp.Or(user.Tenant, model.DefaultTenant),
+			Roles:     roles,
+			CreatedAt: user.CreatedAt,
+		},
+		Balance: model.BalanceResponse{
+			Current:   balance.Current,
+			Withdrawn: balance.Withdrawn,
+			Reserved:  balance.Reserved,
+		},
+		Orders:         make([]model.OrderResponse, 0, len(orders)),
+		Withdrawals:    make([]model.WithdrawalResponse, 0, len(withdrawals)),
+		BalanceHistory: make([]model.BalanceHistoryEntry, 0, len(ledger)+len(withdrawals)+len(adjustments)),
+	}
+
+	for _, order := range orders {
+		resp := model.OrderResponse{
+			Number:     order.Number,
+			Status:     order.Status,
+			UploadedAt: order.UploadedAt,
+		}
+		if order.Status == model.OrderStatusProcessed {
+			resp.Accrual = order.Accrual
+		}
+		export.Orders = append(export.Orders, resp)
+	}
+
+	for _, w := range withdrawals {
+		export.Withdrawals = append(export.Withdrawals, model.WithdrawalResponse{
+			Order:       w.OrderNumber,
+			Sum:         w.Amount,
+			ProcessedAt: w.ProcessedAt,
+		})
+		export.BalanceHistory = append(export.BalanceHistory, model.BalanceHistoryEntry{
+			Operation: model.BalanceHistoryWithdrawal,
+			Amount:    -w.Amount,
+			Order:     w.OrderNumber,
+			CreatedAt: w.ProcessedAt,
+		})
+	}
+
+	for _, entry := range ledger {
+		export.BalanceHistory = append(export.BalanceHistory, model.BalanceHistoryEntry{
+			Operation:    string(entry.Operation),
+			Amount:       entry.Amount,
+			Order:        entry.OrderNumber,
+			Counterparty: entry.Counterparty,
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+
+	for _, adjustment := range adjustments {
+		export.BalanceHistory = append(export.BalanceHistory, model.BalanceHistoryEntry{
+			Operation: model.BalanceHistoryAdjustment,
+			Amount:    adjustment.Amount,
+			Reason:    adjustment.Reason,
+			CreatedAt: adjustment.CreatedAt,
+		})
+	}
+
+	slices.SortStableFunc(export.BalanceHistory, func(a, b model.BalanceHistoryEntry) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return export, nil
+}
+
+func writeExportJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeExportZIP записывает выгрузку архивом с отдельным JSON-файлом на каждый раздел.
+func writeExportZIP(w io.Writer, export *model.UserExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"profile.json", export.Profile},
+		{"balance.json", export.Balance},
+		{"orders.json", export.Orders},
+		{"withdrawals.json", export.Withdrawals},
+		{"balance_history.json", export.BalanceHistory},
+	}
+
+	for _, file := range files {
+		fw, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeExportJSON(fw, file.value); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func (s *AccountSvc) DeleteAccount(ctx context.Context, userID int64) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("ошибка генерации обезличенного логина: %w", err)
+	}
+	// Случайный суффикс не дает заранее занять обезличенный логин и тем самым помешать удалению.
+	anonymizedLogin := fmt.Sprintf("deleted-%d-%s", userID, hex.EncodeToString(suffix))
+
+	if err := s.users.DeleteUser(ctx, userID, anonymizedLogin); err != nil {
+		return fmt.Errorf("ошибка удаления учетной записи: %w", err)
+	}
+
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditUserDeleted, userActor(userID), nil, nil)
+
+	return nil
+}
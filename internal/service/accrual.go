@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+)
+
+const accrualRequestTimeout = 5 * time.Second
+
+// AccrualClient запрашивает статус заказов в системе расчета начислений. Заказы арендатора
+// с собственной системой расчета запрашиваются у нее, остальные — по общему адресу.
+type AccrualClient struct {
+	client     *http.Client
+	defaultURL string
+	tenantURLs map[string]string
+}
+
+func NewAccrualClient(cfg *config.Config) *AccrualClient {
+	tenantURLs := make(map[string]string, len(cfg.Tenants))
+	for _, tenant := range cfg.Tenants {
+		if tenant.AccrualSystemAddress != "" {
+			tenantURLs[tenant.Name] = tenant.AccrualSystemAddress
+		}
+	}
+
+	return &AccrualClient{
+		client:     &http.Client{Timeout: accrualRequestTimeout},
+		defaultURL: cfg.AccrualSystemAddress,
+		tenantURLs: tenantURLs,
+	}
+}
+
+// address возвращает адрес системы расчета арендатора или общий адрес,
+// если у арендатора нет собственной системы.
+func (c *AccrualClient) address(tenant string) string {
+	if address, ok := c.tenantURLs[tenant]; ok {
+		return address
+	}
+	return c.defaultURL
+}
+
+// GetOrder запрашивает заказ number в системе расчета арендатора tenant. Для заказа, который система
+// расчета не знает (ответ 204), возвращает nil без ошибки. На ответ 429 возвращает
+// *errors.AccrualRateLimitError с паузой из заголовка Retry-After; повторять запрос решает вызывающий.
+func (c *AccrualClient) GetOrder(ctx context.Context, tenant, number string) (*model.AccrualResponse, error) {
+	baseURL, err := url.Parse(c.address(tenant))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга URL системы начислений: %w", err)
+	}
+	baseURL.Path = fmt.Sprintf("/api/orders/%s", number)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания HTTP-запроса: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выполнения HTTP-запроса к системе начислений: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения тела ответа: %w", err)
+		}
+
+		var accrual model.AccrualResponse
+		if err := json.Unmarshal(body, &accrual); err != nil {
+			return nil, fmt.Errorf("ошибка разбора JSON-ответа: %w", err)
+		}
+		return &accrual, nil
+	case http.StatusNoContent:
+		return nil, nil
+	case http.StatusTooManyRequests:
+		rateLimitErr := &errors.AccrualRateLimitError{}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			rateLimitErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, rateLimitErr
+	default:
+		return nil, fmt.Errorf("неожиданный ответ от системы начислений: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+)
+
+const adminSearchLimit = 50
+
+type AdminSvc struct {
+	userRepo    repository.UserRepository
+	orderRepo   repository.OrderRepository
+	balanceRepo repository.BalanceRepository
+	orders      OrderService
+	balances    BalanceService
+	audit       AuditService
+}
+
+func NewAdminService(userRepo repository.UserRepository, orderRepo repository.OrderRepository, balanceRepo repository.BalanceRepository, orders OrderService, balances BalanceService, audit AuditService) *AdminSvc {
+	return &AdminSvc{
+		userRepo:    userRepo,
+		orderRepo:   orderRepo,
+		balanceRepo: balanceRepo,
+		orders:      orders,
+		balances:    balances,
+		audit:       audit,
+	}
+}
+
+func (s *AdminSvc) SearchUsers(ctx context.Context, loginQuery string) ([]*model.UserSummary, error) {
+	users, err := s.userRepo.SearchUsers(ctx, tenantScope(ctx), strings.TrimSpace(loginQuery), adminSearchLimit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска пользователей: %w", err)
+	}
+
+	return users, nil
+}
+
+func (s *AdminSvc) GetUser(ctx context.Context, userID int64) (*model.UserSummary, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.balanceRepo.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения баланса: %w", err)
+	}
+
+	return &model.UserSummary{
+		ID:        user.ID,
+		Login:     user.Login,
+		CreatedAt: user.CreatedAt,
+		Current:   balance.Current,
+		Withdrawn: balance.Withdrawn,
+	}, nil
+}
+
+func (s *AdminSvc) GetUserOrders(ctx context.Context, userID int64) ([]model.OrderResponse, error) {
+	if err := s.ensureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.orders.GetOrdersByUserID(ctx, userID)
+}
+
+func (s *AdminSvc) GetUserWithdrawals(ctx context.Context, userID int64) ([]model.WithdrawalResponse, error) {
+	if err := s.ensureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.balances.GetWithdrawals(ctx, userID)
+}
+
+func (s *AdminSvc) RequeueOrder(ctx context.Context, number string, actor string) error {
+	if err := s.orderRepo.RequeueOrder(ctx, TenantFromContext(ctx), number); err != nil {
+		return fmt.Errorf("ошибка повторной постановки заказа: %w", err)
+	}
+
+	recordAudit(ctx, s.audit, actor, model.AuditOrderRequeued, "order:"+number, nil, nil)
+
+	return nil
+}
+
+func (s *AdminSvc) AdjustBalance(ctx context.Context, userID int64, req model.BalanceAdjustmentRequest, actor string) (*model.BalanceAdjustment, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: причина корректировки обязательна", errors.ErrInvalidAdjustment)
+	}
+
+	if req.Amount == 0 || math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) {
+		return nil, fmt.Errorf("%w: сумма корректировки должна быть ненулевым числом", errors.ErrInvalidAdjustment)
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before, _ := s.balances.GetBalance(ctx, userID)
+
+	adjustment, err := s.balanceRepo.AdjustBalance(ctx, userID, req.Amount, reason, actor)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка корректировки баланса: %w", err)
+	}
+
+	after, _ := s.balances.GetBalance(ctx, userID)
+	recordAudit(WithTenant(ctx, user.Tenant), s.audit, actor, model.AuditBalanceAdjusted, userActor(userID), before, map[string]any{
+		"balance": after,
+		"reason":  reason,
+	})
+
+	return adjustment, nil
+}
+
+func (s *AdminSvc) GetBalanceAdjustments(ctx context.Context, userID int64) ([]*model.BalanceAdjustment, error) {
+	if err := s.ensureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	adjustments, err := s.balanceRepo.GetAdjustments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения корректировок баланса: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+func (s *AdminSvc) GetUserRoles(ctx context.Context, userID int64) ([]model.Role, error) {
+	if err := s.ensureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (s *AdminSvc) SetUserRoles(ctx context.Context, userID int64, roles []model.Role, actor string) ([]model.Role, error) {
+	unique := make([]model.Role, 0, len(roles))
+	for _, role := range roles {
+		if !slices.Contains(model.Roles, role) {
+			return nil, fmt.Errorf("%w: %q", errors.ErrInvalidRole, role)
+		}
+		if !slices.Contains(unique, role) {
+			unique = append(unique, role)
+		}
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before, _ := s.userRepo.GetUserRoles(ctx, userID)
+
+	if err := s.userRepo.SetUserRoles(ctx, userID, unique, actor); err != nil {
+		return nil, fmt.Errorf("ошибка изменения ролей пользователя: %w", err)
+	}
+
+	recordAudit(WithTenant(ctx, user.Tenant), s.audit, actor, model.AuditUserRolesChanged, userActor(userID), before, unique)
+
+	return unique, nil
+}
+
+func (s *AdminSvc) GetRoleChanges(ctx context.Context, userID int64) ([]*model.RoleChange, error) {
+	if err := s.ensureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.userRepo.GetRoleChanges(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории ролей: %w", err)
+	}
+
+	return changes, nil
+}
+
+// ensureUserExists отличает отсутствующего пользователя от пользователя без записей,
+// чтобы поддержка получала 404, а не пустой список.
+func (s *AdminSvc) ensureUserExists(ctx context.Context, userID int64) error {
+	_, err := s.getUser(ctx, userID)
+	return err
+}
+
+// getUser возвращает пользователя, доступного вызывающему. Пользователь другого арендатора
+// для сотрудника, вошедшего по JWT, не отличается от несуществующего.
+func (s *AdminSvc) getUser(ctx context.Context, userID int64) (*model.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	if scope := tenantScope(ctx); scope != "" && user.Tenant != scope {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", errors.ErrUserNotFound)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	auditRecordTimeout = 5 * time.Second
+	auditDefaultLimit  = 100
+	auditMaxLimit      = 1000
+	auditExportPage    = 1000
+
+	anonymousActor = "anonymous"
+)
+
+type requestMetaKey struct{}
+
+// WithRequestMeta сохраняет в контексте данные запроса для журнала аудита.
+func WithRequestMeta(ctx context.Context, meta model.RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func requestMetaFromContext(ctx context.Context) model.RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(model.RequestMeta)
+	return meta
+}
+
+func userActor(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+type AuditSvc struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditSvc {
+	return &AuditSvc{
+		repo: repo,
+	}
+}
+
+func (s *AuditSvc) Record(ctx context.Context, event *model.AuditEvent) error {
+	meta := requestMetaFromContext(ctx)
+	event.Tenant = TenantFromContext(ctx)
+	event.IP = meta.IP
+	event.UserAgent = meta.UserAgent
+	event.RequestID = meta.RequestID
+
+	if err := s.repo.AppendAuditEvent(ctx, event); err != nil {
+		return fmt.Errorf("ошибка записи события аудита: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AuditSvc) GetEvents(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+	filter.Limit = min(filter.Limit, auditMaxLimit)
+	filter.Tenant = tenantScope(ctx)
+
+	events, err := s.repo.GetAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения событий аудита: %w", err)
+	}
+
+	return events, nil
+}
+
+func (s *AuditSvc) Export(ctx context.Context, filter model.AuditFilter, format model.ExportFormat, w io.Writer) error {
+	var write func(*model.AuditEvent) error
+	var flush func() error
+
+	switch format {
+	case model.ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"id", "created_at", "tenant", "actor", "action", "target", "ip", "user_agent", "request_id", "before", "after"}); err != nil {
+			return fmt.Errorf("ошибка записи заголовка выгрузки: %w", err)
+		}
+		write = func(event *model.AuditEvent) error {
+			return cw.Write([]string{
+				strconv.FormatInt(event.ID, 10),
+				event.CreatedAt.Format(time.RFC3339),
+				event.Tenant,
+				event.Actor,
+				string(event.Action),
+				event.Target,
+				event.IP,
+				event.UserAgent,
+				event.RequestID,
+				string(event.Before),
+				string(event.After),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case model.ExportJSONL:
+		encoder := json.NewEncoder(w)
+		write = func(event *model.AuditEvent) error {
+			return encoder.Encode(event)
+		}
+		flush = func() error { return nil }
+	default:
+		return fmt.Errorf("%w: %q", errors.ErrInvalidExportFormat, format)
+	}
+
+	filter.Limit = auditExportPage
+	filter.Tenant = tenantScope(ctx)
+	for {
+		events, err := s.repo.GetAuditEvents(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("ошибка получения событий аудита: %w", err)
+		}
+
+		for _, event := range events {
+			if err := write(event); err != nil {
+				return fmt.Errorf("ошибка записи выгрузки: %w", err)
+			}
+		}
+
+		if err := flush(); err != nil {
+			return fmt.Errorf("ошибка записи выгрузки: %w", err)
+		}
+
+		if len(events) < filter.Limit {
+			return nil
+		}
+		filter.AfterID = events[len(events)-1].ID
+	}
+}
+
+// recordAudit записывает событие аудита без прерывания основной операции: ошибки только логируются.
+func recordAudit(ctx context.Context, audit AuditService, actor string, action model.AuditAction, target string, before, after any) {
+	if audit == nil {
+		return
+	}
+
+	event := &model.AuditEvent{
+		Actor:  actor,
+		Action: action,
+		Target: target,
+		Before: marshalAuditPayload(before),
+		After:  marshalAuditPayload(after),
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditRecordTimeout)
+	defer cancel()
+
+	if err := audit.Record(ctx, event); err != nil {
+		log.Errorf("Ошибка записи события аудита %s: %s", action, err.Error())
+	}
+}
+
+func marshalAuditPayload(payload any) json.RawMessage {
+	if payload == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("Ошибка сериализации данных аудита: %s", err.Error())
+		return nil
+	}
+
+	return data
+}
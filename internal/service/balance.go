@@ -3,19 +3,41 @@ package service
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
+	"github.com/Gerfey/gophermart/internal/config"
 	"github.com/Gerfey/gophermart/internal/errors"
 	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	reservationSweepInterval = 30 * time.Second
+	reservationSweepBatch    = 100
 )
 
 type BalanceSvc struct {
-	repo repository.BalanceRepository
+	repo               repository.BalanceRepository
+	users              repository.UserRepository
+	audit              AuditService
+	validators         *OrderNumberValidators
+	withdrawalPolicy   *WithdrawalPolicy
+	transferDailyLimit float64
+	reservationTTL     time.Duration
 }
 
-func NewBalanceService(repo repository.BalanceRepository) *BalanceSvc {
+func NewBalanceService(repo repository.BalanceRepository, users repository.UserRepository, audit AuditService, validators *OrderNumberValidators, cfg *config.Config) *BalanceSvc {
 	return &BalanceSvc{
-		repo: repo,
+		repo:               repo,
+		users:              users,
+		audit:              audit,
+		validators:         validators,
+		withdrawalPolicy:   NewWithdrawalPolicy(cfg.WithdrawalRules),
+		transferDailyLimit: cfg.TransferDailyLimit,
+		reservationTTL:     cfg.BalanceReservationTTL,
 	}
 }
 
@@ -28,24 +50,41 @@ func (s *BalanceSvc) GetBalance(ctx context.Context, userID int64) (model.Balanc
 	response := model.BalanceResponse{
 		Current:   balance.Current,
 		Withdrawn: balance.Withdrawn,
+		Reserved:  balance.Reserved,
 	}
 
 	return response, nil
 }
 
-func (s *BalanceSvc) Withdraw(ctx context.Context, userID int64, orderNumber string, amount float64) error {
-	if !IsValidLuhnNumber(orderNumber) {
-		return fmt.Errorf("%w", errors.ErrInvalidLuhn)
+func (s *BalanceSvc) Withdraw(ctx context.Context, userID int64, req model.WithdrawRequest) error {
+	if err := s.validators.Validate(TenantFromContext(ctx), req.Order); err != nil {
+		return fmt.Errorf("%w", err)
 	}
 
-	err := s.repo.Withdraw(ctx, userID, amount, orderNumber)
+	before, _ := s.GetBalance(ctx, userID)
+
+	err := s.repo.Withdraw(ctx, userID, req.Sum, req.Order, s.withdrawalCheck(ctx))
 	if err != nil {
 		return fmt.Errorf("ошибка списания баллов: %w", err)
 	}
 
+	after, _ := s.GetBalance(ctx, userID)
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditBalanceWithdrawn, "order:"+req.Order, before, after)
+
 	return nil
 }
 
+// withdrawalCheck возвращает проверку списания правилами арендатора запроса или nil,
+// если правила не заданы.
+func (s *BalanceSvc) withdrawalCheck(ctx context.Context) repository.WithdrawalCheck {
+	rules := s.withdrawalPolicy.For(TenantFromContext(ctx))
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return rules.Check
+}
+
 func (s *BalanceSvc) GetWithdrawals(ctx context.Context, userID int64) ([]model.WithdrawalResponse, error) {
 	withdrawals, err := s.repo.GetWithdrawals(ctx, userID)
 	if err != nil {
@@ -63,3 +102,168 @@ func (s *BalanceSvc) GetWithdrawals(ctx context.Context, userID int64) ([]model.
 
 	return response, nil
 }
+
+func (s *BalanceSvc) Transfer(ctx context.Context, userID int64, req model.TransferRequest, idempotencyKey string) (*model.TransferResponse, error) {
+	recipient, err := s.users.GetUserByLogin(ctx, TenantFromContext(ctx), req.Recipient)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска получателя перевода: %w", err)
+	}
+	if recipient.DeletedAt != nil {
+		return nil, fmt.Errorf("ошибка поиска получателя перевода: %w", errors.ErrUserNotFound)
+	}
+	if recipient.ID == userID {
+		return nil, fmt.Errorf("%w", errors.ErrTransferToSelf)
+	}
+
+	before, _ := s.GetBalance(ctx, userID)
+
+	transfer, err := s.repo.Transfer(ctx, &model.Transfer{
+		SenderID:       userID,
+		RecipientID:    recipient.ID,
+		Amount:         req.Sum,
+		IdempotencyKey: idempotencyKey,
+	}, s.transferDailyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка перевода баллов: %w", err)
+	}
+
+	if !transfer.Replayed {
+		after, _ := s.GetBalance(ctx, userID)
+		recordAudit(ctx, s.audit, userActor(userID), model.AuditBalanceTransfer, "user:"+strconv.FormatInt(recipient.ID, 10), before, after)
+	}
+
+	return &model.TransferResponse{
+		ID:           transfer.ID,
+		Direction:    model.TransferDirectionOut,
+		Counterparty: recipient.Login,
+		Sum:          transfer.Amount,
+		CreatedAt:    transfer.CreatedAt,
+	}, nil
+}
+
+func (s *BalanceSvc) GetTransfers(ctx context.Context, userID int64) ([]model.TransferResponse, error) {
+	entries, err := s.repo.GetTransfers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории переводов: %w", err)
+	}
+
+	response := make([]model.TransferResponse, 0, len(entries))
+	for _, entry := range entries {
+		direction := model.TransferDirectionIn
+		if entry.Operation == model.LedgerTransferOut {
+			direction = model.TransferDirectionOut
+		}
+
+		var transferID int64
+		if entry.TransferID != nil {
+			transferID = *entry.TransferID
+		}
+
+		response = append(response, model.TransferResponse{
+			ID:           transferID,
+			Direction:    direction,
+			Counterparty: entry.Counterparty,
+			Sum:          math.Abs(entry.Amount),
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *BalanceSvc) Reserve(ctx context.Context, userID int64, req model.ReservationRequest) (*model.Reservation, error) {
+	if err := s.validators.Validate(TenantFromContext(ctx), req.Order); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	before, _ := s.GetBalance(ctx, userID)
+
+	reservation, err := s.repo.Reserve(ctx, userID, req.Sum, req.Order, s.reservationTTL, s.withdrawalCheck(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка резервирования баллов: %w", err)
+	}
+
+	after, _ := s.GetBalance(ctx, userID)
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditBalanceReserved, reservationTarget(reservation.ID), before, after)
+
+	return reservation, nil
+}
+
+func (s *BalanceSvc) ConfirmReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	before, _ := s.GetBalance(ctx, userID)
+
+	reservation, err := s.repo.ConfirmReservation(ctx, userID, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подтверждения резерва: %w", err)
+	}
+
+	if !reservation.Replayed {
+		after, _ := s.GetBalance(ctx, userID)
+		recordAudit(ctx, s.audit, userActor(userID), model.AuditReserveConfirmed, reservationTarget(reservation.ID), before, after)
+	}
+
+	return reservation, nil
+}
+
+func (s *BalanceSvc) CancelReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error) {
+	before, _ := s.GetBalance(ctx, userID)
+
+	reservation, err := s.repo.CancelReservation(ctx, userID, reservationID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка отмены резерва: %w", err)
+	}
+
+	if !reservation.Replayed {
+		after, _ := s.GetBalance(ctx, userID)
+		recordAudit(ctx, s.audit, userActor(userID), model.AuditReserveCancelled, reservationTarget(reservation.ID), before, after)
+	}
+
+	return reservation, nil
+}
+
+func (s *BalanceSvc) GetReservations(ctx context.Context, userID int64) ([]*model.Reservation, error) {
+	reservations, err := s.repo.GetReservations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения резервов: %w", err)
+	}
+
+	return reservations, nil
+}
+
+func (s *BalanceSvc) RunReservationSweeper(ctx context.Context) {
+	log.Info("Запуск снятия просроченных резервов")
+
+	ticker := time.NewTicker(reservationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Остановка снятия просроченных резервов")
+			return
+		case <-ticker.C:
+			s.ExpireReservations(ctx)
+		}
+	}
+}
+
+// ExpireReservations снимает все резервы с истекшим сроком пакетами по reservationSweepBatch.
+func (s *BalanceSvc) ExpireReservations(ctx context.Context) {
+	for ctx.Err() == nil {
+		expired, err := s.repo.ExpireReservations(ctx, reservationSweepBatch)
+		if err != nil {
+			log.Errorf("Ошибка снятия просроченных резервов: %s", err.Error())
+			return
+		}
+		if expired > 0 {
+			log.Infof("Снято просроченных резервов: %d", expired)
+		}
+		if expired < reservationSweepBatch {
+			return
+		}
+	}
+}
+
+func reservationTarget(id int64) string {
+	return "reservation:" + strconv.FormatInt(id, 10)
+}
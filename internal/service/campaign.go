@@ -0,0 +1,279 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+type CampaignSvc struct {
+	repo   repository.CampaignRepository
+	orders repository.OrderRepository
+	audit  AuditService
+}
+
+func NewCampaignService(repo repository.CampaignRepository, orders repository.OrderRepository, audit AuditService) *CampaignSvc {
+	return &CampaignSvc{
+		repo:   repo,
+		orders: orders,
+		audit:  audit,
+	}
+}
+
+// campaignFromRequest проверяет параметры кампании и собирает из них кампанию. Сотрудник,
+// вошедший по JWT, создает кампании только в своем арендаторе, арендатор из запроса не учитывается.
+func campaignFromRequest(ctx context.Context, req model.CampaignRequest) (*model.Campaign, error) {
+	campaign := &model.Campaign{
+		Tenant:   cmp.Or(tenantScope(ctx), req.Tenant, model.DefaultTenant),
+		Name:     strings.TrimSpace(req.Name),
+		Type:     req.Type,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Active:   req.Active == nil || *req.Active,
+	}
+
+	if campaign.Name == "" {
+		return nil, fmt.Errorf("%w: не указано название", errors.ErrInvalidCampaign)
+	}
+
+	switch req.Type {
+	case model.CampaignWelcomeBonus:
+		campaign.Amount = req.Amount
+	case model.CampaignNthOrder:
+		if req.OrderIndex < 1 {
+			return nil, fmt.Errorf("%w: номер заказа order_index должен быть положительным", errors.ErrInvalidCampaign)
+		}
+		campaign.Amount = req.Amount
+		campaign.OrderIndex = req.OrderIndex
+	case model.CampaignAccrualMultiplier:
+		if req.Multiplier <= 1 || math.IsInf(req.Multiplier, 0) {
+			return nil, fmt.Errorf("%w: множитель должен быть больше 1", errors.ErrInvalidCampaign)
+		}
+		campaign.Multiplier = req.Multiplier
+	default:
+		return nil, fmt.Errorf("%w: неизвестный тип кампании %q", errors.ErrInvalidCampaign, req.Type)
+	}
+
+	if req.Type != model.CampaignAccrualMultiplier && (req.Amount <= 0 || math.IsInf(req.Amount, 0)) {
+		return nil, fmt.Errorf("%w: сумма бонуса должна быть положительной", errors.ErrInvalidCampaign)
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return nil, fmt.Errorf("%w: окончание кампании должно быть позже начала", errors.ErrInvalidCampaign)
+	}
+
+	return campaign, nil
+}
+
+func (s *CampaignSvc) CreateCampaign(ctx context.Context, req model.CampaignRequest, actor string) (*model.Campaign, error) {
+	campaign, err := campaignFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCampaign(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("ошибка создания кампании: %w", err)
+	}
+
+	recordAudit(WithTenant(ctx, campaign.Tenant), s.audit, actor, model.AuditCampaignCreated, campaignTarget(campaign.ID), nil, campaign)
+
+	return campaign, nil
+}
+
+func (s *CampaignSvc) UpdateCampaign(ctx context.Context, id int64, req model.CampaignRequest, actor string) (*model.Campaign, error) {
+	before, err := s.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign, err := campaignFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	campaign.ID = id
+
+	if err := s.repo.UpdateCampaign(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("ошибка изменения кампании: %w", err)
+	}
+
+	recordAudit(WithTenant(ctx, campaign.Tenant), s.audit, actor, model.AuditCampaignUpdated, campaignTarget(id), before, campaign)
+
+	return campaign, nil
+}
+
+func (s *CampaignSvc) DeleteCampaign(ctx context.Context, id int64, actor string) error {
+	before, err := s.GetCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteCampaign(ctx, id); err != nil {
+		return fmt.Errorf("ошибка удаления кампании: %w", err)
+	}
+
+	recordAudit(WithTenant(ctx, before.Tenant), s.audit, actor, model.AuditCampaignDeleted, campaignTarget(id), before, nil)
+
+	return nil
+}
+
+// GetCampaign возвращает кампанию, доступную вызывающему. Кампания другого арендатора
+// для сотрудника, вошедшего по JWT, не отличается от несуществующей.
+func (s *CampaignSvc) GetCampaign(ctx context.Context, id int64) (*model.Campaign, error) {
+	campaign, err := s.repo.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения кампании: %w", err)
+	}
+
+	if scope := tenantScope(ctx); scope != "" && campaign.Tenant != scope {
+		return nil, fmt.Errorf("ошибка получения кампании: %w", errors.ErrCampaignNotFound)
+	}
+
+	return campaign, nil
+}
+
+func (s *CampaignSvc) GetCampaigns(ctx context.Context) ([]*model.Campaign, error) {
+	campaigns, err := s.repo.GetCampaigns(ctx, tenantScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения кампаний: %w", err)
+	}
+	return campaigns, nil
+}
+
+func (s *CampaignSvc) GetBonuses(ctx context.Context, userID int64) ([]*model.CampaignBonus, error) {
+	bonuses, err := s.repo.GetCampaignBonuses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения бонусов кампаний: %w", err)
+	}
+	return bonuses, nil
+}
+
+func (s *CampaignSvc) OnRegistration(ctx context.Context, userID int64) {
+	campaigns, err := s.activeCampaigns(ctx, TenantFromContext(ctx), model.CampaignWelcomeBonus, time.Now())
+	if err != nil {
+		log.Errorf("Ошибка начисления приветственных бонусов пользователю %d: %s", userID, err.Error())
+		return
+	}
+
+	for _, campaign := range campaigns {
+		err := s.credit(ctx, campaign, &model.CampaignBonus{
+			UserID:    userID,
+			Reference: userActor(userID),
+			Amount:    campaign.Amount,
+		})
+		if err != nil {
+			log.Errorf("Ошибка начисления приветственного бонуса пользователю %d: %s", userID, err.Error())
+		}
+	}
+}
+
+// OnOrderProcessed начисляет бонусы кампаний за заказ. Уже начисленные бонусы повторно
+// не начисляются, поэтому после ошибки вызов можно повторить.
+func (s *CampaignSvc) OnOrderProcessed(ctx context.Context, order *model.Order, accrual float64) error {
+	tenant := cmp.Or(order.Tenant, model.DefaultTenant)
+	uploadedAt := order.UploadedAt
+	if uploadedAt.IsZero() {
+		uploadedAt = time.Now()
+	}
+
+	newBonus := func(amount float64) *model.CampaignBonus {
+		return &model.CampaignBonus{
+			UserID:      order.UserID,
+			OrderID:     order.ID,
+			OrderNumber: order.Number,
+			Reference:   "order:" + strconv.FormatInt(order.ID, 10),
+			Amount:      amount,
+		}
+	}
+
+	multiplierCampaigns, err := s.activeCampaigns(ctx, tenant, model.CampaignAccrualMultiplier, uploadedAt)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, campaign := range multiplierCampaigns {
+		amount := math.Round(accrual*(campaign.Multiplier-1)*100) / 100
+		if amount > 0 {
+			errs = append(errs, s.credit(ctx, campaign, newBonus(amount)))
+		}
+	}
+
+	nthCampaigns, err := s.activeCampaigns(ctx, tenant, model.CampaignNthOrder, uploadedAt)
+	if err != nil || len(nthCampaigns) == 0 {
+		return stderrors.Join(append(errs, err)...)
+	}
+
+	index, err := s.orderIndex(ctx, order)
+	if err != nil {
+		return stderrors.Join(append(errs, fmt.Errorf("ошибка определения номера заказа %s пользователя: %w", order.Number, err))...)
+	}
+
+	for _, campaign := range nthCampaigns {
+		if campaign.OrderIndex == index {
+			errs = append(errs, s.credit(ctx, campaign, newBonus(campaign.Amount)))
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+// activeCampaigns возвращает кампании арендатора указанного типа, действующие в момент at.
+func (s *CampaignSvc) activeCampaigns(ctx context.Context, tenant string, campaignType model.CampaignType, at time.Time) ([]*model.Campaign, error) {
+	campaigns, err := s.repo.GetActiveCampaigns(ctx, tenant, campaignType)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения кампаний %s: %w", campaignType, err)
+	}
+
+	return slices.DeleteFunc(campaigns, func(campaign *model.Campaign) bool {
+		return !campaign.ActiveAt(at)
+	}), nil
+}
+
+// orderIndex возвращает порядковый номер заказа среди заказов пользователя по времени загрузки, начиная с 1.
+func (s *CampaignSvc) orderIndex(ctx context.Context, order *model.Order) (int, error) {
+	orders, err := s.orders.GetOrdersByUserID(ctx, order.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения заказов пользователя: %w", err)
+	}
+
+	slices.SortFunc(orders, func(a, b *model.Order) int {
+		return cmp.Or(a.UploadedAt.Compare(b.UploadedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	for i, candidate := range orders {
+		if candidate.ID == order.ID {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w", errors.ErrOrderNotFound)
+}
+
+// credit начисляет бонус кампании, если он еще не начислен.
+func (s *CampaignSvc) credit(ctx context.Context, campaign *model.Campaign, bonus *model.CampaignBonus) error {
+	bonus.CampaignID = campaign.ID
+	bonus.CampaignName = campaign.Name
+
+	credited, err := s.repo.CreditCampaignBonus(ctx, bonus)
+	if err != nil {
+		return fmt.Errorf("ошибка начисления бонуса кампании %d пользователю %d: %w", campaign.ID, bonus.UserID, err)
+	}
+	if credited {
+		log.Infof("Пользователю %d начислен бонус %.2f по кампании %q", bonus.UserID, bonus.Amount, campaign.Name)
+	}
+	return nil
+}
+
+func campaignTarget(id int64) string {
+	return "campaign:" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventsBatchSize      = 100
+	eventsPollInterval   = 15 * time.Second
+	eventsRetention      = 24 * time.Hour
+	eventsPruneInterval  = time.Hour
+	eventsPublishTimeout = 5 * time.Second
+)
+
+type EventSvc struct {
+	repo     repository.EventRepository
+	listener repository.NotificationListener
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan struct{}]struct{}
+}
+
+func NewEventService(repo repository.EventRepository, listener repository.NotificationListener) *EventSvc {
+	return &EventSvc{
+		repo:        repo,
+		listener:    listener,
+		subscribers: make(map[int64]map[chan struct{}]struct{}),
+	}
+}
+
+func (s *EventSvc) Publish(ctx context.Context, userID int64, eventType model.UserEventType, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации события: %w", err)
+	}
+
+	if _, err := s.repo.AppendEvent(ctx, userID, eventType, data); err != nil {
+		return fmt.Errorf("ошибка публикации события: %w", err)
+	}
+
+	return nil
+}
+
+func (s *EventSvc) Subscribe(ctx context.Context, userID int64, lastEventID int64) (<-chan *model.UserEvent, error) {
+	signal := s.register(userID)
+
+	if lastEventID <= 0 {
+		var err error
+		lastEventID, err = s.repo.GetLastEventID(ctx, userID)
+		if err != nil {
+			s.unregister(userID, signal)
+			return nil, fmt.Errorf("ошибка получения последнего события: %w", err)
+		}
+	}
+
+	out := make(chan *model.UserEvent)
+
+	go func() {
+		defer close(out)
+		defer s.unregister(userID, signal)
+
+		ticker := time.NewTicker(eventsPollInterval)
+		defer ticker.Stop()
+
+		for {
+			events, err := s.repo.GetEventsAfter(ctx, userID, lastEventID, eventsBatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Errorf("Ошибка получения событий пользователя %d: %s", userID, err.Error())
+			}
+
+			for _, event := range events {
+				select {
+				case out <- event:
+					lastEventID = event.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(events) == eventsBatchSize {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-signal:
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (s *EventSvc) Run(ctx context.Context) {
+	notifications, err := s.listener.Listen(ctx, repository.UserEventsChannel)
+	if err != nil {
+		log.Errorf("Ошибка подписки на события пользователей, доставка только по опросу: %s", err.Error())
+	}
+
+	pruneTicker := time.NewTicker(eventsPruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pruneTicker.C:
+			s.prune(ctx)
+		case payload, ok := <-notifications:
+			if !ok {
+				notifications = nil
+				continue
+			}
+
+			userID, err := strconv.ParseInt(payload, 10, 64)
+			if err != nil {
+				log.Warnf("Некорректное уведомление о событии пользователя: %q", payload)
+				continue
+			}
+
+			s.wake(userID)
+		}
+	}
+}
+
+// publishEvent публикует событие без прерывания основной операции: ошибки только логируются.
+func publishEvent(ctx context.Context, events EventService, userID int64, eventType model.UserEventType, payload any) {
+	if events == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), eventsPublishTimeout)
+	defer cancel()
+
+	if err := events.Publish(ctx, userID, eventType, payload); err != nil {
+		log.Errorf("Ошибка публикации события %s пользователя %d: %s", eventType, userID, err.Error())
+	}
+}
+
+func (s *EventSvc) prune(ctx context.Context) {
+	deleted, err := s.repo.DeleteEventsBefore(ctx, time.Now().Add(-eventsRetention))
+	if err != nil {
+		log.Errorf("Ошибка удаления устаревших событий: %s", err.Error())
+		return
+	}
+
+	if deleted > 0 {
+		log.Infof("Удалено устаревших событий пользователей: %d", deleted)
+	}
+}
+
+func (s *EventSvc) register(userID int64) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signal := make(chan struct{}, 1)
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[chan struct{}]struct{})
+	}
+	s.subscribers[userID][signal] = struct{}{}
+
+	return signal
+}
+
+func (s *EventSvc) unregister(userID int64, signal chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers[userID], signal)
+	if len(s.subscribers[userID]) == 0 {
+		delete(s.subscribers, userID)
+	}
+}
+
+func (s *EventSvc) wake(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for signal := range s.subscribers[userID] {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+	}
+}
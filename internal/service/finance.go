@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// financeDefaultDays число суток в отчете, если начало периода не указано.
+	financeDefaultDays = 30
+	// financeMaxDays наибольшая длина периода отчета.
+	financeMaxDays = 366
+	// financeRunWindow время, в течение которого плановый расчет, начатый одним экземпляром сервиса,
+	// не запускается другими. Расчет просыпается раз в сутки, поэтому часа достаточно, чтобы отсечь
+	// одновременные запуски и не пропустить следующие сутки.
+	financeRunWindow = time.Hour
+)
+
+type FinanceSvc struct {
+	repo  repository.FinanceRepository
+	delay time.Duration
+}
+
+func NewFinanceService(repo repository.FinanceRepository, cfg *config.Config) *FinanceSvc {
+	return &FinanceSvc{
+		repo:  repo,
+		delay: cfg.FinanceReportDelay,
+	}
+}
+
+// startOfDay возвращает начало суток UTC, в которые попадает t.
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// financePeriod проверяет период отчета с from по to включительно. Нулевой to означает вчерашние сутки,
+// нулевой from — financeDefaultDays суток, заканчивающихся to.
+func financePeriod(from, to, now time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = startOfDay(now).AddDate(0, 0, -1)
+	}
+	to = startOfDay(to)
+
+	if from.IsZero() {
+		from = to.AddDate(0, 0, 1-financeDefaultDays)
+	}
+	from = startOfDay(from)
+
+	if from.After(to) {
+		return from, to, fmt.Errorf("%w: начало отчета должно быть не позже окончания", errors.ErrInvalidPeriod)
+	}
+	if to.Sub(from) >= financeMaxDays*24*time.Hour {
+		return from, to, fmt.Errorf("%w: период отчета не может быть длиннее %d суток", errors.ErrInvalidPeriod, financeMaxDays)
+	}
+
+	return from, to, nil
+}
+
+func (s *FinanceSvc) GetDailyReports(ctx context.Context, from, to time.Time) ([]*model.FinanceDailyReport, error) {
+	from, to, err := financePeriod(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.repo.GetReports(ctx, TenantFromContext(ctx), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения финансовых отчетов: %w", err)
+	}
+
+	return reports, nil
+}
+
+func (s *FinanceSvc) GetSummary(ctx context.Context, from, to time.Time) (*model.FinanceSummary, error) {
+	from, to, err := financePeriod(from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := s.repo.GetReports(ctx, TenantFromContext(ctx), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения финансовых отчетов: %w", err)
+	}
+
+	summary := &model.FinanceSummary{
+		From: from.Format(time.DateOnly),
+		To:   to.Format(time.DateOnly),
+		Days: len(reports),
+	}
+	for _, report := range reports {
+		summary.Accrued += report.Accrued
+		summary.Withdrawn += report.Withdrawn
+		summary.NewUsers += report.NewUsers
+		summary.OrdersProcessed += report.OrdersProcessed
+		summary.OrdersInvalid += report.OrdersInvalid
+		summary.OrdersUnregistered += report.OrdersUnregistered
+		if report.Liability != nil {
+			summary.Liability = report.Liability
+			summary.LiabilityDay = report.Day
+		}
+	}
+	summary.Accrued = roundPoints(summary.Accrued)
+	summary.Withdrawn = roundPoints(summary.Withdrawn)
+
+	return summary, nil
+}
+
+func (s *FinanceSvc) ExportDailyReports(ctx context.Context, from, to time.Time, format model.ExportFormat, w io.Writer) error {
+	if format != model.ExportCSV {
+		return fmt.Errorf("%w: %q", errors.ErrInvalidExportFormat, format)
+	}
+
+	reports, err := s.GetDailyReports(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"day", "tenant", "accrued", "withdrawn", "new_users", "orders_processed", "orders_invalid", "orders_unregistered", "liability"}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("ошибка записи заголовка выгрузки: %w", err)
+	}
+
+	for _, report := range reports {
+		var liability string
+		if report.Liability != nil {
+			liability = strconv.FormatFloat(*report.Liability, 'f', 2, 64)
+		}
+		record := []string{
+			report.Day,
+			report.Tenant,
+			strconv.FormatFloat(report.Accrued, 'f', 2, 64),
+			strconv.FormatFloat(report.Withdrawn, 'f', 2, 64),
+			strconv.Itoa(report.NewUsers),
+			strconv.Itoa(report.OrdersProcessed),
+			strconv.Itoa(report.OrdersInvalid),
+			strconv.Itoa(report.OrdersUnregistered),
+			liability,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("ошибка записи выгрузки: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("ошибка записи выгрузки: %w", err)
+	}
+
+	return nil
+}
+
+// MaterializeReports рассчитывает отчеты за все сутки от последних рассчитанных до until, не включая их,
+// и возвращает число рассчитанных суток. Обязательства фиксируются только для суток, закончившихся
+// не раньше начала текущих: для более ранних суток текущий остаток баллов уже не соответствует им.
+func (s *FinanceSvc) MaterializeReports(ctx context.Context, until time.Time) (int, error) {
+	next, err := s.repo.GetNextReportDay(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка определения суток для расчета отчетов: %w", err)
+	}
+	if next == nil {
+		return 0, nil
+	}
+
+	today := startOfDay(time.Now())
+	count := 0
+	for day := startOfDay(*next); day.Before(until) && ctx.Err() == nil; day = day.AddDate(0, 0, 1) {
+		snapshot := !day.AddDate(0, 0, 1).Before(today)
+		if err := s.repo.MaterializeReport(ctx, day, snapshot); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// scheduledMaterialization рассчитывает отчеты до until, если в течение financeRunWindow расчет
+// не запускал другой экземпляр сервиса.
+func (s *FinanceSvc) scheduledMaterialization(ctx context.Context, until time.Time) {
+	started, err := s.repo.StartReportMaterialization(ctx, financeRunWindow)
+	if err != nil {
+		log.Errorf("Ошибка запуска расчета финансовых отчетов: %s", err.Error())
+		return
+	}
+	if !started {
+		return
+	}
+
+	count, err := s.MaterializeReports(ctx, until)
+	if err != nil {
+		log.Errorf("Ошибка расчета финансовых отчетов: %s", err.Error())
+	} else if count > 0 {
+		log.Infof("Рассчитано суточных финансовых отчетов: %d", count)
+	}
+}
+
+func (s *FinanceSvc) RunReportMaterialization(ctx context.Context) {
+	log.Info("Запуск расчета финансовых отчетов")
+
+	for {
+		now := time.Now()
+		today := startOfDay(now)
+
+		s.scheduledMaterialization(ctx, today)
+
+		next := today.Add(s.delay)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Info("Остановка расчета финансовых отчетов")
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -2,13 +2,11 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	stderrors "errors"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Gerfey/gophermart/internal/config"
@@ -25,30 +23,63 @@ const (
 	ErrOrderRegisteredBy = 409
 
 	defaultCheckInterval = 10 * time.Second
+	defaultClaimBatch    = 100
+	defaultClaimLease    = time.Minute
+
+	// MaxOrderBatchSize максимальное число номеров в одном пакетном запросе.
+	MaxOrderBatchSize = 1000
 )
 
 type OrderSvc struct {
-	orderRepo        repository.OrderRepository
-	balanceRepo      repository.BalanceRepository
-	accrualSystemURL string
-	checkInterval    time.Duration
+	orderRepo     repository.OrderRepository
+	balanceRepo   repository.BalanceRepository
+	listener      repository.NotificationListener
+	events        EventService
+	audit         AuditService
+	tiers         TierService
+	campaigns     CampaignService
+	referrals     ReferralService
+	accrual       *AccrualClient
+	checkInterval time.Duration
+	workerID      string
+	claimBatch    int
+	claimLease    time.Duration
+	retries       sync.WaitGroup
+	validators    *OrderNumberValidators
+
+	unregisteredTTL        time.Duration
+	unregisteredMaxBackoff time.Duration
 }
 
-func NewOrderService(orderRepo repository.OrderRepository, balanceRepo repository.BalanceRepository, cfg *config.Config) *OrderSvc {
+func NewOrderService(orderRepo repository.OrderRepository, balanceRepo repository.BalanceRepository, listener repository.NotificationListener, events EventService, audit AuditService, tiers TierService, campaigns CampaignService, referrals ReferralService, validators *OrderNumberValidators, accrual *AccrualClient, cfg *config.Config) *OrderSvc {
 	return &OrderSvc{
-		orderRepo:        orderRepo,
-		balanceRepo:      balanceRepo,
-		accrualSystemURL: cfg.AccrualSystemAddress,
-		checkInterval:    defaultCheckInterval,
+		orderRepo:     orderRepo,
+		balanceRepo:   balanceRepo,
+		listener:      listener,
+		events:        events,
+		audit:         audit,
+		tiers:         tiers,
+		campaigns:     campaigns,
+		referrals:     referrals,
+		accrual:       accrual,
+		checkInterval: defaultCheckInterval,
+		workerID:      cfg.WorkerID,
+		claimBatch:    defaultClaimBatch,
+		claimLease:    defaultClaimLease,
+		validators:    validators,
+
+		unregisteredTTL:        cfg.UnregisteredOrderTTL,
+		unregisteredMaxBackoff: cfg.UnregisteredOrderMaxBackoff,
 	}
 }
 
 func (s *OrderSvc) CreateOrder(ctx context.Context, userID int64, number string) (int, error) {
-	if !IsValidLuhnNumber(number) {
-		return ErrOrderNotValid, fmt.Errorf("%w", errors.ErrInvalidLuhn)
+	tenant := TenantFromContext(ctx)
+	if err := s.validators.Validate(tenant, number); err != nil {
+		return ErrOrderNotValid, fmt.Errorf("%w", err)
 	}
 
-	existingOrder, err := s.orderRepo.GetOrderByNumber(ctx, number)
+	existingOrder, err := s.orderRepo.GetOrderByNumber(ctx, tenant, number)
 	if err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("ошибка проверки существования заказа: %w", err)
 	}
@@ -60,14 +91,90 @@ func (s *OrderSvc) CreateOrder(ctx context.Context, userID int64, number string)
 		return ErrOrderRegisteredBy, fmt.Errorf("%w", errors.ErrOrderAlreadyExists)
 	}
 
-	_, err = s.orderRepo.CreateOrder(ctx, userID, number)
+	_, err = s.orderRepo.CreateOrder(ctx, tenant, userID, number)
 	if err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("ошибка создания заказа: %w", err)
 	}
 
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditOrderUploaded, "order:"+number, nil, nil)
+
 	return ErrOrderAccepted, nil
 }
 
+func (s *OrderSvc) CreateOrders(ctx context.Context, userID int64, numbers []string) ([]model.BatchOrderResult, error) {
+	seen := make(map[string]bool, len(numbers))
+	unique := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		number = strings.TrimSpace(number)
+		if number == "" || seen[number] {
+			continue
+		}
+		seen[number] = true
+		unique = append(unique, number)
+	}
+
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("%w: пакет не содержит номеров заказов", errors.ErrInvalidBatch)
+	}
+	if len(unique) > MaxOrderBatchSize {
+		return nil, fmt.Errorf("%w: в пакете больше %d номеров", errors.ErrInvalidBatch, MaxOrderBatchSize)
+	}
+
+	results := make([]model.BatchOrderResult, len(unique))
+	valid := make([]string, 0, len(unique))
+	positions := make(map[string]int, len(unique))
+	tenant := TenantFromContext(ctx)
+	validator := s.validators.For(tenant)
+	for i, number := range unique {
+		if err := validator.Validate(number); err != nil {
+			results[i] = invalidBatchOrderResult(number, err)
+			continue
+		}
+		positions[number] = i
+		valid = append(valid, number)
+	}
+
+	var accepted []string
+	if len(valid) > 0 {
+		created, err := s.orderRepo.CreateOrders(ctx, tenant, userID, valid)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка пакетного создания заказов: %w", err)
+		}
+
+		for _, result := range created {
+			results[positions[result.Number]] = result
+			if result.Result == model.BatchOrderAccepted {
+				accepted = append(accepted, result.Number)
+			}
+		}
+	}
+
+	if len(accepted) > 0 {
+		recordAudit(ctx, s.audit, userActor(userID), model.AuditOrdersBatch, userActor(userID), nil, map[string]any{
+			"accepted": accepted,
+			"total":    len(unique),
+		})
+	}
+
+	return results, nil
+}
+
+// invalidBatchOrderResult описывает номер пакета, не прошедший проверку. Нарушение алгоритма Луна
+// сохраняет прежний результат invalid_luhn, остальные правила возвращают invalid_number.
+func invalidBatchOrderResult(number string, err error) model.BatchOrderResult {
+	result := model.BatchOrderResult{Number: number, Result: model.BatchOrderInvalidNumber, Reason: err.Error()}
+	if stderrors.Is(err, errors.ErrInvalidLuhn) {
+		result.Result = model.BatchOrderInvalidLuhn
+	}
+
+	var numberErr *errors.OrderNumberError
+	if stderrors.As(err, &numberErr) {
+		result.Reason = numberErr.Reason
+	}
+
+	return result
+}
+
 func (s *OrderSvc) GetOrdersByUserID(ctx context.Context, userID int64) ([]model.OrderResponse, error) {
 	orders, err := s.orderRepo.GetOrdersByUserID(ctx, userID)
 	if err != nil {
@@ -92,134 +199,354 @@ func (s *OrderSvc) GetOrdersByUserID(ctx context.Context, userID int64) ([]model
 	return response, nil
 }
 
+func (s *OrderSvc) GetOrder(ctx context.Context, userID int64, number string) (*model.OrderDetailResponse, error) {
+	order, err := s.orderRepo.GetOrderByNumber(ctx, TenantFromContext(ctx), number)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заказа: %w", err)
+	}
+
+	// Чужой заказ неотличим от несуществующего, чтобы не раскрывать занятые номера.
+	if order == nil || order.UserID != userID {
+		return nil, fmt.Errorf("%w", errors.ErrOrderNotFound)
+	}
+
+	history, err := s.orderRepo.GetOrderHistory(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории статусов заказа: %w", err)
+	}
+
+	response := &model.OrderDetailResponse{
+		Number:        order.Number,
+		Status:        order.Status,
+		UploadedAt:    order.UploadedAt,
+		LastCheckedAt: order.LastCheckedAt,
+		History:       make([]model.OrderStatusChange, 0, len(history)),
+	}
+
+	if order.Status == model.OrderStatusProcessed {
+		response.Accrual = order.Accrual
+	}
+
+	for _, change := range history {
+		response.History = append(response.History, *change)
+	}
+
+	return response, nil
+}
+
 func (s *OrderSvc) ProcessOrdersBackground(ctx context.Context) {
 	log.Info("Запуск фоновой обработки заказов")
 
 	ticker := time.NewTicker(s.checkInterval)
 	defer ticker.Stop()
 
+	// Периодический обход остается страховкой на случай пропущенных уведомлений.
+	newOrders, err := s.listener.Listen(ctx, repository.NewOrdersChannel)
+	if err != nil {
+		log.Errorf("Ошибка подписки на уведомления о новых заказах, проверка только по расписанию: %s", err.Error())
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			log.Info("Остановка фоновой обработки заказов")
+			log.Info("Остановка фоновой обработки заказов, ожидание отложенных проверок")
+			s.retries.Wait()
 			return
 		case <-ticker.C:
 			s.processOrders(ctx)
+			s.retryOrderBonuses(ctx)
+		case payload, ok := <-newOrders:
+			if !ok {
+				newOrders = nil
+				continue
+			}
+			s.processNewOrder(ctx, payload)
 		}
 	}
 }
 
+func (s *OrderSvc) processNewOrder(ctx context.Context, payload string) {
+	tenant, number := repository.ParseNewOrderPayload(payload)
+
+	order, err := s.orderRepo.ClaimOrderByNumber(ctx, s.workerID, tenant, number, s.claimLease)
+	if err != nil {
+		log.Errorf("Ошибка захвата нового заказа %s: %s", number, err.Error())
+		return
+	}
+
+	if order == nil {
+		return
+	}
+
+	s.checkOrderStatus(ctx, order)
+}
+
 func (s *OrderSvc) processOrders(ctx context.Context) {
-	orders, err := s.orderRepo.GetNewOrProcessingOrders(ctx)
+	orders, err := s.orderRepo.ClaimOrdersForCheck(ctx, s.workerID, s.claimBatch, s.claimLease)
 	if err != nil {
 		log.Errorf("Ошибка получения заказов для проверки: %s", err.Error())
 		return
 	}
 
-	for _, order := range orders {
+	for i, order := range orders {
+		if ctx.Err() != nil {
+			for _, rest := range orders[i:] {
+				s.releaseOrder(rest)
+			}
+			return
+		}
 		s.checkOrderStatus(ctx, order)
 	}
 }
 
+// releaseOrder снимает захват заказа текущим обработчиком, чтобы заказ
+// мог быть проверен снова на следующем цикле любым экземпляром сервиса.
+func (s *OrderSvc) releaseOrder(order *model.Order) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.orderRepo.ReleaseOrder(ctx, order.ID, s.workerID); err != nil {
+		log.Errorf("Ошибка освобождения заказа %s: %s", order.Number, err.Error())
+	}
+}
+
 func (s *OrderSvc) checkOrderStatus(ctx context.Context, order *model.Order) {
-	baseURL, err := url.Parse(s.accrualSystemURL)
-	if err != nil {
-		log.Errorf("Ошибка парсинга URL системы начислений: %s", err.Error())
+	release := true
+	defer func() {
+		if release {
+			s.releaseOrder(order)
+		}
+	}()
+
+	accrualResp, err := s.accrual.GetOrder(ctx, order.Tenant, order.Number)
+
+	// Ответ системы начислений уже получен: обновление заказа и баланса должно
+	// завершиться даже при остановке приложения, иначе начисление будет потеряно.
+	updateCtx := context.WithoutCancel(ctx)
+
+	var rateLimitErr *errors.AccrualRateLimitError
+	switch {
+	case stderrors.As(err, &rateLimitErr):
+		if rateLimitErr.RetryAfter <= 0 {
+			log.Warn("Получен статус TooManyRequests без корректного заголовка Retry-After")
+			return
+		}
+
+		log.Warnf("Превышен лимит запросов, повторная попытка через %s", rateLimitErr.RetryAfter)
+
+		// Заказ остается захваченным до повторной проверки, чтобы его не взял в работу
+		// другой экземпляр сервиса. Захват продлевается на время ожидания и еще на одну
+		// аренду, которой хватает на саму повторную проверку.
+		extended, err := s.orderRepo.ExtendOrderClaim(updateCtx, order.ID, s.workerID, rateLimitErr.RetryAfter+s.claimLease)
+		if err != nil {
+			log.Errorf("Ошибка продления захвата заказа %s: %s", order.Number, err.Error())
+			return
+		}
+		release = false
+		if !extended {
+			// Захват потерян: заказ уже проверяет другой обработчик, освобождать его нельзя.
+			return
+		}
+
+		retryTimer := time.NewTimer(rateLimitErr.RetryAfter)
+		s.retries.Add(1)
+		go func() {
+			defer s.retries.Done()
+			defer retryTimer.Stop()
+
+			select {
+			case <-ctx.Done():
+				s.releaseOrder(order)
+				return
+			case <-retryTimer.C:
+				log.Infof("Повторная попытка проверки статуса заказа %s после ожидания", order.Number)
+				s.checkOrderStatus(ctx, order)
+			}
+		}()
+	case err != nil:
+		log.Errorf("Ошибка запроса заказа %s в системе начислений: %s", order.Number, err.Error())
+	case accrualResp == nil:
+		s.handleUnregisteredOrder(updateCtx, order)
+	default:
+		s.applyAccrual(updateCtx, order, accrualResp)
+	}
+}
+
+// applyAccrual переносит в заказ статус и начисление, полученные от системы расчета.
+func (s *OrderSvc) applyAccrual(ctx context.Context, order *model.Order, accrualResp *model.AccrualResponse) {
+	switch accrualResp.Status {
+	case model.AccrualStatusRegistered, model.AccrualStatusProcessing:
+		var newStatus model.OrderStatus
+		if accrualResp.Status == model.AccrualStatusRegistered {
+			newStatus = model.OrderStatusNew
+		} else {
+			newStatus = model.OrderStatusProcessing
+		}
+
+		if order.Status != newStatus {
+			if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, newStatus); err != nil {
+				log.Errorf("Ошибка обновления статуса заказа: %s", err.Error())
+				return
+			}
+			s.publishOrderEvent(ctx, order, newStatus, 0)
+		}
+	case model.AccrualStatusInvalid:
+		if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, model.OrderStatusInvalid); err != nil {
+			log.Errorf("Ошибка обновления статуса заказа как невалидного: %s", err.Error())
+			return
+		}
+		s.publishOrderEvent(ctx, order, model.OrderStatusInvalid, 0)
+	case model.AccrualStatusProcessed:
+		bonus := s.tierBonus(ctx, order.UserID, accrualResp.Accrual)
+		err := s.orderRepo.ProcessOrderAccrual(ctx, order.ID, accrualResp.Accrual, bonus, s.claimLease)
+		if stderrors.Is(err, errors.ErrOrderProcessed) {
+			// Повторный ответ PROCESSED: начисление уже зачислено, а бонусы поставлены в очередь.
+			log.Infof("Заказ %s уже обработан, повторное начисление пропущено", order.Number)
+			return
+		}
+		if err != nil {
+			log.Errorf("Ошибка зачисления начисления по заказу: %s", err.Error())
+			return
+		}
+		s.publishOrderEvent(ctx, order, model.OrderStatusProcessed, accrualResp.Accrual)
+		s.creditOrderBonuses(ctx, order, accrualResp.Accrual)
+		s.publishBalanceEvent(ctx, order.UserID)
+	}
+
+	s.recordOrderCheck(ctx, order, accrualResp.Status)
+}
+
+// creditOrderBonuses начисляет бонусы кампаний и реферальной программы за обработанный заказ
+// и снимает задачу из очереди бонусов. После ошибки задача остается в очереди и повторяется
+// фоновой обработкой, когда истечет ее аренда.
+func (s *OrderSvc) creditOrderBonuses(ctx context.Context, order *model.Order, accrual float64) {
+	var errs []error
+	if s.campaigns != nil {
+		errs = append(errs, s.campaigns.OnOrderProcessed(ctx, order, accrual))
+	}
+	if s.referrals != nil {
+		errs = append(errs, s.referrals.OnOrderProcessed(ctx, order))
+	}
+	if err := stderrors.Join(errs...); err != nil {
+		log.Errorf("Ошибка начисления бонусов за заказ %s, повтор через %s: %s", order.Number, s.claimLease, err.Error())
 		return
 	}
 
-	baseURL.Path = fmt.Sprintf("/api/orders/%s", order.Number)
+	if err := s.orderRepo.CompleteOrderBonuses(ctx, order.ID); err != nil {
+		log.Errorf("Ошибка снятия бонусов за заказ %s с очереди: %s", order.Number, err.Error())
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL.String(), nil)
+// retryOrderBonuses повторяет начисление бонусов за заказы, которое не завершилось
+// при обработке заказа.
+func (s *OrderSvc) retryOrderBonuses(ctx context.Context) {
+	tasks, err := s.orderRepo.ClaimOrderBonuses(ctx, s.claimBatch, s.claimLease)
 	if err != nil {
-		log.Errorf("Ошибка создания HTTP-запроса: %s", err.Error())
+		log.Errorf("Ошибка получения бонусов за заказы для повтора: %s", err.Error())
 		return
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Infof("Повторное начисление бонусов за заказ %s, попытка %d", task.Order.Number, task.Attempts)
+		s.creditOrderBonuses(ctx, &task.Order, task.Accrual)
+	}
+}
+
+// handleUnregisteredOrder применяет политику старения к заказу, о котором система расчета
+// не знает: интервал между проверками растет вдвое с каждым ответом 204 вплоть до
+// unregisteredMaxBackoff, а по истечении unregisteredTTL заказ получает конечный статус UNREGISTERED.
+func (s *OrderSvc) handleUnregisteredOrder(ctx context.Context, order *model.Order) {
+	since, checks, err := s.orderRepo.MarkOrderUnregistered(ctx, order.ID)
 	if err != nil {
-		log.Errorf("Ошибка выполнения HTTP-запроса к системе начислений: %s", err.Error())
+		log.Errorf("Ошибка отметки незарегистрированного заказа %s: %s", order.Number, err.Error())
 		return
 	}
-	defer resp.Body.Close()
 
-	switch resp.StatusCode {
-	case http.StatusOK:
-		var accrualResp model.AccrualResponse
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Errorf("Ошибка чтения тела ответа: %s", err.Error())
+	if s.unregisteredTTL > 0 && time.Since(since) >= s.unregisteredTTL {
+		log.Warnf("Заказ %s не зарегистрирован в системе расчета в течение %s, проверки прекращены", order.Number, s.unregisteredTTL)
+
+		if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, model.OrderStatusUnregistered); err != nil {
+			log.Errorf("Ошибка обновления статуса незарегистрированного заказа: %s", err.Error())
 			return
 		}
+		s.publishOrderEvent(ctx, order, model.OrderStatusUnregistered, 0)
+	} else {
+		backoff := s.unregisteredBackoff(checks)
+		log.Warnf("Заказ %s не зарегистрирован в системе расчета, следующая проверка через %s", order.Number, backoff)
 
-		if err := json.Unmarshal(body, &accrualResp); err != nil {
-			log.Errorf("Ошибка разбора JSON-ответа: %s", err.Error())
-			return
+		if err := s.orderRepo.DeferOrderCheck(ctx, order.ID, time.Now().Add(backoff)); err != nil {
+			log.Errorf("Ошибка переноса проверки заказа: %s", err.Error())
 		}
+	}
 
-		switch accrualResp.Status {
-		case model.AccrualStatusRegistered, model.AccrualStatusProcessing:
-			var newStatus model.OrderStatus
-			if accrualResp.Status == model.AccrualStatusRegistered {
-				newStatus = model.OrderStatusNew
-			} else {
-				newStatus = model.OrderStatusProcessing
-			}
+	s.recordOrderCheck(ctx, order, model.AccrualStatusNotRegistered)
+}
 
-			if order.Status != newStatus {
-				if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, newStatus); err != nil {
-					log.Errorf("Ошибка обновления статуса заказа: %s", err.Error())
-				}
-			}
-		case model.AccrualStatusInvalid:
-			if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, model.OrderStatusInvalid); err != nil {
-				log.Errorf("Ошибка обновления статуса заказа как невалидного: %s", err.Error())
-			}
-		case model.AccrualStatusProcessed:
-			if err := s.orderRepo.UpdateOrderAccrual(ctx, order.ID, accrualResp.Accrual); err != nil {
-				log.Errorf("Ошибка обновления начисления заказа: %s", err.Error())
-				return
-			}
+// unregisteredBackoff возвращает интервал до следующей проверки после checks ответов 204 подряд.
+func (s *OrderSvc) unregisteredBackoff(checks int) time.Duration {
+	limit := max(s.unregisteredMaxBackoff, s.checkInterval)
 
-			if err := s.balanceRepo.AddAccrual(ctx, order.UserID, accrualResp.Accrual); err != nil {
-				log.Errorf("Ошибка добавления начисления к балансу: %s", err.Error())
-			}
-		}
-	case http.StatusNoContent:
-		log.Warnf("Заказ %s не зарегистрирован в системе расчета", order.Number)
-	case http.StatusTooManyRequests:
-		retryAfter := resp.Header.Get("Retry-After")
-		if retryAfter != "" {
-			seconds, err := strconv.Atoi(retryAfter)
-			if err == nil {
-				log.Warnf("Превышен лимит запросов, повторная попытка через %d секунд", seconds)
-
-				retryTimer := time.NewTimer(time.Duration(seconds) * time.Second)
-				go func() {
-					defer retryTimer.Stop()
-
-					select {
-					case <-ctx.Done():
-						return
-					case <-retryTimer.C:
-						log.Infof("Повторная попытка проверки статуса заказа %s после ожидания", order.Number)
-						s.checkOrderStatus(ctx, order)
-					}
-				}()
-			} else {
-				log.Warnf("Не удалось распарсить заголовок Retry-After: %s", err.Error())
-			}
-		} else {
-			log.Warn("Получен статус TooManyRequests без заголовка Retry-After")
-		}
-	default:
-		log.Errorf("Неожиданный ответ от системы начислений: %d", resp.StatusCode)
+	backoff := s.checkInterval
+	for i := 1; i < checks && backoff < limit; i++ {
+		backoff *= 2
 	}
+
+	return min(backoff, limit)
+}
+
+// recordOrderCheck сохраняет время проверки и, при смене статуса, запись в истории заказа.
+// Ошибка записи истории не отменяет уже примененное обновление заказа.
+func (s *OrderSvc) recordOrderCheck(ctx context.Context, order *model.Order, accrualStatus model.AccrualSystemStatus) {
+	if err := s.orderRepo.RecordOrderCheck(ctx, order.ID, accrualStatus); err != nil {
+		log.Errorf("Ошибка записи истории статусов заказа %s: %s", order.Number, err.Error())
+	}
+}
+
+func (s *OrderSvc) publishOrderEvent(ctx context.Context, order *model.Order, status model.OrderStatus, accrual float64) {
+	publishEvent(ctx, s.events, order.UserID, model.UserEventOrder, model.OrderResponse{
+		Number:     order.Number,
+		Status:     status,
+		Accrual:    accrual,
+		UploadedAt: order.UploadedAt,
+	})
+}
+
+// tierBonus возвращает надбавку уровня лояльности к начислению. Если уровень определить не удалось,
+// начисление зачисляется без надбавки, чтобы не задерживать обработку заказа.
+func (s *OrderSvc) tierBonus(ctx context.Context, userID int64, accrual float64) *model.TierBonus {
+	if s.tiers == nil {
+		return nil
+	}
+
+	bonus, err := s.tiers.AccrualBonus(ctx, userID, accrual)
+	if err != nil {
+		log.Errorf("Ошибка расчета надбавки уровня лояльности: %s", err.Error())
+		return nil
+	}
+	return bonus
+}
+
+func (s *OrderSvc) publishBalanceEvent(ctx context.Context, userID int64) {
+	balance, err := s.balanceRepo.GetBalance(ctx, userID)
+	if err != nil {
+		log.Errorf("Ошибка получения баланса для события: %s", err.Error())
+		return
+	}
+
+	publishEvent(ctx, s.events, userID, model.UserEventBalance, model.BalanceResponse{
+		Current:   balance.Current,
+		Withdrawn: balance.Withdrawn,
+	})
 }
 
 func IsValidLuhnNumber(number string) bool {
 	number = strings.TrimSpace(number)
+	if number == "" {
+		return false
+	}
 
 	for _, c := range number {
 		if c < '0' || c > '9' {
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Верстка PDF: страница A4 в альбомной ориентации, размеры в пунктах.
+const (
+	pdfPageWidth   = 842
+	pdfPageHeight  = 595
+	pdfMargin      = 36
+	pdfFontSize    = 8
+	pdfLeading     = 11
+	pdfRowsPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// Объекты документа с заранее известными номерами; записываются при закрытии,
+// когда известен список страниц.
+const (
+	pdfCatalogID = 1
+	pdfPagesID   = 2
+	pdfFontID    = 3
+)
+
+// pdfColumns задает ширину колонок в символах моноширинного шрифта. Отрицательная ширина
+// выравнивает колонку по правому краю.
+var pdfColumns = []int{25, 16, 16, 16, 40, -12, -12}
+
+// pdfFontEncoding дополняет WinAnsiEncoding кириллицей в раскладке Windows-1251,
+// чтобы логины и причины корректировок выводились стандартным шрифтом без встраивания.
+var pdfFontEncoding = func() string {
+	var b strings.Builder
+	b.WriteString("<< /Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [168 /afii10023 184 /afii10071 192")
+	for _, base := range []int{10017, 10065} {
+		for i := 0; i < 32; i++ {
+			// Ё и ё стоят в списке глифов сразу после Е и е.
+			if i >= 6 {
+				b.WriteString(" /afii" + strconv.Itoa(base+i+1))
+			} else {
+				b.WriteString(" /afii" + strconv.Itoa(base+i))
+			}
+		}
+	}
+	b.WriteString("] >>")
+	return b.String()
+}()
+
+// pdfWriter записывает таблицу в PDF построчно: каждая заполненная страница сразу выводится
+// в w, в памяти хранится только текущая. Первая строка считается заголовком таблицы
+// и повторяется на каждой странице.
+type pdfWriter struct {
+	w       *pdfCounter
+	offsets []int64
+	pages   []int
+	page    bytes.Buffer
+	rows    int
+	header  string
+}
+
+func newPDFWriter(w io.Writer) (*pdfWriter, error) {
+	p := &pdfWriter{
+		w:       &pdfCounter{w: w},
+		offsets: make([]int64, pdfFontID),
+	}
+
+	if _, err := io.WriteString(p.w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"); err != nil {
+		return nil, fmt.Errorf("ошибка записи заголовка PDF: %w", err)
+	}
+
+	return p, nil
+}
+
+// WriteRow добавляет строку таблицы. Ячейки типа float64 выводятся с двумя знаками после запятой,
+// string — текстом, обрезанным по ширине колонки, nil — пустыми ячейками.
+func (p *pdfWriter) WriteRow(cells []any) error {
+	line, err := pdfLine(cells)
+	if err != nil {
+		return err
+	}
+
+	if p.rows == pdfRowsPerPage {
+		if err := p.flushPage(); err != nil {
+			return err
+		}
+	}
+	if p.rows == 0 && p.header != "" {
+		p.addLine(p.header)
+	}
+	if p.header == "" {
+		p.header = line
+	}
+	p.addLine(line)
+
+	return nil
+}
+
+// Close выводит последнюю страницу, шрифт, дерево страниц и таблицу перекрестных ссылок.
+func (p *pdfWriter) Close() error {
+	if p.rows > 0 || len(p.pages) == 0 {
+		if err := p.flushPage(); err != nil {
+			return err
+		}
+	}
+
+	font := "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding " + pdfFontEncoding + " >>"
+	if err := p.writeObject(pdfFontID, font); err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, id := range p.pages {
+		kids[i] = strconv.Itoa(id) + " 0 R"
+	}
+	pages := fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))
+	if err := p.writeObject(pdfPagesID, pages); err != nil {
+		return err
+	}
+
+	if err := p.writeObject(pdfCatalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesID)); err != nil {
+		return err
+	}
+
+	xref := p.w.n
+	var b strings.Builder
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, pdfCatalogID, xref)
+
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+func (p *pdfWriter) addLine(line string) {
+	y := pdfPageHeight - pdfMargin - pdfFontSize - p.rows*pdfLeading
+	fmt.Fprintf(&p.page, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", pdfFontSize, pdfMargin, y, line)
+	p.rows++
+}
+
+// flushPage выводит содержимое текущей страницы и саму страницу.
+func (p *pdfWriter) flushPage() error {
+	contentID := p.newObject()
+	content := fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", p.page.Len(), p.page.Bytes())
+	if err := p.writeObject(contentID, content); err != nil {
+		return err
+	}
+
+	pageID := p.newObject()
+	page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesID, pdfPageWidth, pdfPageHeight, pdfFontID, contentID)
+	if err := p.writeObject(pageID, page); err != nil {
+		return err
+	}
+
+	p.pages = append(p.pages, pageID)
+	p.page.Reset()
+	p.rows = 0
+	return nil
+}
+
+func (p *pdfWriter) newObject() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) writeObject(id int, body string) error {
+	p.offsets[id-1] = p.w.n
+	if _, err := fmt.Fprintf(p.w, "%d 0 obj\n%s\nendobj\n", id, body); err != nil {
+		return fmt.Errorf("ошибка записи объекта PDF: %w", err)
+	}
+	return nil
+}
+
+// pdfLine собирает строку таблицы из ячеек, выровненных по ширине колонок,
+// и кодирует ее для строкового литерала PDF.
+func pdfLine(cells []any) (string, error) {
+	var b strings.Builder
+	for i, cell := range cells {
+		var text string
+		switch value := cell.(type) {
+		case nil:
+		case float64:
+			text = strconv.FormatFloat(value, 'f', 2, 64)
+		case string:
+			text = value
+		default:
+			return "", fmt.Errorf("неподдерживаемый тип ячейки %T", cell)
+		}
+
+		width := pdfColumns[len(pdfColumns)-1]
+		if i < len(pdfColumns) {
+			width = pdfColumns[i]
+		}
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		b.WriteString(pdfText(pdfFit(text, width)))
+	}
+	return b.String(), nil
+}
+
+// pdfFit обрезает или дополняет пробелами текст до ширины колонки.
+func pdfFit(text string, width int) string {
+	right := width < 0
+	if right {
+		width = -width
+	}
+
+	runes := []rune(text)
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	padding := strings.Repeat(" ", width-len(runes))
+
+	if right {
+		return padding + string(runes)
+	}
+	return string(runes) + padding
+}
+
+// pdfText кодирует текст в однобайтовую кодировку шрифта и экранирует служебные символы
+// строкового литерала. Символы вне кодировки заменяются на "?".
+func pdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= ' ' && r <= '~':
+			b.WriteByte(byte(r))
+		case r >= 'А' && r <= 'я':
+			b.WriteByte(byte(r - 'А' + 0xC0))
+		case r == 'Ё':
+			b.WriteByte(0xA8)
+		case r == 'ё':
+			b.WriteByte(0xB8)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfCounter считает записанные байты для таблицы перекрестных ссылок.
+type pdfCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *pdfCounter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+)
+
+type RateLimitSvc struct {
+	store  repository.RateLimitStore
+	limits map[string]config.RateLimit
+}
+
+func NewRateLimitService(store repository.RateLimitStore, cfg *config.Config) *RateLimitSvc {
+	return &RateLimitSvc{
+		store:  store,
+		limits: cfg.RateLimits,
+	}
+}
+
+func (s *RateLimitSvc) Allow(ctx context.Context, rule string, key string) (model.RateLimitResult, error) {
+	limit, ok := s.limits[rule]
+	if !ok || limit.Limit == 0 {
+		return model.RateLimitResult{Allowed: true}, nil
+	}
+
+	hits, resetAt, err := s.store.Hit(ctx, rule+":"+key, limit.Window)
+	if err != nil {
+		return model.RateLimitResult{}, fmt.Errorf("ошибка проверки ограничения запросов: %w", err)
+	}
+
+	return model.RateLimitResult{
+		Allowed:   hits <= limit.Limit,
+		Limit:     limit.Limit,
+		Remaining: max(limit.Limit-hits, 0),
+		ResetAt:   resetAt,
+	}, nil
+}
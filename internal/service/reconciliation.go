@@ -0,0 +1,295 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// reconciliationCheckInterval период, с которым обработчик проверяет, не пора ли запустить плановую сверку.
+	reconciliationCheckInterval = time.Hour
+	// reconciliationMaxAttempts число запросов заказа к системе расчета, включая повторы после ответа 429.
+	reconciliationMaxAttempts = 3
+	// reconciliationDefaultRetryAfter пауза перед повтором, если система расчета не прислала Retry-After.
+	reconciliationDefaultRetryAfter = time.Second
+)
+
+type ReconciliationSvc struct {
+	repo       repository.ReconciliationRepository
+	audit      AuditService
+	accrual    *AccrualClient
+	sampleSize int
+	interval   time.Duration
+	autoFix    bool
+}
+
+func NewReconciliationService(repo repository.ReconciliationRepository, audit AuditService, accrual *AccrualClient, cfg *config.Config) *ReconciliationSvc {
+	return &ReconciliationSvc{
+		repo:       repo,
+		audit:      audit,
+		accrual:    accrual,
+		sampleSize: cfg.ReconciliationSampleSize,
+		interval:   cfg.ReconciliationInterval,
+		autoFix:    cfg.ReconciliationAutoFix,
+	}
+}
+
+func (s *ReconciliationSvc) Reconcile(ctx context.Context, autoFix bool) (*model.ReconciliationRun, error) {
+	return s.reconcile(ctx, autoFix, 0)
+}
+
+func (s *ReconciliationSvc) RunReconciliation(ctx context.Context) {
+	if s.interval <= 0 {
+		log.Info("Плановая сверка с системой расчета отключена")
+		return
+	}
+
+	log.Info("Запуск плановой сверки с системой расчета")
+
+	ticker := time.NewTicker(min(s.interval, reconciliationCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.reconcile(ctx, s.autoFix, s.interval); err != nil {
+			log.Errorf("Ошибка сверки с системой расчета: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("Остановка плановой сверки с системой расчета")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile выполняет сверку, если за minInterval не было другого запуска. Если запуск пропущен,
+// возвращает nil без ошибки. Итоги прерванной сверки сохраняются.
+func (s *ReconciliationSvc) reconcile(ctx context.Context, autoFix bool, minInterval time.Duration) (*model.ReconciliationRun, error) {
+	run, err := s.repo.StartRun(ctx, autoFix, minInterval)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запуска сверки: %w", err)
+	}
+	if run == nil {
+		return nil, nil
+	}
+
+	log.Infof("Сверка %d запущена, исправление расхождений: %t", run.ID, autoFix)
+
+	// Заказы сверяются первыми: исправление начисления меняет баланс, и проверка
+	// балансов должна видеть его результат.
+	err = s.checkOrders(ctx, run)
+	if err == nil {
+		err = s.checkOrderLedger(ctx, run)
+	}
+	if err == nil {
+		err = s.checkBalances(ctx, run)
+	}
+
+	if finishErr := s.repo.FinishRun(context.WithoutCancel(ctx), run); finishErr != nil {
+		log.Errorf("Ошибка сохранения итогов сверки %d: %s", run.ID, finishErr.Error())
+	}
+
+	log.Infof("Сверка %d завершена: проверено заказов %d (без ответа %d), балансов %d, расхождений %d, исправлено %d",
+		run.ID, run.OrdersChecked, run.OrdersFailed, run.BalancesChecked, run.Discrepancies, run.Corrected)
+
+	return run, err
+}
+
+// checkOrders повторно запрашивает выборку обработанных заказов в системе расчета и сравнивает
+// статус и начисление с сохраненными.
+func (s *ReconciliationSvc) checkOrders(ctx context.Context, run *model.ReconciliationRun) error {
+	orders, err := s.repo.GetProcessedOrders(ctx, s.sampleSize)
+	if err != nil {
+		return fmt.Errorf("ошибка получения заказов для сверки: %w", err)
+	}
+
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		run.OrdersChecked++
+
+		accrual, err := s.fetchAccrual(ctx, order)
+		if err != nil {
+			run.OrdersFailed++
+			log.Warnf("Не удалось сверить заказ %s с системой расчета: %s", order.Number, err.Error())
+			continue
+		}
+
+		discrepancy := &model.ReconciliationDiscrepancy{
+			Tenant:      order.Tenant,
+			UserID:      order.UserID,
+			OrderID:     order.ID,
+			OrderNumber: order.Number,
+			Actual:      order.Accrual,
+		}
+
+		switch {
+		case accrual == nil:
+			discrepancy.Kind = model.ReconciliationOrderStatus
+			discrepancy.Details = "заказ не зарегистрирован в системе расчета"
+		case accrual.Status != model.AccrualStatusProcessed:
+			discrepancy.Kind = model.ReconciliationOrderStatus
+			discrepancy.Expected = accrual.Accrual
+			discrepancy.Details = fmt.Sprintf("система расчета вернула статус %s", accrual.Status)
+		case math.Abs(accrual.Accrual-order.Accrual) >= model.ReconciliationTolerance:
+			discrepancy.Kind = model.ReconciliationOrderAccrual
+			discrepancy.Expected = accrual.Accrual
+			if run.AutoFix {
+				s.correctOrderAccrual(ctx, order, discrepancy)
+			}
+		default:
+			continue
+		}
+
+		s.report(ctx, run, discrepancy)
+	}
+
+	return nil
+}
+
+// correctOrderAccrual заменяет начисление заказа ответом системы расчета и зачисляет разницу корректировкой.
+func (s *ReconciliationSvc) correctOrderAccrual(ctx context.Context, order *model.Order, discrepancy *model.ReconciliationDiscrepancy) {
+	reason := fmt.Sprintf("Сверка с системой расчета: начисление по заказу %s изменено с %.2f на %.2f",
+		order.Number, order.Accrual, discrepancy.Expected)
+
+	adjustment, err := s.repo.CorrectOrderAccrual(ctx, order, discrepancy.Expected, reason)
+	if err != nil {
+		log.Errorf("Ошибка исправления начисления заказа %s: %s", order.Number, err.Error())
+		discrepancy.Details = "начисление не исправлено: " + err.Error()
+		return
+	}
+
+	discrepancy.Corrected = true
+	discrepancy.AdjustmentID = &adjustment.ID
+
+	recordAudit(WithTenant(ctx, order.Tenant), s.audit, model.ReconciliationActor, model.AuditBalanceAdjusted, userActor(order.UserID), nil, adjustment)
+}
+
+// checkOrderLedger сверяет начисления обработанных заказов с зачислениями по ним в журнале движения баллов.
+// Такие расхождения не исправляются автоматически.
+func (s *ReconciliationSvc) checkOrderLedger(ctx context.Context, run *model.ReconciliationRun) error {
+	discrepancies, err := s.repo.FindOrderLedgerDiscrepancies(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка сверки заказов с журналом движения баллов: %w", err)
+	}
+
+	for _, discrepancy := range discrepancies {
+		discrepancy.Details = "начисление по заказу не совпадает с зачислением на баланс"
+		s.report(ctx, run, discrepancy)
+	}
+
+	return nil
+}
+
+// checkBalances пересчитывает балансы по истории операций. В режиме исправления остаток и сумма
+// списаний расходящегося баланса заменяются пересчитанными.
+func (s *ReconciliationSvc) checkBalances(ctx context.Context, run *model.ReconciliationRun) error {
+	checked, discrepancies, err := s.repo.FindBalanceDiscrepancies(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка пересчета балансов: %w", err)
+	}
+	run.BalancesChecked = checked
+
+	// Расхождения остатка и суммы списаний одного баланса исправляются одной корректировкой.
+	type correction struct {
+		adjustment *model.BalanceAdjustment
+		err        error
+	}
+	corrections := make(map[int64]correction)
+	for _, discrepancy := range discrepancies {
+		if run.AutoFix {
+			result, done := corrections[discrepancy.UserID]
+			if !done {
+				result.adjustment, result.err = s.correctBalance(ctx, discrepancy.UserID, discrepancies)
+				corrections[discrepancy.UserID] = result
+			}
+			if result.err != nil {
+				discrepancy.Details = "баланс не исправлен: " + result.err.Error()
+			} else {
+				discrepancy.Corrected = true
+				discrepancy.AdjustmentID = &result.adjustment.ID
+			}
+		}
+		s.report(ctx, run, discrepancy)
+	}
+
+	return nil
+}
+
+// correctBalance приводит остаток и сумму списаний пользователя к пересчитанным по истории операций
+// и возвращает записанную корректировку.
+func (s *ReconciliationSvc) correctBalance(ctx context.Context, userID int64, discrepancies []*model.ReconciliationDiscrepancy) (*model.BalanceAdjustment, error) {
+	adjustment, err := s.repo.CorrectBalance(ctx, userID, "Сверка с историей операций: баланс приведен к пересчету")
+	if err != nil {
+		log.Errorf("Ошибка исправления баланса пользователя %d: %s", userID, err.Error())
+		return nil, err
+	}
+	if adjustment == nil {
+		return nil, fmt.Errorf("баланс совпал с историей операций до исправления")
+	}
+
+	tenant := model.DefaultTenant
+	before := make(map[model.ReconciliationKind]float64)
+	after := make(map[string]any)
+	for _, discrepancy := range discrepancies {
+		if discrepancy.UserID == userID {
+			tenant = discrepancy.Tenant
+			before[discrepancy.Kind] = discrepancy.Actual
+			after[string(discrepancy.Kind)] = discrepancy.Expected
+		}
+	}
+	after["adjustment_id"] = adjustment.ID
+	recordAudit(WithTenant(ctx, tenant), s.audit, model.ReconciliationActor, model.AuditBalanceReconciled, userActor(userID), before, after)
+
+	return adjustment, nil
+}
+
+// report записывает расхождение в отчет запуска сверки.
+func (s *ReconciliationSvc) report(ctx context.Context, run *model.ReconciliationRun, discrepancy *model.ReconciliationDiscrepancy) {
+	discrepancy.RunID = run.ID
+	run.Discrepancies++
+	if discrepancy.Corrected {
+		run.Corrected++
+	}
+
+	log.Warnf("Сверка %d: расхождение %s у пользователя %d, заказ %q: ожидается %.2f, сохранено %.2f",
+		run.ID, discrepancy.Kind, discrepancy.UserID, discrepancy.OrderNumber, discrepancy.Expected, discrepancy.Actual)
+
+	if err := s.repo.SaveDiscrepancy(context.WithoutCancel(ctx), discrepancy); err != nil {
+		log.Errorf("Ошибка записи расхождения сверки %d: %s", run.ID, err.Error())
+	}
+}
+
+// fetchAccrual запрашивает заказ в системе расчета арендатора. Для заказа, неизвестного системе
+// расчета, возвращает nil. После ответа 429 запрос повторяется через Retry-After.
+func (s *ReconciliationSvc) fetchAccrual(ctx context.Context, order *model.Order) (*model.AccrualResponse, error) {
+	for attempt := 1; ; attempt++ {
+		accrual, err := s.accrual.GetOrder(ctx, order.Tenant, order.Number)
+
+		var rateLimitErr *errors.AccrualRateLimitError
+		if !stderrors.As(err, &rateLimitErr) || attempt >= reconciliationMaxAttempts {
+			return accrual, err
+		}
+
+		timer := time.NewTimer(cmp.Or(rateLimitErr.RetryAfter, reconciliationDefaultRetryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// referralCodeAlphabet символы реферального кода без похожих друг на друга 0/O и 1/I.
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	referralCodeLength   = 8
+	// referralCodeAttempts число попыток выдать код, если сгенерированный код уже занят.
+	referralCodeAttempts = 3
+	// referralIPWindow окно, в котором считаются регистрации по реферальным кодам с одного IP.
+	referralIPWindow = 24 * time.Hour
+)
+
+type ReferralSvc struct {
+	repo          repository.ReferralRepository
+	referrerBonus float64
+	refereeBonus  float64
+	maxRewards    int
+	maxPerIP      int
+}
+
+func NewReferralService(repo repository.ReferralRepository, cfg *config.Config) *ReferralSvc {
+	return &ReferralSvc{
+		repo:          repo,
+		referrerBonus: cfg.ReferralReferrerBonus,
+		refereeBonus:  cfg.ReferralRefereeBonus,
+		maxRewards:    cfg.ReferralMaxRewards,
+		maxPerIP:      cfg.ReferralMaxPerIP,
+	}
+}
+
+// Enabled сообщает, настроен ли хотя бы один из реферальных бонусов.
+func (s *ReferralSvc) Enabled() bool {
+	return s.referrerBonus > 0 || s.refereeBonus > 0
+}
+
+func (s *ReferralSvc) ResolveCode(ctx context.Context, code string) (*model.ReferralCode, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || !s.Enabled() {
+		return nil, nil
+	}
+
+	referralCode, err := s.repo.FindReferralCode(ctx, TenantFromContext(ctx), code)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки реферального кода: %w", err)
+	}
+
+	return referralCode, nil
+}
+
+// OnRegistration записывает приглашение. Регистрация с IP, с которого пригласивший последний раз
+// запрашивал свои приглашения, и регистрации сверх лимита с одного IP сразу отклоняются.
+func (s *ReferralSvc) OnRegistration(ctx context.Context, refereeID int64, code *model.ReferralCode) {
+	if code == nil {
+		return
+	}
+
+	referral := &model.Referral{
+		ReferrerID: code.UserID,
+		RefereeID:  refereeID,
+		IP:         requestMetaFromContext(ctx).IP,
+		Status:     model.ReferralStatusPending,
+	}
+
+	switch {
+	case referral.IP != "" && referral.IP == code.IP:
+		referral.Status = model.ReferralStatusRejected
+		referral.RejectReason = model.ReferralRejectSameIP
+	case referral.IP != "" && s.maxPerIP > 0:
+		count, err := s.repo.CountReferralsByIP(ctx, referral.IP, time.Now().Add(-referralIPWindow))
+		if err != nil {
+			log.Errorf("Ошибка проверки регистраций по реферальным кодам с IP %s: %s", referral.IP, err.Error())
+			return
+		}
+		if count >= s.maxPerIP {
+			referral.Status = model.ReferralStatusRejected
+			referral.RejectReason = model.ReferralRejectIPLimit
+		}
+	}
+
+	if err := s.repo.CreateReferral(ctx, referral); err != nil {
+		log.Errorf("Ошибка записи приглашения пользователя %d: %s", refereeID, err.Error())
+		return
+	}
+
+	if referral.Status == model.ReferralStatusRejected {
+		log.Warnf("Приглашение пользователя %d пользователем %d отклонено: %s", refereeID, code.UserID, referral.RejectReason)
+	}
+}
+
+func (s *ReferralSvc) OnOrderProcessed(ctx context.Context, order *model.Order) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	referral, err := s.repo.RewardReferral(ctx, order.UserID, order.ID, s.referrerBonus, s.refereeBonus, s.maxRewards)
+	if err != nil {
+		return fmt.Errorf("ошибка начисления реферальных бонусов за заказ %s: %w", order.Number, err)
+	}
+	if referral == nil {
+		return nil
+	}
+
+	if referral.Status == model.ReferralStatusRewarded {
+		log.Infof("Начислены реферальные бонусы: пользователю %d — %.2f, пользователю %d — %.2f",
+			referral.ReferrerID, referral.ReferrerBonus, referral.RefereeID, referral.RefereeBonus)
+	} else {
+		log.Warnf("Приглашение пользователя %d пользователем %d отклонено: %s", referral.RefereeID, referral.ReferrerID, referral.RejectReason)
+	}
+	return nil
+}
+
+func (s *ReferralSvc) GetReferrals(ctx context.Context, userID int64) (*model.ReferralsResponse, error) {
+	code, err := s.issueCode(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	referrals, err := s.repo.GetReferrals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения приглашений: %w", err)
+	}
+
+	return &model.ReferralsResponse{
+		Code:      code,
+		Referrals: referrals,
+	}, nil
+}
+
+// issueCode возвращает реферальный код пользователя, при необходимости выдавая новый,
+// и запоминает IP запроса для проверки саморефералов.
+func (s *ReferralSvc) issueCode(ctx context.Context, userID int64) (string, error) {
+	code := &model.ReferralCode{
+		UserID: userID,
+		Tenant: TenantFromContext(ctx),
+		IP:     requestMetaFromContext(ctx).IP,
+	}
+
+	var err error
+	for range referralCodeAttempts {
+		code.Code, err = newReferralCode()
+		if err != nil {
+			return "", err
+		}
+
+		if err = s.repo.SaveReferralCode(ctx, code); err == nil {
+			return code.Code, nil
+		}
+	}
+
+	return "", fmt.Errorf("ошибка выдачи реферального кода: %w", err)
+}
+
+func newReferralCode() (string, error) {
+	buf := make([]byte, referralCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации реферального кода: %w", err)
+	}
+
+	for i, b := range buf {
+		buf[i] = referralCodeAlphabet[int(b)%len(referralCodeAlphabet)]
+	}
+	return string(buf), nil
+}
@@ -5,6 +5,8 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/Gerfey/gophermart/internal/config"
 	"github.com/Gerfey/gophermart/internal/model"
@@ -15,16 +17,22 @@ import (
 // Предоставляет методы для регистрации, аутентификации и проверки токенов пользователей.
 type UserService interface {
 	// RegisterUser регистрирует нового пользователя с указанными логином и паролем.
+	// Если указан реферальный код, пользователь записывается приглашенным владельцем кода;
+	// для неизвестного кода возвращается ErrInvalidReferralCode.
 	// Возвращает JWT токен в случае успешной регистрации или ошибку.
-	RegisterUser(ctx context.Context, login, password string) (string, error)
+	RegisterUser(ctx context.Context, credentials model.UserCredentials) (string, error)
 
 	// LoginUser аутентифицирует пользователя с указанными логином и паролем.
 	// Возвращает JWT токен в случае успешной аутентификации или ошибку.
 	LoginUser(ctx context.Context, login, password string) (string, error)
 
-	// ParseToken проверяет JWT токен и возвращает идентификатор пользователя.
+	// ParseToken проверяет JWT токен и возвращает идентификатор и роли пользователя.
 	// Возвращает ошибку, если токен недействителен или истек срок его действия.
-	ParseToken(token string) (int64, error)
+	ParseToken(token string) (*model.UserIdentity, error)
+
+	// GetRoles возвращает действующие роли пользователя. Роли в токене фиксируются
+	// при входе, поэтому права на служебные разделы проверяются по этому методу.
+	GetRoles(ctx context.Context, userID int64) ([]model.Role, error)
 }
 
 // OrderService интерфейс для работы с заказами.
@@ -34,29 +42,292 @@ type OrderService interface {
 	// Возвращает HTTP-код ответа и ошибку, если она возникла.
 	CreateOrder(ctx context.Context, userID int64, number string) (int, error)
 
+	// CreateOrders регистрирует пакет заказов пользователя и возвращает результат по каждому номеру
+	// в порядке их первого появления в пакете. Повторяющиеся и пустые номера пропускаются.
+	CreateOrders(ctx context.Context, userID int64, numbers []string) ([]model.BatchOrderResult, error)
+
 	// GetOrdersByUserID возвращает список заказов пользователя.
 	// Возвращает ошибку, если не удалось получить заказы.
 	GetOrdersByUserID(ctx context.Context, userID int64) ([]model.OrderResponse, error)
 
+	// GetOrder возвращает заказ пользователя вместе с историей смены статусов.
+	// Возвращает ErrOrderNotFound, если заказа нет или он принадлежит другому пользователю.
+	GetOrder(ctx context.Context, userID int64, number string) (*model.OrderDetailResponse, error)
+
 	// ProcessOrdersBackground запускает фоновую обработку заказов.
-	// Периодически проверяет статус заказов в системе начислений и обновляет их в базе данных.
+	// Проверяет статус новых заказов сразу по уведомлению из базы данных, а также периодически
+	// обходит все необработанные заказы и обновляет их в базе данных.
 	ProcessOrdersBackground(ctx context.Context)
 }
 
 // BalanceService интерфейс для работы с балансом пользователей.
-// Предоставляет методы для получения баланса, списания средств и получения истории списаний.
+// Предоставляет методы для получения баланса, списания средств, переводов между пользователями
+// и получения истории операций.
 type BalanceService interface {
 	// GetBalance возвращает текущий баланс пользователя.
 	// Возвращает ошибку, если не удалось получить баланс.
 	GetBalance(ctx context.Context, userID int64) (model.BalanceResponse, error)
 
 	// Withdraw списывает указанную сумму с баланса пользователя на указанный заказ.
-	// Возвращает ошибку, если не удалось выполнить списание.
-	Withdraw(ctx context.Context, userID int64, orderNumber string, amount float64) error
+	// Списание проверяется правилами арендатора в транзакции списания; при нарушении
+	// возвращается *errors.WithdrawalRuleError.
+	Withdraw(ctx context.Context, userID int64, req model.WithdrawRequest) error
 
 	// GetWithdrawals возвращает историю списаний пользователя.
 	// Возвращает ошибку, если не удалось получить историю списаний.
 	GetWithdrawals(ctx context.Context, userID int64) ([]model.WithdrawalResponse, error)
+
+	// Transfer переводит баллы пользователю с указанным логином в пределах арендатора.
+	// Повторный запрос с тем же ключом идемпотентности возвращает ранее выполненный перевод.
+	Transfer(ctx context.Context, userID int64, req model.TransferRequest, idempotencyKey string) (*model.TransferResponse, error)
+
+	// GetTransfers возвращает историю входящих и исходящих переводов пользователя.
+	GetTransfers(ctx context.Context, userID int64) ([]model.TransferResponse, error)
+
+	// Statement записывает в w выписку движения баллов за период [from, to) в формате CSV, XLSX или PDF
+	// с остатками на начало и конец периода. Строки выводятся по мере чтения из базы данных.
+	// Неподдерживаемый формат или период возвращают ошибку до начала записи.
+	Statement(ctx context.Context, userID int64, from, to time.Time, format model.ExportFormat, w io.Writer) error
+
+	// Reserve резервирует баллы под заказ: они перестают быть доступными, но не считаются списанными
+	// до подтверждения. Неподтвержденный резерв снимается по истечении срока.
+	Reserve(ctx context.Context, userID int64, req model.ReservationRequest) (*model.Reservation, error)
+
+	// ConfirmReservation подтверждает активный резерв и превращает его в списание.
+	ConfirmReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error)
+
+	// CancelReservation отменяет активный резерв и возвращает баллы в доступный баланс.
+	CancelReservation(ctx context.Context, userID, reservationID int64) (*model.Reservation, error)
+
+	// GetReservations возвращает резервы пользователя, начиная с последнего.
+	GetReservations(ctx context.Context, userID int64) ([]*model.Reservation, error)
+
+	// RunReservationSweeper запускает фоновое снятие резервов с истекшим сроком.
+	RunReservationSweeper(ctx context.Context)
+}
+
+// TierService интерфейс уровней лояльности. Уровень определяется по сумме начислений пользователя
+// за последние 12 месяцев и увеличивает последующие начисления системы расчета.
+type TierService interface {
+	// GetProfile возвращает профиль пользователя с текущим уровнем лояльности и историей уровней.
+	GetProfile(ctx context.Context, userID int64) (*model.ProfileResponse, error)
+
+	// AccrualBonus возвращает надбавку текущего уровня пользователя к начислению системы расчета
+	// или nil, если надбавки нет.
+	AccrualBonus(ctx context.Context, userID int64, accrual float64) (*model.TierBonus, error)
+
+	// RunTierRecalculation запускает периодический пересчет уровней лояльности всех пользователей.
+	RunTierRecalculation(ctx context.Context)
+}
+
+// CampaignService интерфейс промо-кампаний. Бонусы кампаний начисляются отдельно от начислений
+// системы расчета при регистрации пользователя и при обработке заказа. Сотруднику, вошедшему
+// по JWT, доступны только кампании его арендатора.
+type CampaignService interface {
+	// CreateCampaign создает кампанию и записывает действие сотрудника в журнал аудита.
+	CreateCampaign(ctx context.Context, req model.CampaignRequest, actor string) (*model.Campaign, error)
+
+	// UpdateCampaign заменяет параметры кампании. Уже начисленные бонусы не пересчитываются.
+	UpdateCampaign(ctx context.Context, id int64, req model.CampaignRequest, actor string) (*model.Campaign, error)
+
+	// DeleteCampaign удаляет кампанию; начисленные по ней бонусы сохраняются.
+	DeleteCampaign(ctx context.Context, id int64, actor string) error
+
+	// GetCampaign возвращает кампанию по идентификатору.
+	GetCampaign(ctx context.Context, id int64) (*model.Campaign, error)
+
+	// GetCampaigns возвращает доступные кампании, начиная с последней.
+	GetCampaigns(ctx context.Context) ([]*model.Campaign, error)
+
+	// GetBonuses возвращает бонусы кампаний, начисленные пользователю.
+	GetBonuses(ctx context.Context, userID int64) ([]*model.CampaignBonus, error)
+
+	// OnRegistration начисляет приветственные бонусы действующих кампаний арендатора.
+	OnRegistration(ctx context.Context, userID int64)
+
+	// OnOrderProcessed начисляет бонусы кампаний за обработанный заказ с начислением accrual.
+	// Повторный вызов не начисляет бонусы дважды, поэтому после ошибки его можно повторить.
+	OnOrderProcessed(ctx context.Context, order *model.Order, accrual float64) error
+}
+
+// ReferralService интерфейс реферальной программы. Пригласивший и приглашенный получают бонусы,
+// когда обработан первый заказ приглашенного.
+type ReferralService interface {
+	// ResolveCode ищет реферальный код в пределах арендатора. Возвращает nil, если код пустой
+	// или реферальная программа отключена, и ErrInvalidReferralCode, если кода нет.
+	ResolveCode(ctx context.Context, code string) (*model.ReferralCode, error)
+
+	// OnRegistration записывает приглашение зарегистрированного пользователя владельцем кода.
+	OnRegistration(ctx context.Context, refereeID int64, code *model.ReferralCode)
+
+	// OnOrderProcessed начисляет бонусы по ожидающему приглашению владельца обработанного заказа.
+	// Приглашение закрывается вместе с начислением, поэтому после ошибки вызов можно повторить.
+	OnOrderProcessed(ctx context.Context, order *model.Order) error
+
+	// GetReferrals возвращает реферальный код пользователя, выдавая его при первом обращении,
+	// и приглашенных им пользователей.
+	GetReferrals(ctx context.Context, userID int64) (*model.ReferralsResponse, error)
+}
+
+// AccountService интерфейс управления учетной записью пользователем: выгрузки персональных данных
+// и закрытия учетной записи.
+type AccountService interface {
+	// Export выгружает профиль, баланс, заказы, списания и историю движения баллов пользователя
+	// в формате json или zip.
+	Export(ctx context.Context, userID int64, format model.ExportFormat, w io.Writer) error
+
+	// DeleteAccount обезличивает учетную запись, в том числе логин в журнале аудита, снимает
+	// активные резервы и отзывает все сессии. Финансовые записи пользователя сохраняются.
+	DeleteAccount(ctx context.Context, userID int64) error
+
+	// ValidateSession проверяет, что учетная запись владельца токена не удалена.
+	// Для удаленной учетной записи возвращает ErrUserDeleted.
+	ValidateSession(ctx context.Context, userID int64) error
+}
+
+// FinanceService интерфейс финансовой отчетности. Суточные показатели арендаторов рассчитываются
+// фоновой задачей после окончания суток, отчеты запрашиваются по периоду в пределах арендатора запроса.
+type FinanceService interface {
+	// GetDailyReports возвращает суточные отчеты арендатора за период с from по to включительно.
+	// Нулевые from и to означают последние 30 суток, заканчивающиеся вчерашними.
+	GetDailyReports(ctx context.Context, from, to time.Time) ([]*model.FinanceDailyReport, error)
+
+	// GetSummary возвращает итоги суточных отчетов арендатора за период.
+	GetSummary(ctx context.Context, from, to time.Time) (*model.FinanceSummary, error)
+
+	// ExportDailyReports записывает суточные отчеты арендатора за период в w в формате csv.
+	ExportDailyReports(ctx context.Context, from, to time.Time, format model.ExportFormat, w io.Writer) error
+
+	// MaterializeReports рассчитывает отчеты за сутки, для которых они еще не рассчитаны, до until,
+	// не включая их, и возвращает число рассчитанных суток.
+	MaterializeReports(ctx context.Context, until time.Time) (int, error)
+
+	// RunReportMaterialization запускает ежесуточный расчет финансовых отчетов.
+	RunReportMaterialization(ctx context.Context)
+}
+
+// ReconciliationService интерфейс сверки с системой расчета. Сверка повторно запрашивает выборку
+// обработанных заказов в системе расчета, сверяет начисления заказов с журналом движения баллов
+// и пересчитывает балансы по истории операций; найденные расхождения записываются в отчет.
+type ReconciliationService interface {
+	// Reconcile выполняет сверку и возвращает ее итоги. При autoFix начисления заказов исправляются
+	// по ответу системы расчета с корректировкой баланса, а расходящиеся балансы — по истории операций.
+	Reconcile(ctx context.Context, autoFix bool) (*model.ReconciliationRun, error)
+
+	// RunReconciliation запускает плановую сверку. За интервал сверку выполняет один экземпляр сервиса.
+	RunReconciliation(ctx context.Context)
+}
+
+// EventService интерфейс для работы с событиями пользователей.
+// События публикуются при изменении статуса заказа и баланса и доставляются подписчикам
+// всех экземпляров приложения через уведомления Postgres.
+type EventService interface {
+	// Publish сохраняет событие пользователя и уведомляет подписчиков.
+	// Возвращает ошибку, если не удалось сохранить событие.
+	Publish(ctx context.Context, userID int64, eventType model.UserEventType, payload any) error
+
+	// Subscribe возвращает канал событий пользователя, начиная с события, следующего за lastEventID.
+	// Если lastEventID не больше нуля, доставляются только новые события. Канал закрывается после отмены контекста.
+	Subscribe(ctx context.Context, userID int64, lastEventID int64) (<-chan *model.UserEvent, error)
+
+	// Run принимает уведомления о новых событиях и будит подписчиков этого экземпляра приложения.
+	Run(ctx context.Context)
+}
+
+// WebhookService интерфейс для работы с исходящими вебхуками.
+// Предоставляет управление подписками, просмотр и повторную отправку доставок,
+// а также фоновую отправку событий из outbox. Сотруднику, вошедшему по JWT, доступны
+// только подписки и доставки его арендатора.
+type WebhookService interface {
+	// CreateSubscription создает подписку на события. Если секрет не указан, он генерируется.
+	// Возвращает подписку вместе с секретом для проверки подписи.
+	CreateSubscription(ctx context.Context, req model.WebhookSubscriptionRequest) (*model.WebhookSubscription, error)
+
+	// GetSubscriptions возвращает список подписок без секретов.
+	GetSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error)
+
+	// DeleteSubscription удаляет подписку и ее доставки.
+	DeleteSubscription(ctx context.Context, id int64) error
+
+	// GetDeliveries возвращает последние доставки, при непустом статусе — только с этим статусом.
+	GetDeliveries(ctx context.Context, status model.WebhookDeliveryStatus) ([]*model.WebhookDelivery, error)
+
+	// ReplayDelivery ставит доставку, в том числе недоставленную, в очередь на повторную отправку.
+	ReplayDelivery(ctx context.Context, id int64) error
+
+	// RunDispatcher запускает фоновую отправку вебхуков с повторами и экспоненциальной задержкой.
+	RunDispatcher(ctx context.Context)
+}
+
+// StaffService интерфейс для аутентификации сотрудников по служебным токенам API.
+type StaffService interface {
+	// ParseStaffToken проверяет служебный токен и возвращает сотрудника с его ролью.
+	ParseStaffToken(token string) (model.Staff, error)
+}
+
+// AdminService интерфейс административных операций службы поддержки.
+// Предоставляет поиск пользователей, просмотр их заказов и списаний,
+// повторную проверку заказов и ручные корректировки баланса.
+type AdminService interface {
+	// SearchUsers ищет пользователей по подстроке логина.
+	SearchUsers(ctx context.Context, loginQuery string) ([]*model.UserSummary, error)
+
+	// GetUser возвращает пользователя вместе с его балансом.
+	GetUser(ctx context.Context, userID int64) (*model.UserSummary, error)
+
+	// GetUserOrders возвращает заказы пользователя.
+	GetUserOrders(ctx context.Context, userID int64) ([]model.OrderResponse, error)
+
+	// GetUserWithdrawals возвращает историю списаний пользователя.
+	GetUserWithdrawals(ctx context.Context, userID int64) ([]model.WithdrawalResponse, error)
+
+	// RequeueOrder отправляет необработанный заказ на немедленную повторную проверку в системе начислений.
+	RequeueOrder(ctx context.Context, number string, actor string) error
+
+	// AdjustBalance применяет ручную корректировку баланса с обязательной причиной.
+	// Корректировка сохраняется вместе с автором для последующего аудита.
+	AdjustBalance(ctx context.Context, userID int64, req model.BalanceAdjustmentRequest, actor string) (*model.BalanceAdjustment, error)
+
+	// GetBalanceAdjustments возвращает историю ручных корректировок баланса пользователя.
+	GetBalanceAdjustments(ctx context.Context, userID int64) ([]*model.BalanceAdjustment, error)
+
+	// GetUserRoles возвращает роли пользователя.
+	GetUserRoles(ctx context.Context, userID int64) ([]model.Role, error)
+
+	// SetUserRoles заменяет набор ролей пользователя. Каждая выданная и отозванная
+	// роль записывается в журнал изменений вместе с автором.
+	SetUserRoles(ctx context.Context, userID int64, roles []model.Role, actor string) ([]model.Role, error)
+
+	// GetRoleChanges возвращает журнал изменений ролей пользователя.
+	GetRoleChanges(ctx context.Context, userID int64) ([]*model.RoleChange, error)
+}
+
+// AuditService интерфейс журнала аудита действий, влияющих на безопасность и деньги.
+type AuditService interface {
+	// Record добавляет событие в журнал, дополняя его арендатором, IP, user agent и идентификатором
+	// запроса из контекста.
+	Record(ctx context.Context, event *model.AuditEvent) error
+
+	// GetEvents возвращает события журнала по фильтру. Сотруднику, вошедшему по JWT,
+	// доступны только события его арендатора.
+	GetEvents(ctx context.Context, filter model.AuditFilter) ([]*model.AuditEvent, error)
+
+	// Export построчно выгружает события журнала по фильтру в формате CSV или JSONL
+	// с тем же ограничением по арендатору, что и GetEvents.
+	Export(ctx context.Context, filter model.AuditFilter, format model.ExportFormat, w io.Writer) error
+}
+
+// RateLimitService интерфейс ограничения частоты запросов.
+type TenantService interface {
+	// Resolve определяет арендатора запроса по имени из заголовка X-Tenant, а если оно не задано,
+	// по хосту запроса. Неизвестный хост относится к арендатору по умолчанию, неизвестное имя — ошибка.
+	Resolve(name, host string) (string, error)
+}
+
+type RateLimitService interface {
+	// Allow учитывает запрос по ключу (IP или пользователь) в ограничении с указанным именем
+	// и сообщает, укладывается ли он в лимит. Ограничение без настроек всегда пропускает запрос.
+	Allow(ctx context.Context, rule string, key string) (model.RateLimitResult, error)
 }
 
 // Service структура, объединяющая все сервисы приложения.
@@ -68,6 +339,32 @@ type Service struct {
 	Orders   OrderService
 	// Balances сервис для работы с балансом
 	Balances BalanceService
+	// Tiers сервис уровней лояльности
+	Tiers TierService
+	// Campaigns сервис промо-кампаний
+	Campaigns CampaignService
+	// Referrals сервис реферальной программы
+	Referrals ReferralService
+	// Accounts сервис выгрузки данных и удаления учетной записи
+	Accounts AccountService
+	// Finance сервис финансовой отчетности
+	Finance FinanceService
+	// Reconciliation сервис сверки с системой расчета
+	Reconciliation ReconciliationService
+	// Events сервис событий пользователей
+	Events EventService
+	// Webhooks сервис исходящих вебхуков
+	Webhooks WebhookService
+	// Staff сервис аутентификации сотрудников
+	Staff StaffService
+	// Admin сервис административных операций
+	Admin AdminService
+	// Audit сервис журнала аудита
+	Audit AuditService
+	// RateLimits сервис ограничения частоты запросов
+	RateLimits RateLimitService
+	// Tenants сервис определения арендатора запроса
+	Tenants TenantService
 }
 
 // NewService создает новый экземпляр Service с инициализированными сервисами.
@@ -78,9 +375,37 @@ type Service struct {
 // Возвращает:
 //   - *Service: инициализированный экземпляр сервисов
 func NewService(repos *repository.Repository, cfg *config.Config) *Service {
+	audit := NewAuditService(repos.Audit)
+	events := NewEventService(repos.Events, repos.Notifications)
+	validators := NewOrderNumberValidators(cfg.OrderNumberRules)
+	tiers := NewTierService(repos.Tiers, repos.Users, cfg)
+	campaigns := NewCampaignService(repos.Campaigns, repos.Orders, audit)
+	referrals := NewReferralService(repos.Referrals, cfg)
+	accrual := NewAccrualClient(cfg)
+	orders := NewOrderService(repos.Orders, repos.Balances, repos.Notifications, events, audit, tiers, campaigns, referrals, validators, accrual, cfg)
+	balances := NewBalanceService(repos.Balances, repos.Users, audit, validators, cfg)
+
+	rateLimitStore := repos.RateLimits
+	if cfg.RateLimitBackend != config.RateLimitBackendPostgres {
+		rateLimitStore = repository.NewMemoryRateLimitStore()
+	}
+
 	return &Service{
-		Users:    NewUserService(repos.Users, cfg),
-		Orders:   NewOrderService(repos.Orders, repos.Balances, cfg),
-		Balances: NewBalanceService(repos.Balances),
+		Users:      NewUserService(repos.Users, audit, campaigns, referrals, cfg),
+		Orders:     orders,
+		Balances:   balances,
+		Tiers:      tiers,
+		Campaigns:  campaigns,
+		Referrals:  referrals,
+		Accounts:   NewAccountService(repos.Users, repos.Orders, repos.Balances, audit),
+		Finance:    NewFinanceService(repos.Finance, cfg),
+		Reconciliation: NewReconciliationService(repos.Reconciliation, audit, accrual, cfg),
+		Events:     events,
+		Webhooks:   NewWebhookService(repos.Webhooks),
+		Staff:      NewStaffService(cfg),
+		Admin:      NewAdminService(repos.Users, repos.Orders, repos.Balances, orders, balances, audit),
+		Audit:      audit,
+		RateLimits: NewRateLimitService(rateLimitStore, cfg),
+		Tenants:    NewTenantService(cfg),
 	}
 }
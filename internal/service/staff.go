@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+)
+
+const adminStaffName = "admin"
+
+type staffToken struct {
+	staff model.Staff
+	token []byte
+}
+
+type StaffSvc struct {
+	tokens []staffToken
+}
+
+func NewStaffService(cfg *config.Config) *StaffSvc {
+	var tokens []staffToken
+
+	if cfg.AdminAPIToken != "" {
+		tokens = append(tokens, staffToken{
+			staff: model.Staff{Name: adminStaffName, Role: model.RoleAdmin},
+			token: []byte(cfg.AdminAPIToken),
+		})
+	}
+
+	for _, t := range cfg.StaffTokens {
+		tokens = append(tokens, staffToken{
+			staff: model.Staff{Name: t.Name, Role: t.Role},
+			token: []byte(t.Token),
+		})
+	}
+
+	return &StaffSvc{
+		tokens: tokens,
+	}
+}
+
+func (s *StaffSvc) ParseStaffToken(token string) (model.Staff, error) {
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), t.token) == 1 {
+			return t.staff, nil
+		}
+	}
+
+	return model.Staff{}, fmt.Errorf("%w", errors.ErrInvalidStaffToken)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+)
+
+var statementHeader = []any{"date", "operation", "order", "counterparty", "reason", "amount", "balance"}
+
+// statementWriter записывает строки выписки в формате выгрузки.
+type statementWriter interface {
+	WriteRow(cells []any) error
+	Close() error
+}
+
+// csvStatementWriter записывает строки выписки в CSV; числа выводятся с двумя знаками после запятой.
+type csvStatementWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvStatementWriter) WriteRow(cells []any) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch value := cell.(type) {
+		case float64:
+			record[i] = strconv.FormatFloat(value, 'f', 2, 64)
+		case string:
+			record[i] = value
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvStatementWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// statementPeriod проверяет период выписки [from, to). Нулевой to означает текущий момент,
+// нулевой from — начало месяца, в который попадает to. Будущая часть периода отбрасывается.
+func statementPeriod(from, to, now time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() || to.After(now) {
+		to = now
+	}
+	if from.IsZero() {
+		from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location())
+	}
+
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%w: начало выписки должно быть раньше окончания", errors.ErrInvalidPeriod)
+	}
+
+	return from, to, nil
+}
+
+func (s *BalanceSvc) Statement(ctx context.Context, userID int64, from, to time.Time, format model.ExportFormat, w io.Writer) error {
+	from, to, err := statementPeriod(from, to, time.Now())
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case model.ExportCSV, model.ExportXLSX, model.ExportPDF:
+	default:
+		return fmt.Errorf("%w: %q", errors.ErrInvalidExportFormat, format)
+	}
+
+	// Входящий остаток запрашивается до начала записи, чтобы ошибка базы данных
+	// не оставляла клиенту начатую выписку.
+	balance, err := s.repo.GetBalanceAt(ctx, userID, from)
+	if err != nil {
+		return fmt.Errorf("ошибка получения остатка на начало выписки: %w", err)
+	}
+	balance = roundPoints(balance)
+
+	var sw statementWriter
+	switch format {
+	case model.ExportXLSX:
+		if sw, err = newXLSXWriter(w, "Выписка"); err != nil {
+			return fmt.Errorf("ошибка записи выписки: %w", err)
+		}
+	case model.ExportPDF:
+		if sw, err = newPDFWriter(w); err != nil {
+			return fmt.Errorf("ошибка записи выписки: %w", err)
+		}
+	default:
+		sw = &csvStatementWriter{w: csv.NewWriter(w)}
+	}
+
+	rows := [][]any{
+		statementHeader,
+		{from.Format(time.RFC3339), model.StatementOpeningBalance, "", "", "", nil, balance},
+	}
+	for _, row := range rows {
+		if err := sw.WriteRow(row); err != nil {
+			return fmt.Errorf("ошибка записи выписки: %w", err)
+		}
+	}
+
+	err = s.repo.StreamStatement(ctx, userID, from, to, func(entry *model.BalanceHistoryEntry) error {
+		balance = roundPoints(balance + entry.Amount)
+		row := []any{
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.Operation,
+			entry.Order,
+			entry.Counterparty,
+			entry.Reason,
+			entry.Amount,
+			balance,
+		}
+		if err := sw.WriteRow(row); err != nil {
+			return fmt.Errorf("ошибка записи выписки: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка формирования выписки: %w", err)
+	}
+
+	if err := sw.WriteRow([]any{to.Format(time.RFC3339), model.StatementClosingBalance, "", "", "", nil, balance}); err != nil {
+		return fmt.Errorf("ошибка записи выписки: %w", err)
+	}
+
+	if err := sw.Close(); err != nil {
+		return fmt.Errorf("ошибка записи выписки: %w", err)
+	}
+
+	return nil
+}
+
+// roundPoints округляет сумму баллов до сотых, убирая накопленную погрешность сложения.
+func roundPoints(points float64) float64 {
+	return math.Round(points*100) / 100
+}
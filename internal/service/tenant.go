@@ -0,0 +1,83 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+)
+
+type tenantKey struct{}
+
+// WithTenant сохраняет в контексте арендатора, в пределах которого выполняется запрос.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext возвращает арендатора запроса или арендатора по умолчанию, если он не задан.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return cmp.Or(tenant, model.DefaultTenant)
+}
+
+type tenantBoundKey struct{}
+
+// WithTenantBound отмечает, что вызывающий ограничен арендатором запроса. Так действует сотрудник,
+// вошедший по JWT пользователя; служебные токены сотрудников работают во всех арендаторах.
+func WithTenantBound(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantBoundKey{}, true)
+}
+
+// tenantScope возвращает арендатора, которым ограничен вызывающий, или пустую строку,
+// если вызывающему доступны все арендаторы.
+func tenantScope(ctx context.Context) string {
+	if bound, _ := ctx.Value(tenantBoundKey{}).(bool); bound {
+		return TenantFromContext(ctx)
+	}
+	return ""
+}
+
+type TenantSvc struct {
+	tenants map[string]bool
+	hosts   map[string]string
+}
+
+func NewTenantService(cfg *config.Config) *TenantSvc {
+	s := &TenantSvc{
+		tenants: map[string]bool{model.DefaultTenant: true},
+		hosts:   make(map[string]string),
+	}
+
+	for _, tenant := range cfg.Tenants {
+		s.tenants[tenant.Name] = true
+		for _, host := range tenant.Hosts {
+			s.hosts[strings.ToLower(host)] = tenant.Name
+		}
+	}
+
+	return s
+}
+
+func (s *TenantSvc) Resolve(name, host string) (string, error) {
+	if name != "" {
+		if !s.tenants[name] {
+			return "", fmt.Errorf("%w: %s", errors.ErrUnknownTenant, name)
+		}
+		return name, nil
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	if tenant, ok := s.hosts[strings.ToLower(host)]; ok {
+		return tenant, nil
+	}
+
+	return model.DefaultTenant, nil
+}
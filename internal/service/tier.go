@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+// tierRecalcBatch число пользователей, обрабатываемых за один запрос при пересчете уровней.
+const tierRecalcBatch = 500
+
+// LoyaltyProgram уровни лояльности в порядке возрастания порога. Нулевая программа уровней не содержит.
+type LoyaltyProgram struct {
+	tiers []config.LoyaltyTier
+}
+
+func NewLoyaltyProgram(tiers []config.LoyaltyTier) *LoyaltyProgram {
+	return &LoyaltyProgram{tiers: tiers}
+}
+
+// Enabled сообщает, настроены ли уровни лояльности.
+func (p *LoyaltyProgram) Enabled() bool {
+	return p != nil && len(p.tiers) > 0
+}
+
+// TierFor возвращает уровень с наибольшим порогом, не превышающим points.
+func (p *LoyaltyProgram) TierFor(points float64) config.LoyaltyTier {
+	tier := p.tiers[0]
+	for _, candidate := range p.tiers[1:] {
+		if points < candidate.Threshold {
+			break
+		}
+		tier = candidate
+	}
+	return tier
+}
+
+// Tier возвращает уровень по имени и следующий за ним уровень, если он есть.
+func (p *LoyaltyProgram) Tier(name string) (tier config.LoyaltyTier, next *config.LoyaltyTier, ok bool) {
+	for i, candidate := range p.tiers {
+		if candidate.Name != name {
+			continue
+		}
+		if i+1 < len(p.tiers) {
+			next = &p.tiers[i+1]
+		}
+		return candidate, next, true
+	}
+	return config.LoyaltyTier{}, nil, false
+}
+
+// Bonus возвращает надбавку уровня к начислению accrual, округленную до сотых.
+func (p *LoyaltyProgram) Bonus(tier config.LoyaltyTier, accrual float64) float64 {
+	return math.Round((accrual*(tier.Multiplier-1)+tier.Bonus)*100) / 100
+}
+
+type TierSvc struct {
+	repo     repository.TierRepository
+	users    repository.UserRepository
+	program  *LoyaltyProgram
+	interval time.Duration
+}
+
+func NewTierService(repo repository.TierRepository, users repository.UserRepository, cfg *config.Config) *TierSvc {
+	return &TierSvc{
+		repo:     repo,
+		users:    users,
+		program:  NewLoyaltyProgram(cfg.LoyaltyTiers),
+		interval: cfg.TierRecalcInterval,
+	}
+}
+
+// userTier возвращает сохраненный уровень пользователя и сумму начислений, по которой он определен.
+// Пользователь без назначенного уровня относится к начальному. Уровень, удаленный из конфигурации,
+// определяется заново по сохраненной сумме.
+func (s *TierSvc) userTier(ctx context.Context, userID int64) (config.LoyaltyTier, *model.UserTier, error) {
+	stored, err := s.repo.GetUserTier(ctx, userID)
+	if err != nil {
+		return config.LoyaltyTier{}, nil, fmt.Errorf("ошибка получения уровня лояльности: %w", err)
+	}
+	if stored == nil {
+		return s.program.TierFor(0), nil, nil
+	}
+
+	if tier, _, ok := s.program.Tier(stored.Tier); ok {
+		return tier, stored, nil
+	}
+	return s.program.TierFor(stored.Points), stored, nil
+}
+
+func (s *TierSvc) GetProfile(ctx context.Context, userID int64) (*model.ProfileResponse, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователя: %w", err)
+	}
+
+	profile := &model.ProfileResponse{Login: user.Login}
+	if !s.program.Enabled() {
+		return profile, nil
+	}
+
+	tier, stored, err := s.userTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.TierResponse{
+		Name:       tier.Name,
+		Multiplier: tier.Multiplier,
+		Bonus:      tier.Bonus,
+	}
+	if stored != nil {
+		response.Points = stored.Points
+		response.UpdatedAt = &stored.UpdatedAt
+	}
+	if _, next, _ := s.program.Tier(tier.Name); next != nil {
+		response.NextTier = next.Name
+		response.PointsToNextTier = math.Max(math.Round((next.Threshold-response.Points)*100)/100, 0)
+	}
+	profile.Tier = response
+
+	history, err := s.repo.GetTierHistory(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории уровней лояльности: %w", err)
+	}
+	profile.TierHistory = history
+
+	return profile, nil
+}
+
+func (s *TierSvc) AccrualBonus(ctx context.Context, userID int64, accrual float64) (*model.TierBonus, error) {
+	if !s.program.Enabled() || accrual <= 0 {
+		return nil, nil
+	}
+
+	tier, _, err := s.userTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := s.program.Bonus(tier, accrual)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	return &model.TierBonus{Tier: tier.Name, Amount: amount}, nil
+}
+
+func (s *TierSvc) RunTierRecalculation(ctx context.Context) {
+	if !s.program.Enabled() {
+		return
+	}
+
+	log.Info("Запуск пересчета уровней лояльности")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.scheduledRecalculation(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Остановка пересчета уровней лояльности")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduledRecalculation выполняет плановый пересчет, если его не запускал другой экземпляр сервиса.
+// Запуски сравниваются с половиной интервала: этого достаточно, чтобы одновременно проснувшиеся
+// экземпляры не пересчитывали уровни вместе, и плановый запуск не пропускается из-за неточности таймера.
+func (s *TierSvc) scheduledRecalculation(ctx context.Context) {
+	started, err := s.repo.StartTierRecalculation(ctx, s.interval/2)
+	if err != nil {
+		log.Errorf("Ошибка запуска пересчета уровней лояльности: %s", err.Error())
+		return
+	}
+	if !started {
+		return
+	}
+
+	s.RecalculateTiers(ctx)
+}
+
+// RecalculateTiers определяет уровни всех пользователей по начислениям за последние 12 месяцев
+// и возвращает число пользователей, у которых уровень изменился.
+func (s *TierSvc) RecalculateTiers(ctx context.Context) int {
+	if !s.program.Enabled() {
+		return 0
+	}
+
+	since := time.Now().AddDate(-1, 0, 0)
+	changed := 0
+
+	var afterUserID int64
+	for ctx.Err() == nil {
+		batch, err := s.repo.GetTierPoints(ctx, since, afterUserID, tierRecalcBatch)
+		if err != nil {
+			log.Errorf("Ошибка получения начислений для пересчета уровней: %s", err.Error())
+			break
+		}
+
+		for _, points := range batch {
+			tier := s.program.TierFor(points.Points)
+			tierChanged, err := s.repo.SetUserTier(ctx, points.UserID, tier.Name, points.Points)
+			if err != nil {
+				log.Errorf("Ошибка сохранения уровня лояльности пользователя %d: %s", points.UserID, err.Error())
+				continue
+			}
+			if tierChanged {
+				log.Infof("Уровень лояльности пользователя %d изменен на %s", points.UserID, tier.Name)
+				changed++
+			}
+		}
+
+		if len(batch) < tierRecalcBatch {
+			break
+		}
+		afterUserID = batch[len(batch)-1].UserID
+	}
+
+	return changed
+}
@@ -1,12 +1,14 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/model"
 	"github.com/Gerfey/gophermart/internal/repository"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -16,67 +18,99 @@ const tokenTTL = 24 * time.Hour
 
 type UserSvc struct {
 	repo       repository.UserRepository
+	audit      AuditService
+	campaigns  CampaignService
+	referrals  ReferralService
 	signingKey string
 	tokenTTL   time.Duration
 }
 
 type tokenClaims struct {
 	jwt.RegisteredClaims
-	UserID int64 `json:"user_id"`
+	UserID int64        `json:"user_id"`
+	Tenant string       `json:"tenant,omitempty"`
+	Roles  []model.Role `json:"roles"`
 }
 
-func NewUserService(repo repository.UserRepository, cfg *config.Config) *UserSvc {
+func NewUserService(repo repository.UserRepository, audit AuditService, campaigns CampaignService, referrals ReferralService, cfg *config.Config) *UserSvc {
 	return &UserSvc{
 		repo:       repo,
+		audit:      audit,
+		campaigns:  campaigns,
+		referrals:  referrals,
 		signingKey: cfg.JWTSigningKey,
 		tokenTTL:   tokenTTL,
 	}
 }
 
-func (s *UserSvc) RegisterUser(ctx context.Context, login, password string) (string, error) {
-	user, err := s.repo.GetUserByLogin(ctx, login)
+func (s *UserSvc) RegisterUser(ctx context.Context, credentials model.UserCredentials) (string, error) {
+	tenant := TenantFromContext(ctx)
+	login := credentials.Login
+
+	user, err := s.repo.GetUserByLogin(ctx, tenant, login)
 	if err == nil && user != nil {
 		return "", fmt.Errorf("пользователь с логином %s уже существует", login)
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	var referralCode *model.ReferralCode
+	if s.referrals != nil {
+		referralCode, err = s.referrals.ResolveCode(ctx, credentials.ReferralCode)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("ошибка хеширования пароля: %w", err)
 	}
 
-	userID, err := s.repo.CreateUser(ctx, login, string(hashedPassword))
+	userID, err := s.repo.CreateUser(ctx, tenant, login, string(hashedPassword))
 	if err != nil {
 		return "", err
 	}
 
-	token, err := s.generateToken(userID)
+	token, err := s.generateToken(ctx, userID, tenant)
 	if err != nil {
 		return "", err
 	}
 
+	recordAudit(ctx, s.audit, userActor(userID), model.AuditUserRegistered, userActor(userID), nil, map[string]string{"login": login})
+
+	if s.campaigns != nil {
+		s.campaigns.OnRegistration(ctx, userID)
+	}
+	if s.referrals != nil {
+		s.referrals.OnRegistration(ctx, userID, referralCode)
+	}
+
 	return token, nil
 }
 
 func (s *UserSvc) LoginUser(ctx context.Context, login, password string) (string, error) {
-	user, err := s.repo.GetUserByLogin(ctx, login)
+	user, err := s.repo.GetUserByLogin(ctx, TenantFromContext(ctx), login)
 	if err != nil {
+		recordAudit(ctx, s.audit, anonymousActor, model.AuditUserLoginFailed, login, nil, nil)
 		return "", fmt.Errorf("неверный логин или пароль")
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
+		recordAudit(ctx, s.audit, anonymousActor, model.AuditUserLoginFailed, userActor(user.ID), nil, nil)
 		return "", fmt.Errorf("неверный логин или пароль")
 	}
 
-	token, err := s.generateToken(user.ID)
+	token, err := s.generateToken(ctx, user.ID, user.Tenant)
 	if err != nil {
 		return "", err
 	}
 
+	recordAudit(ctx, s.audit, userActor(user.ID), model.AuditUserLoggedIn, userActor(user.ID), nil, nil)
+
 	return token, nil
 }
 
-func (s *UserSvc) ParseToken(tokenString string) (int64, error) {
+func (s *UserSvc) ParseToken(tokenString string) (*model.UserIdentity, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("неверный метод подписи токена: %v", token.Header["alg"])
@@ -85,24 +119,53 @@ func (s *UserSvc) ParseToken(tokenString string) (int64, error) {
 	})
 
 	if err != nil {
-		return 0, fmt.Errorf("ошибка парсинга токена: %w", err)
+		return nil, fmt.Errorf("ошибка парсинга токена: %w", err)
 	}
 
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok || !token.Valid {
-		return 0, errors.New("невалидный токен")
+		return nil, errors.New("невалидный токен")
 	}
 
-	return claims.UserID, nil
+	roles := claims.Roles
+	if len(roles) == 0 {
+		// Токены, выпущенные до появления ролей, принадлежат обычным покупателям.
+		roles = []model.Role{model.RoleCustomer}
+	}
+
+	return &model.UserIdentity{
+		UserID: claims.UserID,
+		// Токены, выпущенные до появления арендаторов, относятся к арендатору по умолчанию.
+		Tenant: cmp.Or(claims.Tenant, model.DefaultTenant),
+		Roles:  roles,
+	}, nil
 }
 
-func (s *UserSvc) generateToken(userID int64) (string, error) {
+func (s *UserSvc) GetRoles(ctx context.Context, userID int64) ([]model.Role, error) {
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+
+	return roles, nil
+}
+
+// generateToken выпускает токен с арендатором и текущими ролями пользователя. Роли в токене
+// носят справочный характер: доступ к служебным разделам проверяется по ролям из базы.
+func (s *UserSvc) generateToken(ctx context.Context, userID int64, tenant string) (string, error) {
+	roles, err := s.repo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения ролей пользователя: %w", err)
+	}
+
 	claims := &tokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 		UserID: userID,
+		Tenant: tenant,
+		Roles:  roles,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
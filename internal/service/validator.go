@@ -0,0 +1,184 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOrderNumberMinLength = 2
+	defaultOrderNumberMaxLength = 32
+)
+
+// OrderNumberValidator проверяет номер заказа. При нарушении правила возвращает
+// *errors.OrderNumberError с описанием причины.
+type OrderNumberValidator interface {
+	Validate(number string) error
+}
+
+// LuhnValidator принимает только цифровые номера с корректной контрольной суммой по алгоритму Луна.
+type LuhnValidator struct{}
+
+func (LuhnValidator) Validate(number string) error {
+	if !IsValidLuhnNumber(number) {
+		return &errors.OrderNumberError{
+			Rule:   config.OrderNumberRuleLuhn,
+			Reason: "номер должен состоять из цифр и проходить проверку по алгоритму Луна",
+			Err:    errors.ErrInvalidLuhn,
+		}
+	}
+	return nil
+}
+
+// LengthValidator ограничивает длину номера в символах. Нулевой Max снимает верхнюю границу.
+type LengthValidator struct {
+	Min int
+	Max int
+}
+
+func (v LengthValidator) Validate(number string) error {
+	length := utf8.RuneCountInString(number)
+	if length >= v.Min && (v.Max == 0 || length <= v.Max) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("длина номера должна быть не меньше %d символов", v.Min)
+	if v.Max > 0 {
+		reason = fmt.Sprintf("длина номера должна быть от %d до %d символов", v.Min, v.Max)
+	}
+
+	return &errors.OrderNumberError{Rule: config.OrderNumberRuleLength, Reason: reason}
+}
+
+// PrefixValidator требует, чтобы номер начинался с заданного префикса.
+type PrefixValidator struct {
+	Prefix string
+}
+
+func (v PrefixValidator) Validate(number string) error {
+	if !strings.HasPrefix(number, v.Prefix) {
+		return &errors.OrderNumberError{
+			Rule:   config.OrderNumberRulePrefix,
+			Reason: fmt.Sprintf("номер должен начинаться с %q", v.Prefix),
+		}
+	}
+	return nil
+}
+
+// RegexValidator требует совпадения номера с регулярным выражением. Чтобы проверялся
+// весь номер, шаблон должен содержать якоря ^ и $.
+type RegexValidator struct {
+	Pattern *regexp.Regexp
+}
+
+func (v RegexValidator) Validate(number string) error {
+	if !v.Pattern.MatchString(number) {
+		return &errors.OrderNumberError{
+			Rule:   config.OrderNumberRuleRegex,
+			Reason: fmt.Sprintf("номер не соответствует формату %s", v.Pattern.String()),
+		}
+	}
+	return nil
+}
+
+// CompositeValidator применяет проверки по порядку и возвращает первое нарушение.
+type CompositeValidator []OrderNumberValidator
+
+func (v CompositeValidator) Validate(number string) error {
+	for _, validator := range v {
+		if err := validator.Validate(number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultOrderNumberValidator проверка номеров для арендаторов без собственных правил.
+var DefaultOrderNumberValidator OrderNumberValidator = CompositeValidator{
+	LengthValidator{Min: defaultOrderNumberMinLength, Max: defaultOrderNumberMaxLength},
+	LuhnValidator{},
+}
+
+// orderNumberRuleFactories сопоставляет тип правила из конфигурации с конструктором проверки.
+var orderNumberRuleFactories = map[string]func(rule config.OrderNumberRule) OrderNumberValidator{
+	config.OrderNumberRuleLuhn: func(config.OrderNumberRule) OrderNumberValidator {
+		return LuhnValidator{}
+	},
+	config.OrderNumberRuleLength: func(rule config.OrderNumberRule) OrderNumberValidator {
+		return LengthValidator{Min: rule.Min, Max: rule.Max}
+	},
+	config.OrderNumberRulePrefix: func(rule config.OrderNumberRule) OrderNumberValidator {
+		return PrefixValidator{Prefix: rule.Prefix}
+	},
+	config.OrderNumberRuleRegex: func(rule config.OrderNumberRule) OrderNumberValidator {
+		// Шаблон уже проверен при загрузке конфигурации.
+		return RegexValidator{Pattern: regexp.MustCompile(rule.Pattern)}
+	},
+}
+
+// OrderNumberValidators реестр проверок номеров заказов по арендаторам. Арендатор без
+// собственной проверки использует проверку арендатора по умолчанию, а при ее отсутствии
+// DefaultOrderNumberValidator. Нулевой реестр также использует DefaultOrderNumberValidator.
+type OrderNumberValidators struct {
+	mutex      sync.RWMutex
+	validators map[string]OrderNumberValidator
+}
+
+// NewOrderNumberValidators создает реестр с проверками, собранными из правил конфигурации.
+func NewOrderNumberValidators(rules map[string][]config.OrderNumberRule) *OrderNumberValidators {
+	registry := &OrderNumberValidators{validators: make(map[string]OrderNumberValidator, len(rules))}
+
+	for tenant, tenantRules := range rules {
+		composite := make(CompositeValidator, 0, len(tenantRules))
+		for _, rule := range tenantRules {
+			factory, ok := orderNumberRuleFactories[rule.Type]
+			if !ok {
+				log.Errorf("Неизвестный тип правила проверки номера заказа %q для %q пропущен", rule.Type, tenant)
+				continue
+			}
+			composite = append(composite, factory(rule))
+		}
+		registry.Register(tenant, composite)
+	}
+
+	return registry
+}
+
+// Register назначает проверку номеров заказов арендатору, заменяя предыдущую.
+func (r *OrderNumberValidators) Register(tenant string, validator OrderNumberValidator) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.validators[tenant] = validator
+}
+
+// For возвращает проверку номеров заказов арендатора.
+func (r *OrderNumberValidators) For(tenant string) OrderNumberValidator {
+	if r == nil {
+		return DefaultOrderNumberValidator
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if validator, ok := r.validators[tenant]; ok {
+		return validator
+	}
+	if validator, ok := r.validators[model.DefaultTenant]; ok {
+		return validator
+	}
+	return DefaultOrderNumberValidator
+}
+
+// Validate проверяет номер заказа правилами арендатора.
+func (r *OrderNumberValidators) Validate(tenant, number string) error {
+	return r.For(tenant).Validate(number)
+}
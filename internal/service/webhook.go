@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	"github.com/Gerfey/gophermart/internal/repository"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookDispatchInterval = 5 * time.Second
+	webhookBatchSize        = 50
+	webhookRequestTimeout   = 10 * time.Second
+	webhookClaimLease       = time.Minute
+	webhookMaxAttempts      = 8
+	webhookBaseBackoff      = 10 * time.Second
+	webhookMaxBackoff       = time.Hour
+	webhookDeliveriesLimit  = 100
+	webhookErrorBodyLimit   = 512
+
+	webhookSignatureHeader = "X-Gophermart-Signature"
+	webhookTimestampHeader = "X-Gophermart-Timestamp"
+	webhookEventHeader     = "X-Gophermart-Event"
+	webhookDeliveryHeader  = "X-Gophermart-Delivery"
+)
+
+type WebhookSvc struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+}
+
+type webhookEnvelope struct {
+	ID        int64                  `json:"id"`
+	Type      model.WebhookEventType `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      json.RawMessage        `json:"data"`
+}
+
+func NewWebhookService(repo repository.WebhookRepository) *WebhookSvc {
+	return &WebhookSvc{
+		repo:   repo,
+		client: &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+func (s *WebhookSvc) CreateSubscription(ctx context.Context, req model.WebhookSubscriptionRequest) (*model.WebhookSubscription, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: адрес должен быть абсолютным http(s) URL", errors.ErrInvalidWebhook)
+	}
+
+	for _, eventType := range req.EventTypes {
+		switch eventType {
+		case model.WebhookEventOrderProcessed, model.WebhookEventOrderInvalid, model.WebhookEventBalanceWithdrawn,
+			model.WebhookEventBalanceTransferred:
+		default:
+			return nil, fmt.Errorf("%w: неизвестный тип события %q", errors.ErrInvalidWebhook, eventType)
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("ошибка генерации секрета подписки: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	// Сотрудник, вошедший по JWT, подписывается только на события своего арендатора.
+	sub := &model.WebhookSubscription{
+		Tenant:     cmp.Or(tenantScope(ctx), req.Tenant, model.DefaultTenant),
+		URL:        target.String(),
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+	}
+
+	id, err := s.repo.CreateSubscription(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания подписки: %w", err)
+	}
+	sub.ID = id
+
+	return sub, nil
+}
+
+func (s *WebhookSvc) GetSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	subscriptions, err := s.repo.GetSubscriptions(ctx, tenantScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения подписок: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (s *WebhookSvc) DeleteSubscription(ctx context.Context, id int64) error {
+	if err := s.repo.DeleteSubscription(ctx, tenantScope(ctx), id); err != nil {
+		return fmt.Errorf("ошибка удаления подписки: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WebhookSvc) GetDeliveries(ctx context.Context, status model.WebhookDeliveryStatus) ([]*model.WebhookDelivery, error) {
+	deliveries, err := s.repo.GetDeliveries(ctx, tenantScope(ctx), status, webhookDeliveriesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения доставок: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *WebhookSvc) ReplayDelivery(ctx context.Context, id int64) error {
+	if err := s.repo.ReplayDelivery(ctx, tenantScope(ctx), id); err != nil {
+		return fmt.Errorf("ошибка повторной отправки: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WebhookSvc) RunDispatcher(ctx context.Context) {
+	log.Info("Запуск отправки вебхуков")
+
+	ticker := time.NewTicker(webhookDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("Остановка отправки вебхуков")
+			return
+		case <-ticker.C:
+			s.dispatch(ctx)
+		}
+	}
+}
+
+func (s *WebhookSvc) dispatch(ctx context.Context) {
+	if _, err := s.repo.FanOutOutbox(ctx, webhookBatchSize); err != nil {
+		log.Errorf("Ошибка распределения событий вебхуков: %s", err.Error())
+	}
+
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, webhookBatchSize, webhookClaimLease)
+	if err != nil {
+		log.Errorf("Ошибка получения доставок вебхуков: %s", err.Error())
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		s.deliver(ctx, delivery)
+	}
+}
+
+func (s *WebhookSvc) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	// Результат попытки фиксируется даже при остановке, иначе доставка будет ждать истечения аренды.
+	updateCtx := context.WithoutCancel(ctx)
+
+	sendErr := s.send(ctx, delivery)
+	if sendErr == nil {
+		if err := s.repo.MarkDelivered(updateCtx, delivery.ID); err != nil {
+			log.Errorf("Ошибка отметки доставки вебхука %d: %s", delivery.ID, err.Error())
+		}
+		return
+	}
+
+	dead := delivery.Attempts >= webhookMaxAttempts
+	nextAttemptAt := time.Now().Add(webhookBackoff(delivery.Attempts))
+
+	if dead {
+		log.Errorf("Доставка вебхука %d переведена в %s после %d попыток: %s", delivery.ID, model.WebhookDeliveryDead, delivery.Attempts, sendErr.Error())
+	} else {
+		log.Warnf("Ошибка доставки вебхука %d, попытка %d: %s", delivery.ID, delivery.Attempts, sendErr.Error())
+	}
+
+	if err := s.repo.MarkFailed(updateCtx, delivery.ID, nextAttemptAt, sendErr.Error(), dead); err != nil {
+		log.Errorf("Ошибка отметки неудачной доставки вебхука %d: %s", delivery.ID, err.Error())
+	}
+}
+
+func (s *WebhookSvc) send(ctx context.Context, delivery *model.WebhookDelivery) error {
+	body, err := json.Marshal(webhookEnvelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventTime,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации вебхука: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, string(delivery.EventType))
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+SignWebhookPayload(delivery.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка выполнения запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyLimit))
+		return fmt.Errorf("получатель ответил %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+
+	return nil
+}
+
+// SignWebhookPayload вычисляет HMAC-SHA256 подпись вебхука от строки "<timestamp>.<body>".
+// Получатель должен вычислить ту же подпись и сравнить ее с заголовком X-Gophermart-Signature.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempt int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempt && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, webhookMaxBackoff)
+}
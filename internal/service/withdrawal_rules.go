@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Gerfey/gophermart/internal/config"
+	"github.com/Gerfey/gophermart/internal/errors"
+	"github.com/Gerfey/gophermart/internal/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// WithdrawalRule проверяет списание по статистике, собранной в транзакции списания.
+// При нарушении возвращает *errors.WithdrawalRuleError с описанием причины.
+type WithdrawalRule interface {
+	Check(stats model.WithdrawalStats) error
+}
+
+// MinAmountRule запрещает списания меньше Min.
+type MinAmountRule struct {
+	Min float64
+}
+
+func (r MinAmountRule) Check(stats model.WithdrawalStats) error {
+	if stats.Amount < r.Min {
+		return &errors.WithdrawalRuleError{
+			Rule:   config.WithdrawalRuleMinAmount,
+			Reason: fmt.Sprintf("минимальная сумма списания %v", r.Min),
+		}
+	}
+	return nil
+}
+
+// MaxPerOrderRule ограничивает сумму всех списаний по одному заказу.
+type MaxPerOrderRule struct {
+	Max float64
+}
+
+func (r MaxPerOrderRule) Check(stats model.WithdrawalStats) error {
+	if stats.WithdrawnForOrder+stats.Amount > r.Max {
+		return &errors.WithdrawalRuleError{
+			Rule:   config.WithdrawalRuleMaxPerOrder,
+			Reason: fmt.Sprintf("по заказу можно списать не более %v, уже списано %v", r.Max, stats.WithdrawnForOrder),
+		}
+	}
+	return nil
+}
+
+// DailyCapRule ограничивает сумму списаний пользователя за текущие сутки.
+type DailyCapRule struct {
+	Cap float64
+}
+
+func (r DailyCapRule) Check(stats model.WithdrawalStats) error {
+	if stats.WithdrawnToday+stats.Amount > r.Cap {
+		return &errors.WithdrawalRuleError{
+			Rule:   config.WithdrawalRuleDailyCap,
+			Reason: fmt.Sprintf("за сутки можно списать не более %v, уже списано %v", r.Cap, stats.WithdrawnToday),
+		}
+	}
+	return nil
+}
+
+// MonthlyCapRule ограничивает сумму списаний пользователя за текущий месяц.
+type MonthlyCapRule struct {
+	Cap float64
+}
+
+func (r MonthlyCapRule) Check(stats model.WithdrawalStats) error {
+	if stats.WithdrawnThisMonth+stats.Amount > r.Cap {
+		return &errors.WithdrawalRuleError{
+			Rule:   config.WithdrawalRuleMonthlyCap,
+			Reason: fmt.Sprintf("за месяц можно списать не более %v, уже списано %v", r.Cap, stats.WithdrawnThisMonth),
+		}
+	}
+	return nil
+}
+
+// CoolingOffRule запрещает списания в течение Period после первого начисления пользователю.
+// Пользователи без начислений правилом не ограничиваются.
+type CoolingOffRule struct {
+	Period time.Duration
+}
+
+func (r CoolingOffRule) Check(stats model.WithdrawalStats) error {
+	if stats.FirstAccrualAt == nil {
+		return nil
+	}
+
+	availableAt := stats.FirstAccrualAt.Add(r.Period)
+	if stats.Now.Before(availableAt) {
+		return &errors.WithdrawalRuleError{
+			Rule:   config.WithdrawalRuleCoolingOff,
+			Reason: fmt.Sprintf("списание станет доступно после %s", availableAt.UTC().Format(time.RFC3339)),
+		}
+	}
+	return nil
+}
+
+// WithdrawalRules применяет правила по порядку и возвращает первое нарушение.
+type WithdrawalRules []WithdrawalRule
+
+func (r WithdrawalRules) Check(stats model.WithdrawalStats) error {
+	for _, rule := range r {
+		if err := rule.Check(stats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withdrawalRuleFactories сопоставляет тип правила из конфигурации с конструктором правила.
+var withdrawalRuleFactories = map[string]func(rule config.WithdrawalRule) WithdrawalRule{
+	config.WithdrawalRuleMinAmount: func(rule config.WithdrawalRule) WithdrawalRule {
+		return MinAmountRule{Min: rule.Amount}
+	},
+	config.WithdrawalRuleMaxPerOrder: func(rule config.WithdrawalRule) WithdrawalRule {
+		return MaxPerOrderRule{Max: rule.Amount}
+	},
+	config.WithdrawalRuleDailyCap: func(rule config.WithdrawalRule) WithdrawalRule {
+		return DailyCapRule{Cap: rule.Amount}
+	},
+	config.WithdrawalRuleMonthlyCap: func(rule config.WithdrawalRule) WithdrawalRule {
+		return MonthlyCapRule{Cap: rule.Amount}
+	},
+	config.WithdrawalRuleCoolingOff: func(rule config.WithdrawalRule) WithdrawalRule {
+		// Период уже проверен при загрузке конфигурации.
+		period, _ := time.ParseDuration(rule.Period)
+		return CoolingOffRule{Period: period}
+	},
+}
+
+// WithdrawalPolicy правила списания баллов по арендаторам. Арендатор без собственных правил
+// использует правила арендатора по умолчанию; нулевая политика списания не ограничивает.
+type WithdrawalPolicy struct {
+	rules map[string]WithdrawalRules
+}
+
+// NewWithdrawalPolicy собирает правила списания из конфигурации.
+func NewWithdrawalPolicy(rules map[string][]config.WithdrawalRule) *WithdrawalPolicy {
+	policy := &WithdrawalPolicy{rules: make(map[string]WithdrawalRules, len(rules))}
+
+	for tenant, tenantRules := range rules {
+		compiled := make(WithdrawalRules, 0, len(tenantRules))
+		for _, rule := range tenantRules {
+			factory, ok := withdrawalRuleFactories[rule.Type]
+			if !ok {
+				log.Errorf("Неизвестный тип правила списания %q для %q пропущен", rule.Type, tenant)
+				continue
+			}
+			compiled = append(compiled, factory(rule))
+		}
+		policy.rules[tenant] = compiled
+	}
+
+	return policy
+}
+
+// For возвращает правила списания арендатора.
+func (p *WithdrawalPolicy) For(tenant string) WithdrawalRules {
+	if p == nil {
+		return nil
+	}
+
+	if rules, ok := p.rules[tenant]; ok {
+		return rules
+	}
+	return p.rules[model.DefaultTenant]
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Служебные части книги XLSX с одним листом. Лист записывается последним, чтобы его строки
+// можно было выводить в архив по мере получения.
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter записывает книгу XLSX из одного листа построчно, не накапливая строки в памяти.
+// Строки записываются как встроенные строки ячеек, числа — как числовые ячейки.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+
+	for _, part := range xlsxParts {
+		if err := writeZIPEntry(archive, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, fmt.Errorf("ошибка записи названия листа: %w", err)
+	}
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writeZIPEntry(archive, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания листа: %w", err)
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, fmt.Errorf("ошибка записи листа: %w", err)
+	}
+
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+// WriteRow записывает строку листа. Ячейки типа float64 становятся числами, string — текстом,
+// nil — пустыми ячейками.
+func (x *xlsxWriter) WriteRow(cells []any) error {
+	x.rows++
+	if _, err := io.WriteString(x.sheet, `<row r="`+strconv.Itoa(x.rows)+`">`); err != nil {
+		return err
+	}
+
+	for _, cell := range cells {
+		var err error
+		switch value := cell.(type) {
+		case nil:
+			_, err = io.WriteString(x.sheet, `<c/>`)
+		case float64:
+			_, err = io.WriteString(x.sheet, `<c><v>`+strconv.FormatFloat(value, 'f', -1, 64)+`</v></c>`)
+		case string:
+			if _, err = io.WriteString(x.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+				return err
+			}
+			if err = xml.EscapeText(x.sheet, []byte(value)); err != nil {
+				return err
+			}
+			_, err = io.WriteString(x.sheet, `</t></is></c>`)
+		default:
+			err = fmt.Errorf("неподдерживаемый тип ячейки %T", cell)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(x.sheet, `</row>`)
+	return err
+}
+
+// Close завершает лист и архив.
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+func writeZIPEntry(archive *zip.Writer, name, content string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла %s: %w", name, err)
+	}
+	if _, err := io.WriteString(file, content); err != nil {
+		return fmt.Errorf("ошибка записи файла %s: %w", name, err)
+	}
+	return nil
+}
@@ -6,7 +6,9 @@ package mock_service
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/Gerfey/gophermart/internal/model"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +37,36 @@ func (m *MockBalanceService) EXPECT() *MockBalanceServiceMockRecorder {
 	return m.recorder
 }
 
+// CancelReservation mocks base method.
+func (m *MockBalanceService) CancelReservation(arg0 context.Context, arg1 int64, arg2 int64) (*model.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelReservation", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelReservation indicates an expected call of CancelReservation.
+func (mr *MockBalanceServiceMockRecorder) CancelReservation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReservation", reflect.TypeOf((*MockBalanceService)(nil).CancelReservation), arg0, arg1, arg2)
+}
+
+// ConfirmReservation mocks base method.
+func (m *MockBalanceService) ConfirmReservation(arg0 context.Context, arg1 int64, arg2 int64) (*model.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmReservation", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmReservation indicates an expected call of ConfirmReservation.
+func (mr *MockBalanceServiceMockRecorder) ConfirmReservation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmReservation", reflect.TypeOf((*MockBalanceService)(nil).ConfirmReservation), arg0, arg1, arg2)
+}
+
 // GetBalance mocks base method.
 func (m *MockBalanceService) GetBalance(arg0 context.Context, arg1 int64) (model.BalanceResponse, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +82,36 @@ func (mr *MockBalanceServiceMockRecorder) GetBalance(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockBalanceService)(nil).GetBalance), arg0, arg1)
 }
 
+// GetReservations mocks base method.
+func (m *MockBalanceService) GetReservations(arg0 context.Context, arg1 int64) ([]*model.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReservations", arg0, arg1)
+	ret0, _ := ret[0].([]*model.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReservations indicates an expected call of GetReservations.
+func (mr *MockBalanceServiceMockRecorder) GetReservations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReservations", reflect.TypeOf((*MockBalanceService)(nil).GetReservations), arg0, arg1)
+}
+
+// GetTransfers mocks base method.
+func (m *MockBalanceService) GetTransfers(arg0 context.Context, arg1 int64) ([]model.TransferResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransfers", arg0, arg1)
+	ret0, _ := ret[0].([]model.TransferResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfers indicates an expected call of GetTransfers.
+func (mr *MockBalanceServiceMockRecorder) GetTransfers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfers", reflect.TypeOf((*MockBalanceService)(nil).GetTransfers), arg0, arg1)
+}
+
 // GetWithdrawals mocks base method.
 func (m *MockBalanceService) GetWithdrawals(arg0 context.Context, arg1 int64) ([]model.WithdrawalResponse, error) {
 	m.ctrl.T.Helper()
@@ -65,16 +127,72 @@ func (mr *MockBalanceServiceMockRecorder) GetWithdrawals(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockBalanceService)(nil).GetWithdrawals), arg0, arg1)
 }
 
+// Reserve mocks base method.
+func (m *MockBalanceService) Reserve(arg0 context.Context, arg1 int64, arg2 model.ReservationRequest) (*model.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockBalanceServiceMockRecorder) Reserve(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockBalanceService)(nil).Reserve), arg0, arg1, arg2)
+}
+
+// RunReservationSweeper mocks base method.
+func (m *MockBalanceService) RunReservationSweeper(arg0 context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunReservationSweeper", arg0)
+}
+
+// RunReservationSweeper indicates an expected call of RunReservationSweeper.
+func (mr *MockBalanceServiceMockRecorder) RunReservationSweeper(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunReservationSweeper", reflect.TypeOf((*MockBalanceService)(nil).RunReservationSweeper), arg0)
+}
+
+// Statement mocks base method.
+func (m *MockBalanceService) Statement(arg0 context.Context, arg1 int64, arg2 time.Time, arg3 time.Time, arg4 model.ExportFormat, arg5 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Statement", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Statement indicates an expected call of Statement.
+func (mr *MockBalanceServiceMockRecorder) Statement(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Statement", reflect.TypeOf((*MockBalanceService)(nil).Statement), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// Transfer mocks base method.
+func (m *MockBalanceService) Transfer(arg0 context.Context, arg1 int64, arg2 model.TransferRequest, arg3 string) (*model.TransferResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*model.TransferResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockBalanceServiceMockRecorder) Transfer(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockBalanceService)(nil).Transfer), arg0, arg1, arg2, arg3)
+}
+
 // Withdraw mocks base method.
-func (m *MockBalanceService) Withdraw(arg0 context.Context, arg1 int64, arg2 string, arg3 float64) error {
+func (m *MockBalanceService) Withdraw(arg0 context.Context, arg1 int64, arg2 model.WithdrawRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "Withdraw", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Withdraw indicates an expected call of Withdraw.
-func (mr *MockBalanceServiceMockRecorder) Withdraw(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockBalanceServiceMockRecorder) Withdraw(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockBalanceService)(nil).Withdraw), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Withdraw", reflect.TypeOf((*MockBalanceService)(nil).Withdraw), arg0, arg1, arg2)
 }
@@ -50,6 +50,36 @@ func (mr *MockOrderServiceMockRecorder) CreateOrder(arg0, arg1, arg2 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockOrderService)(nil).CreateOrder), arg0, arg1, arg2)
 }
 
+// CreateOrders mocks base method.
+func (m *MockOrderService) CreateOrders(arg0 context.Context, arg1 int64, arg2 []string) ([]model.BatchOrderResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.BatchOrderResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOrders indicates an expected call of CreateOrders.
+func (mr *MockOrderServiceMockRecorder) CreateOrders(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrders", reflect.TypeOf((*MockOrderService)(nil).CreateOrders), arg0, arg1, arg2)
+}
+
+// GetOrder mocks base method.
+func (m *MockOrderService) GetOrder(arg0 context.Context, arg1 int64, arg2 string) (*model.OrderDetailResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.OrderDetailResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockOrderServiceMockRecorder) GetOrder(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockOrderService)(nil).GetOrder), arg0, arg1, arg2)
+}
+
 // GetOrdersByUserID mocks base method.
 func (m *MockOrderService) GetOrdersByUserID(arg0 context.Context, arg1 int64) ([]model.OrderResponse, error) {
 	m.ctrl.T.Helper()
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	model "github.com/Gerfey/gophermart/internal/model"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,8 +35,23 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 	return m.recorder
 }
 
+// GetRoles mocks base method.
+func (m *MockUserService) GetRoles(arg0 context.Context, arg1 int64) ([]model.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRoles", arg0, arg1)
+	ret0, _ := ret[0].([]model.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRoles indicates an expected call of GetRoles.
+func (mr *MockUserServiceMockRecorder) GetRoles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoles", reflect.TypeOf((*MockUserService)(nil).GetRoles), arg0, arg1)
+}
+
 // LoginUser mocks base method.
-func (m *MockUserService) LoginUser(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockUserService) LoginUser(arg0 context.Context, arg1 string, arg2 string) (string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LoginUser", arg0, arg1, arg2)
 	ret0, _ := ret[0].(string)
@@ -50,10 +66,10 @@ func (mr *MockUserServiceMockRecorder) LoginUser(arg0, arg1, arg2 interface{}) *
 }
 
 // ParseToken mocks base method.
-func (m *MockUserService) ParseToken(arg0 string) (int64, error) {
+func (m *MockUserService) ParseToken(arg0 string) (*model.UserIdentity, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ParseToken", arg0)
-	ret0, _ := ret[0].(int64)
+	ret0, _ := ret[0].(*model.UserIdentity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -65,16 +81,16 @@ func (mr *MockUserServiceMockRecorder) ParseToken(arg0 interface{}) *gomock.Call
 }
 
 // RegisterUser mocks base method.
-func (m *MockUserService) RegisterUser(arg0 context.Context, arg1, arg2 string) (string, error) {
+func (m *MockUserService) RegisterUser(arg0 context.Context, arg1 model.UserCredentials) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "RegisterUser", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RegisterUser indicates an expected call of RegisterUser.
-func (mr *MockUserServiceMockRecorder) RegisterUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) RegisterUser(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockUserService)(nil).RegisterUser), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockUserService)(nil).RegisterUser), arg0, arg1)
 }